@@ -2,29 +2,47 @@ package main
 
 import (
 	"GoCodeMentor/internal/handler"
-	"GoCodeMentor/internal/pkg/siliconflow"
+	"GoCodeMentor/internal/pkg/llm"
+	"GoCodeMentor/internal/pkg/runner"
 	"GoCodeMentor/internal/repository"
 	"GoCodeMentor/internal/router"
 	"GoCodeMentor/internal/service"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware 登录验证中间件（支持Cookie或Header）
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware 登录验证中间件（只信任服务端校验过的会话令牌）
+func AuthMiddleware(authSvc service.IAuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 针对所有鉴权路由禁用缓存，防止角色切换或登出后的状态残留
 		c.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
 		c.Header("Pragma", "no-cache")
 		c.Header("Expires", "0")
 
-		// 优先从Cookie获取，其次从Header获取
-		userID, _ := c.Cookie("user_id")
-		if userID == "" {
-			userID = c.GetHeader("X-User-ID")
+		// 优先从Cookie获取，其次从 Authorization: Bearer 头获取（供脚本/工具调用）
+		token, _ := c.Cookie(handler.SessionCookieName)
+		if token == "" {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
 		}
 
-		if userID == "" {
+		// 用户身份和角色均从会话对应的数据库记录中读取，不再信任客户端提交的 user_id / user_role
+		user, err := authSvc.ValidateSession(token)
+		if err != nil {
+			// 清理残留的展示用 Cookie，避免登录页误判为已登录而来回跳转
+			c.SetCookie(handler.SessionCookieName, "", -1, "/", "", false, true)
+			c.SetCookie("user_id", "", -1, "/", "", false, false)
+			c.SetCookie("user_role", "", -1, "/", "", false, false)
+			c.SetCookie("user_name", "", -1, "/", "", false, false)
+
 			// 检查是否是页面请求还是API请求
 			path := c.Request.URL.Path
 			isAPI := len(path) >= 4 && path[:4] == "/api"
@@ -39,15 +57,10 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 获取角色信息
-		userRole, _ := c.Cookie("user_role")
-		if userRole == "" {
-			userRole = c.GetHeader("X-User-Role")
-		}
-
 		// 将用户信息存入上下文，方便后续使用
-		c.Set("userID", userID)
-		c.Set("userRole", userRole)
+		c.Set("userID", user.ID)
+		c.Set("userRole", user.Role)
+		c.Set("userName", user.Name)
 		c.Next()
 	}
 }
@@ -55,15 +68,8 @@ func AuthMiddleware() gin.HandlerFunc {
 // TeacherAuthMiddleware 教师权限验证中间件
 func TeacherAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 先尝试从上下文获取（如果 AuthMiddleware 已经运行）
+		// 角色只能来自 AuthMiddleware 校验后的上下文，因此必须挂在其后
 		userRole := c.GetString("userRole")
-		if userRole == "" {
-			// 否则从Cookie/Header获取
-			userRole, _ = c.Cookie("user_role")
-			if userRole == "" {
-				userRole = c.GetHeader("X-User-Role")
-			}
-		}
 
 		if userRole != "teacher" && userRole != "admin" {
 			path := c.Request.URL.Path
@@ -84,9 +90,6 @@ func TeacherAuthMiddleware() gin.HandlerFunc {
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole := c.GetString("userRole")
-		if userRole == "" {
-			userRole, _ = c.Cookie("user_role")
-		}
 
 		if userRole != "admin" {
 			path := c.Request.URL.Path
@@ -114,27 +117,79 @@ func main() {
 	repos := repository.NewRepositories(db)
 	resourceRepo := repository.NewResourceRepository(db)
 
-	// 3. 初始化 Services
-	client := siliconflow.NewClient()
+	// 3. 初始化模型提供方（按功能选择）
+	llmConfig, err := llm.LoadConfig("./configs/llm_config.yaml")
+	if err != nil {
+		log.Printf("加载模型配置失败，使用默认配置: %v", err)
+		llmConfig = llm.DefaultConfig()
+	}
+	llmRegistry, err := llm.NewRegistry(llmConfig)
+	if err != nil {
+		panic("模型提供方初始化失败：" + err.Error())
+	}
+
+	// 编程题沙箱运行器，找不到 go 工具链时跳过自动测试；无法隔离网络和文件系统时默认拒绝运行学生代码，
+	// 只有设置 RUNNER_ALLOW_UNSANDBOXED=true 时才在无隔离的环境中运行
+	runnerLimits := runner.DefaultLimits()
+	runnerLimits.AllowUnsandboxed = os.Getenv("RUNNER_ALLOW_UNSANDBOXED") == "true"
+	codeRunner, err := runner.New(runnerLimits)
+	if err != nil {
+		log.Printf("代码运行器不可用，编程题将只由 AI 批改: %v", err)
+	} else if !codeRunner.Sandboxed() {
+		if runnerLimits.AllowUnsandboxed {
+			log.Printf("警告: 无法隔离网络和文件系统，已按 RUNNER_ALLOW_UNSANDBOXED 在未隔离的环境中运行学生代码")
+		} else {
+			log.Printf("无法隔离网络和文件系统，编程题不运行自动测试，只由 AI 批改")
+		}
+	}
+
+	// 持久化批改队列，worker 在 Services 初始化之后启动
+	gradingQueue := service.NewGradingQueue(repos.GradingJobRepo, service.DefaultGradingQueueOptions())
+
+	// 4. 初始化 Services
 	userSvc := service.NewUserService(repos.UserRepo)
-	classSvc := service.NewClassService(repos.ClassRepo, repos.UserRepo, repos.AssignmentRepo, repos.SubmissionRepo, client)
-	assignSvc := service.NewAssignmentService(repos.AssignmentRepo, repos.AssignmentClassRepo, repos.QuestionRepo, repos.SubmissionRepo, repos.UserRepo, repos.ClassRepo, client)
+	authSvc := service.NewAuthService(repos.UserSessionRepo, repos.UserRepo)
+	authzSvc := service.NewAuthorizationService(repos.ClassRepo, repos.ClassStaffRepo, repos.ClassMemberRepo, repos.AssignmentRepo, repos.AssignmentClassRepo)
+	classSvc := service.NewClassService(repos.ClassRepo, repos.ClassMemberRepo, repos.ClassStaffRepo, repos.JoinRequestRepo, repos.GroupRepo, repos.UserRepo, repos.AssignmentRepo, repos.SubmissionRepo, llmRegistry.For(llm.FeatureClassAnalysis), authzSvc)
+	// 作业、考试、迟交规则和小组作业服务共用同一组提交相关的仓储
+	submissionStore := service.NewSubmissionStore(repos.AssignmentRepo, repos.AssignmentClassRepo, repos.ExtensionRepo, repos.QuestionRepo, repos.SubmissionRepo, repos.SubmissionVersionRepo, repos.AnswerDraftRepo, repos.UserRepo, repos.ClassRepo, repos.ClassMemberRepo, repos.GroupRepo, gradingQueue)
+	assignSvc := service.NewAssignmentService(submissionStore, repos.ExamSessionRepo, llmRegistry.For(llm.FeatureGeneration), llmRegistry.For(llm.FeatureGrading), codeRunner, repos.GradingJobRepo, repos.SimilarityRepo, repos.GradeAppealRepo, repos.TermRepo, authzSvc)
+	examSvc := service.NewExamService(submissionStore, repos.ExamSessionRepo, authzSvc)
+	latePolicySvc := service.NewLatePolicyService(submissionStore, authzSvc)
+	groupSubmissionSvc := service.NewGroupSubmissionService(submissionStore, authzSvc)
 	feedbackSvc := service.NewFeedbackService(repos.FeedbackRepo)
 	resourceSvc := service.NewResourceService(resourceRepo)
-	sessionSvc := service.NewSessionService(client, repos.SessionRepo, repos.MessageRepo, repos.UserRepo, repos.ClassRepo)
+	similaritySvc := service.NewSimilarityService(repos.SimilarityRepo, repos.AssignmentRepo, repos.QuestionRepo, repos.SubmissionRepo, authzSvc)
+	gradebookSvc := service.NewGradebookService(repos.GradebookRepo, repos.GradeOverrideRepo, repos.GradeAuditRepo, repos.ClassRepo, repos.ClassMemberRepo, repos.UserRepo, repos.AssignmentRepo, repos.AssignmentClassRepo, repos.ExtensionRepo, repos.QuestionRepo, repos.SubmissionRepo, authzSvc)
+	questionBankSvc := service.NewQuestionBankService(repos.BankQuestionRepo, repos.QuestionRepo, repos.SubmissionRepo, authzSvc)
+	courseSvc := service.NewCourseService(repos.CourseRepo, repos.TermRepo, repos.ClassRepo, authzSvc)
+	sessionSvc := service.NewSessionService(llmRegistry.For(llm.FeatureChat), repos.SessionRepo, repos.MessageRepo, repos.UserRepo, repos.ClassMemberRepo, authzSvc)
 
-	// 4. 初始化 Handlers
-	userHandler := handler.NewUserHandler(userSvc)
-	classHandler := handler.NewClassHandler(classSvc, userSvc, assignSvc)
-	assignmentHandler := handler.NewAssignmentHandler(assignSvc, userSvc)
+	if err := gradingQueue.Start(assignSvc.GradeSubmission); err != nil {
+		panic("批改队列启动失败：" + err.Error())
+	}
+	if n, err := assignSvc.EnqueueOrphanedSubmissions(""); err != nil {
+		log.Printf("补建批改任务失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已为 %d 份缺少批改任务的提交补建任务", n)
+	}
+
+	// 5. 初始化 Handlers
+	userHandler := handler.NewUserHandler(userSvc, authSvc)
+	classHandler := handler.NewClassHandler(classSvc, userSvc, assignSvc, authzSvc)
+	assignmentHandler := handler.NewAssignmentHandler(assignSvc, examSvc, latePolicySvc, groupSubmissionSvc, userSvc, classSvc, authzSvc)
 	feedbackHandler := handler.NewFeedbackHandler(feedbackSvc)
 	resourceHandler := handler.NewResourceHandler(resourceSvc)
 	sessionHandler := handler.NewSessionHandler(sessionSvc)
 	pageHandler := handler.NewPageHandler()
-	excelHandler := handler.NewExcelHandler(classSvc, userSvc)
+	excelHandler := handler.NewExcelHandler(classSvc, userSvc, gradebookSvc, authzSvc)
 	wisdomGraphHandler := handler.NewWisdomGraphHandler(db)
+	questionBankHandler := handler.NewQuestionBankHandler(questionBankSvc)
+	similarityHandler := handler.NewSimilarityHandler(similaritySvc)
+	gradebookHandler := handler.NewGradebookHandler(gradebookSvc)
+	courseHandler := handler.NewCourseHandler(courseSvc)
 
-	// 5. 初始化 Gin 引擎并设置路由
+	// 6. 初始化 Gin 引擎并设置路由
 	r := gin.Default()
 	router.Setup(
 		r,
@@ -147,11 +202,74 @@ func main() {
 		pageHandler,
 		excelHandler,
 		wisdomGraphHandler,
-		AuthMiddleware(),
+		questionBankHandler,
+		similarityHandler,
+		gradebookHandler,
+		courseHandler,
+		AuthMiddleware(authSvc),
 		TeacherAuthMiddleware(),
 		AdminAuthMiddleware(),
 	)
 
-	// 6. 启动服务
-	r.Run(":8082")
+	// 7. 启动服务，收到退出信号后停止接收请求并等待批改任务执行完
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":8082", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("服务启动失败: %v", err)
+		}
+	}()
+
+	// 定期自动提交已超时的考试（学生关闭页面后不会再主动交卷）
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if count, err := examSvc.AutoSubmitExpiredExams(); err != nil {
+					log.Printf("自动提交超时考试失败: %v", err)
+				} else if count > 0 {
+					log.Printf("已自动提交 %d 份超时考试", count)
+				}
+			}
+		}
+	}()
+
+	// 定期按发放时间和关闭时间发放、关闭作业
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if count, err := assignSvc.ApplyPublishSchedules(); err != nil {
+					log.Printf("更新作业发放状态失败: %v", err)
+				} else if count > 0 {
+					log.Printf("已更新 %d 个班级的作业发放状态", count)
+				}
+			}
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("正在关闭服务...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP 服务关闭失败: %v", err)
+	}
+	if err := gradingQueue.Shutdown(shutdownCtx); err != nil {
+		log.Printf("批改队列未能在超时前完成，剩余任务将在下次启动后继续: %v", err)
+	}
+	if err := similaritySvc.Shutdown(shutdownCtx); err != nil {
+		log.Printf("相似度检测未能在超时前停止: %v", err)
+	}
 }
@@ -0,0 +1,62 @@
+package dto
+
+import "time"
+
+// AssignmentRequest defines the request body for creating or updating an assignment by hand.
+type AssignmentRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	Type        string `json:"type"`     // code, choice, fill, mixed
+	Category    string `json:"category"` // homework, quiz, exam; empty keeps the current category
+}
+
+// QuestionRequest defines the request body for creating or updating a question.
+type QuestionRequest struct {
+	Type    string   `json:"type" binding:"required"` // choice, fill, code
+	Content string   `json:"content" binding:"required"`
+	Options []string `json:"options"`
+	Answer  string   `json:"answer"`
+	Score   int      `json:"score"`
+}
+
+// ReorderQuestionsRequest defines the request body for reordering the questions of an assignment.
+type ReorderQuestionsRequest struct {
+	QuestionIDs []string `json:"question_ids" binding:"required"`
+}
+
+// LatePolicyRequest defines the request body for setting the late submission policy of an assignment in a class.
+type LatePolicyRequest struct {
+	AllowLate         bool       `json:"allow_late"`
+	GraceMinutes      int        `json:"grace_minutes"`
+	LatePenaltyPerDay int        `json:"late_penalty_per_day"` // percent
+	HardCutoff        *time.Time `json:"hard_cutoff"`
+}
+
+// ExtensionRequest defines the request body for granting a student a deadline extension.
+type ExtensionRequest struct {
+	Deadline time.Time `json:"deadline" binding:"required"`
+	Reason   string    `json:"reason"`
+}
+
+// ExamSettingsRequest defines the request body for configuring the exam mode of an assignment.
+type ExamSettingsRequest struct {
+	ExamMode         bool `json:"exam_mode"`
+	DurationMinutes  int  `json:"duration_minutes"`
+	ShuffleQuestions bool `json:"shuffle_questions"`
+	ShuffleOptions   bool `json:"shuffle_options"`
+}
+
+// GradeAppealRequest defines the request body for a student appealing the score of a question.
+type GradeAppealRequest struct {
+	QuestionID string `json:"question_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+// AppealResolutionRequest defines the request body for a teacher resolving a grade appeal.
+// Score is required when accepting; Comment is required when rejecting.
+// DiscardReview, when reevaluating, drops the teacher's earlier score overrides.
+type AppealResolutionRequest struct {
+	Score         *int   `json:"score"`
+	Comment       string `json:"comment"`
+	DiscardReview bool   `json:"discard_review"`
+}
@@ -0,0 +1,55 @@
+package dto
+
+import "time"
+
+// InviteCodeSettingsRequest defines the request body for configuring a class invite code.
+type InviteCodeSettingsRequest struct {
+	ExpiresAt       *time.Time `json:"expires_at"` // nil means the code never expires
+	MaxUses         int        `json:"max_uses"`   // 0 means unlimited
+	RequireApproval bool       `json:"require_approval"`
+}
+
+// CourseRequest defines the request body for creating or updating a course.
+type CourseRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// TermRequest defines the request body for creating a term.
+type TermRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+// ClassCourseRequest defines the request body for placing a class in a course and term; nil clears the field.
+type ClassCourseRequest struct {
+	CourseID *string `json:"course_id"`
+	TermID   *string `json:"term_id"`
+}
+
+// CloneAssignmentsRequest defines the request body for copying a class's assignments into another class.
+type CloneAssignmentsRequest struct {
+	TargetClassID string   `json:"target_class_id" binding:"required"`
+	AssignmentIDs []string `json:"assignment_ids"` // empty copies every assignment published to the source class
+	ShiftDays     *int     `json:"shift_days"`     // nil derives the shift from the two classes' term start dates
+}
+
+// ClassGroupRequest defines the request body for creating or renaming a class group.
+type ClassGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// GroupMemberRequest defines the request body for moving a student into a group; an empty group removes the student from their group.
+type GroupMemberRequest struct {
+	GroupID string `json:"group_id"`
+}
+
+// GenerateGroupsRequest defines the request body for splitting a class into groups automatically.
+// Exactly one of GroupCount and GroupSize should be set; the existing groups are replaced.
+type GenerateGroupsRequest struct {
+	Mode       string `json:"mode"`        // random (default) or balanced by past scores
+	GroupCount int    `json:"group_count"` // number of groups to create
+	GroupSize  int    `json:"group_size"`  // students per group, used when group_count is 0
+}
@@ -0,0 +1,29 @@
+package dto
+
+// GradeCategory defines the weight of an assignment category in the final grade.
+type GradeCategory struct {
+	Name       string  `json:"name"`        // homework, quiz, exam
+	Weight     float64 `json:"weight"`      // relative weight, normalised over categories that have scores
+	DropLowest int     `json:"drop_lowest"` // number of lowest scores dropped in this category
+}
+
+// LetterGrade maps a minimum final percentage to a letter grade.
+type LetterGrade struct {
+	Letter string  `json:"letter"`
+	Min    float64 `json:"min"`
+}
+
+// GradebookSettingsRequest defines the request body for configuring the gradebook of a class.
+type GradebookSettingsRequest struct {
+	Categories    []GradeCategory `json:"categories"`
+	MissingAsZero bool            `json:"missing_as_zero"`
+	LetterScale   []LetterGrade   `json:"letter_scale"`
+	// AssignmentCategories optionally moves assignments into categories: assignment ID -> category
+	AssignmentCategories map[string]string `json:"assignment_categories"`
+}
+
+// GradeOverrideRequest defines the request body for manually overriding a gradebook cell.
+type GradeOverrideRequest struct {
+	Score  *float64 `json:"score"` // null removes the override and restores the submission score
+	Reason string   `json:"reason"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+// BankQuestionRequest defines the request body for creating or updating a question bank item.
+type BankQuestionRequest struct {
+	QuestionRequest
+	KnowledgePointID *uint  `json:"knowledge_point_id"`
+	Difficulty       string `json:"difficulty"` // easy, medium, hard
+	Shared           bool   `json:"shared"`
+}
+
+// BankQuestionQuery defines the filters for searching the question bank.
+type BankQuestionQuery struct {
+	Type             string `form:"type"`
+	Difficulty       string `form:"difficulty"`
+	KnowledgePointID *uint  `form:"knowledge_point_id"`
+	Keyword          string `form:"keyword"`
+	Mine             bool   `form:"mine"`
+}
+
+// SaveToBankRequest defines the request body for saving assignment questions into the question bank.
+type SaveToBankRequest struct {
+	QuestionIDs      []string `json:"question_ids"` // empty means all questions of the assignment
+	KnowledgePointID *uint    `json:"knowledge_point_id"`
+	Difficulty       string   `json:"difficulty"`
+	Shared           bool     `json:"shared"`
+}
+
+// BankPickRule describes how many bank items of a kind to draw at random, e.g. 5 medium choice questions on goroutines.
+type BankPickRule struct {
+	Type             string `json:"type"`
+	KnowledgePointID *uint  `json:"knowledge_point_id"`
+	Difficulty       string `json:"difficulty"`
+	Count            int    `json:"count"`
+}
+
+// AddFromBankRequest defines the request body for adding bank items to an assignment, picked by ID and/or by rules.
+type AddFromBankRequest struct {
+	ItemIDs []string       `json:"item_ids"`
+	Rules   []BankPickRule `json:"rules"`
+}
@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/pkg/runner"
 	"GoCodeMentor/internal/service"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,13 +18,26 @@ import (
 
 // AssignmentHandler handles assignment-related requests.
 type AssignmentHandler struct {
-	assignSvc service.IAssignmentService
-	userSvc   service.IUserService
+	assignSvc     service.IAssignmentService
+	examSvc       service.IExamService
+	latePolicySvc service.ILatePolicyService
+	groupSvc      service.IGroupSubmissionService
+	userSvc       service.IUserService
+	classSvc      service.IClassService
+	authz         service.IAuthorizationService
 }
 
 // NewAssignmentHandler creates a new AssignmentHandler.
-func NewAssignmentHandler(assignSvc service.IAssignmentService, userSvc service.IUserService) *AssignmentHandler {
-	return &AssignmentHandler{assignSvc: assignSvc, userSvc: userSvc}
+func NewAssignmentHandler(assignSvc service.IAssignmentService, examSvc service.IExamService, latePolicySvc service.ILatePolicyService, groupSvc service.IGroupSubmissionService, userSvc service.IUserService, classSvc service.IClassService, authz service.IAuthorizationService) *AssignmentHandler {
+	return &AssignmentHandler{
+		assignSvc:     assignSvc,
+		examSvc:       examSvc,
+		latePolicySvc: latePolicySvc,
+		groupSvc:      groupSvc,
+		userSvc:       userSvc,
+		classSvc:      classSvc,
+		authz:         authz,
+	}
 }
 
 // GenerateAssignmentByAI handles the generation of an assignment by AI.
@@ -51,6 +69,153 @@ func (h *AssignmentHandler) GenerateAssignmentByAI(c *gin.Context) {
 	c.JSON(200, assign)
 }
 
+// CreateAssignment handles a teacher creating an assignment by hand.
+func (h *AssignmentHandler) CreateAssignment(c *gin.Context) {
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以创建作业"})
+		return
+	}
+
+	var req dto.AssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	assign, err := h.assignSvc.CreateAssignment(userID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, assign)
+}
+
+// UpdateAssignment handles a teacher editing the title, description or type of an assignment.
+func (h *AssignmentHandler) UpdateAssignment(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以修改作业"})
+		return
+	}
+
+	var req dto.AssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	assign, err := h.assignSvc.UpdateAssignment(userID, assignID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, assign)
+}
+
+// CreateQuestion handles a teacher adding a question to an assignment.
+func (h *AssignmentHandler) CreateQuestion(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以添加题目"})
+		return
+	}
+
+	var req dto.QuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	question, err := h.assignSvc.CreateQuestion(userID, assignID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, question)
+}
+
+// UpdateQuestion handles a teacher editing a question.
+func (h *AssignmentHandler) UpdateQuestion(c *gin.Context) {
+	questionID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以修改题目"})
+		return
+	}
+
+	var req dto.QuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	question, err := h.assignSvc.UpdateQuestion(userID, questionID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, question)
+}
+
+// DeleteQuestion handles a teacher deleting a question.
+func (h *AssignmentHandler) DeleteQuestion(c *gin.Context) {
+	questionID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以删除题目"})
+		return
+	}
+
+	if err := h.assignSvc.DeleteQuestion(userID, questionID); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "题目删除成功"})
+}
+
+// ReorderQuestions handles a teacher changing the order of the questions in an assignment.
+func (h *AssignmentHandler) ReorderQuestions(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以调整题目顺序"})
+		return
+	}
+
+	var req dto.ReorderQuestionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.assignSvc.ReorderQuestions(userID, assignID, req.QuestionIDs); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "题目顺序已更新"})
+}
+
 // GetAssignments handles getting a list of assignments.
 func (h *AssignmentHandler) GetAssignments(c *gin.Context) {
 	userID := c.GetString("userID")
@@ -64,16 +229,7 @@ func (h *AssignmentHandler) GetAssignments(c *gin.Context) {
 		}
 		c.JSON(200, assignments)
 	} else {
-		user, err := h.userSvc.GetByID(userID)
-		if err != nil {
-			c.JSON(200, []interface{}{})
-			return
-		}
-		if user.ClassID == nil {
-			c.JSON(200, []interface{}{})
-			return
-		}
-		assignments, err := h.assignSvc.GetAssignmentsByClass(*user.ClassID)
+		assignments, err := h.assignSvc.GetAssignmentsForStudent(userID)
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
@@ -94,8 +250,11 @@ func (h *AssignmentHandler) PublishAssignment(c *gin.Context) {
 	}
 
 	var req struct {
-		ClassID  string `json:"class_id"`
-		Deadline string `json:"deadline"`
+		ClassID   string     `json:"class_id"`
+		Deadline  string     `json:"deadline"`
+		ReleaseAt *time.Time `json:"release_at"` // 定时发放时间，为空时立即发放
+		ClosesAt  *time.Time `json:"closes_at"`  // 关闭时间，为空时不关闭
+		GroupWork bool       `json:"group_work"` // 是否作为小组作业发布
 	}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": "参数错误"})
@@ -106,6 +265,14 @@ func (h *AssignmentHandler) PublishAssignment(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "班级ID不能为空"})
 		return
 	}
+	if _, err := h.authz.AuthorizeClass(userID, req.ClassID, service.CapPublish); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.authz.AuthorizeAssignment(userID, assignID, service.CapPublish); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
 	students, err := h.userSvc.GetStudentsByClassID(req.ClassID)
 	if err != nil {
@@ -151,14 +318,37 @@ func (h *AssignmentHandler) PublishAssignment(c *gin.Context) {
 	endOfDay := time.Date(deadlineTime.Year(), deadlineTime.Month(), deadlineTime.Day(), 23, 59, 59, 0, deadlineTime.Location())
 	deadline := &endOfDay
 
-	err = h.assignSvc.PublishAssignment(assignID, req.ClassID, deadline)
+	err = h.assignSvc.PublishAssignment(assignID, req.ClassID, deadline, req.ReleaseAt, req.ClosesAt, req.GroupWork)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	if req.ReleaseAt != nil && req.ReleaseAt.After(now) {
+		c.JSON(200, gin.H{"message": "已设置定时发放"})
+		return
+	}
 	c.JSON(200, gin.H{"message": "发布成功"})
 }
 
+// UnpublishAssignment handles a teacher withdrawing an assignment from one class.
+func (h *AssignmentHandler) UnpublishAssignment(c *gin.Context) {
+	assignID := c.Param("id")
+	classID := c.Param("classId")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以撤回作业"})
+		return
+	}
+
+	if err := h.assignSvc.UnpublishAssignment(userID, assignID, classID); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "已从该班级撤回"})
+}
+
 // GetAssignmentDetail handles getting the details of an assignment.
 func (h *AssignmentHandler) GetAssignmentDetail(c *gin.Context) {
 	id := c.Param("id")
@@ -167,6 +357,17 @@ func (h *AssignmentHandler) GetAssignmentDetail(c *gin.Context) {
 		c.JSON(404, gin.H{"error": "作业不存在"})
 		return
 	}
+	if c.GetString("userRole") != "teacher" {
+		// 考试的题目只能在开始考试后通过考试接口获取
+		if assign.ExamMode {
+			questions = nil
+		}
+		c.JSON(200, gin.H{
+			"assignment": assign,
+			"questions":  studentQuestions(questions, false),
+		})
+		return
+	}
 	c.JSON(200, gin.H{
 		"assignment": assign,
 		"questions":  questions,
@@ -208,38 +409,95 @@ func (h *AssignmentHandler) SubmitAssignment(c *gin.Context) {
 		return
 	}
 
-	answers := make(map[string]string)
-	for k, v := range req.Answers {
-		if str, ok := v.(string); ok {
-			answers[k] = str
-		} else {
-			answers[k] = fmt.Sprintf("%v", v)
-		}
+	answers := stringifyAnswers(req.Answers)
+
+	// 截止时间、迟交规则和个人延期由 SubmitAssignment 按学生所在班级检查
+	if _, _, err := h.assignSvc.GetAssignmentDetail(assignID); err != nil {
+		c.JSON(404, gin.H{"error": "作业不存在"})
+		return
 	}
 
-	// 检查作业是否已过期
-	assign, _, err := h.assignSvc.GetAssignmentDetail(assignID)
+	submissionID, err := h.assignSvc.SubmitAssignment(assignID, userID, req.StudentName, answers, req.Code)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "作业不存在"})
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 检查截止时间
-	if assign.Deadline != nil {
-		now := time.Now()
-		if now.After(*assign.Deadline) {
-			c.JSON(400, gin.H{"error": "作业提交已截止，无法提交"})
-			return
-		}
+	c.JSON(200, gin.H{"id": submissionID, "message": "提交成功"})
+}
+
+// SaveDraft handles a student autosaving the answers of an assignment they are working on.
+func (h *AssignmentHandler) SaveDraft(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "只有学生可以保存草稿"})
+		return
 	}
 
-	submissionID, err := h.assignSvc.SubmitAssignment(assignID, userID, req.StudentName, answers, req.Code)
+	var req struct {
+		Answers map[string]interface{} `json:"answers"`
+		Code    string                 `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	draft, err := h.assignSvc.SaveDraft(assignID, userID, stringifyAnswers(req.Answers), req.Code)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "已自动保存", "saved_at": draft.UpdatedAt})
+}
+
+// GetDraft handles a student restoring the autosaved answers of an assignment.
+func (h *AssignmentHandler) GetDraft(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "只有学生可以查看草稿"})
+		return
+	}
+
+	draft, err := h.assignSvc.GetDraft(assignID, userID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	if draft == nil {
+		c.JSON(200, gin.H{"exists": false})
+		return
+	}
 
-	c.JSON(200, gin.H{"id": submissionID, "message": "提交成功"})
+	var answers map[string]interface{}
+	json.Unmarshal([]byte(draft.Answers), &answers)
+	c.JSON(200, gin.H{
+		"exists":   true,
+		"answers":  answers,
+		"code":     draft.CodeContent,
+		"saved_at": draft.UpdatedAt,
+	})
+}
+
+// draftInfo 草稿晚于最近一次提交时视为作答中，返回给前端的草稿摘要；否则返回 nil
+func draftInfo(draft *model.AnswerDraft, submittedAt *time.Time) gin.H {
+	if draft == nil || (submittedAt != nil && !draft.UpdatedAt.After(*submittedAt)) {
+		return nil
+	}
+	var answers map[string]interface{}
+	json.Unmarshal([]byte(draft.Answers), &answers)
+	return gin.H{
+		"answered": len(answers),
+		"has_code": draft.CodeContent != "",
+		"saved_at": draft.UpdatedAt,
+	}
 }
 
 // GetStudentAssignments handles getting all assignments for a specific student (teacher view).
@@ -259,23 +517,44 @@ func (h *AssignmentHandler) GetStudentAssignments(c *gin.Context) {
 		return
 	}
 
-	if student.ClassID == nil {
+	// 学生可能同时在多个班级，教师只能看到自己有权查看的班级和作业
+	studentClasses, err := h.classSvc.GetStudentClasses(studentID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取学生班级失败"})
+		return
+	}
+	classes := make([]model.Class, 0, len(studentClasses))
+	for _, class := range studentClasses {
+		if _, err := h.authz.AuthorizeClass(userID, class.ID, service.CapViewClass); err == nil {
+			classes = append(classes, class)
+		}
+	}
+
+	if len(classes) == 0 {
 		c.JSON(200, gin.H{
 			"student":     student,
+			"classes":     classes,
 			"assignments": []interface{}{},
 		})
 		return
 	}
 
-	assignments, err := h.assignSvc.GetAssignmentsByClass(*student.ClassID)
+	allAssignments, err := h.assignSvc.GetAssignmentsForStudent(studentID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	var assignments []model.Assignment
+	for _, assign := range allAssignments {
+		if _, err := h.authz.AuthorizeAssignment(userID, assign.ID, service.CapViewClass); err == nil {
+			assignments = append(assignments, assign)
+		}
+	}
 
 	if len(assignments) == 0 {
 		c.JSON(200, gin.H{
 			"student":     student,
+			"classes":     classes,
 			"assignments": []interface{}{},
 		})
 		return
@@ -292,9 +571,23 @@ func (h *AssignmentHandler) GetStudentAssignments(c *gin.Context) {
 		return
 	}
 
+	drafts, err := h.assignSvc.GetDraftsByStudentAndAssignments(studentID, assignmentIDs)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取答案草稿失败"})
+		return
+	}
+	draftMap := make(map[string]*model.AnswerDraft, len(drafts))
+	for i := range drafts {
+		draftMap[drafts[i].AssignmentID] = &drafts[i]
+	}
+
 	// 将提交记录转为 map 方便快速查找: map[assignmentID] -> submission
 	submissionMap := make(map[string]interface{}) // 使用 interface{} 以便存储完整的 submissionInfo
+	submittedAt := make(map[string]*time.Time)
 	for _, sub := range submissions {
+		updatedAt := sub.UpdatedAt
+		submittedAt[sub.AssignmentID] = &updatedAt
+
 		var answers map[string]interface{}
 		var detailedScore map[string]interface{}
 		json.Unmarshal([]byte(sub.Answers), &answers)
@@ -319,9 +612,15 @@ func (h *AssignmentHandler) GetStudentAssignments(c *gin.Context) {
 	for _, assign := range assignments {
 		submissionInfo, found := submissionMap[assign.ID]
 
+		// 只有草稿、还没有提交的作业显示为作答中，草稿不参与批改
+		draft := draftInfo(draftMap[assign.ID], submittedAt[assign.ID])
+
 		var status string
 		if !found {
 			status = "未提交"
+			if draft != nil {
+				status = "作答中"
+			}
 			submissionInfo = gin.H{}
 		} else {
 			subStatus := submissionInfo.(gin.H)["status"].(string)
@@ -336,11 +635,13 @@ func (h *AssignmentHandler) GetStudentAssignments(c *gin.Context) {
 			"assignment": assign,
 			"status":     status,
 			"submission": submissionInfo,
+			"draft":      draft,
 		})
 	}
 
 	c.JSON(200, gin.H{
 		"student":     student,
+		"classes":     classes,
 		"assignments": assignmentDetails,
 	})
 }
@@ -361,30 +662,56 @@ func (h *AssignmentHandler) GetMyAssignments(c *gin.Context) {
 		return
 	}
 
-	if student.ClassID == nil {
+	classes, err := h.classSvc.GetStudentClasses(studentID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取班级失败"})
+		return
+	}
+
+	if len(classes) == 0 {
 		c.JSON(200, gin.H{
 			"student":     student,
+			"classes":     classes,
 			"assignments": []interface{}{},
 		})
 		return
 	}
 
-	assignments, err := h.assignSvc.GetAssignmentsByClass(*student.ClassID)
+	assignments, err := h.assignSvc.GetAssignmentsForStudent(studentID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
+	assignmentIDs := make([]string, len(assignments))
+	for i, a := range assignments {
+		assignmentIDs[i] = a.ID
+	}
+	drafts, err := h.assignSvc.GetDraftsByStudentAndAssignments(studentID, assignmentIDs)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取答案草稿失败"})
+		return
+	}
+	draftMap := make(map[string]*model.AnswerDraft, len(drafts))
+	for i := range drafts {
+		draftMap[drafts[i].AssignmentID] = &drafts[i]
+	}
+
 	var assignmentDetails []gin.H
 	for _, assign := range assignments {
 		submission, err := h.assignSvc.GetSubmissionByAssignmentAndStudent(assign.ID, studentID)
 
 		var status string
 		var submissionInfo gin.H
+		var draft gin.H
 		if err != nil || submission == nil {
 			status = "未提交"
+			if draft = draftInfo(draftMap[assign.ID], nil); draft != nil {
+				status = "作答中"
+			}
 			submissionInfo = gin.H{}
 		} else {
+			draft = draftInfo(draftMap[assign.ID], &submission.UpdatedAt)
 			if submission.Status == "graded" {
 				status = "已查看"
 			} else {
@@ -413,11 +740,13 @@ func (h *AssignmentHandler) GetMyAssignments(c *gin.Context) {
 			"assignment": assign,
 			"status":     status,
 			"submission": submissionInfo,
+			"draft":      draft,
 		})
 	}
 
 	c.JSON(200, gin.H{
 		"student":     student,
+		"classes":     classes,
 		"assignments": assignmentDetails,
 	})
 }
@@ -434,11 +763,17 @@ func (h *AssignmentHandler) GetAssignmentSubmissionForStudent(c *gin.Context) {
 		return
 	}
 
-	// 权限检查：只有教师或者学生本人可以查看
+	// 权限检查：只有作业所在班级的教学人员或者学生本人可以查看
 	if userRole != "teacher" && userID != studentID {
 		c.JSON(403, gin.H{"error": "无权查看"})
 		return
 	}
+	if userRole == "teacher" {
+		if _, err := h.authz.AuthorizeAssignment(userID, assignID, service.CapViewClass); err != nil {
+			c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	assign, questions, err := h.assignSvc.GetAssignmentDetail(assignID)
 	if err != nil {
@@ -450,6 +785,10 @@ func (h *AssignmentHandler) GetAssignmentSubmissionForStudent(c *gin.Context) {
 
 	var submissionInfo gin.H
 	if err != nil || submission == nil {
+		// 考试交卷前学生不能通过这里看到题目和答案
+		if assign.ExamMode && userRole != "teacher" {
+			questions = nil
+		}
 		submissionInfo = gin.H{
 			"submitted": false,
 			"answers":   gin.H{},
@@ -461,13 +800,19 @@ func (h *AssignmentHandler) GetAssignmentSubmissionForStudent(c *gin.Context) {
 		var detailedScore map[string]interface{}
 		var questionScores map[string]interface{}
 		var questionFeedback map[string]interface{}
+		var staticAnalysis map[string]interface{}
 
 		json.Unmarshal([]byte(submission.Answers), &answers)
 		json.Unmarshal([]byte(submission.DetailedScore), &detailedScore)
 		json.Unmarshal([]byte(submission.QuestionScores), &questionScores)
 		json.Unmarshal([]byte(submission.QuestionFeedback), &questionFeedback)
+		runResults := runResultsJSON(submission.RunResults, userRole != "teacher")
+		json.Unmarshal([]byte(submission.StaticAnalysis), &staticAnalysis)
+		var aiQuestionScores map[string]interface{}
+		json.Unmarshal([]byte(submission.AIQuestionScores), &aiQuestionScores)
 
 		submissionInfo = gin.H{
+			"id":                submission.ID,
 			"submitted":         true,
 			"student_name":      submission.StudentName,
 			"answers":           answers,
@@ -478,12 +823,36 @@ func (h *AssignmentHandler) GetAssignmentSubmissionForStudent(c *gin.Context) {
 			"detailed_score":    detailedScore,
 			"question_scores":   questionScores,
 			"question_feedback": questionFeedback,
+			"run_results":       runResults,
+			"static_analysis":   staticAnalysis,
+			"ai_scores":         aiQuestionScores,
+			"ai_raw_score":      submission.AIRawScore,
+			"teacher_reviewed":  submission.TeacherReviewed,
+			"reviewed_at":       submission.ReviewedAt,
+			"attempts":          submission.Attempts,
+			"counted_attempt":   submission.CountedAttempt,
+			"raw_score":         submission.RawScore,
+			"is_late":           submission.IsLate,
+			"late_minutes":      submission.LateMinutes,
+			"late_penalty":      submission.LatePenalty,
 			"status":            submission.Status,
 			"created_at":        submission.CreatedAt,
 			"updated_at":        submission.UpdatedAt,
+			"group_id":          submission.GroupID,
+			"group_score":       submission.GroupScore,
+			"score_adjustment":  submission.ScoreAdjustment,
 		}
 	}
 
+	if userRole != "teacher" {
+		// 学生提交后可以看到参考答案，但看不到测试代码、测试用例和判分规则
+		c.JSON(200, gin.H{
+			"assignment": assign,
+			"questions":  studentQuestions(questions, err == nil && submission != nil),
+			"submission": submissionInfo,
+		})
+		return
+	}
 	c.JSON(200, gin.H{
 		"assignment": assign,
 		"questions":  questions,
@@ -501,6 +870,9 @@ func (h *AssignmentHandler) UpdateSubmissionScore(c *gin.Context) {
 		c.JSON(403, gin.H{"error": "只有教师可以修改分数"})
 		return
 	}
+	if !h.authorizeSubmission(c, submissionID, service.CapGrade) {
+		return
+	}
 
 	var req struct {
 		Score int `json:"score"`
@@ -510,14 +882,8 @@ func (h *AssignmentHandler) UpdateSubmissionScore(c *gin.Context) {
 		return
 	}
 
-	if req.Score < 0 || req.Score > 100 {
-		c.JSON(400, gin.H{"error": "分数必须在0-100之间"})
-		return
-	}
-
-	err := h.assignSvc.UpdateSubmissionScore(submissionID, req.Score)
-	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := h.assignSvc.UpdateSubmissionScore(userID, submissionID, req.Score); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -534,6 +900,9 @@ func (h *AssignmentHandler) UpdateTeacherFeedback(c *gin.Context) {
 		c.JSON(403, gin.H{"error": "只有教师可以添加批注"})
 		return
 	}
+	if !h.authorizeSubmission(c, submissionID, service.CapGrade) {
+		return
+	}
 
 	var req struct {
 		Feedback string `json:"feedback"`
@@ -552,46 +921,835 @@ func (h *AssignmentHandler) UpdateTeacherFeedback(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "批注更新成功"})
 }
 
-// RegradeSubmission handles triggering AI regrading for a submission.
-func (h *AssignmentHandler) RegradeSubmission(c *gin.Context) {
+// UpdateQuestionScore handles a teacher adjusting the score of one question in a submission.
+func (h *AssignmentHandler) UpdateQuestionScore(c *gin.Context) {
 	submissionID := c.Param("id")
+	questionID := c.Param("questionId")
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
 	if userID == "" || userRole != "teacher" {
-		c.JSON(403, gin.H{"error": "只有教师可以重新批改"})
+		c.JSON(403, gin.H{"error": "只有教师可以修改分数"})
 		return
 	}
 
-	err := h.assignSvc.RegradeSubmission(submissionID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	var req struct {
+		Score *int `json:"score"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Score == nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "重新批改已触发，请稍后查看结果"})
+	if err := h.assignSvc.UpdateQuestionScore(userID, submissionID, questionID, *req.Score); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondQuestionGrade(c, submissionID, questionID)
 }
 
-// GetPublishedClasses handles getting the list of classes an assignment is published to.
-func (h *AssignmentHandler) GetPublishedClasses(c *gin.Context) {
-	assignID := c.Param("id")
+// UpdateQuestionFeedback handles a teacher changing the comment on one question in a submission.
+func (h *AssignmentHandler) UpdateQuestionFeedback(c *gin.Context) {
+	submissionID := c.Param("id")
+	questionID := c.Param("questionId")
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
 	if userID == "" || userRole != "teacher" {
-		c.JSON(403, gin.H{"error": "只有教师可以查看发布信息"})
+		c.JSON(403, gin.H{"error": "只有教师可以添加批注"})
 		return
 	}
 
-	// 验证教师是否有权查看此作业的发布信息
-	assign, _, err := h.assignSvc.GetAssignmentDetail(assignID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "作业不存在"})
-		return
+	var req struct {
+		Feedback string `json:"feedback"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.assignSvc.UpdateQuestionFeedback(userID, submissionID, questionID, req.Feedback); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondQuestionGrade(c, submissionID, questionID)
+}
+
+// GetQuestionGrade handles fetching the score and comment of one question in a submission.
+func (h *AssignmentHandler) GetQuestionGrade(c *gin.Context) {
+	submissionID := c.Param("id")
+	if !h.canViewSubmission(c, submissionID) {
+		return
+	}
+	h.respondQuestionGrade(c, submissionID, c.Param("questionId"))
+}
+
+// respondQuestionGrade writes a question's current score and comment, the AI score it replaced
+// and the submission totals, so clients can refresh without reloading the whole submission.
+func (h *AssignmentHandler) respondQuestionGrade(c *gin.Context, submissionID, questionID string) {
+	score, err := h.assignSvc.GetQuestionScore(submissionID, questionID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	feedback, err := h.assignSvc.GetQuestionFeedback(submissionID, questionID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	submission, err := h.assignSvc.GetSubmission(submissionID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "提交记录不存在"})
+		return
+	}
+
+	var aiScores map[string]int
+	json.Unmarshal([]byte(submission.AIQuestionScores), &aiScores)
+	var aiScore *int
+	if original, ok := aiScores[questionID]; ok {
+		aiScore = &original
+	}
+
+	c.JSON(200, gin.H{
+		"question_id":      questionID,
+		"score":            score,
+		"feedback":         feedback,
+		"ai_score":         aiScore,
+		"total_score":      submission.TotalScore,
+		"raw_score":        submission.RawScore,
+		"teacher_reviewed": submission.TeacherReviewed,
+		"reviewed_at":      submission.ReviewedAt,
+	})
+}
+
+// RegradeSubmission handles triggering AI regrading for a submission.
+// Teacher score overrides are kept unless the discard_review query parameter is true.
+func (h *AssignmentHandler) RegradeSubmission(c *gin.Context) {
+	submissionID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以重新批改"})
+		return
+	}
+	if !h.authorizeSubmission(c, submissionID, service.CapGrade) {
+		return
+	}
+
+	err := h.assignSvc.RegradeSubmission(submissionID, c.Query("discard_review") == "true")
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "重新批改已触发，请稍后查看结果"})
+}
+
+// SetScoreAdjustment handles adjusting one member's score on a group submission.
+func (h *AssignmentHandler) SetScoreAdjustment(c *gin.Context) {
+	var req struct {
+		Adjustment int `json:"adjustment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	submission, err := h.groupSvc.SetScoreAdjustment(c.GetString("userID"), c.Param("id"), req.Adjustment)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"id":               submission.ID,
+		"group_score":      submission.GroupScore,
+		"score_adjustment": submission.ScoreAdjustment,
+		"total_score":      submission.TotalScore,
+	})
+}
+
+// GetGroupMembersSubmissions handles listing every member's copy of a group submission.
+func (h *AssignmentHandler) GetGroupMembersSubmissions(c *gin.Context) {
+	copies, err := h.groupSvc.GetGroupMembersSubmissions(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(copies))
+	for _, sub := range copies {
+		result = append(result, gin.H{
+			"id":               sub.ID,
+			"student_id":       sub.StudentID,
+			"student_name":     sub.StudentName,
+			"group_score":      sub.GroupScore,
+			"score_adjustment": sub.ScoreAdjustment,
+			"total_score":      sub.TotalScore,
+			"status":           sub.Status,
+		})
+	}
+	c.JSON(200, result)
+}
+
+// GetSubmissionVersions handles listing every attempt of a submission.
+func (h *AssignmentHandler) GetSubmissionVersions(c *gin.Context) {
+	submissionID := c.Param("id")
+	if !h.canViewSubmission(c, submissionID) {
+		return
+	}
+
+	versions, err := h.assignSvc.GetSubmissionVersions(submissionID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(versions))
+	for _, v := range versions {
+		var answers, questionScores, questionFeedback, detailedScore, staticAnalysis map[string]interface{}
+		json.Unmarshal([]byte(v.Answers), &answers)
+		json.Unmarshal([]byte(v.QuestionScores), &questionScores)
+		json.Unmarshal([]byte(v.QuestionFeedback), &questionFeedback)
+		json.Unmarshal([]byte(v.DetailedScore), &detailedScore)
+		runResults := runResultsJSON(v.RunResults, c.GetString("userRole") != "teacher")
+		json.Unmarshal([]byte(v.StaticAnalysis), &staticAnalysis)
+		var aiQuestionScores map[string]interface{}
+		json.Unmarshal([]byte(v.AIQuestionScores), &aiQuestionScores)
+
+		result = append(result, gin.H{
+			"attempt":           v.Attempt,
+			"answers":           answers,
+			"code":              v.CodeContent,
+			"total_score":       v.TotalScore,
+			"raw_score":         v.RawScore,
+			"is_late":           v.IsLate,
+			"late_minutes":      v.LateMinutes,
+			"late_penalty":      v.LatePenalty,
+			"ai_feedback":       v.AIFeedback,
+			"question_scores":   questionScores,
+			"question_feedback": questionFeedback,
+			"detailed_score":    detailedScore,
+			"run_results":       runResults,
+			"static_analysis":   staticAnalysis,
+			"ai_scores":         aiQuestionScores,
+			"ai_raw_score":      v.AIRawScore,
+			"teacher_reviewed":  v.TeacherReviewed,
+			"status":            v.Status,
+			"graded_at":         v.GradedAt,
+			"created_at":        v.CreatedAt,
+		})
+	}
+
+	c.JSON(200, result)
+}
+
+// DiffSubmissionVersions handles comparing two attempts of a submission question by question.
+func (h *AssignmentHandler) DiffSubmissionVersions(c *gin.Context) {
+	submissionID := c.Param("id")
+	if !h.canViewSubmission(c, submissionID) {
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	diff, err := h.assignSvc.DiffSubmissionVersions(submissionID, from, to)
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, diff)
+}
+
+// UpdateAttemptPolicy handles a teacher setting the maximum attempts and the counted attempt of an assignment.
+func (h *AssignmentHandler) UpdateAttemptPolicy(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置提交次数"})
+		return
+	}
+
+	var req struct {
+		MaxAttempts int    `json:"max_attempts"`
+		ScorePolicy string `json:"score_policy"` // last, best
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.assignSvc.UpdateAttemptPolicy(userID, assignID, req.MaxAttempts, req.ScorePolicy); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "提交设置已更新"})
+}
+
+// UpdateLatePolicy handles a teacher setting the late submission policy of an assignment in one class.
+func (h *AssignmentHandler) UpdateLatePolicy(c *gin.Context) {
+	assignID := c.Param("id")
+	classID := c.Param("classId")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置迟交规则"})
+		return
+	}
+
+	var req dto.LatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.latePolicySvc.UpdateLatePolicy(userID, assignID, classID, req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "迟交规则已更新"})
+}
+
+// GetExtensions handles listing the per-student deadline extensions of an assignment.
+func (h *AssignmentHandler) GetExtensions(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看延期"})
+		return
+	}
+
+	extensions, err := h.latePolicySvc.GetExtensions(userID, assignID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(extensions))
+	for _, ext := range extensions {
+		result = append(result, gin.H{
+			"student_id": ext.StudentID,
+			"deadline":   ext.Deadline,
+			"reason":     ext.Reason,
+			"granted_by": ext.GrantedBy,
+			"updated_at": ext.UpdatedAt,
+		})
+	}
+
+	c.JSON(200, result)
+}
+
+// GrantExtension handles a teacher granting or changing a student's deadline extension.
+func (h *AssignmentHandler) GrantExtension(c *gin.Context) {
+	assignID := c.Param("id")
+	studentID := c.Param("studentId")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置延期"})
+		return
+	}
+
+	var req dto.ExtensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.latePolicySvc.GrantExtension(userID, assignID, studentID, req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "延期设置成功"})
+}
+
+// RevokeExtension handles a teacher removing a student's deadline extension.
+func (h *AssignmentHandler) RevokeExtension(c *gin.Context) {
+	assignID := c.Param("id")
+	studentID := c.Param("studentId")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以取消延期"})
+		return
+	}
+
+	if err := h.latePolicySvc.RevokeExtension(userID, assignID, studentID); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "延期已取消"})
+}
+
+// UpdateExamSettings handles a teacher configuring the exam mode of an assignment.
+func (h *AssignmentHandler) UpdateExamSettings(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置考试"})
+		return
+	}
+
+	var req dto.ExamSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.examSvc.UpdateExamSettings(userID, assignID, req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "考试设置已更新"})
+}
+
+// GetExamSessions handles a teacher viewing who has started, is taking, or has finished an exam.
+func (h *AssignmentHandler) GetExamSessions(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看考试情况"})
+		return
+	}
+
+	sessions, err := h.examSvc.GetExamSessions(userID, assignID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	result := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		var answers map[string]string
+		json.Unmarshal([]byte(session.SavedAnswers), &answers)
+
+		remaining := 0
+		if session.Status == model.ExamInProgress && session.EndsAt.After(now) {
+			remaining = int(session.EndsAt.Sub(now).Seconds())
+		}
+		result = append(result, gin.H{
+			"student_id":        session.StudentID,
+			"student_name":      session.StudentName,
+			"status":            session.Status,
+			"started_at":        session.StartedAt,
+			"ends_at":           session.EndsAt,
+			"remaining_seconds": remaining,
+			"answered":          len(answers),
+			"last_saved_at":     session.LastSavedAt,
+			"submitted_at":      session.SubmittedAt,
+			"submission_id":     session.SubmissionID,
+		})
+	}
+
+	c.JSON(200, result)
+}
+
+// StartExam handles a student starting the timer of an exam.
+func (h *AssignmentHandler) StartExam(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "只有学生可以参加考试"})
+		return
+	}
+
+	exam, err := h.examSvc.StartExam(assignID, userID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, exam)
+}
+
+// GetExam handles a student loading their exam paper, remaining time and saved answers.
+func (h *AssignmentHandler) GetExam(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "只有学生可以参加考试"})
+		return
+	}
+
+	exam, err := h.examSvc.GetExam(assignID, userID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, exam)
+}
+
+// SaveExamAnswers handles a student saving the draft answers of an exam.
+func (h *AssignmentHandler) SaveExamAnswers(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "只有学生可以参加考试"})
+		return
+	}
+
+	var req struct {
+		Answers map[string]interface{} `json:"answers"`
+		Code    string                 `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
 	}
 
-	if assign.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权查看其他教师的作业发布信息"})
+	savedAt, err := h.examSvc.SaveExamAnswers(assignID, userID, stringifyAnswers(req.Answers), req.Code)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "已保存", "saved_at": savedAt})
+}
+
+// SubmitExam handles a student handing in an exam.
+func (h *AssignmentHandler) SubmitExam(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "只有学生可以参加考试"})
+		return
+	}
+
+	var req struct {
+		Answers map[string]interface{} `json:"answers"`
+		Code    string                 `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	submissionID, err := h.examSvc.SubmitExam(assignID, userID, stringifyAnswers(req.Answers), req.Code)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"id": submissionID, "message": "交卷成功"})
+}
+
+// runResultsJSON decodes the stored per-question test results; for students the
+// inputs and expected outputs of IO cases are hidden so teacher test data does not leak.
+func runResultsJSON(raw string, hideCases bool) map[string]runner.Result {
+	var results map[string]runner.Result
+	json.Unmarshal([]byte(raw), &results)
+	if hideCases {
+		for id, r := range results {
+			results[id] = r.Redacted()
+		}
+	}
+	return results
+}
+
+// studentQuestion is the view of a question sent to students. It keeps the
+// field names of model.Question but never carries the teacher's test code,
+// IO test cases or grading rule.
+type studentQuestion struct {
+	ID           string `json:"ID"`
+	AssignmentID string `json:"AssignmentID"`
+	Type         string `json:"Type"`
+	Content      string `json:"Content"`
+	Options      string `json:"Options"`
+	Answer       string `json:"Answer,omitempty"`
+	Score        int    `json:"Score"`
+	OrderNum     int    `json:"OrderNum"`
+}
+
+// studentQuestions converts questions for students; the reference answer is
+// only included when withAnswers is set.
+func studentQuestions(questions []model.Question, withAnswers bool) []studentQuestion {
+	views := make([]studentQuestion, 0, len(questions))
+	for _, q := range questions {
+		view := studentQuestion{
+			ID:           q.ID,
+			AssignmentID: q.AssignmentID,
+			Type:         q.Type,
+			Content:      q.Content,
+			Options:      q.Options,
+			Score:        q.Score,
+			OrderNum:     q.OrderNum,
+		}
+		if withAnswers {
+			view.Answer = q.Answer
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// stringifyAnswers 将请求中的答案统一转换为字符串
+func stringifyAnswers(raw map[string]interface{}) map[string]string {
+	answers := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if str, ok := v.(string); ok {
+			answers[k] = str
+		} else {
+			answers[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	return answers
+}
+
+// canViewSubmission 只有作业所在班级的教学人员或者提交的学生本人可以查看提交，无权时直接写入错误响应
+func (h *AssignmentHandler) canViewSubmission(c *gin.Context, submissionID string) bool {
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" {
+		c.JSON(401, gin.H{"error": "请先登录"})
+		return false
+	}
+
+	submission, err := h.assignSvc.GetSubmission(submissionID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "提交记录不存在"})
+		return false
+	}
+	if userRole != "teacher" && submission.StudentID != userID {
+		c.JSON(403, gin.H{"error": "无权查看"})
+		return false
+	}
+	if userRole == "teacher" {
+		if _, err := h.authz.AuthorizeAssignment(userID, submission.AssignmentID, service.CapViewClass); err != nil {
+			c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// authorizeSubmission 校验当前教师对提交所属作业拥有该权限，无权时直接写入错误响应
+func (h *AssignmentHandler) authorizeSubmission(c *gin.Context, submissionID string, capability service.Capability) bool {
+	submission, err := h.assignSvc.GetSubmission(submissionID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "提交记录不存在"})
+		return false
+	}
+	if _, err := h.authz.AuthorizeAssignment(c.GetString("userID"), submission.AssignmentID, capability); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// GetGradingJobs handles listing the grading jobs of an assignment, optionally filtered by status.
+func (h *AssignmentHandler) GetGradingJobs(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看批改任务"})
+		return
+	}
+
+	if _, err := h.authz.AuthorizeAssignment(userID, assignID, service.CapGrade); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// status 支持逗号分隔的多个状态，如 ?status=queued,failed
+	var statuses []string
+	if status := c.Query("status"); status != "" {
+		statuses = strings.Split(status, ",")
+	}
+
+	jobs, err := h.assignSvc.GetGradingJobs(assignID, statuses)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, gin.H{
+			"id":            job.ID,
+			"submission_id": job.SubmissionID,
+			"status":        job.Status,
+			"attempts":      job.Attempts,
+			"max_attempts":  job.MaxAttempts,
+			"last_error":    job.LastError,
+			"next_run_at":   job.NextRunAt,
+			"started_at":    job.StartedAt,
+			"finished_at":   job.FinishedAt,
+			"created_at":    job.CreatedAt,
+		})
+	}
+
+	c.JSON(200, result)
+}
+
+// RequeueGradingJobs handles bulk requeueing failed or retrying grading jobs of an assignment.
+func (h *AssignmentHandler) RequeueGradingJobs(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以重新排队批改任务"})
+		return
+	}
+
+	if _, err := h.authz.AuthorizeAssignment(userID, assignID, service.CapGrade); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// job_ids 为空时重新排队该作业下所有失败或等待重试的任务，并为缺少任务的提交补建任务
+	var req struct {
+		JobIDs []string `json:"job_ids"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "参数错误"})
+			return
+		}
+	}
+
+	count, err := h.assignSvc.RequeueGradingJobs(assignID, req.JobIDs)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "批改任务已重新排队", "count": count})
+}
+
+// UpdateQuestionTests handles a teacher setting the test file and stdin/stdout cases of a code question.
+func (h *AssignmentHandler) UpdateQuestionTests(c *gin.Context) {
+	questionID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置测试"})
+		return
+	}
+
+	var req struct {
+		TestCode  string            `json:"test_code"`
+		TestCases []runner.TestCase `json:"test_cases"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.assignSvc.UpdateQuestionTests(userID, questionID, req.TestCode, req.TestCases); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "测试设置成功"})
+}
+
+// UpdateQuestionGradingRule handles a teacher setting the grading rule of a choice or fill question.
+func (h *AssignmentHandler) UpdateQuestionGradingRule(c *gin.Context) {
+	questionID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置判分规则"})
+		return
+	}
+
+	var req service.GradingRule
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.assignSvc.UpdateQuestionGradingRule(userID, questionID, req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "判分规则设置成功"})
+}
+
+// UpdateQuestionRubric handles a teacher setting the rubric (criteria and point levels) of a question.
+func (h *AssignmentHandler) UpdateQuestionRubric(c *gin.Context) {
+	questionID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以设置评分标准"})
+		return
+	}
+
+	var req service.QuestionRubric
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.assignSvc.UpdateQuestionRubric(userID, questionID, req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "评分标准设置成功"})
+}
+
+// GetPublishedClasses handles getting the list of classes an assignment is published to.
+func (h *AssignmentHandler) GetPublishedClasses(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看发布信息"})
+		return
+	}
+
+	// 验证教师是否有权查看此作业的发布信息
+	if _, err := h.authz.AuthorizeAssignment(userID, assignID, service.CapViewClass); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -614,6 +1772,9 @@ func (h *AssignmentHandler) DownloadSubmissionCode(c *gin.Context) {
 		c.JSON(403, gin.H{"error": "只有教师可以下载代码"})
 		return
 	}
+	if !h.authorizeSubmission(c, submissionID, service.CapViewClass) {
+		return
+	}
 
 	code, fileName, err := h.assignSvc.GetSubmissionCodeForDownload(submissionID)
 	if err != nil {
@@ -638,22 +1799,177 @@ func (h *AssignmentHandler) DeleteAssignment(c *gin.Context) {
 	}
 
 	// 验证教师是否有权删除此作业
-	assign, _, err := h.assignSvc.GetAssignmentDetail(assignID)
+	if _, err := h.authz.AuthorizeAssignment(userID, assignID, service.CapDeleteAssignment); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.assignSvc.DeleteAssignment(assignID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "作业不存在"})
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "作业删除成功"})
+}
+
+// CreateAppeal handles a student appealing the score of one question in a graded submission.
+func (h *AssignmentHandler) CreateAppeal(c *gin.Context) {
+	submissionID := c.Param("id")
+	userID := c.GetString("userID")
+
+	if userID == "" {
+		c.JSON(401, gin.H{"error": "请先登录"})
+		return
+	}
+
+	var req dto.GradeAppealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
 		return
 	}
 
-	if assign.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权删除其他教师的作业"})
+	appeal, err := h.assignSvc.CreateAppeal(userID, submissionID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, appealJSON(*appeal))
+}
+
+// GetSubmissionAppeals handles listing the appeals of a submission and how they were resolved.
+func (h *AssignmentHandler) GetSubmissionAppeals(c *gin.Context) {
+	submissionID := c.Param("id")
+	if !h.canViewSubmission(c, submissionID) {
 		return
 	}
 
-	err = h.assignSvc.DeleteAssignment(assignID)
+	appeals, err := h.assignSvc.GetSubmissionAppeals(submissionID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "作业删除成功"})
+	result := make([]gin.H, 0, len(appeals))
+	for _, appeal := range appeals {
+		result = append(result, appealJSON(appeal))
+	}
+	c.JSON(200, result)
+}
+
+// GetClassAppeals handles listing the grade appeals of a class, optionally filtered by status.
+func (h *AssignmentHandler) GetClassAppeals(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看成绩申诉"})
+		return
+	}
+
+	// status 支持逗号分隔的多个状态，如 ?status=open,reevaluating
+	var statuses []string
+	if status := c.Query("status"); status != "" {
+		statuses = strings.Split(status, ",")
+	}
+
+	appeals, err := h.assignSvc.GetClassAppeals(userID, classID, statuses)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 附带作业标题和题号，方便教师定位
+	type questionInfo struct {
+		number   int
+		maxScore int
+		content  string
+	}
+	titles := make(map[string]string)
+	questions := make(map[string]questionInfo)
+	result := make([]gin.H, 0, len(appeals))
+	for _, appeal := range appeals {
+		if _, ok := titles[appeal.AssignmentID]; !ok {
+			assign, qs, err := h.assignSvc.GetAssignmentDetail(appeal.AssignmentID)
+			if err == nil {
+				titles[appeal.AssignmentID] = assign.Title
+				for i, q := range qs {
+					questions[q.ID] = questionInfo{number: i + 1, maxScore: q.Score, content: q.Content}
+				}
+			} else {
+				titles[appeal.AssignmentID] = ""
+			}
+		}
+
+		item := appealJSON(appeal)
+		item["assignment_title"] = titles[appeal.AssignmentID]
+		if q, ok := questions[appeal.QuestionID]; ok {
+			item["question_number"] = q.number
+			item["question_max_score"] = q.maxScore
+			item["question_content"] = q.content
+		}
+		result = append(result, item)
+	}
+	c.JSON(200, result)
+}
+
+// AcceptAppeal handles a teacher accepting a grade appeal and adjusting the question score.
+func (h *AssignmentHandler) AcceptAppeal(c *gin.Context) {
+	h.resolveAppeal(c, h.assignSvc.AcceptAppeal)
+}
+
+// RejectAppeal handles a teacher rejecting a grade appeal with a comment.
+func (h *AssignmentHandler) RejectAppeal(c *gin.Context) {
+	h.resolveAppeal(c, h.assignSvc.RejectAppeal)
+}
+
+// ReevaluateAppeal handles a teacher sending an appealed submission back to AI grading.
+func (h *AssignmentHandler) ReevaluateAppeal(c *gin.Context) {
+	h.resolveAppeal(c, h.assignSvc.ReevaluateAppeal)
+}
+
+func (h *AssignmentHandler) resolveAppeal(c *gin.Context, resolve func(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error)) {
+	appealID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以处理成绩申诉"})
+		return
+	}
+
+	var req dto.AppealResolutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	appeal, err := resolve(userID, appealID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, appealJSON(*appeal))
+}
+
+func appealJSON(appeal model.GradeAppeal) gin.H {
+	return gin.H{
+		"id":              appeal.ID,
+		"submission_id":   appeal.SubmissionID,
+		"assignment_id":   appeal.AssignmentID,
+		"class_id":        appeal.ClassID,
+		"student_id":      appeal.StudentID,
+		"student_name":    appeal.StudentName,
+		"question_id":     appeal.QuestionID,
+		"reason":          appeal.Reason,
+		"status":          appeal.Status,
+		"original_score":  appeal.OriginalScore,
+		"resolved_score":  appeal.ResolvedScore,
+		"teacher_comment": appeal.TeacherComment,
+		"resolved_at":     appeal.ResolvedAt,
+		"created_at":      appeal.CreatedAt,
+	}
 }
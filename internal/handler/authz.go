@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"GoCodeMentor/internal/service"
+	"errors"
+)
+
+// authzStatus maps an authorization error to an HTTP status code: 403 when the
+// user lacks the capability or the class is archived, 404 when the class or
+// assignment does not exist.
+func authzStatus(err error) int {
+	if errors.Is(err, service.ErrForbidden) || errors.Is(err, service.ErrArchived) {
+		return 403
+	}
+	return 404
+}
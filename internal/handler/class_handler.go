@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"GoCodeMentor/internal/dto"
 	"GoCodeMentor/internal/service"
+	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
 )
 
 // ClassHandler handles class-related requests.
@@ -11,11 +16,12 @@ type ClassHandler struct {
 	classSvc  service.IClassService
 	userSvc   service.IUserService
 	assignSvc service.IAssignmentService
+	authz     service.IAuthorizationService
 }
 
 // NewClassHandler creates a new ClassHandler.
-func NewClassHandler(classSvc service.IClassService, userSvc service.IUserService, assignSvc service.IAssignmentService) *ClassHandler {
-	return &ClassHandler{classSvc: classSvc, userSvc: userSvc, assignSvc: assignSvc}
+func NewClassHandler(classSvc service.IClassService, userSvc service.IUserService, assignSvc service.IAssignmentService, authz service.IAuthorizationService) *ClassHandler {
+	return &ClassHandler{classSvc: classSvc, userSvc: userSvc, assignSvc: assignSvc, authz: authz}
 }
 
 // CreateClass handles the creation of a new class.
@@ -80,14 +86,12 @@ func (h *ClassHandler) GetStudentsByClassID(c *gin.Context) {
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
-	class, err := h.classSvc.GetClassByID(classID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "班级不存在"})
+	if userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "无权查看"})
 		return
 	}
-
-	if userRole != "teacher" || class.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权查看"})
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapViewClass); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -117,34 +121,55 @@ func (h *ClassHandler) JoinClass(c *gin.Context) {
 		return
 	}
 
-	err := h.classSvc.JoinClass(userID, req.Code)
+	pending, err := h.classSvc.JoinClass(userID, req.Code)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
+	if pending {
+		c.JSON(200, gin.H{"message": "已提交申请，等待教师审批", "pending": true})
+		return
+	}
 
-	c.JSON(200, gin.H{"message": "加入成功"})
+	c.JSON(200, gin.H{"message": "加入成功", "pending": false})
 }
 
-// AddStudentToClass handles a teacher adding a student to a class.
-func (h *ClassHandler) AddStudentToClass(c *gin.Context) {
-	classID := c.Param("id")
+// GetMyClasses handles a student getting all the classes they belong to.
+func (h *ClassHandler) GetMyClasses(c *gin.Context) {
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
-	class, err := h.classSvc.GetClassByID(classID)
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "无权查看"})
+		return
+	}
+
+	classes, err := h.classSvc.GetStudentClasses(userID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "班级不存在"})
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(200, classes)
+}
+
+// AddStudentToClass handles a teacher adding a student to a class.
+func (h *ClassHandler) AddStudentToClass(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
 
-	if userRole != "teacher" || class.TeacherID != userID {
+	if userRole != "teacher" {
 		c.JSON(403, gin.H{"error": "无权操作"})
 		return
 	}
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapManageStudents); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
 	var req struct {
 		StudentID string `json:"student_id"`
+		Role      string `json:"role"` // student（默认）或 auditor（旁听）
 	}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": "参数错误"})
@@ -157,7 +182,7 @@ func (h *ClassHandler) AddStudentToClass(c *gin.Context) {
 		return
 	}
 
-	if err := h.classSvc.AddStudentToClass(req.StudentID, classID); err != nil {
+	if err := h.classSvc.AddStudentToClass(req.StudentID, classID, req.Role); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
@@ -172,14 +197,12 @@ func (h *ClassHandler) RemoveStudentFromClass(c *gin.Context) {
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
-	class, err := h.classSvc.GetClassByID(classID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "班级不存在"})
+	if userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "无权操作"})
 		return
 	}
-
-	if userRole != "teacher" || class.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权操作"})
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapManageStudents); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -197,14 +220,12 @@ func (h *ClassHandler) DeleteClass(c *gin.Context) {
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
-	class, err := h.classSvc.GetClassByID(classID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "班级不存在"})
+	if userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "无权操作"})
 		return
 	}
-
-	if userRole != "teacher" || class.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权操作"})
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapDeleteClass); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -222,14 +243,12 @@ func (h *ClassHandler) GetClassStats(c *gin.Context) {
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
-	class, err := h.classSvc.GetClassByID(classID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "班级不存在"})
+	if userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "无权查看"})
 		return
 	}
-
-	if userRole != "teacher" || class.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权查看"})
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapViewClass); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -301,17 +320,31 @@ func (h *ClassHandler) GetClassStats(c *gin.Context) {
 		assignmentStats = append(assignmentStats, stat)
 	}
 
+	groups, err := h.classSvc.GetGroups(userID, classID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取班级小组失败"})
+		return
+	}
+
 	c.JSON(200, gin.H{
 		"student_count":     len(students),
 		"assignment_count":  len(assignments),
 		"unsubmitted_count": len(classUnsubmittedSet),
 		"assignment_stats":  assignmentStats,
+		"groups":            groups.Groups,
+		"ungrouped":         groups.Ungrouped,
 	})
 }
 
 // AnalyzeClass handles generating an AI-powered academic analysis for a class.
 func (h *ClassHandler) AnalyzeClass(c *gin.Context) {
 	classID := c.Param("id")
+	userID := c.GetString("userID")
+
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapViewClass); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
 	// Use the request context for cancellation propagation.
 	ctx := c.Request.Context()
@@ -324,3 +357,451 @@ func (h *ClassHandler) AnalyzeClass(c *gin.Context) {
 
 	c.JSON(200, gin.H{"report": report})
 }
+
+// GetClassStaff handles listing the owner, co-teachers, TAs and pending invitations of a class,
+// along with the current user's own role and capabilities in it.
+func (h *ClassHandler) GetClassStaff(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+
+	staff, err := h.classSvc.GetClassStaff(userID, classID)
+	if err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	role, capabilities, err := h.authz.ClassCapabilities(userID, classID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	class, err := h.classSvc.GetClassByID(classID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "班级不存在"})
+		return
+	}
+
+	owner := gin.H{"user_id": class.TeacherID}
+	if teacher, err := h.userSvc.GetByID(class.TeacherID); err == nil {
+		owner["username"] = teacher.Username
+		owner["name"] = teacher.Name
+	}
+
+	result := make([]gin.H, 0, len(staff))
+	for _, s := range staff {
+		item := gin.H{
+			"id":           s.ID,
+			"user_id":      s.UserID,
+			"role":         s.Role,
+			"status":       s.Status,
+			"invited_by":   s.InvitedBy,
+			"responded_at": s.RespondedAt,
+			"created_at":   s.CreatedAt,
+		}
+		if user, err := h.userSvc.GetByID(s.UserID); err == nil {
+			item["username"] = user.Username
+			item["name"] = user.Name
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(200, gin.H{
+		"my_role":      role,
+		"capabilities": capabilities,
+		"owner":        owner,
+		"staff":        result,
+	})
+}
+
+// InviteStaff handles a class owner inviting another teacher as a co-teacher or TA.
+func (h *ClassHandler) InviteStaff(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+
+	var req struct {
+		Username string `json:"username"`
+		Role     string `json:"role"` // co_teacher 或 ta
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	staff, err := h.classSvc.InviteStaff(userID, classID, req.Username, req.Role)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, staff)
+}
+
+// UpdateStaffRole handles a class owner changing the role of a co-teacher or TA.
+func (h *ClassHandler) UpdateStaffRole(c *gin.Context) {
+	classID := c.Param("id")
+	staffID := c.Param("staffId")
+	userID := c.GetString("userID")
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.classSvc.UpdateStaffRole(userID, classID, staffID, req.Role); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "角色已更新"})
+}
+
+// RemoveStaff handles a class owner removing a staff member or withdrawing an invitation,
+// or a staff member leaving the class.
+func (h *ClassHandler) RemoveStaff(c *gin.Context) {
+	classID := c.Param("id")
+	staffID := c.Param("staffId")
+	userID := c.GetString("userID")
+
+	if err := h.classSvc.RemoveStaff(userID, classID, staffID); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "已移除"})
+}
+
+// GetStaffInvitations handles a teacher listing the class invitations they have not answered yet.
+func (h *ClassHandler) GetStaffInvitations(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	invitations, err := h.classSvc.GetStaffInvitations(userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(invitations))
+	for _, inv := range invitations {
+		item := gin.H{
+			"id":         inv.ID,
+			"class_id":   inv.ClassID,
+			"role":       inv.Role,
+			"created_at": inv.CreatedAt,
+		}
+		if class, err := h.classSvc.GetClassByID(inv.ClassID); err == nil {
+			item["class_name"] = class.Name
+		}
+		if inviter, err := h.userSvc.GetByID(inv.InvitedBy); err == nil {
+			item["invited_by"] = inviter.Name
+		}
+		result = append(result, item)
+	}
+	c.JSON(200, result)
+}
+
+// AcceptStaffInvitation handles a teacher accepting a class invitation.
+func (h *ClassHandler) AcceptStaffInvitation(c *gin.Context) {
+	h.respondStaffInvitation(c, true)
+}
+
+// DeclineStaffInvitation handles a teacher declining a class invitation.
+func (h *ClassHandler) DeclineStaffInvitation(c *gin.Context) {
+	h.respondStaffInvitation(c, false)
+}
+
+func (h *ClassHandler) respondStaffInvitation(c *gin.Context, accept bool) {
+	userID := c.GetString("userID")
+
+	if err := h.classSvc.RespondStaffInvitation(userID, c.Param("id"), accept); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if accept {
+		c.JSON(200, gin.H{"message": "已加入班级"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "已拒绝邀请"})
+}
+
+// UpdateInviteSettings handles a teacher setting the expiry, usage limit and approval requirement of the class invite code.
+func (h *ClassHandler) UpdateInviteSettings(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto.InviteCodeSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	class, err := h.classSvc.UpdateInviteSettings(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, class)
+}
+
+// RegenerateInviteCode handles a teacher replacing the class invite code; the old code stops working at once.
+func (h *ClassHandler) RegenerateInviteCode(c *gin.Context) {
+	class, err := h.classSvc.RegenerateInviteCode(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, class)
+}
+
+// RevokeInviteCode handles a teacher disabling the class invite code until it is regenerated.
+func (h *ClassHandler) RevokeInviteCode(c *gin.Context) {
+	class, err := h.classSvc.RevokeInviteCode(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, class)
+}
+
+// GetJoinQRCode handles generating a QR code that opens the student dashboard with the class invite code filled in.
+func (h *ClassHandler) GetJoinQRCode(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+
+	class, err := h.authz.AuthorizeClass(userID, classID, service.CapManageStudents)
+	if err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if class.CodeRevoked {
+		c.JSON(400, gin.H{"error": "邀请码已停用"})
+		return
+	}
+
+	link := fmt.Sprintf("http://localhost:8081/?join=%s", url.QueryEscape(class.Code))
+	png, err := qrcode.Encode(link, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "生成二维码失败"})
+		return
+	}
+
+	c.Data(200, "image/png", png)
+}
+
+// GetJoinRequests handles a teacher listing the join requests of a class, optionally filtered by status.
+func (h *ClassHandler) GetJoinRequests(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+
+	// status 支持逗号分隔的多个状态，如 ?status=pending
+	var statuses []string
+	if status := c.Query("status"); status != "" {
+		statuses = strings.Split(status, ",")
+	}
+
+	requests, err := h.classSvc.GetJoinRequests(userID, classID, statuses)
+	if err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(requests))
+	for _, r := range requests {
+		item := gin.H{
+			"id":          r.ID,
+			"student_id":  r.StudentID,
+			"status":      r.Status,
+			"reviewed_by": r.ReviewedBy,
+			"reviewed_at": r.ReviewedAt,
+			"created_at":  r.CreatedAt,
+		}
+		if student, err := h.userSvc.GetByID(r.StudentID); err == nil {
+			item["username"] = student.Username
+			item["name"] = student.Name
+		}
+		result = append(result, item)
+	}
+	c.JSON(200, result)
+}
+
+// ApproveJoinRequest handles a teacher admitting a student who asked to join the class.
+func (h *ClassHandler) ApproveJoinRequest(c *gin.Context) {
+	h.reviewJoinRequest(c, true)
+}
+
+// RejectJoinRequest handles a teacher turning down a request to join the class.
+func (h *ClassHandler) RejectJoinRequest(c *gin.Context) {
+	h.reviewJoinRequest(c, false)
+}
+
+func (h *ClassHandler) reviewJoinRequest(c *gin.Context, approve bool) {
+	userID := c.GetString("userID")
+
+	if err := h.classSvc.ReviewJoinRequest(userID, c.Param("id"), c.Param("requestId"), approve); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if approve {
+		c.JSON(200, gin.H{"message": "已通过申请"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "已拒绝申请"})
+}
+
+// GetMyJoinRequests handles a student listing the join requests they have submitted.
+func (h *ClassHandler) GetMyJoinRequests(c *gin.Context) {
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "student" {
+		c.JSON(403, gin.H{"error": "无权查看"})
+		return
+	}
+
+	requests, err := h.classSvc.GetMyJoinRequests(userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(requests))
+	for _, r := range requests {
+		item := gin.H{
+			"id":          r.ID,
+			"class_id":    r.ClassID,
+			"status":      r.Status,
+			"reviewed_at": r.ReviewedAt,
+			"created_at":  r.CreatedAt,
+		}
+		if class, err := h.classSvc.GetClassByID(r.ClassID); err == nil {
+			item["class_name"] = class.Name
+		}
+		result = append(result, item)
+	}
+	c.JSON(200, result)
+}
+
+// ArchiveClass handles the class owner archiving a class, which keeps grades and chats viewable but blocks changes.
+func (h *ClassHandler) ArchiveClass(c *gin.Context) {
+	class, err := h.classSvc.ArchiveClass(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, class)
+}
+
+// UnarchiveClass handles the class owner restoring an archived class.
+func (h *ClassHandler) UnarchiveClass(c *gin.Context) {
+	class, err := h.classSvc.UnarchiveClass(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, class)
+}
+
+// CloneAssignments handles copying a class's assignments into another class, usually the same course in a new term.
+func (h *ClassHandler) CloneAssignments(c *gin.Context) {
+	var req dto.CloneAssignmentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	clones, err := h.assignSvc.CloneClassAssignments(c.GetString("userID"), c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	published := 0
+	for _, a := range clones {
+		if a.Status != "draft" {
+			published++
+		}
+	}
+	c.JSON(200, gin.H{
+		"message":     fmt.Sprintf("已复制 %d 个作业，其中 %d 个已按新学期时间发布", len(clones), published),
+		"assignments": clones,
+	})
+}
+
+// GetGroups handles listing a class's groups, their members and the students without a group.
+func (h *ClassHandler) GetGroups(c *gin.Context) {
+	groups, err := h.classSvc.GetGroups(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, groups)
+}
+
+// CreateGroup handles creating an empty group in a class.
+func (h *ClassHandler) CreateGroup(c *gin.Context) {
+	var req dto.ClassGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	group, err := h.classSvc.CreateGroup(c.GetString("userID"), c.Param("id"), req.Name)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, group)
+}
+
+// RenameGroup handles renaming a group.
+func (h *ClassHandler) RenameGroup(c *gin.Context) {
+	var req dto.ClassGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	group, err := h.classSvc.RenameGroup(c.GetString("userID"), c.Param("id"), c.Param("groupId"), req.Name)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, group)
+}
+
+// DeleteGroup handles deleting a group; its members become ungrouped.
+func (h *ClassHandler) DeleteGroup(c *gin.Context) {
+	if err := h.classSvc.DeleteGroup(c.GetString("userID"), c.Param("id"), c.Param("groupId")); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "小组已删除"})
+}
+
+// SetStudentGroup handles moving a student into a group, or out of their group when group_id is empty.
+func (h *ClassHandler) SetStudentGroup(c *gin.Context) {
+	var req dto.GroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.classSvc.SetStudentGroup(c.GetString("userID"), c.Param("id"), c.Param("studentId"), req.GroupID); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "分组已更新"})
+}
+
+// GenerateGroups handles splitting a class into groups randomly or balanced by past scores, replacing the current groups.
+func (h *ClassHandler) GenerateGroups(c *gin.Context) {
+	var req dto.GenerateGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	groups, err := h.classSvc.GenerateGroups(c.GetString("userID"), c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, groups)
+}
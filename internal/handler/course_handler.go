@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CourseHandler handles course and term requests.
+type CourseHandler struct {
+	courseSvc service.ICourseService
+}
+
+// NewCourseHandler creates a new CourseHandler.
+func NewCourseHandler(courseSvc service.ICourseService) *CourseHandler {
+	return &CourseHandler{courseSvc: courseSvc}
+}
+
+// GetCourses handles listing the courses created by the teacher.
+func (h *CourseHandler) GetCourses(c *gin.Context) {
+	courses, err := h.courseSvc.GetCourses(c.GetString("userID"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取课程失败"})
+		return
+	}
+	c.JSON(200, courses)
+}
+
+// CreateCourse handles creating a course.
+func (h *CourseHandler) CreateCourse(c *gin.Context) {
+	var req dto.CourseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	course, err := h.courseSvc.CreateCourse(c.GetString("userID"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, course)
+}
+
+// UpdateCourse handles renaming or describing a course.
+func (h *CourseHandler) UpdateCourse(c *gin.Context) {
+	var req dto.CourseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	course, err := h.courseSvc.UpdateCourse(c.GetString("userID"), c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, course)
+}
+
+// DeleteCourse handles deleting a course that no longer has classes.
+func (h *CourseHandler) DeleteCourse(c *gin.Context) {
+	if err := h.courseSvc.DeleteCourse(c.GetString("userID"), c.Param("id")); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "课程已删除"})
+}
+
+// GetCourseClasses handles listing every class of a course across terms, archived ones included.
+func (h *CourseHandler) GetCourseClasses(c *gin.Context) {
+	classes, err := h.courseSvc.GetCourseClasses(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, classes)
+}
+
+// GetTerms handles listing all terms, newest first.
+func (h *CourseHandler) GetTerms(c *gin.Context) {
+	terms, err := h.courseSvc.GetTerms()
+	if err != nil {
+		c.JSON(500, gin.H{"error": "获取学期失败"})
+		return
+	}
+	c.JSON(200, terms)
+}
+
+// CreateTerm handles creating a term shared by all teachers.
+func (h *CourseHandler) CreateTerm(c *gin.Context) {
+	var req dto.TermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	term, err := h.courseSvc.CreateTerm(c.GetString("userID"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, term)
+}
+
+// SetClassCourse handles placing a class in a course and term.
+func (h *CourseHandler) SetClassCourse(c *gin.Context) {
+	var req dto.ClassCourseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	class, err := h.courseSvc.SetClassCourse(c.GetString("userID"), c.Param("id"), req)
+	if err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, class)
+}
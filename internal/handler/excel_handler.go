@@ -1,22 +1,27 @@
 package handler
 
 import (
+	"GoCodeMentor/internal/model"
 	"GoCodeMentor/internal/pkg/excel"
 	"GoCodeMentor/internal/service"
 	"bytes"
+	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ExcelHandler handles excel import/export requests.
 type ExcelHandler struct {
-	classSvc service.IClassService
-	userSvc  service.IUserService
+	classSvc     service.IClassService
+	userSvc      service.IUserService
+	gradebookSvc service.IGradebookService
+	authz        service.IAuthorizationService
 }
 
 // NewExcelHandler creates a new ExcelHandler.
-func NewExcelHandler(classSvc service.IClassService, userSvc service.IUserService) *ExcelHandler {
-	return &ExcelHandler{classSvc: classSvc, userSvc: userSvc}
+func NewExcelHandler(classSvc service.IClassService, userSvc service.IUserService, gradebookSvc service.IGradebookService, authz service.IAuthorizationService) *ExcelHandler {
+	return &ExcelHandler{classSvc: classSvc, userSvc: userSvc, gradebookSvc: gradebookSvc, authz: authz}
 }
 
 // DownloadStudentTemplate handles downloading the student list template.
@@ -63,14 +68,12 @@ func (h *ExcelHandler) ImportStudentsToClass(c *gin.Context) {
 	userID := c.GetString("userID")
 	userRole := c.GetString("userRole")
 
-	class, err := h.classSvc.GetClassByID(classID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "班级不存在"})
+	if userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "无权操作"})
 		return
 	}
-
-	if userRole != "teacher" || class.TeacherID != userID {
-		c.JSON(403, gin.H{"error": "无权操作"})
+	if _, err := h.authz.AuthorizeClass(userID, classID, service.CapManageStudents); err != nil {
+		c.JSON(authzStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -116,7 +119,7 @@ func (h *ExcelHandler) ImportStudentsToClass(c *gin.Context) {
 			studentID = existingStudent.ID
 		}
 
-		if err := h.classSvc.AddStudentToClass(studentID, classID); err != nil {
+		if err := h.classSvc.AddStudentToClass(studentID, classID, model.ClassRoleStudent); err != nil {
 			failCount++
 			failMessages = append(failMessages, s.Username+": 添加到班级失败 - "+err.Error())
 			continue
@@ -223,7 +226,7 @@ func (h *ExcelHandler) ImportClassesAndStudents(c *gin.Context) {
 		if s.Class != "" {
 			classID := classMap[s.Class]
 			if classID != "" {
-				if err := h.classSvc.AddStudentToClass(studentID, classID); err != nil {
+				if err := h.classSvc.AddStudentToClass(studentID, classID, model.ClassRoleStudent); err != nil {
 					studentFailCount++
 					failMessages = append(failMessages, s.Username+": 添加到班级失败 - "+err.Error())
 					continue
@@ -243,3 +246,92 @@ func (h *ExcelHandler) ImportClassesAndStudents(c *gin.Context) {
 		"total":           len(students),
 	})
 }
+
+// ExportAssignmentScores handles downloading the score sheet of an assignment as xlsx (default) or csv.
+// An optional class_id query parameter limits the sheet to one class.
+func (h *ExcelHandler) ExportAssignmentScores(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以导出成绩"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "xlsx")
+	if format != "xlsx" && format != "csv" {
+		c.JSON(400, gin.H{"error": "不支持的导出格式"})
+		return
+	}
+
+	sheet, err := h.gradebookSvc.ExportAssignment(userID, assignID, c.Query("class_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendSheets(c, []excel.Sheet{*sheet}, sheet.Name+"_成绩单", format)
+}
+
+// ExportClassGradebook handles downloading the gradebook of a class. The xlsx workbook has a summary sheet
+// followed by one sheet per assignment; the csv contains the summary only.
+func (h *ExcelHandler) ExportClassGradebook(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以导出成绩"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "xlsx")
+	if format != "xlsx" && format != "csv" {
+		c.JSON(400, gin.H{"error": "不支持的导出格式"})
+		return
+	}
+
+	class, err := h.classSvc.GetClassByID(classID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "班级不存在"})
+		return
+	}
+
+	sheets, err := h.gradebookSvc.ExportClass(userID, classID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendSheets(c, sheets, class.Name+"_成绩册", format)
+}
+
+// sendSheets writes the sheets as an xlsx workbook, or the first sheet as csv, as a file download.
+func sendSheets(c *gin.Context, sheets []excel.Sheet, name, format string) {
+	filename := name + "_" + time.Now().Format("20060102") + "." + format
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == "csv" {
+		if err := excel.WriteCSV(&buf, sheets[0]); err != nil {
+			c.JSON(500, gin.H{"error": "生成文件失败"})
+			return
+		}
+	} else {
+		f, err := excel.CreateWorkbook(sheets)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "创建工作簿失败"})
+			return
+		}
+		defer f.Close()
+		if err := f.Write(&buf); err != nil {
+			c.JSON(500, gin.H{"error": "生成文件失败"})
+			return
+		}
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	c.Data(200, contentType, buf.Bytes())
+}
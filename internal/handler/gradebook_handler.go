@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GradebookHandler handles class gradebook requests.
+type GradebookHandler struct {
+	gradebookSvc service.IGradebookService
+}
+
+// NewGradebookHandler creates a new GradebookHandler.
+func NewGradebookHandler(gradebookSvc service.IGradebookService) *GradebookHandler {
+	return &GradebookHandler{gradebookSvc: gradebookSvc}
+}
+
+// GetGradebook handles getting the score matrix, category percentages and final grades of a class.
+func (h *GradebookHandler) GetGradebook(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看成绩册"})
+		return
+	}
+
+	book, err := h.gradebookSvc.GetGradebook(userID, classID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, book)
+}
+
+// UpdateSettings handles a teacher configuring category weights, dropped scores, missing work and the letter scale.
+func (h *GradebookHandler) UpdateSettings(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以修改成绩册设置"})
+		return
+	}
+
+	var req dto.GradebookSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	config, err := h.gradebookSvc.UpdateSettings(userID, classID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, config)
+}
+
+// OverrideCell handles a teacher manually overriding, or restoring, one gradebook cell.
+func (h *GradebookHandler) OverrideCell(c *gin.Context) {
+	classID := c.Param("id")
+	assignID := c.Param("assignmentId")
+	studentID := c.Param("studentId")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以修改成绩"})
+		return
+	}
+
+	var req dto.GradeOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if err := h.gradebookSvc.OverrideCell(userID, classID, assignID, studentID, req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "成绩已更新"})
+}
+
+// GetAuditLog handles listing who changed which gradebook cell, optionally for one student.
+func (h *GradebookHandler) GetAuditLog(c *gin.Context) {
+	classID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看成绩修改记录"})
+		return
+	}
+
+	logs, err := h.gradebookSvc.GetAuditLog(userID, classID, c.Query("student_id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, logs)
+}
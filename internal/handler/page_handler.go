@@ -83,6 +83,16 @@ func (h *PageHandler) ClassStudentsPage(c *gin.Context) {
 	c.File("web/templates/class_students.html")
 }
 
+// ClassGradebookPage renders the class gradebook page.
+func (h *PageHandler) ClassGradebookPage(c *gin.Context) {
+	c.File("web/templates/class_gradebook.html")
+}
+
+// ClassAppealsPage renders the grade appeal queue of a class.
+func (h *PageHandler) ClassAppealsPage(c *gin.Context) {
+	c.File("web/templates/class_appeals.html")
+}
+
 // AccountManagementPage renders the account management page (Admin only).
 func (h *PageHandler) AccountManagementPage(c *gin.Context) {
 	userRole := c.GetString("userRole")
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuestionBankHandler handles question bank requests.
+type QuestionBankHandler struct {
+	bankSvc service.IQuestionBankService
+}
+
+// NewQuestionBankHandler creates a new QuestionBankHandler.
+func NewQuestionBankHandler(bankSvc service.IQuestionBankService) *QuestionBankHandler {
+	return &QuestionBankHandler{bankSvc: bankSvc}
+}
+
+// SearchItems handles searching the question bank by type, difficulty, knowledge point and keyword.
+func (h *QuestionBankHandler) SearchItems(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var query dto.BankQuestionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	items, err := h.bankSvc.Search(userID, query)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, items)
+}
+
+// GetItem handles getting a single question bank item.
+func (h *QuestionBankHandler) GetItem(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	item, err := h.bankSvc.GetItem(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, item)
+}
+
+// CreateItem handles adding a question to the bank.
+func (h *QuestionBankHandler) CreateItem(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto.BankQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	item, err := h.bankSvc.CreateItem(userID, req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, item)
+}
+
+// UpdateItem handles editing a question bank item.
+func (h *QuestionBankHandler) UpdateItem(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto.BankQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	item, err := h.bankSvc.UpdateItem(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, item)
+}
+
+// DeleteItem handles deleting a question bank item.
+func (h *QuestionBankHandler) DeleteItem(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	if err := h.bankSvc.DeleteItem(userID, c.Param("id")); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "题目删除成功"})
+}
+
+// SaveFromAssignment handles saving questions of an assignment, e.g. AI-generated ones, into the bank.
+func (h *QuestionBankHandler) SaveFromAssignment(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto.SaveToBankRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "参数错误"})
+			return
+		}
+	}
+
+	items, err := h.bankSvc.SaveFromAssignment(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "已保存到题库", "items": items})
+}
+
+// AddToAssignment handles assembling assignment questions from the bank, by picked items and/or rules.
+func (h *QuestionBankHandler) AddToAssignment(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var req dto.AddFromBankRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	questions, err := h.bankSvc.AddToAssignment(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "题目已加入作业", "questions": questions})
+}
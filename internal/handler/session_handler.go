@@ -46,6 +46,48 @@ func (h *SessionHandler) Chat(c *gin.Context) {
 	})
 }
 
+// ChatStream handles the streaming chat endpoint using Server-Sent Events.
+// Events: "token" (answer fragment), then "done" or "error" carrying the session id.
+func (h *SessionHandler) ChatStream(c *gin.Context) {
+	var req struct {
+		SessionID string `json:"session_id"`
+		Question  string `json:"question"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		userID = "anonymous" // 未登录用户使用匿名
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// 客户端断开时请求上下文会被取消，从而中止模型的流式输出
+	ctx := c.Request.Context()
+	answer, sessionID, err := h.sessionSvc.ChatStream(ctx, req.SessionID, userID, req.Question, func(token string) error {
+		c.SSEvent("token", gin.H{"content": token})
+		c.Writer.Flush()
+		return ctx.Err()
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		c.SSEvent("error", gin.H{"error": err.Error(), "session_id": sessionID, "partial": answer})
+		c.Writer.Flush()
+		return
+	}
+
+	c.SSEvent("done", gin.H{"session_id": sessionID})
+	c.Writer.Flush()
+}
+
 // GetHistory handles getting chat history.
 func (h *SessionHandler) GetHistory(c *gin.Context) {
 	sessionID := c.Query("session_id")
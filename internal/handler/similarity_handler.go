@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"GoCodeMentor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SimilarityHandler handles submission similarity (plagiarism) detection requests.
+type SimilarityHandler struct {
+	similaritySvc service.ISimilarityService
+}
+
+// NewSimilarityHandler creates a new SimilarityHandler.
+func NewSimilarityHandler(similaritySvc service.ISimilarityService) *SimilarityHandler {
+	return &SimilarityHandler{similaritySvc: similaritySvc}
+}
+
+// StartCheck handles a teacher starting a background similarity check over all submissions of an assignment.
+func (h *SimilarityHandler) StartCheck(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以进行相似度检测"})
+		return
+	}
+
+	var req struct {
+		Threshold float64 `json:"threshold"` // 0~1，默认 0.8
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "参数错误"})
+			return
+		}
+	}
+
+	report, err := h.similaritySvc.StartCheck(userID, assignID, req.Threshold)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(202, gin.H{"id": report.ID, "status": report.Status, "message": "相似度检测已开始，请稍后查看报告"})
+}
+
+// GetReport handles getting the latest similarity report of an assignment.
+func (h *SimilarityHandler) GetReport(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看相似度报告"})
+		return
+	}
+
+	report, result, err := h.similaritySvc.GetLatestReport(userID, assignID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(404, gin.H{"error": "该作业还没有相似度检测报告"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"id":               report.ID,
+		"status":           report.Status,
+		"threshold":        report.Threshold,
+		"submission_count": report.SubmissionCount,
+		"suspicious_pairs": report.SuspiciousPairs,
+		"error":            report.Error,
+		"started_at":       report.StartedAt,
+		"finished_at":      report.FinishedAt,
+		"pairs":            result.Pairs,
+		"clusters":         result.Clusters,
+	})
+}
+
+// GetPairDetail handles showing two suspicious submissions side by side with their matching regions.
+func (h *SimilarityHandler) GetPairDetail(c *gin.Context) {
+	assignID := c.Param("id")
+	userID := c.GetString("userID")
+	userRole := c.GetString("userRole")
+
+	if userID == "" || userRole != "teacher" {
+		c.JSON(403, gin.H{"error": "只有教师可以查看相似度报告"})
+		return
+	}
+
+	submissionA := c.Query("a")
+	submissionB := c.Query("b")
+	if submissionA == "" || submissionB == "" {
+		c.JSON(400, gin.H{"error": "参数错误"})
+		return
+	}
+
+	detail, err := h.similaritySvc.GetPairDetail(userID, assignID, submissionA, submissionB)
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, detail)
+}
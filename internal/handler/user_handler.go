@@ -6,14 +6,18 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// SessionCookieName is the cookie that carries the opaque session token.
+const SessionCookieName = "session_token"
+
 // UserHandler handles user-related requests.
 type UserHandler struct {
 	userSvc service.IUserService
+	authSvc service.IAuthService
 }
 
 // NewUserHandler creates a new UserHandler.
-func NewUserHandler(userSvc service.IUserService) *UserHandler {
-	return &UserHandler{userSvc: userSvc}
+func NewUserHandler(userSvc service.IUserService, authSvc service.IAuthService) *UserHandler {
+	return &UserHandler{userSvc: userSvc, authSvc: authSvc}
 }
 
 // Register handles user registration.
@@ -60,7 +64,15 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	token, _, err := h.authSvc.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "创建会话失败"})
+		return
+	}
+
 	// 设置会话Cookie（不设置MaxAge，即浏览器关闭即失效）
+	// 身份只以 HttpOnly 的会话令牌为准，其余 Cookie 仅供前端展示使用
+	c.SetCookie(SessionCookieName, token, 0, "/", "", false, true)
 	c.SetCookie("user_id", user.ID, 0, "/", "", false, false)
 	c.SetCookie("user_role", user.Role, 0, "/", "", false, false)
 	c.SetCookie("user_name", user.Name, 0, "/", "", false, false)
@@ -73,6 +85,20 @@ func (h *UserHandler) Login(c *gin.Context) {
 	})
 }
 
+// Logout handles user logout by revoking the current session.
+func (h *UserHandler) Logout(c *gin.Context) {
+	if token, _ := c.Cookie(SessionCookieName); token != "" {
+		h.authSvc.RevokeSession(token)
+	}
+
+	c.SetCookie(SessionCookieName, "", -1, "/", "", false, true)
+	c.SetCookie("user_id", "", -1, "/", "", false, false)
+	c.SetCookie("user_role", "", -1, "/", "", false, false)
+	c.SetCookie("user_name", "", -1, "/", "", false, false)
+
+	c.JSON(200, gin.H{"message": "已退出登录"})
+}
+
 // FindUser handles finding a user by username.
 func (h *UserHandler) FindUser(c *gin.Context) {
 	username := c.Query("username")
@@ -154,5 +180,11 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// 密码重置后强制该用户的所有会话重新登录
+	if err := h.authSvc.RevokeUserSessions(req.UserID); err != nil {
+		c.JSON(500, gin.H{"error": "吊销会话失败: " + err.Error()})
+		return
+	}
+
 	c.JSON(200, gin.H{"message": "密码重置成功"})
 }
@@ -9,17 +9,29 @@ import (
 // ========== 用户系统 ==========
 
 type User struct {
-	ID        string  `gorm:"primaryKey;type:uuid"`
-	Username  string  `gorm:"size:100;uniqueIndex"`
-	Password  string  `gorm:"size:100"`
-	Name      string  `gorm:"size:100"`
-	Role      string  `gorm:"size:20"` // teacher, student
-	ClassID   *string `gorm:"index;type:uuid"`
+	ID        string `gorm:"primaryKey;type:uuid"`
+	Username  string `gorm:"size:100;uniqueIndex"`
+	Password  string `gorm:"size:100"`
+	Name      string `gorm:"size:100"`
+	Role      string `gorm:"size:20"` // teacher, student
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
+// UserSession 登录会话，Cookie 中只保存随机令牌，数据库保存其哈希
+type UserSession struct {
+	ID           string     `gorm:"primaryKey;type:uuid"`
+	UserID       string     `gorm:"index;type:uuid"`
+	TokenHash    string     `gorm:"size:64;uniqueIndex"`
+	ExpiresAt    time.Time  `gorm:"index"` // 绝对过期时间
+	LastActiveAt time.Time  // 最近一次访问时间（用于空闲超时）
+	RevokedAt    *time.Time `gorm:"type:timestamp"` // 登出或被吊销的时间
+	UserAgent    string     `gorm:"size:255"`
+	ClientIP     string     `gorm:"size:64"`
+	CreatedAt    time.Time
+}
+
 type Class struct {
 	ID        string `gorm:"primaryKey;type:uuid"`
 	Name      string `gorm:"size:100"`
@@ -27,6 +39,123 @@ type Class struct {
 	Code      string `gorm:"size:20;uniqueIndex"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// 邀请码的有效期和使用限制，重新生成邀请码时使用次数清零
+	CodeExpiresAt   *time.Time `gorm:"type:timestamp"` // 为空表示永不过期
+	CodeMaxUses     int        `gorm:"default:0"`      // 0 表示不限次数
+	CodeUses        int        `gorm:"default:0"`
+	CodeRevoked     bool       `gorm:"default:false"`
+	RequireApproval bool       `gorm:"default:false"` // 学生凭邀请码提交申请，教师审批后才加入
+
+	// 班级所属的课程和学期；归档后班级只读，保留成绩和答疑记录
+	CourseID   *string    `gorm:"index;type:uuid"`
+	TermID     *string    `gorm:"index;type:uuid"`
+	ArchivedAt *time.Time `gorm:"type:timestamp"`
+}
+
+// Course 课程，同一门课程每个学期可以开设多个班级
+type Course struct {
+	ID          string `gorm:"primaryKey;type:uuid"`
+	TeacherID   string `gorm:"index;type:uuid"`
+	Name        string `gorm:"size:100"`
+	Code        string `gorm:"size:50"` // 课程编号，如 CS101
+	Description string `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Term 学期，由教师创建，所有教师共用
+type Term struct {
+	ID        string     `gorm:"primaryKey;type:uuid"`
+	Name      string     `gorm:"size:100;uniqueIndex"` // 如 2025 秋季学期
+	StartDate *time.Time `gorm:"type:timestamp"`
+	EndDate   *time.Time `gorm:"type:timestamp"`
+	CreatedBy string     `gorm:"size:100"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// 学生凭邀请码加入班级的申请状态
+const (
+	JoinPending  = "pending"
+	JoinApproved = "approved"
+	JoinRejected = "rejected"
+)
+
+// ClassJoinRequest 班级开启审批后，学生凭邀请码提交的加入申请
+type ClassJoinRequest struct {
+	ID         string     `gorm:"primaryKey;type:uuid"`
+	ClassID    string     `gorm:"uniqueIndex:idx_class_join_request;type:uuid"`
+	StudentID  string     `gorm:"uniqueIndex:idx_class_join_request;index;type:uuid"`
+	Status     string     `gorm:"size:20;index;default:'pending'"`
+	ReviewedBy string     `gorm:"size:100"`
+	ReviewedAt *time.Time `gorm:"type:timestamp"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ClassGroup 班级内的学习小组，发布为小组作业时组员共用一份提交
+type ClassGroup struct {
+	ID        string `gorm:"primaryKey;type:uuid"`
+	ClassID   string `gorm:"index;type:uuid"`
+	Name      string `gorm:"size:100"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ClassGroupMember 学生所在的小组，学生在一个班级中最多属于一个小组
+type ClassGroupMember struct {
+	ID        string `gorm:"primaryKey;type:uuid"`
+	GroupID   string `gorm:"index;type:uuid"`
+	ClassID   string `gorm:"uniqueIndex:idx_group_member_class_student;type:uuid"`
+	StudentID string `gorm:"uniqueIndex:idx_group_member_class_student;size:100"`
+	CreatedAt time.Time
+}
+
+// 班级教学人员的角色和邀请状态
+const (
+	StaffOwner     = "owner"      // 班级创建者（Class.TeacherID），不在 ClassStaff 表中
+	StaffCoTeacher = "co_teacher" // 合作教师
+	StaffTA        = "ta"         // 助教
+
+	StaffInvited  = "invited" // 已邀请，等待对方接受
+	StaffActive   = "active"
+	StaffDeclined = "declined"
+)
+
+// ClassStaff 班级的合作教师和助教，由班级所有者邀请，对方接受后生效
+type ClassStaff struct {
+	ID          string     `gorm:"primaryKey;type:uuid"`
+	ClassID     string     `gorm:"uniqueIndex:idx_class_staff;type:uuid"`
+	UserID      string     `gorm:"uniqueIndex:idx_class_staff;index;type:uuid"`
+	Role        string     `gorm:"size:20"` // co_teacher, ta
+	Status      string     `gorm:"size:20;index;default:'invited'"`
+	InvitedBy   string     `gorm:"size:100"`
+	RespondedAt *time.Time `gorm:"type:timestamp"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// 学生在班级中的身份和成员状态
+const (
+	ClassRoleStudent = "student" // 正式学生，计入成绩册和班级统计
+	ClassRoleAuditor = "auditor" // 旁听生，可以查看和提交作业，但不计入成绩册
+
+	MemberActive  = "active"
+	MemberRemoved = "removed" // 已退出或被教师移出，保留记录以便重新加入
+)
+
+// ClassMember 学生与班级的成员关系，一个学生可以同时属于多个班级
+type ClassMember struct {
+	ID        string     `gorm:"primaryKey;type:uuid"`
+	ClassID   string     `gorm:"uniqueIndex:idx_class_member;type:uuid"`
+	StudentID string     `gorm:"uniqueIndex:idx_class_member;index;type:uuid"`
+	Role      string     `gorm:"size:20;default:'student'"`
+	Status    string     `gorm:"size:20;index;default:'active'"`
+	JoinedAt  time.Time  `gorm:"type:timestamp"`
+	LeftAt    *time.Time `gorm:"type:timestamp"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // ========== 答疑系统 ==========
@@ -45,6 +174,7 @@ type ChatMessage struct {
 	SessionID string `gorm:"index;type:uuid"`
 	Role      string `gorm:"size:20"` // system, user, assistant
 	Content   string `gorm:"type:text"`
+	Partial   bool   // 流式回答因模型出错中断，只保存了部分内容
 	CreatedAt time.Time
 }
 
@@ -55,13 +185,21 @@ type Assignment struct {
 	Title       string     `gorm:"size:200"`
 	Description string     `gorm:"type:text"`
 	TeacherID   string     `gorm:"size:100"`
-	Type        string     `gorm:"size:20;default:'code'"`  // code, choice, fill, mixed
-	Status      string     `gorm:"size:20;default:'draft'"` // draft, published, closed
-	ClassID     *string    `gorm:"index;type:uuid"`         // 发布到的班级
-	Rubric      string     `gorm:"type:jsonb"`              // 评分标准
-	Deadline    *time.Time `gorm:"type:timestamp"`          // 截止时间
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Type        string     `gorm:"size:20;default:'code'"`     // code, choice, fill, mixed
+	Status      string     `gorm:"size:20;default:'draft'"`    // draft, published, closed
+	ClassID     *string    `gorm:"index;type:uuid"`            // 发布到的班级
+	Rubric      string     `gorm:"type:jsonb"`                 // 评分标准
+	Deadline    *time.Time `gorm:"type:timestamp"`             // 截止时间
+	MaxAttempts int        `gorm:"default:0"`                  // 最多提交次数，0 表示不限
+	ScorePolicy string     `gorm:"size:10;default:'last'"`     // 计分方式：last 最后一次，best 最高分
+	Category    string     `gorm:"size:20;default:'homework'"` // 成绩册分类：homework, quiz, exam
+	// 考试模式：学生开始考试后计时，时间到自动提交最后保存的答案
+	ExamMode         bool
+	DurationMinutes  int  // 考试时长（分钟）
+	ShuffleQuestions bool // 每个学生的题目顺序随机
+	ShuffleOptions   bool // 每个学生的选择题选项顺序随机
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 type Question struct {
@@ -71,8 +209,12 @@ type Question struct {
 	Content      string `gorm:"type:text"`
 	Options      string `gorm:"type:jsonb"` // 选择题选项
 	Answer       string `gorm:"type:text"`
+	GradingRule  string `gorm:"type:jsonb;default:'{}'"` // 客观题判分规则：大小写、空白、替代答案、正则、多选部分得分
 	Score        int
 	OrderNum     int
+	TestCode     string  `gorm:"type:text"`               // 编程题：教师提供的 _test.go 内容
+	TestCases    string  `gorm:"type:jsonb;default:'[]'"` // 编程题：标准输入/输出用例，JSON数组
+	BankItemID   *string `gorm:"index;type:uuid"`         // 从题库选入时对应的题库题目
 }
 
 type Submission struct {
@@ -88,19 +230,268 @@ type Submission struct {
 	QuestionFeedback string `gorm:"type:jsonb"` // 每个题目的批注，JSON格式：{"question_id": "feedback"}
 	QuestionScores   string `gorm:"type:jsonb"` // 每个题目的分数，JSON格式：{"question_id": score}
 	DetailedScore    string `gorm:"type:jsonb"`
+	RunResults       string `gorm:"type:jsonb;default:'{}'"`     // 编程题自动测试结果，JSON格式：{"question_id": 运行结果}
+	StaticAnalysis   string `gorm:"type:jsonb;default:'{}'"`     // Go 代码静态检查结果，JSON格式：{"question_id": 检查结果}，旧作业的整体代码框为 "code"
 	Status           string `gorm:"size:20;default:'submitted'"` // submitted, graded
+	Attempts         int    `gorm:"default:1"`                   // 已提交次数
+	CountedAttempt   int    // 计入成绩的提交版本号
+	RawScore         *int   // 扣除迟交罚分前的得分，TotalScore 为扣分后的得分
+	IsLate           bool   // 是否迟交
+	LateMinutes      int    // 迟交时长（分钟）
+	LatePenalty      int    // 迟交扣分百分比
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
+
+	// 教师逐题复核（区别于仅由 AI 批改）
+	AIQuestionScores string     `gorm:"type:jsonb;default:'{}'"` // 教师改分前 AI 给出的得分，JSON格式：{"question_id": score}
+	AIRawScore       *int       // 教师直接修改总分前 AI 给出的原始得分
+	TeacherReviewed  bool       // 教师是否逐题复核过
+	ReviewedBy       string     `gorm:"size:100"`
+	ReviewedAt       *time.Time `gorm:"type:timestamp"`
+
+	// 小组作业：小组共用一份主提交（GroupID 非空、GroupSubmissionID 为空，StudentID 为第一次提交的组员），
+	// 批改、复核和版本都作用在主提交上；每个组员另有一份副本，得分为小组得分加上教师给该组员的个人调整
+	GroupID           *string `gorm:"index;type:uuid"`
+	GroupSubmissionID *string `gorm:"index;type:uuid"` // 副本对应的主提交
+	GroupScore        *int    // 副本：个人调整前的小组得分
+	ScoreAdjustment   int     // 副本：个人调整分，可以为负
 }
 
+// AnswerDraft 学生作答中自动保存的答案草稿，不参与批改；提交成功后删除
+type AnswerDraft struct {
+	ID           string `gorm:"primaryKey;type:uuid"`
+	AssignmentID string `gorm:"uniqueIndex:idx_draft_assignment_student;type:uuid"`
+	StudentID    string `gorm:"uniqueIndex:idx_draft_assignment_student;size:100"`
+	Answers      string `gorm:"type:jsonb;default:'{}'"`
+	CodeContent  string `gorm:"type:text"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time // 最后一次自动保存的时间
+}
+
+// SubmissionVersion 每次提交保存为一个不可修改的版本；
+// Submission 中的答案和成绩是按作业计分方式选出的那个版本
+type SubmissionVersion struct {
+	ID               string `gorm:"primaryKey;type:uuid"`
+	SubmissionID     string `gorm:"index;type:uuid"`
+	AssignmentID     string `gorm:"index;type:uuid"`
+	StudentID        string `gorm:"size:100"`
+	Attempt          int    // 第几次提交，从 1 开始
+	Answers          string `gorm:"type:jsonb"`
+	CodeContent      string `gorm:"type:text"`
+	TotalScore       *int
+	AIFeedback       string     `gorm:"type:text"`
+	QuestionScores   string     `gorm:"type:jsonb;default:'{}'"`
+	QuestionFeedback string     `gorm:"type:jsonb;default:'{}'"`
+	DetailedScore    string     `gorm:"type:jsonb;default:'{}'"`
+	RunResults       string     `gorm:"type:jsonb;default:'{}'"`
+	StaticAnalysis   string     `gorm:"type:jsonb;default:'{}'"`
+	RawScore         *int       // 扣除迟交罚分前的得分
+	IsLate           bool       // 是否迟交
+	LateMinutes      int        // 迟交时长（分钟）
+	LatePenalty      int        // 迟交扣分百分比
+	AIQuestionScores string     `gorm:"type:jsonb;default:'{}'"` // 教师改分前 AI 给出的得分
+	AIRawScore       *int       // 教师直接修改总分前 AI 给出的原始得分
+	TeacherReviewed  bool       // 教师是否逐题复核过
+	ReviewedBy       string     `gorm:"size:100"`
+	ReviewedAt       *time.Time `gorm:"type:timestamp"`
+	Status           string     `gorm:"size:20;default:'submitted'"` // submitted, graded
+	GradedAt         *time.Time `gorm:"type:timestamp"`
+	CreatedAt        time.Time  // 提交时间
+}
+
+// 批改任务状态
+const (
+	GradingJobQueued    = "queued"    // 等待执行（含等待重试）
+	GradingJobRunning   = "running"   // 正在批改
+	GradingJobSucceeded = "succeeded" // 批改完成
+	GradingJobFailed    = "failed"    // 重试次数用尽
+)
+
+// GradingJob 批改任务，持久化在数据库中，服务重启后继续执行
+type GradingJob struct {
+	ID           string     `gorm:"primaryKey;type:uuid"`
+	SubmissionID string     `gorm:"index;uniqueIndex:idx_grading_job_active,where:status = 'queued' OR status = 'running';type:uuid"` // 同一提交最多一个排队中或执行中的任务
+	AssignmentID string     `gorm:"index;type:uuid"`
+	Status       string     `gorm:"size:20;index;default:'queued'"`
+	Rerun        bool       // 执行期间又有新的提交，完成后需要重新排队
+	Attempts     int        // 已执行次数
+	MaxAttempts  int        // 最多执行次数
+	LastError    string     `gorm:"type:text"`
+	NextRunAt    time.Time  `gorm:"index"` // 下一次可执行时间（用于退避重试）
+	StartedAt    *time.Time `gorm:"type:timestamp"`
+	FinishedAt   *time.Time `gorm:"type:timestamp"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// 相似度检测状态
+const (
+	SimilarityRunning   = "running"
+	SimilaritySucceeded = "succeeded"
+	SimilarityFailed    = "failed"
+)
+
+// SimilarityReport 一次作业内提交相似度（抄袭）检测的结果，检测在后台执行
+type SimilarityReport struct {
+	ID              string  `gorm:"primaryKey;type:uuid"`
+	AssignmentID    string  `gorm:"index;type:uuid"`
+	Status          string  `gorm:"size:20;default:'running'"`
+	Threshold       float64 // 相似度达到该值的提交对视为可疑
+	SubmissionCount int     // 参与比较的提交数
+	SuspiciousPairs int     // 可疑提交对数
+	Result          string  `gorm:"type:jsonb;default:'{}'"` // 可疑提交对、分组和相同片段
+	Error           string  `gorm:"type:text"`
+	RequestedBy     string  `gorm:"size:100"`
+	StartedAt       time.Time
+	FinishedAt      *time.Time `gorm:"type:timestamp"`
+	CreatedAt       time.Time
+}
+
+// 作业在班级中的发放状态
+const (
+	AssignmentClassScheduled = "scheduled" // 等待定时发放，学生不可见
+	AssignmentClassReleased  = "released"  // 已发放
+	AssignmentClassClosed    = "closed"    // 已关闭，学生可以查看但不能再提交
+)
+
 // 作业与班级关联表（支持多班级发布）
 type AssignmentClass struct {
 	ID           string     `gorm:"primaryKey;type:uuid"`
 	AssignmentID string     `gorm:"index;type:uuid"`
 	ClassID      string     `gorm:"index;type:uuid"`
 	Deadline     *time.Time `gorm:"type:timestamp"` // 该班级的截止时间
-	ReleasedAt   *time.Time `gorm:"type:timestamp"` // 发放时间（发布给该班级的时间）
+	ReleasedAt   *time.Time `gorm:"type:timestamp"` // 发放时间，晚于当前时间表示定时发放
+	ClosesAt     *time.Time `gorm:"type:timestamp"` // 关闭时间，之后不再接受提交（个人延期更晚时以延期为准），为空表示不关闭
+	// 发放状态：scheduled, released, closed，由定时任务按发放和关闭时间切换
+	Status string `gorm:"size:20;index;default:'released'"`
+	// 迟交规则：不允许迟交时截止（含宽限期）后拒绝提交；
+	// 允许迟交时宽限期后每迟交一天（不足一天按一天计）扣除一定比例的得分，最终截止时间后拒绝提交
+	AllowLate         bool
+	GraceMinutes      int        // 宽限时间（分钟），宽限期内提交不算迟交
+	LatePenaltyPerDay int        // 每迟交一天扣除的得分百分比
+	HardCutoff        *time.Time `gorm:"type:timestamp"` // 最终截止时间，为空表示不限
+	CreatedAt         time.Time
+
+	// 小组作业：同组学生共用一份提交，成绩同步给每个组员
+	GroupWork bool `gorm:"default:false"`
+}
+
+// DeadlineExtension 教师为单个学生延长的截止时间，优先于班级截止时间
+type DeadlineExtension struct {
+	ID           string    `gorm:"primaryKey;type:uuid"`
+	AssignmentID string    `gorm:"uniqueIndex:idx_extension_assignment_student;type:uuid"`
+	StudentID    string    `gorm:"uniqueIndex:idx_extension_assignment_student;size:100"`
+	Deadline     time.Time `gorm:"type:timestamp"`
+	Reason       string    `gorm:"size:500"`
+	GrantedBy    string    `gorm:"size:100"`
 	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// 考试状态
+const (
+	ExamInProgress    = "in_progress"    // 作答中
+	ExamSubmitted     = "submitted"      // 学生交卷
+	ExamAutoSubmitted = "auto_submitted" // 时间到自动交卷
+)
+
+// ExamSession 学生一次考试的计时与作答草稿；Seed 决定该学生看到的题目和选项顺序
+type ExamSession struct {
+	ID           string     `gorm:"primaryKey;type:uuid"`
+	AssignmentID string     `gorm:"uniqueIndex:idx_exam_assignment_student;type:uuid"`
+	StudentID    string     `gorm:"uniqueIndex:idx_exam_assignment_student;size:100"`
+	StudentName  string     `gorm:"size:100"`
+	Seed         int64      // 乱序种子
+	StartedAt    time.Time  `gorm:"type:timestamp"`
+	EndsAt       time.Time  `gorm:"type:timestamp;index"`    // 考试结束时间（开始时间加时长，不晚于截止时间）
+	SavedAnswers string     `gorm:"type:jsonb;default:'{}'"` // 最后保存的答案（按原始选项字母保存）
+	SavedCode    string     `gorm:"type:text"`
+	LastSavedAt  *time.Time `gorm:"type:timestamp"`
+	Status       string     `gorm:"size:20;index;default:'in_progress'"`
+	SubmittedAt  *time.Time `gorm:"type:timestamp"`
+	SubmissionID *string    `gorm:"type:uuid"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// ========== 成绩册 ==========
+
+// 成绩册中的作业分类
+const (
+	CategoryHomework = "homework" // 作业
+	CategoryQuiz     = "quiz"     // 小测
+	CategoryExam     = "exam"     // 考试
+)
+
+// GradebookSettings 班级成绩册的计算规则：各分类权重、去掉最低分的次数、缺交是否按零分计以及等级划分
+type GradebookSettings struct {
+	ID            string `gorm:"primaryKey;type:uuid"`
+	ClassID       string `gorm:"uniqueIndex;type:uuid"`
+	Categories    string `gorm:"type:jsonb;default:'[]'"` // [{"name": "homework", "weight": 40, "drop_lowest": 1}, ...]
+	MissingAsZero bool   // 截止后仍未提交的作业按零分计入
+	LetterScale   string `gorm:"type:jsonb;default:'[]'"` // 等级划分，按最低百分比降序：[{"letter": "A", "min": 90}, ...]
+	UpdatedBy     string `gorm:"size:100"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// GradeOverride 教师在成绩册中手动修改的单元格，优先于提交记录中的得分
+type GradeOverride struct {
+	ID           string  `gorm:"primaryKey;type:uuid"`
+	ClassID      string  `gorm:"uniqueIndex:idx_override_cell;type:uuid"`
+	AssignmentID string  `gorm:"uniqueIndex:idx_override_cell;type:uuid"`
+	StudentID    string  `gorm:"uniqueIndex:idx_override_cell;size:100"`
+	Score        float64 // 覆盖后的得分（原始分值，不是百分比）
+	Reason       string  `gorm:"size:500"`
+	UpdatedBy    string  `gorm:"size:100"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// GradeAuditLog 成绩册单元格的修改记录，只增不改
+type GradeAuditLog struct {
+	ID            uint     `gorm:"primaryKey"`
+	ClassID       string   `gorm:"index;type:uuid"`
+	AssignmentID  string   `gorm:"type:uuid"`
+	StudentID     string   `gorm:"size:100"`
+	OldScore      *float64 // 修改前单元格的得分，为空表示没有成绩
+	NewScore      *float64 // 修改后单元格的得分，为空表示没有成绩
+	Overridden    bool     // 修改后是否为手动成绩；false 表示撤销手动成绩、恢复为提交得分
+	Reason        string   `gorm:"size:500"`
+	ChangedBy     string   `gorm:"size:100"`
+	ChangedByName string   `gorm:"size:100"`
+	CreatedAt     time.Time
+}
+
+// ========== 成绩申诉 ==========
+
+// 成绩申诉状态
+const (
+	AppealOpen         = "open"         // 等待教师处理
+	AppealReevaluating = "reevaluating" // 已交给 AI 重新批改，批改完成后自动结案
+	AppealAccepted     = "accepted"     // 教师接受申诉并调整了分数
+	AppealRejected     = "rejected"     // 教师驳回申诉
+	AppealReevaluated  = "reevaluated"  // AI 重新批改完成
+)
+
+// GradeAppeal 学生针对某道题的得分提出的复核申请
+type GradeAppeal struct {
+	ID             string     `gorm:"primaryKey;type:uuid"`
+	SubmissionID   string     `gorm:"index;type:uuid"`
+	AssignmentID   string     `gorm:"index;type:uuid"`
+	ClassID        string     `gorm:"index;size:100"` // 提出申诉时学生所在的班级，教师按班级处理申诉
+	StudentID      string     `gorm:"index;size:100"`
+	StudentName    string     `gorm:"size:100"`
+	QuestionID     string     `gorm:"type:uuid"`
+	Reason         string     `gorm:"type:text"`
+	Status         string     `gorm:"size:20;index;default:'open'"`
+	OriginalScore  *int       // 提出申诉时该题的得分
+	ResolvedScore  *int       // 结案后该题的得分
+	TeacherComment string     `gorm:"type:text"`
+	ResolvedBy     string     `gorm:"size:100"`
+	ResolvedAt     *time.Time `gorm:"type:timestamp"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 // 包含班级名称的作业-班级关联结构
@@ -112,13 +503,13 @@ type AssignmentClassWithClassName struct {
 // ========== 反馈系统 ==========
 
 type Feedback struct {
-	ID              uint   `gorm:"primaryKey"`
-	Title           string `gorm:"size:200"`
-	Content         string `gorm:"type:text"`
-	AnonymousID     string `gorm:"size:100"`
-	Type            string `gorm:"size:20"` // bug, feature, praise, other
-	Status          string `gorm:"size:20;default:'open';index"`
-	LikeCount       int    `gorm:"index"`
+	ID              uint       `gorm:"primaryKey"`
+	Title           string     `gorm:"size:200"`
+	Content         string     `gorm:"type:text"`
+	AnonymousID     string     `gorm:"size:100"`
+	Type            string     `gorm:"size:20"` // bug, feature, praise, other
+	Status          string     `gorm:"size:20;default:'open';index"`
+	LikeCount       int        `gorm:"index"`
 	TeacherResponse string     `gorm:"type:text"`      // 教师回复内容
 	RespondedAt     *time.Time `gorm:"type:timestamp"` // 回复时间
 	CreatedAt       time.Time
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// BankQuestion 题库中的题目，归属于创建它的教师，可被多个作业复用。
+// 加入作业时会复制为一道新的 Question，之后修改题库不会影响已组好的作业。
+type BankQuestion struct {
+	ID               string `gorm:"primaryKey;type:uuid"`
+	TeacherID        string `gorm:"index;type:uuid"`
+	Shared           bool   `gorm:"index"`         // 是否共享给其他教师
+	Type             string `gorm:"size:20;index"` // choice, fill, code
+	Content          string `gorm:"type:text"`
+	Options          string `gorm:"type:jsonb"`
+	Answer           string `gorm:"type:text"`
+	GradingRule      string `gorm:"type:jsonb;default:'{}'"`
+	TestCode         string `gorm:"type:text"`
+	TestCases        string `gorm:"type:jsonb;default:'[]'"`
+	Score            int
+	KnowledgePointID *uint   `gorm:"index"`
+	Difficulty       string  `gorm:"size:20;index"` // easy, medium, hard
+	SourceQuestionID *string `gorm:"type:uuid"`     // 从作业题目保存而来时记录来源题目
+	UsageCount       int     // 被加入作业的次数
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
@@ -0,0 +1,519 @@
+// Package codecheck 对学生提交的 Go 代码做静态检查：语法错误、gofmt 格式、
+// 类型检查（未使用的变量和导入）、变量遮蔽、忽略错误返回值、圈复杂度和常见风格问题。
+// 检查结果作为事实提供给 AI 批改，并以行内批注的形式展示给学生。
+package codecheck
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"sync"
+
+	"GoCodeMentor/internal/pkg/textdiff"
+)
+
+// 问题类别
+const (
+	RuleSyntax         = "syntax"          // 语法错误
+	RuleFormat         = "gofmt"           // 未按 gofmt 格式化
+	RuleType           = "type"            // 编译（类型检查）错误
+	RuleUnused         = "unused"          // 未使用的变量或导入
+	RuleShadow         = "shadow"          // 变量遮蔽
+	RuleUncheckedError = "unchecked-error" // 忽略了错误返回值
+	RuleComplexity     = "complexity"      // 圈复杂度过高
+	RuleStyle          = "style"           // 风格问题
+)
+
+// 严重程度
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+const (
+	// ComplexityLimit 圈复杂度超过该值时给出警告
+	ComplexityLimit = 10
+	// maxFuncLines 函数超过该行数时给出风格提示
+	maxFuncLines = 60
+	// maxSyntaxErrors 最多报告的语法错误数
+	maxSyntaxErrors = 5
+	// maxFindings 单份代码最多保留的问题数
+	maxFindings = 50
+)
+
+// Finding 一条检查结果
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// FuncComplexity 函数的圈复杂度
+type FuncComplexity struct {
+	Name       string `json:"name"`
+	Line       int    `json:"line"`
+	Complexity int    `json:"complexity"`
+}
+
+// Report 一份代码的检查结果
+type Report struct {
+	Findings   []Finding        `json:"findings"`
+	FormatDiff string           `json:"format_diff,omitempty"` // gofmt 前后的差异（unified 格式）
+	Complexity []FuncComplexity `json:"complexity,omitempty"`
+}
+
+// HasErrors 是否存在语法或编译错误
+func (r *Report) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// source 解析后的代码；学生代码可能缺少 package 声明或只是一段语句，解析时会补全
+type source struct {
+	fset   *token.FileSet
+	file   *ast.File
+	offset int  // 补全内容占用的行数
+	whole  bool // 补全后仍是完整文件（只补了 package 声明），可以检查 gofmt
+}
+
+func (s *source) line(pos token.Pos) int {
+	return s.fset.Position(pos).Line - s.offset
+}
+
+// Analyze 检查一段 Go 代码
+func Analyze(code string) *Report {
+	report := &Report{Findings: []Finding{}}
+	if strings.TrimSpace(code) == "" {
+		return report
+	}
+
+	src, syntaxErrs := parse(code)
+	if src == nil {
+		report.Findings = append(report.Findings, syntaxErrs...)
+		return report.finish()
+	}
+
+	if src.whole {
+		checkFormat(report, code, src.offset > 0)
+	}
+	info := typeCheck(report, src)
+	if info != nil {
+		checkShadow(report, src, info)
+		checkUncheckedErrors(report, src, info)
+	}
+	checkComplexity(report, src)
+	checkStyle(report, src)
+	return report.finish()
+}
+
+func (r *Report) finish() *Report {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		if r.Findings[i].Line != r.Findings[j].Line {
+			return r.Findings[i].Line < r.Findings[j].Line
+		}
+		return r.Findings[i].Column < r.Findings[j].Column
+	})
+	if len(r.Findings) > maxFindings {
+		r.Findings = r.Findings[:maxFindings]
+	}
+	return r
+}
+
+func parse(code string) (*source, []Finding) {
+	// 依次尝试：完整文件、缺少 package 声明的文件、一段语句
+	attempts := []struct {
+		prefix, suffix string
+		whole          bool
+	}{
+		{"", "", true},
+		{"package main\n\n", "", true},
+		{"package main\nfunc _() {\n", "\n}", false},
+	}
+
+	// 都解析失败时，采用解析得最远的那次尝试的错误，它最可能反映代码的真实写法
+	var bestErrs scanner.ErrorList
+	var bestOffset int
+	for _, attempt := range attempts {
+		fset := token.NewFileSet()
+		offset := strings.Count(attempt.prefix, "\n")
+		file, err := parser.ParseFile(fset, "", attempt.prefix+code+attempt.suffix, parser.AllErrors)
+		if err == nil {
+			return &source{fset: fset, file: file, offset: offset, whole: attempt.whole}, nil
+		}
+		var list scanner.ErrorList
+		if !errors.As(err, &list) || len(list) == 0 {
+			continue
+		}
+		if bestErrs == nil || list[0].Pos.Line-offset > bestErrs[0].Pos.Line-bestOffset {
+			bestErrs, bestOffset = list, offset
+		}
+	}
+	return nil, syntaxFindings(bestErrs, bestOffset, strings.Count(code, "\n")+1)
+}
+
+// syntaxFindings 转换解析错误；后续错误多是第一个错误的连锁反应，每行只保留一条并限制总数
+func syntaxFindings(list scanner.ErrorList, offset, lines int) []Finding {
+	if len(list) == 0 {
+		return []Finding{{Rule: RuleSyntax, Severity: SeverityError, Line: 1, Message: "语法错误: 代码无法解析"}}
+	}
+	var findings []Finding
+	seen := make(map[int]bool)
+	for _, e := range list {
+		line := min(max(e.Pos.Line-offset, 1), lines)
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		findings = append(findings, Finding{Rule: RuleSyntax, Severity: SeverityError, Line: line, Column: e.Pos.Column, Message: "语法错误: " + e.Msg})
+		if len(findings) == maxSyntaxErrors {
+			break
+		}
+	}
+	return findings
+}
+
+func checkFormat(report *Report, code string, addedPackage bool) {
+	input := code
+	if addedPackage {
+		input = "package main\n\n" + code
+	}
+	formatted, err := format.Source([]byte(input))
+	if err != nil {
+		return
+	}
+	output := string(formatted)
+	if addedPackage {
+		output = strings.TrimPrefix(output, "package main\n\n")
+	}
+	if strings.TrimRight(output, "\n") == strings.TrimRight(code, "\n") {
+		return
+	}
+
+	lines := textdiff.Lines(code, output)
+	report.FormatDiff = textdiff.Unified(lines)
+	line := 1
+	for _, l := range lines {
+		if l.Op != textdiff.Equal {
+			break
+		}
+		line++
+	}
+	report.Findings = append(report.Findings, Finding{
+		Rule: RuleFormat, Severity: SeverityInfo, Line: line,
+		Message: "代码未按 gofmt 格式化（缩进、空格或换行与标准格式不一致）",
+	})
+}
+
+var (
+	importerMu sync.Mutex
+	// stdImporter 从标准库源码导入包，结果会被缓存；不是并发安全的，使用时需加锁
+	stdImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+)
+
+// typeCheck 做类型检查，报告编译错误和未使用的变量/导入；无法导入依赖包时只保留可靠的结果
+func typeCheck(report *Report, src *source) *types.Info {
+	info := &types.Info{
+		Types:  make(map[ast.Expr]types.TypeAndValue),
+		Defs:   make(map[*ast.Ident]types.Object),
+		Uses:   make(map[*ast.Ident]types.Object),
+		Scopes: make(map[ast.Node]*types.Scope),
+	}
+
+	var typeErrs []types.Error
+	importFailed := false
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			importerMu.Lock()
+			defer importerMu.Unlock()
+			pkg, err := stdImporter.Import(path)
+			if err != nil {
+				importFailed = true
+			}
+			return pkg, err
+		}),
+		Error: func(err error) {
+			var te types.Error
+			if errors.As(err, &te) {
+				typeErrs = append(typeErrs, te)
+			}
+		},
+	}
+	conf.Check("main", src.fset, []*ast.File{src.file}, info)
+
+	for _, te := range typeErrs {
+		rule, severity := RuleType, SeverityError
+		switch {
+		case strings.Contains(te.Msg, "declared and not used"), strings.Contains(te.Msg, "imported and not used"):
+			rule = RuleUnused
+		case importFailed || te.Soft || src.offset > 0:
+			// 依赖包无法导入或代码不是完整文件（缺少 import）时，其他类型错误不可靠
+			continue
+		}
+		pos := src.fset.Position(te.Pos)
+		report.Findings = append(report.Findings, Finding{
+			Rule: rule, Severity: severity, Line: pos.Line - src.offset, Column: pos.Column,
+			Message: translateTypeError(te.Msg),
+		})
+	}
+	return info
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+func translateTypeError(msg string) string {
+	switch {
+	case strings.Contains(msg, "declared and not used"):
+		return "变量声明后未使用: " + msg
+	case strings.Contains(msg, "imported and not used"):
+		return "导入的包未使用: " + msg
+	}
+	return "编译错误: " + msg
+}
+
+// checkShadow 报告用 := 重新声明外层同名局部变量的情况（vet shadow）
+func checkShadow(report *Report, src *source, info *types.Info) {
+	ast.Inspect(src.file, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE {
+			return true
+		}
+		for _, lhs := range assign.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok || ident.Name == "_" {
+				continue
+			}
+			obj, ok := info.Defs[ident].(*types.Var)
+			if !ok || obj.Parent() == nil || obj.Parent().Parent() == nil {
+				continue
+			}
+			_, outer := obj.Parent().Parent().LookupParent(ident.Name, ident.Pos())
+			shadowed, ok := outer.(*types.Var)
+			if !ok || shadowed.Pkg() == nil || shadowed.Parent() == shadowed.Pkg().Scope() {
+				// 只关心遮蔽局部变量，遮蔽包级变量或内置标识符不报告
+				continue
+			}
+			report.Findings = append(report.Findings, Finding{
+				Rule: RuleShadow, Severity: SeverityWarning, Line: src.line(ident.Pos()),
+				Message: fmt.Sprintf("变量 %s 遮蔽了第 %d 行声明的同名变量，修改的可能不是你以为的那个变量", ident.Name, src.line(shadowed.Pos())),
+			})
+		}
+		return true
+	})
+}
+
+// 返回 error 但通常不需要检查的函数
+var ignoredErrorFuncs = map[string]bool{
+	"fmt.Print": true, "fmt.Println": true, "fmt.Printf": true,
+	"fmt.Fprint": true, "fmt.Fprintln": true, "fmt.Fprintf": true,
+}
+
+// checkUncheckedErrors 报告调用结果中的 error 被直接丢弃或赋给 _ 的情况
+func checkUncheckedErrors(report *Report, src *source, info *types.Info) {
+	ast.Inspect(src.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			call, ok := n.X.(*ast.CallExpr)
+			if !ok || ignoredErrorFuncs[calleeName(call)] {
+				return true
+			}
+			if errorResultIndex(info, call) >= 0 {
+				report.Findings = append(report.Findings, Finding{
+					Rule: RuleUncheckedError, Severity: SeverityWarning, Line: src.line(call.Pos()),
+					Message: fmt.Sprintf("%s 返回的错误没有被检查", calleeName(call)),
+				})
+			}
+		case *ast.AssignStmt:
+			if len(n.Rhs) != 1 {
+				return true
+			}
+			call, ok := n.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			index := errorResultIndex(info, call)
+			if index < 0 || index >= len(n.Lhs) {
+				return true
+			}
+			if ident, ok := n.Lhs[index].(*ast.Ident); ok && ident.Name == "_" {
+				report.Findings = append(report.Findings, Finding{
+					Rule: RuleUncheckedError, Severity: SeverityWarning, Line: src.line(call.Pos()),
+					Message: fmt.Sprintf("%s 返回的错误被赋给 _ 而忽略", calleeName(call)),
+				})
+			}
+		}
+		return true
+	})
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// errorResultIndex 返回调用结果中 error 的位置，没有 error 时返回 -1
+func errorResultIndex(info *types.Info, call *ast.CallExpr) int {
+	tv, ok := info.Types[call]
+	if !ok || tv.Type == nil {
+		return -1
+	}
+	if tuple, ok := tv.Type.(*types.Tuple); ok {
+		for i := 0; i < tuple.Len(); i++ {
+			if types.Identical(tuple.At(i).Type(), errorType) {
+				return i
+			}
+		}
+		return -1
+	}
+	if types.Identical(tv.Type, errorType) {
+		return 0
+	}
+	return -1
+}
+
+func calleeName(call *ast.CallExpr) string {
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return fn.Name
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok {
+			return x.Name + "." + fn.Sel.Name
+		}
+		return fn.Sel.Name
+	}
+	return "函数调用"
+}
+
+// checkComplexity 计算每个函数的圈复杂度：1 + 分支数（if、for、case、&&、||）
+func checkComplexity(report *Report, src *source) {
+	for _, decl := range src.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		line := src.line(fn.Pos())
+		if line < 1 {
+			// 为补全语句片段而添加的函数，按代码第一行计
+			line = 1
+		}
+		name := fn.Name.Name
+		if name == "_" {
+			name = "代码片段"
+		}
+
+		complexity := cyclomatic(fn.Body)
+		report.Complexity = append(report.Complexity, FuncComplexity{Name: name, Line: line, Complexity: complexity})
+		if complexity > ComplexityLimit {
+			report.Findings = append(report.Findings, Finding{
+				Rule: RuleComplexity, Severity: SeverityWarning, Line: line,
+				Message: fmt.Sprintf("%s 的圈复杂度为 %d（建议不超过 %d），可以考虑拆分为更小的函数", name, complexity, ComplexityLimit),
+			})
+		}
+	}
+}
+
+func cyclomatic(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// checkStyle 检查常见的风格问题：下划线命名、与布尔字面量比较、return 之后多余的 else、过长的函数
+func checkStyle(report *Report, src *source) {
+	ast.Inspect(src.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			checkName(report, src, n.Name)
+			if n.Body != nil && src.line(n.Pos()) >= 1 {
+				if lines := src.line(n.End()) - src.line(n.Pos()) + 1; lines > maxFuncLines {
+					report.Findings = append(report.Findings, Finding{
+						Rule: RuleStyle, Severity: SeverityInfo, Line: src.line(n.Pos()),
+						Message: fmt.Sprintf("函数 %s 有 %d 行，过长的函数难以阅读，建议拆分", n.Name.Name, lines),
+					})
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						checkName(report, src, ident)
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, ident := range n.Names {
+				checkName(report, src, ident)
+			}
+		case *ast.BinaryExpr:
+			if (n.Op == token.EQL || n.Op == token.NEQ) && (isBoolLiteral(n.X) || isBoolLiteral(n.Y)) {
+				report.Findings = append(report.Findings, Finding{
+					Rule: RuleStyle, Severity: SeverityInfo, Line: src.line(n.Pos()),
+					Message: "不需要与 true/false 比较，直接使用布尔表达式即可",
+				})
+			}
+		case *ast.IfStmt:
+			if n.Else != nil && endsWithReturn(n.Body) {
+				report.Findings = append(report.Findings, Finding{
+					Rule: RuleStyle, Severity: SeverityInfo, Line: src.line(n.Else.Pos()),
+					Message: "if 分支以 return 结束，可以去掉 else 并减少缩进",
+				})
+			}
+		}
+		return true
+	})
+}
+
+func checkName(report *Report, src *source, ident *ast.Ident) {
+	name := ident.Name
+	if name == "_" || !strings.Contains(strings.Trim(name, "_"), "_") || src.line(ident.Pos()) < 1 {
+		return
+	}
+	report.Findings = append(report.Findings, Finding{
+		Rule: RuleStyle, Severity: SeverityInfo, Line: src.line(ident.Pos()),
+		Message: fmt.Sprintf("Go 使用驼峰命名，%s 不应包含下划线", name),
+	})
+}
+
+func isBoolLiteral(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && (ident.Name == "true" || ident.Name == "false")
+}
+
+func endsWithReturn(block *ast.BlockStmt) bool {
+	if block == nil || len(block.List) == 0 {
+		return false
+	}
+	_, ok := block.List[len(block.List)-1].(*ast.ReturnStmt)
+	return ok
+}
@@ -0,0 +1,161 @@
+package excel
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxSheetNameLen Excel 工作表名称的最大长度
+const maxSheetNameLen = 31
+
+// Sheet 一个导出的表格：标题行加数据行，单元格为 nil 时留空
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// CreateWorkbook 把多个表格写入同一个工作簿，每个表格一个工作表
+func CreateWorkbook(sheets []Sheet) (*excelize.File, error) {
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("没有可导出的数据")
+	}
+
+	f := excelize.NewFile()
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E5E7EB"}, Pattern: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for i, sheet := range sheets {
+		name := uniqueSheetName(sheet.Name, used)
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), name); err != nil {
+				return nil, err
+			}
+		} else if _, err := f.NewSheet(name); err != nil {
+			return nil, err
+		}
+		if err := writeSheet(f, name, sheet, headerStyle); err != nil {
+			return nil, fmt.Errorf("写入工作表 %s 失败: %v", name, err)
+		}
+	}
+	f.SetActiveSheet(0)
+	return f, nil
+}
+
+func writeSheet(f *excelize.File, name string, sheet Sheet, headerStyle int) error {
+	header := make([]interface{}, len(sheet.Header))
+	for i, h := range sheet.Header {
+		header[i] = h
+	}
+	if err := f.SetSheetRow(name, "A1", &header); err != nil {
+		return err
+	}
+	for i, row := range sheet.Rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		values := row
+		if err := f.SetSheetRow(name, cell, &values); err != nil {
+			return err
+		}
+	}
+
+	if len(sheet.Header) > 0 {
+		last, err := excelize.ColumnNumberToName(len(sheet.Header))
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(name, "A1", last+"1", headerStyle); err != nil {
+			return err
+		}
+		if err := f.SetColWidth(name, "A", last, 14); err != nil {
+			return err
+		}
+		// 冻结标题行，方便浏览长名单
+		if err := f.SetPanes(name, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueSheetName 去掉工作表名称中不允许的字符并截断到 31 个字符，重名时追加序号
+func uniqueSheetName(name string, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = "Sheet"
+	}
+	base := truncateRunes(name, maxSheetNameLen)
+	candidate := base
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		suffix := fmt.Sprintf("(%d)", i)
+		candidate = truncateRunes(base, maxSheetNameLen-len(suffix)) + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// WriteCSV 把表格写成 CSV；开头写入 UTF-8 BOM，Excel 打开时中文不会乱码
+func WriteCSV(w io.Writer, sheet Sheet) error {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	header := make([]string, len(sheet.Header))
+	for i, h := range sheet.Header {
+		header[i] = escapeFormula(h)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range sheet.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			switch v := v.(type) {
+			case nil:
+			case string:
+				record[i] = escapeFormula(v)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapeFormula 在以 = + - @ 制表符或回车开头的文本前加单引号，
+// 避免学生姓名、答案等内容在 Excel 中被当作公式执行
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
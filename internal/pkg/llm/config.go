@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// ProviderConfig 单个模型提供方的配置
+type ProviderConfig struct {
+	Name      string   `mapstructure:"-"`
+	Type      string   `mapstructure:"type"` // siliconflow, openai, ollama, scripted
+	BaseURL   string   `mapstructure:"base_url"`
+	Model     string   `mapstructure:"model"`
+	APIKey    string   `mapstructure:"api_key"`
+	APIKeyEnv string   `mapstructure:"api_key_env"` // 设置后优先从该环境变量读取 API Key
+	MaxTokens int      `mapstructure:"max_tokens"`
+	Script    []string `mapstructure:"script"` // scripted 类型依次返回的回复
+}
+
+// Config 模型配置：定义可用的提供方，并为每个功能指定使用哪一个
+type Config struct {
+	DefaultProvider string                    `mapstructure:"default_provider"`
+	Providers       map[string]ProviderConfig `mapstructure:"providers"`
+	Features        map[string]string         `mapstructure:"features"` // 功能名 -> 提供方名
+}
+
+// DefaultConfig 没有配置文件时的兜底配置：所有功能都使用硅基流动
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultProvider: "siliconflow",
+		Providers: map[string]ProviderConfig{
+			"siliconflow": {Type: "siliconflow"},
+		},
+		Features: map[string]string{},
+	}
+}
+
+// LoadConfig 加载模型配置文件
+func LoadConfig(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("config file not found: %w", err)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("unable to decode into struct: %w", err)
+	}
+
+	log.Printf("Successfully loaded LLM config file: %s", v.ConfigFileUsed())
+	return &config, nil
+}
+
+func (c ProviderConfig) resolveAPIKey() string {
+	if c.APIKeyEnv != "" {
+		if key := os.Getenv(c.APIKeyEnv); key != "" {
+			return key
+		}
+	}
+	return c.APIKey
+}
+
+// Registry 保存已创建的提供方，并按功能返回对应的提供方
+type Registry struct {
+	providers       map[string]LLMProvider
+	features        map[string]string
+	defaultProvider string
+}
+
+// NewRegistry 根据配置创建所有提供方
+func NewRegistry(cfg *Config) (*Registry, error) {
+	r := &Registry{
+		providers:       make(map[string]LLMProvider),
+		features:        cfg.Features,
+		defaultProvider: cfg.DefaultProvider,
+	}
+
+	for name, pc := range cfg.Providers {
+		pc.Name = name
+		provider, err := NewProvider(pc)
+		if err != nil {
+			return nil, fmt.Errorf("创建模型提供方 %s 失败: %w", name, err)
+		}
+		r.providers[name] = provider
+	}
+
+	if _, ok := r.providers[r.defaultProvider]; !ok {
+		return nil, fmt.Errorf("默认模型提供方 %q 未配置", r.defaultProvider)
+	}
+	for feature, name := range r.features {
+		if _, ok := r.providers[name]; !ok {
+			return nil, fmt.Errorf("功能 %s 引用了未配置的模型提供方 %q", feature, name)
+		}
+	}
+
+	return r, nil
+}
+
+// NewProvider 按类型创建单个提供方
+func NewProvider(cfg ProviderConfig) (LLMProvider, error) {
+	switch cfg.Type {
+	case "siliconflow", "":
+		return NewSiliconFlowProvider(cfg)
+	case "openai":
+		return NewOpenAIProvider(cfg)
+	case "ollama":
+		return NewOllamaProvider(cfg)
+	case "scripted":
+		return NewScriptedProvider(cfg.Name, cfg.Script...), nil
+	default:
+		return nil, fmt.Errorf("不支持的模型提供方类型: %s", cfg.Type)
+	}
+}
+
+// For 返回某个功能应使用的提供方，未单独配置的功能使用默认提供方
+func (r *Registry) For(feature string) LLMProvider {
+	if name, ok := r.features[feature]; ok {
+		return r.providers[name]
+	}
+	return r.providers[r.defaultProvider]
+}
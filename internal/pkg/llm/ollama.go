@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const ollamaDefaultBaseURL = "http://localhost:11434"
+
+// OllamaProvider 适配 Ollama 风格的本地模型服务（/api/chat）
+type OllamaProvider struct {
+	name      string
+	baseURL   string
+	model     string
+	maxTokens int
+	http      *http.Client
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]int  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error"`
+}
+
+// NewOllamaProvider 创建本地模型提供方
+func NewOllamaProvider(cfg ProviderConfig) (*OllamaProvider, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("ollama 提供方必须配置 model")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+
+	return &OllamaProvider{
+		name:      cfg.Name,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		model:     cfg.Model,
+		maxTokens: cfg.MaxTokens,
+		http:      &http.Client{},
+	}, nil
+}
+
+func (p *OllamaProvider) Name() string {
+	return p.name
+}
+
+// Chat 非流式对话
+func (p *OllamaProvider) Chat(ctx context.Context, systemPrompt, userMessage string) (string, error) {
+	return p.ChatWithHistory(ctx, buildMessages(systemPrompt, userMessage))
+}
+
+// ChatWithHistory 带历史记录的对话
+func (p *OllamaProvider) ChatWithHistory(ctx context.Context, messages []Message) (string, error) {
+	resp, err := p.post(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析 ollama 响应失败: %w", err)
+	}
+	if result.Error != "" {
+		return "", errors.New(result.Error)
+	}
+	return result.Message.Content, nil
+}
+
+// ChatStream 流式对话，Ollama 以逐行 JSON 的形式返回增量内容
+func (p *OllamaProvider) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	resp, err := p.post(ctx, messages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resultChan := make(chan StreamChunk)
+	go func() {
+		defer close(resultChan)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			var chunk ollamaResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				sendChunk(ctx, resultChan, StreamChunk{Err: fmt.Errorf("解析 ollama 流式响应失败: %w", err)})
+				return
+			}
+			if chunk.Error != "" {
+				sendChunk(ctx, resultChan, StreamChunk{Err: errors.New(chunk.Error)})
+				return
+			}
+			if chunk.Message.Content != "" && !sendChunk(ctx, resultChan, StreamChunk{Content: chunk.Message.Content}) {
+				return
+			}
+			if chunk.Done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			sendChunk(ctx, resultChan, StreamChunk{Err: err})
+		}
+	}()
+
+	return resultChan, nil
+}
+
+func (p *OllamaProvider) post(ctx context.Context, messages []Message, stream bool) (*http.Response, error) {
+	reqBody := ollamaRequest{
+		Model:    p.model,
+		Messages: make([]ollamaMessage, 0, len(messages)),
+		Stream:   stream,
+	}
+	for _, m := range messages {
+		reqBody.Messages = append(reqBody.Messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+	if p.maxTokens > 0 {
+		reqBody.Options = map[string]int{"num_predict": p.maxTokens}
+	}
+
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var errResp ollamaResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		if errResp.Error != "" {
+			return nil, fmt.Errorf("ollama 返回错误 (%d): %s", resp.StatusCode, errResp.Error)
+		}
+		return nil, fmt.Errorf("ollama 返回错误状态码: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
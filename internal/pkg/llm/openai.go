@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIProvider 适配任意兼容 OpenAI Chat Completions 接口的服务
+type OpenAIProvider struct {
+	name      string
+	cli       *openai.Client
+	model     string
+	maxTokens int
+}
+
+// NewOpenAIProvider 创建 OpenAI 兼容的提供方
+func NewOpenAIProvider(cfg ProviderConfig) (*OpenAIProvider, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("openai 兼容提供方必须配置 model")
+	}
+
+	config := openai.DefaultConfig(cfg.resolveAPIKey())
+	if cfg.BaseURL != "" {
+		config.BaseURL = cfg.BaseURL
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 4096
+	}
+
+	return &OpenAIProvider{
+		name:      cfg.Name,
+		cli:       openai.NewClientWithConfig(config),
+		model:     cfg.Model,
+		maxTokens: maxTokens,
+	}, nil
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+// Chat 非流式对话
+func (p *OpenAIProvider) Chat(ctx context.Context, systemPrompt, userMessage string) (string, error) {
+	return p.ChatWithHistory(ctx, buildMessages(systemPrompt, userMessage))
+}
+
+// ChatWithHistory 带历史记录的对话
+func (p *OpenAIProvider) ChatWithHistory(ctx context.Context, messages []Message) (string, error) {
+	resp, err := p.cli.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     p.model,
+		Messages:  toOpenAIMessages(messages),
+		MaxTokens: p.maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("模型未返回任何内容")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// ChatStream 流式对话（打字机效果）
+func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	stream, err := p.cli.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:     p.model,
+		Messages:  toOpenAIMessages(messages),
+		MaxTokens: p.maxTokens,
+		Stream:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultChan := make(chan StreamChunk)
+	go func() {
+		defer close(resultChan)
+		defer stream.Close()
+
+		for {
+			response, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				sendChunk(ctx, resultChan, StreamChunk{Err: err})
+				return
+			}
+			if len(response.Choices) > 0 {
+				content := response.Choices[0].Delta.Content
+				if content != "" && !sendChunk(ctx, resultChan, StreamChunk{Content: content}) {
+					return
+				}
+			}
+		}
+	}()
+
+	return resultChan, nil
+}
+
+func toOpenAIMessages(messages []Message) []openai.ChatCompletionMessage {
+	openaiMessages := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, msg := range messages {
+		openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+	return openaiMessages
+}
+
+// sendChunk 向通道发送片段，ctx 被取消时放弃发送并返回 false
+func sendChunk(ctx context.Context, ch chan<- StreamChunk, chunk StreamChunk) bool {
+	select {
+	case ch <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
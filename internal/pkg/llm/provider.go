@@ -0,0 +1,44 @@
+package llm
+
+import "context"
+
+// 各业务功能的名称，用于在配置中为不同任务选择不同的模型
+const (
+	FeatureGeneration    = "generation"     // AI 生成作业
+	FeatureGrading       = "grading"        // AI 批改作业
+	FeatureChat          = "chat"           // AI 助教答疑
+	FeatureClassAnalysis = "class_analysis" // 班级学情分析
+)
+
+// Message 一条对话消息
+type Message struct {
+	Role    string
+	Content string
+}
+
+// StreamChunk 流式输出中的一个片段；Err 不为空表示流在中途出错并即将关闭
+type StreamChunk struct {
+	Content string
+	Err     error
+}
+
+// LLMProvider 大模型提供方的统一接口，所有业务服务只依赖该接口
+type LLMProvider interface {
+	// Name 返回提供方名称，用于日志
+	Name() string
+	// Chat 单轮对话
+	Chat(ctx context.Context, systemPrompt, userMessage string) (string, error)
+	// ChatWithHistory 带历史记录的对话
+	ChatWithHistory(ctx context.Context, messages []Message) (string, error)
+	// ChatStream 带历史记录的流式对话，ctx 取消后通道会被关闭
+	ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error)
+}
+
+// buildMessages 将系统提示词和用户消息拼成消息列表
+func buildMessages(systemPrompt, userMessage string) []Message {
+	var messages []Message
+	if systemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: systemPrompt})
+	}
+	return append(messages, Message{Role: "user", Content: userMessage})
+}
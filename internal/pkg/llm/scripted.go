@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ScriptedProvider 按预设脚本依次返回固定回复的假提供方，不访问网络，
+// 用于本地演示和在测试中驱动 AssignmentService / SessionService。
+type ScriptedProvider struct {
+	name string
+
+	mu        sync.Mutex
+	responses []string
+	next      int
+	calls     [][]Message
+
+	// Responder 不为空时优先使用它根据请求消息生成回复
+	Responder func(messages []Message) (string, error)
+}
+
+// NewScriptedProvider 创建脚本化的假提供方；回复用完后重复返回最后一条
+func NewScriptedProvider(name string, responses ...string) *ScriptedProvider {
+	return &ScriptedProvider{name: name, responses: responses}
+}
+
+func (p *ScriptedProvider) Name() string {
+	return p.name
+}
+
+// Chat 单轮对话
+func (p *ScriptedProvider) Chat(ctx context.Context, systemPrompt, userMessage string) (string, error) {
+	return p.ChatWithHistory(ctx, buildMessages(systemPrompt, userMessage))
+}
+
+// ChatWithHistory 记录请求并返回脚本中的下一条回复
+func (p *ScriptedProvider) ChatWithHistory(ctx context.Context, messages []Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls = append(p.calls, append([]Message(nil), messages...))
+
+	if p.Responder != nil {
+		return p.Responder(messages)
+	}
+	if len(p.responses) == 0 {
+		return "", errors.New("scripted 提供方没有可用的回复")
+	}
+
+	idx := p.next
+	if idx >= len(p.responses) {
+		idx = len(p.responses) - 1
+	} else {
+		p.next++
+	}
+	return p.responses[idx], nil
+}
+
+// ChatStream 将下一条回复按字符逐个输出，便于确定性地测试流式逻辑
+func (p *ScriptedProvider) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	answer, err := p.ChatWithHistory(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	resultChan := make(chan StreamChunk)
+	go func() {
+		defer close(resultChan)
+		for _, r := range answer {
+			if !sendChunk(ctx, resultChan, StreamChunk{Content: string(r)}) {
+				return
+			}
+		}
+	}()
+	return resultChan, nil
+}
+
+// Calls 返回迄今为止收到的所有请求消息
+func (p *ScriptedProvider) Calls() [][]Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]Message(nil), p.calls...)
+}
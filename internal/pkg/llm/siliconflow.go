@@ -0,0 +1,21 @@
+package llm
+
+const (
+	siliconFlowBaseURL      = "https://api.siliconflow.cn/v1"
+	siliconFlowDefaultModel = "Qwen/Qwen2.5-7B-Instruct" // 硅基支持的模型，免费额度可用
+	siliconFlowAPIKeyEnv    = "SILICONFLOW_API_KEY"
+)
+
+// NewSiliconFlowProvider 创建硅基流动提供方，未配置的字段使用硅基流动的默认值
+func NewSiliconFlowProvider(cfg ProviderConfig) (*OpenAIProvider, error) {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = siliconFlowBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = siliconFlowDefaultModel
+	}
+	if cfg.APIKeyEnv == "" {
+		cfg.APIKeyEnv = siliconFlowAPIKeyEnv
+	}
+	return NewOpenAIProvider(cfg)
+}
@@ -0,0 +1,452 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Limits 学生程序运行时的资源限制
+type Limits struct {
+	Timeout        time.Duration // 单次运行的墙钟时间上限
+	CPUSeconds     int           // 单个进程的 CPU 时间上限
+	MemoryMB       int           // 单个进程的虚拟内存上限
+	CompileTimeout time.Duration // 编译阶段的时间上限
+	MaxOutputBytes int           // 捕获输出的最大字节数，超出部分截断
+
+	// AllowUnsandboxed 无法为学生代码隔离网络和文件系统时仍然运行，默认拒绝运行；只应在可信的开发环境中显式开启
+	AllowUnsandboxed bool
+}
+
+// DefaultLimits 默认的资源限制
+func DefaultLimits() Limits {
+	return Limits{
+		Timeout:        5 * time.Second,
+		CPUSeconds:     5,
+		MemoryMB:       1024,
+		CompileTimeout: 60 * time.Second,
+		MaxOutputBytes: 64 * 1024,
+	}
+}
+
+// TestCase 教师定义的标准输入/输出用例
+type TestCase struct {
+	Name           string `json:"name"`
+	Input          string `json:"input"`
+	ExpectedOutput string `json:"expected_output"`
+}
+
+// Spec 描述如何检验一份学生代码：教师的 _test.go 文件和/或输入输出用例
+type Spec struct {
+	TestCode  string     // 教师提供的 _test.go 内容，package 需与学生代码一致
+	TestCases []TestCase // 标准输入/输出用例，要求学生代码为 package main
+}
+
+// Empty 没有任何可执行的检验
+func (s Spec) Empty() bool {
+	return strings.TrimSpace(s.TestCode) == "" && len(s.TestCases) == 0
+}
+
+// ErrSandboxUnavailable 当前环境无法隔离网络和文件系统，且没有显式允许在无隔离的环境中运行学生代码
+var ErrSandboxUnavailable = errors.New("代码运行环境无法隔离网络和文件系统，未运行自动测试")
+
+// CaseResult 单个测试（go test 中的 TestXxx 或一个输入输出用例）的结果
+type CaseResult struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"` // gotest, io
+	Passed     bool   `json:"passed"`
+	Input      string `json:"input,omitempty"`
+	Expected   string `json:"expected,omitempty"`
+	Output     string `json:"output"`
+	Error      string `json:"error,omitempty"` // 超时、运行时错误等
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Result 一次运行的完整结果
+type Result struct {
+	Compiled     bool         `json:"compiled"`
+	CompileError string       `json:"compile_error,omitempty"`
+	Cases        []CaseResult `json:"cases"`
+	Passed       int          `json:"passed"`
+	Total        int          `json:"total"`
+	Output       string       `json:"output,omitempty"`  // go test 的原始输出
+	NotRun       string       `json:"not_run,omitempty"` // 没有运行测试的原因，此时不按通过率计分
+}
+
+// Redacted 返回隐藏了输入输出用例的输入和期望输出的副本，学生只能看到用例是否通过和自己的输出，
+// 看不到教师的测试数据
+func (r Result) Redacted() Result {
+	cases := make([]CaseResult, len(r.Cases))
+	for i, c := range r.Cases {
+		c.Input = ""
+		c.Expected = ""
+		cases[i] = c
+	}
+	r.Cases = cases
+	return r
+}
+
+// PassRate 通过率，未编译通过或没有用例时为 0
+func (r *Result) PassRate() float64 {
+	if r == nil || r.Total == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(r.Total)
+}
+
+// Runner 在隔离的临时模块中编译并运行学生的 Go 代码
+type Runner struct {
+	limits  Limits
+	goBin   string
+	tempDir string
+}
+
+// New 创建运行器，从 PATH 中查找 go 工具链
+func New(limits Limits) (*Runner, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return nil, fmt.Errorf("未找到 go 工具链: %w", err)
+	}
+	return &Runner{limits: limits, goBin: goBin, tempDir: os.TempDir()}, nil
+}
+
+// Sandboxed 当前环境能否在隔离网络和文件系统的沙箱中运行学生代码
+func (r *Runner) Sandboxed() bool {
+	return sandboxAvailable()
+}
+
+var packageRe = regexp.MustCompile(`(?m)^\s*package\s+(\w+)`)
+
+// Run 编译学生代码并执行所有检验
+func (r *Runner) Run(ctx context.Context, code string, spec Spec) (*Result, error) {
+	if spec.Empty() {
+		return nil, errors.New("没有可执行的测试")
+	}
+	if !r.Sandboxed() && !r.limits.AllowUnsandboxed {
+		return nil, ErrSandboxUnavailable
+	}
+
+	dir, err := os.MkdirTemp(r.tempDir, "gocodementor-run-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := writeModule(dir, code, spec.TestCode); err != nil {
+		return nil, err
+	}
+	// bin 存放编译产物，沙箱中只读挂载为 /work；root 是沙箱根目录的挂载点
+	for _, sub := range []string{"bin", "root"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+	}
+
+	result := &Result{Cases: []CaseResult{}}
+
+	if len(spec.TestCases) > 0 {
+		out, err := r.compile(ctx, dir, "build", "-o", "bin/prog.bin", ".")
+		if err != nil {
+			result.CompileError = out
+			result.Total = len(spec.TestCases) + countTests(spec.TestCode)
+			return result, nil
+		}
+		for i, tc := range spec.TestCases {
+			result.Cases = append(result.Cases, r.runCase(ctx, dir, i, tc))
+		}
+	}
+
+	if strings.TrimSpace(spec.TestCode) != "" {
+		out, err := r.compile(ctx, dir, "test", "-c", "-o", "bin/test.bin", ".")
+		if err != nil {
+			result.CompileError = out
+			result.Total = len(result.Cases) + countTests(spec.TestCode)
+			result.Passed = countPassed(result.Cases)
+			return result, nil
+		}
+		cases, output := r.runGoTests(ctx, dir, spec.TestCode)
+		result.Cases = append(result.Cases, cases...)
+		result.Output = output
+	}
+
+	result.Compiled = true
+	result.Total = len(result.Cases)
+	result.Passed = countPassed(result.Cases)
+	return result, nil
+}
+
+// writeModule 写入独立的 go.mod、学生代码和教师测试文件
+func writeModule(dir, code, testCode string) error {
+	files := map[string]string{
+		"go.mod":  "module submission\n\ngo 1.21\n",
+		"main.go": code,
+	}
+	if strings.TrimSpace(testCode) != "" {
+		// 教师测试文件的包名以学生代码为准，避免因包名不一致无法编译
+		if m := packageRe.FindStringSubmatch(code); m != nil {
+			testCode = packageRe.ReplaceAllString(testCode, "package "+m[1])
+		}
+		files["teacher_test.go"] = testCode
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// compile 在禁止联网、禁止下载依赖的环境中执行 go build / go test -c
+func (r *Runner) compile(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.limits.CompileTimeout)
+	defer cancel()
+
+	cmd := sandboxCommand(ctx, r.limits, true, dir, r.goBin, args...)
+	cmd.Env = append(os.Environ(),
+		"GOPROXY=off",
+		"GOFLAGS=-mod=mod",
+		"GOWORK=off",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=0",
+		"GO111MODULE=on",
+	)
+
+	var out limitedBuffer
+	out.limit = r.limits.MaxOutputBytes
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "编译超时", ctx.Err()
+	}
+	return strings.ReplaceAll(out.String(), dir+string(filepath.Separator), ""), err
+}
+
+// runCase 以标准输入运行一个用例并比较标准输出
+func (r *Runner) runCase(ctx context.Context, dir string, idx int, tc TestCase) CaseResult {
+	name := tc.Name
+	if name == "" {
+		name = fmt.Sprintf("用例 %d", idx+1)
+	}
+
+	res := CaseResult{
+		Name:     name,
+		Kind:     "io",
+		Input:    tc.Input,
+		Expected: tc.ExpectedOutput,
+	}
+
+	stdout, errMsg, elapsed := r.execute(ctx, dir, tc.Input, "prog.bin")
+	res.Output = stdout
+	res.Error = errMsg
+	res.DurationMs = elapsed.Milliseconds()
+	res.Passed = errMsg == "" && normalizeOutput(stdout) == normalizeOutput(tc.ExpectedOutput)
+	return res
+}
+
+// testEvent go tool test2json 输出的一条事件
+type testEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// runGoTests 逐个运行教师的 TestXxx，并用 test2json 解析结果。
+// 学生代码和测试框架共用标准输出，可以打印伪造的测试结果后直接退出，因此每个测试单独运行，
+// 只有进程以 0 退出、且 test2json 事件中该测试通过（没有失败）时才认为测试通过；
+// 与 go test 一样开启 paniconexit0，测试过程中调用 os.Exit(0) 会被判为失败
+func (r *Runner) runGoTests(ctx context.Context, dir, testCode string) ([]CaseResult, string) {
+	var cases []CaseResult
+	var output strings.Builder
+	for _, name := range testNames(testCode) {
+		c, out := r.runGoTest(ctx, dir, name)
+		cases = append(cases, c)
+		output.WriteString(out)
+	}
+	return cases, output.String()
+}
+
+// runGoTest 运行单个顶层测试，子测试的结果计入该测试
+func (r *Runner) runGoTest(ctx context.Context, dir, name string) (CaseResult, string) {
+	res := CaseResult{Name: name, Kind: "gotest"}
+	raw, errMsg, elapsed := r.execute(ctx, dir, "", "test.bin",
+		"-test.run=^"+regexp.QuoteMeta(name)+"$", "-test.v=test2json", "-test.paniconexit0", fmt.Sprintf("-test.timeout=%s", r.limits.Timeout))
+	res.DurationMs = elapsed.Milliseconds()
+
+	events, err := r.decodeTestEvents(ctx, raw)
+	if err != nil {
+		res.Error = "解析测试结果失败"
+		return res, raw
+	}
+
+	var output, log strings.Builder
+	passed, failed := false, false
+	for _, ev := range events {
+		output.WriteString(ev.Output)
+		if ev.Test != name && !strings.HasPrefix(ev.Test, name+"/") {
+			continue
+		}
+		switch ev.Action {
+		case "output":
+			if !isFrameworkLine(ev.Output) {
+				log.WriteString(ev.Output)
+			}
+		case "pass":
+			passed = passed || ev.Test == name
+		case "fail":
+			failed = true
+		}
+	}
+	res.Output = strings.TrimRight(log.String(), "\n")
+
+	switch {
+	case errMsg != "" && !failed:
+		// 超时、panic、被学生代码提前退出等
+		res.Error = errMsg
+	case !passed && !failed:
+		res.Error = "测试未执行完成"
+	}
+	res.Passed = errMsg == "" && passed && !failed
+	return res, output.String()
+}
+
+// decodeTestEvents 用 go tool test2json 把 -test.v=test2json 的输出转换为事件。
+// 转换在沙箱外进行，只解析已经捕获的输出，不执行学生代码
+func (r *Runner) decodeTestEvents(ctx context.Context, raw string) ([]testEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.limits.CompileTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.goBin, "tool", "test2json")
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local", "GOFLAGS=")
+	cmd.Stdin = strings.NewReader(raw)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("test2json 失败: %w", err)
+	}
+
+	var events []testEvent
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var ev testEvent
+		if err := dec.Decode(&ev); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// isFrameworkLine 判断是否为测试框架自身输出的 === RUN / --- PASS 等行，这些行不计入测试日志
+func isFrameworkLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ")
+}
+
+// execute 在资源受限的沙箱中运行 dir/bin 中的编译产物，返回标准输出、错误描述和耗时
+func (r *Runner) execute(ctx context.Context, dir, stdin, bin string, args ...string) (string, string, time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, r.limits.Timeout)
+	defer cancel()
+
+	cmd := sandboxCommand(ctx, r.limits, false, dir, bin, args...)
+	cmd.Env = []string{
+		"PATH=/usr/bin:/bin",
+		"HOME=/tmp",
+		"TMPDIR=/tmp",
+		fmt.Sprintf("GOMEMLIMIT=%dMiB", r.limits.MemoryMB),
+	}
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr limitedBuffer
+	stdout.limit = r.limits.MaxOutputBytes
+	stderr.limit = r.limits.MaxOutputBytes
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	elapsed := time.Since(start)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return stdout.String(), fmt.Sprintf("运行超时（超过 %s）", r.limits.Timeout), elapsed
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), msg, elapsed
+	}
+	return stdout.String(), "", elapsed
+}
+
+var testFuncRe = regexp.MustCompile(`(?m)^func\s+(Test\w*)\s*\(\s*\w+\s+\*testing\.T\s*\)`)
+
+func testNames(testCode string) []string {
+	var names []string
+	for _, m := range testFuncRe.FindAllStringSubmatch(testCode, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func countTests(testCode string) int {
+	return len(testNames(testCode))
+}
+
+func countPassed(cases []CaseResult) int {
+	n := 0
+	for _, c := range cases {
+		if c.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+// normalizeOutput 忽略行尾空白和末尾空行的差异
+func normalizeOutput(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// limitedBuffer 超过上限后丢弃后续输出的缓冲区，防止恶意程序刷屏耗尽内存
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			b.buf.Write(p[:remain])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n...（输出过长，已截断）"
+	}
+	return b.buf.String()
+}
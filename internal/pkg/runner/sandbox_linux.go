@@ -0,0 +1,126 @@
+//go:build linux
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	isolateOnce      sync.Once
+	isolateAvailable bool
+)
+
+// isolateScript 在新的用户、网络、挂载和 PID 命名空间中搭建只含临时文件的根目录后运行学生程序：
+// 新根目录是只读的 tmpfs，只挂入只读的系统目录（供 shell 和 setpriv 使用）、只读的 /work（编译产物）、
+// 可写的 /tmp 和本命名空间的 /proc；pivot_root 后卸载原来的根目录，学生程序看不到配置文件、
+// 服务端源码和其他学生的工作目录。最后放弃全部 capability，学生程序无法重新挂载或改写 /work。
+// 参数：$1 为空的挂载点目录，$2 为编译产物所在目录，其余为要运行的命令
+const isolateScript = `set -e
+PATH=/usr/sbin:/sbin:/usr/bin:/bin
+root=$1 work=$2
+shift 2
+mount -t tmpfs -o size=1m,mode=755 sandbox "$root"
+mkdir "$root/work" "$root/tmp" "$root/proc" "$root/.old"
+for d in usr bin sbin lib lib32 lib64; do
+	if [ -L "/$d" ]; then
+		ln -s "$(readlink "/$d")" "$root/$d"
+	elif [ -d "/$d" ]; then
+		mkdir "$root/$d"
+		mount --rbind "/$d" "$root/$d"
+		mount -o remount,bind,ro "$root/$d"
+	fi
+done
+mount --bind "$work" "$root/work"
+mount -o remount,bind,ro "$root/work"
+mount -t tmpfs -o size=64m,mode=1777 tmp "$root/tmp"
+mount -t proc proc "$root/proc"
+mount -o remount,ro "$root"
+cd "$root"
+pivot_root . .old
+umount -l /.old
+cd /work
+%s
+exec setpriv --no-new-privs --bounding-set=-all --inh-caps=-all -- "$@"`
+
+// isolateArgs 调用 unshare 创建命名空间的参数，unshare 退出时一并结束其中的进程
+var isolateArgs = []string{"--net", "--mount", "--pid", "--kill-child", "--map-root-user", "--propagation", "private"}
+
+// canIsolate 检测当前环境能否为学生程序隔离网络和文件系统：在临时目录中完整搭建一次沙箱并运行 true
+func canIsolate() bool {
+	isolateOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "gocodementor-probe-")
+		if err != nil {
+			log.Printf("[代码运行] 无法创建沙箱检测目录: %v", err)
+			return
+		}
+		defer os.RemoveAll(dir)
+		for _, sub := range []string{"bin", "root"} {
+			if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+				log.Printf("[代码运行] 无法创建沙箱检测目录: %v", err)
+				return
+			}
+		}
+
+		argv := append(isolateArgs, "sh", "-c", fmt.Sprintf(isolateScript, ":"), "sh",
+			filepath.Join(dir, "root"), filepath.Join(dir, "bin"), "true")
+		if out, err := exec.Command("unshare", argv...).CombinedOutput(); err == nil {
+			isolateAvailable = true
+		} else {
+			log.Printf("[代码运行] 无法隔离网络和文件系统: %v %s", err, out)
+		}
+	})
+	return isolateAvailable
+}
+
+// sandboxAvailable 能否隔离网络和文件系统；不能隔离时运行器默认拒绝运行学生代码
+func sandboxAvailable() bool {
+	return canIsolate()
+}
+
+// sandboxCommand 构造受限命令，并放入独立进程组，超时时整组杀掉，避免学生程序派生的子进程残留。
+// compile 为 true 时在 dir 中运行 go 工具链，只隔离网络，不限制 CPU 和内存，以免影响编译器本身；
+// 否则运行 dir/bin 中的编译产物 name：独立网络、挂载和 PID 命名空间，根目录只含只读的编译产物和临时文件，
+// 并用 ulimit 限制 CPU/内存。无法隔离（且显式允许不隔离运行）时直接在 dir/bin 中运行。
+func sandboxCommand(ctx context.Context, limits Limits, compile bool, dir, name string, args ...string) *exec.Cmd {
+	var cmd *exec.Cmd
+	switch {
+	case compile:
+		bin, argv := name, args
+		if canIsolate() {
+			bin, argv = "unshare", append([]string{"--net", "--map-root-user", name}, args...)
+		}
+		cmd = exec.CommandContext(ctx, bin, argv...)
+		cmd.Dir = dir
+	case canIsolate():
+		ulimits := fmt.Sprintf("ulimit -t %d; ulimit -v %d; ulimit -c 0", limits.CPUSeconds, limits.MemoryMB*1024)
+		argv := append(isolateArgs, "sh", "-c", fmt.Sprintf(isolateScript, ulimits), "sh",
+			filepath.Join(dir, "root"), filepath.Join(dir, "bin"), "/work/"+name)
+		cmd = exec.CommandContext(ctx, "unshare", append(argv, args...)...)
+		cmd.Dir = dir
+	default:
+		script := fmt.Sprintf(`ulimit -t %d; ulimit -v %d; ulimit -c 0; exec "$0" "$@"`,
+			limits.CPUSeconds, limits.MemoryMB*1024)
+		argv := append([]string{"-c", script, filepath.Join(dir, "bin", name)}, args...)
+		cmd = exec.CommandContext(ctx, "sh", argv...)
+		cmd.Dir = filepath.Join(dir, "bin")
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	return cmd
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// sandboxAvailable 非 Linux 平台无法隔离网络和文件系统
+func sandboxAvailable() bool {
+	return false
+}
+
+// sandboxCommand 非 Linux 平台无法使用命名空间和 ulimit，只依靠超时限制运行时间。
+// compile 为 true 时在 dir 中运行 go 工具链，否则运行 dir/bin 中的编译产物 name
+func sandboxCommand(ctx context.Context, limits Limits, compile bool, dir, name string, args ...string) *exec.Cmd {
+	if !compile {
+		dir = filepath.Join(dir, "bin")
+		name = filepath.Join(dir, name)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.WaitDelay = time.Second
+	return cmd
+}
@@ -0,0 +1,309 @@
+package similarity
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxTokens 单段代码参与比较的记号上限，避免超长提交拖慢整个检测
+const maxTokens = 3000
+
+// Match 两段记号序列中相同的一段：a[A:A+Length] 与 b[B:B+Length]
+type Match struct {
+	A      int
+	B      int
+	Length int
+}
+
+// Region 相同片段在两段代码中对应的行范围（闭区间）
+type Region struct {
+	AFrom int `json:"a_from"`
+	ATo   int `json:"a_to"`
+	BFrom int `json:"b_from"`
+	BTo   int `json:"b_to"`
+}
+
+// Compare 用 Karp-Rabin 贪心串覆盖（Running Karp-Rabin Greedy String Tiling）找出两段记号序列中
+// 不重叠的相同片段，长度小于 minMatch 的片段忽略；相似度为被覆盖记号数占两段总记号数的比例。
+// 每一轮用滚动哈希查找长度至少为 search 的公共片段，从长到短标记后把 search 减半，直到 minMatch
+func Compare(a, b []Token, minMatch int) (float64, []Match) {
+	if len(a) > maxTokens {
+		a = a[:maxTokens]
+	}
+	if len(b) > maxTokens {
+		b = b[:maxTokens]
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0, nil
+	}
+	if minMatch < 1 {
+		minMatch = 1
+	}
+
+	t := &tiling{
+		a:       a,
+		b:       b,
+		hashA:   tokenHashes(a),
+		hashB:   tokenHashes(b),
+		markedA: make([]bool, len(a)),
+		markedB: make([]bool, len(b)),
+	}
+	search := initialSearch
+	if search < minMatch {
+		search = minMatch
+	}
+	for {
+		longest, matches := t.scan(search)
+		if longest > 2*search {
+			// 存在远长于当前搜索长度的片段，用更长的搜索长度重新扫描，保证长片段先被标记
+			search = longest
+			continue
+		}
+		if t.mark(matches) {
+			// 有片段与更长的片段重叠而被跳过，它未被覆盖的部分可能仍然够长，用同样的长度再扫描一次
+			continue
+		}
+		switch {
+		case search > 2*minMatch:
+			search /= 2
+		case search > minMatch:
+			search = minMatch
+		default:
+			sort.Slice(t.tiles, func(i, j int) bool { return t.tiles[i].A < t.tiles[j].A })
+			return float64(2*t.covered) / float64(len(a)+len(b)), t.tiles
+		}
+	}
+}
+
+// initialSearch 第一轮的搜索长度
+const initialSearch = 32
+
+// hashBase 滚动哈希的基数，按 uint64 自然溢出取模
+const hashBase = 1000003
+
+// tiling 一次比较的状态：已被覆盖的记号和已找到的片段
+type tiling struct {
+	a, b             []Token
+	hashA, hashB     []uint64
+	markedA, markedB []bool
+	tiles            []Match
+	covered          int
+}
+
+// scan 找出所有长度至少为 search、不含已覆盖记号的公共片段（每段都尽量向后延伸），返回最长的长度。
+// 发现长度超过 2*search 的片段时立即返回，由调用方换用更长的搜索长度
+func (t *tiling) scan(search int) (int, []Match) {
+	freeA := freeRuns(t.markedA)
+	freeB := freeRuns(t.markedB)
+	windowsB := make(map[uint64][]int)
+	forEachWindow(t.hashB, freeB, search, func(j int, h uint64) {
+		windowsB[h] = append(windowsB[h], j)
+	})
+
+	longest := 0
+	var matches []Match
+	forEachWindow(t.hashA, freeA, search, func(i int, h uint64) {
+		if longest > 2*search {
+			return
+		}
+		for _, j := range windowsB[h] {
+			// 前一个位置也相同的片段已经包含了这一段
+			if i > 0 && j > 0 && freeA[i-1] > 0 && freeB[j-1] > 0 && t.a[i-1].Kind == t.b[j-1].Kind {
+				continue
+			}
+			k := 0
+			for k < freeA[i] && k < freeB[j] && t.a[i+k].Kind == t.b[j+k].Kind {
+				k++
+			}
+			if k < search { // 哈希冲突
+				continue
+			}
+			matches = append(matches, Match{A: i, B: j, Length: k})
+			if k > longest {
+				longest = k
+			}
+		}
+	})
+	return longest, matches
+}
+
+// mark 从长到短把互不重叠的片段标记为已覆盖，返回是否有片段因重叠被跳过
+func (t *tiling) mark(matches []Match) bool {
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Length > matches[j].Length })
+	skipped := false
+	for _, m := range matches {
+		if occluded(t.markedA, m.A, m.Length) || occluded(t.markedB, m.B, m.Length) {
+			skipped = true
+			continue
+		}
+		for k := 0; k < m.Length; k++ {
+			t.markedA[m.A+k] = true
+			t.markedB[m.B+k] = true
+		}
+		t.tiles = append(t.tiles, m)
+		t.covered += m.Length
+	}
+	return skipped
+}
+
+// tokenHashes 把每个记号的种类映射为哈希值
+func tokenHashes(tokens []Token) []uint64 {
+	hashes := make([]uint64, len(tokens))
+	for i, tok := range tokens {
+		h := fnv.New64a()
+		h.Write([]byte(tok.Kind))
+		hashes[i] = h.Sum64()
+	}
+	return hashes
+}
+
+// freeRuns 返回从每个位置开始连续未覆盖的记号数
+func freeRuns(marked []bool) []int {
+	runs := make([]int, len(marked)+1)
+	for i := len(marked) - 1; i >= 0; i-- {
+		if !marked[i] {
+			runs[i] = runs[i+1] + 1
+		}
+	}
+	return runs
+}
+
+// forEachWindow 对每个长度为 size 且不含已覆盖记号的窗口调用 fn，传入起点和窗口的滚动哈希
+func forEachWindow(hashes []uint64, free []int, size int, fn func(start int, h uint64)) {
+	if size > len(hashes) {
+		return
+	}
+	pow := uint64(1)
+	for k := 0; k < size; k++ {
+		pow *= hashBase
+	}
+	var h uint64
+	for i, v := range hashes {
+		h = h*hashBase + v
+		if i >= size {
+			h -= hashes[i-size] * pow
+		}
+		if start := i - size + 1; start >= 0 && free[start] >= size {
+			fn(start, h)
+		}
+	}
+}
+
+func occluded(marked []bool, start, length int) bool {
+	for k := start; k < start+length; k++ {
+		if marked[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// Regions 将相同片段换算为两段代码中的行范围
+func Regions(a, b []Token, matches []Match) []Region {
+	regions := make([]Region, 0, len(matches))
+	for _, m := range matches {
+		aFrom, aTo := lineSpan(a[m.A : m.A+m.Length])
+		bFrom, bTo := lineSpan(b[m.B : m.B+m.Length])
+		regions = append(regions, Region{AFrom: aFrom, ATo: aTo, BFrom: bFrom, BTo: bTo})
+	}
+	return regions
+}
+
+func lineSpan(tokens []Token) (int, int) {
+	from, to := tokens[0].Line, tokens[0].Line
+	for _, t := range tokens[1:] {
+		if t.Line < from {
+			from = t.Line
+		}
+		if t.Line > to {
+			to = t.Line
+		}
+	}
+	return from, to
+}
+
+// NormalizeText 统一大小写并去掉空白和标点，只保留文字和数字
+func NormalizeText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// TextSimilarity 计算两段文本规范化后字符三元组的 Jaccard 相似度，兼容中英文
+func TextSimilarity(a, b string) float64 {
+	ra := []rune(NormalizeText(a))
+	rb := []rune(NormalizeText(b))
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	if len(ra) < 3 || len(rb) < 3 {
+		if string(ra) == string(rb) {
+			return 1
+		}
+		return 0
+	}
+
+	ga := trigrams(ra)
+	gb := trigrams(rb)
+	shared := 0
+	for g := range ga {
+		if gb[g] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ga)+len(gb)-shared)
+}
+
+func trigrams(r []rune) map[string]bool {
+	grams := make(map[string]bool, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		grams[string(r[i:i+3])] = true
+	}
+	return grams
+}
+
+// Cluster 按相似的提交对（下标）做连通分量划分，返回至少包含两个成员的分组，成员按下标升序
+func Cluster(n int, edges [][2]int) [][]int {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	for _, e := range edges {
+		ra, rb := find(e[0]), find(e[1])
+		if ra != rb {
+			parent[ra] = rb
+		}
+	}
+
+	groups := make(map[int][]int)
+	for i := 0; i < n; i++ {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+	var clusters [][]int
+	for _, members := range groups {
+		if len(members) > 1 {
+			clusters = append(clusters, members)
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i]) != len(clusters[j]) {
+			return len(clusters[i]) > len(clusters[j])
+		}
+		return clusters[i][0] < clusters[j][0]
+	})
+	return clusters
+}
@@ -0,0 +1,132 @@
+// Package similarity 比较学生提交之间的相似度：代码按语法树规范化后做最长公共片段匹配，
+// 文本答案按字符 n-gram 计算重合度。
+package similarity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// Token 规范化后的代码记号：标识符和字面量的具体值被抹去，只保留结构
+type Token struct {
+	Kind string
+	Line int // 在原始代码中的行号，从 1 开始
+}
+
+// Tokenize 将 Go 代码转换为规范化记号序列，忽略标识符重命名、注释和格式差异。
+// 代码能解析时按语法树节点生成记号（允许缺少 package 声明或只是一段函数体），
+// 否则退化为词法记号。
+func Tokenize(src string) []Token {
+	if strings.TrimSpace(src) == "" {
+		return nil
+	}
+	if tokens, ok := tokenizeAST(src); ok {
+		return tokens
+	}
+	return tokenizeScanner(src)
+}
+
+func tokenizeAST(src string) ([]Token, bool) {
+	// 依次尝试：完整文件、缺少 package 声明的文件、一段语句
+	attempts := []struct {
+		prefix string
+		suffix string
+	}{
+		{"", ""},
+		{"package main\n", ""},
+		{"package main\nfunc _() {\n", "\n}"},
+	}
+	for _, attempt := range attempts {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", attempt.prefix+src+attempt.suffix, 0)
+		if err != nil {
+			continue
+		}
+		offset := strings.Count(attempt.prefix, "\n")
+		return astTokens(fset, file, offset), true
+	}
+	return nil, false
+}
+
+func astTokens(fset *token.FileSet, file *ast.File, offset int) []Token {
+	var tokens []Token
+	visit := func(n ast.Node) bool {
+		switch n := n.(type) {
+		case nil, *ast.ParenExpr:
+			return true
+		case *ast.CommentGroup, *ast.Comment:
+			return false
+		case *ast.GenDecl:
+			// 导入声明不参与比较
+			if n.Tok == token.IMPORT {
+				return false
+			}
+		}
+		line := fset.Position(n.Pos()).Line - offset
+		if line < 1 {
+			// 为补全代码而添加的函数声明
+			return true
+		}
+		tokens = append(tokens, Token{Kind: nodeKind(n), Line: line})
+		return true
+	}
+	// 从声明开始遍历，跳过包名
+	for _, decl := range file.Decls {
+		ast.Inspect(decl, visit)
+	}
+	return tokens
+}
+
+func nodeKind(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.Ident:
+		return "ID"
+	case *ast.BasicLit:
+		return "LIT_" + n.Kind.String()
+	case *ast.BinaryExpr:
+		return "BIN" + n.Op.String()
+	case *ast.UnaryExpr:
+		return "UN" + n.Op.String()
+	case *ast.AssignStmt:
+		return "ASSIGN" + n.Tok.String()
+	case *ast.IncDecStmt:
+		return "INCDEC" + n.Tok.String()
+	case *ast.BranchStmt:
+		return "BRANCH" + n.Tok.String()
+	case *ast.GenDecl:
+		return "DECL" + n.Tok.String()
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+}
+
+func tokenizeScanner(src string) []Token {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), func(token.Position, string) {}, 0)
+
+	var tokens []Token
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			// 自动插入的分号只反映换行
+			continue
+		}
+		kind := tok.String()
+		switch {
+		case tok == token.IDENT:
+			kind = "ID"
+		case tok.IsLiteral():
+			kind = "LIT_" + tok.String()
+		}
+		tokens = append(tokens, Token{Kind: kind, Line: fset.Position(pos).Line})
+	}
+	return tokens
+}
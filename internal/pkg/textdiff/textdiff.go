@@ -0,0 +1,140 @@
+// Package textdiff 按行比较两段文本，用于展示作业提交版本之间的差异。
+package textdiff
+
+import "strings"
+
+// Op 行的变化类型
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line 差异结果中的一行
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells 限制 LCS 表的大小，超出时退化为整体删除 + 整体插入
+const maxCells = 4_000_000
+
+// Lines 按行比较 a 和 b，返回从 a 变为 b 的逐行差异
+func Lines(a, b string) []Line {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+
+	// 先去掉公共前缀和后缀，缩小需要计算 LCS 的范围
+	prefix := 0
+	for prefix < len(aLines) && prefix < len(bLines) && aLines[prefix] == bLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(aLines)-prefix && suffix < len(bLines)-prefix &&
+		aLines[len(aLines)-1-suffix] == bLines[len(bLines)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]Line, 0, len(aLines)+len(bLines))
+	for _, l := range aLines[:prefix] {
+		result = append(result, Line{Op: Equal, Text: l})
+	}
+	result = append(result, diffMiddle(aLines[prefix:len(aLines)-suffix], bLines[prefix:len(bLines)-suffix])...)
+	for _, l := range aLines[len(aLines)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: l})
+	}
+	return result
+}
+
+// Changed 判断差异结果中是否有变化
+func Changed(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Unified 以 "+ " / "- " / "  " 前缀输出差异，便于直接展示
+func Unified(lines []Line) string {
+	var b strings.Builder
+	for _, l := range lines {
+		switch l.Op {
+		case Insert:
+			b.WriteString("+ ")
+		case Delete:
+			b.WriteString("- ")
+		default:
+			b.WriteString("  ")
+		}
+		b.WriteString(l.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func diffMiddle(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 || n*m > maxCells {
+		result := make([]Line, 0, n+m)
+		for _, l := range a {
+			result = append(result, Line{Op: Delete, Text: l})
+		}
+		for _, l := range b {
+			result = append(result, Line{Op: Insert, Text: l})
+		}
+		return result
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	result := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: Insert, Text: b[j]})
+	}
+	return result
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
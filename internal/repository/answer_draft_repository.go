@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// answerDraftRepository implements the AnswerDraftRepository interface.
+type answerDraftRepository struct {
+	db *gorm.DB
+}
+
+// NewAnswerDraftRepository creates a new AnswerDraftRepository.
+func NewAnswerDraftRepository(db *gorm.DB) AnswerDraftRepository {
+	return &answerDraftRepository{db: db}
+}
+
+func (r *answerDraftRepository) Save(draft *model.AnswerDraft) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "assignment_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"answers", "code_content", "updated_at"}),
+	}).Create(draft).Error
+}
+
+func (r *answerDraftRepository) GetByAssignmentAndStudent(assignmentID, studentID string) (*model.AnswerDraft, error) {
+	var draft model.AnswerDraft
+	result := r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Limit(1).Find(&draft)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &draft, nil
+}
+
+func (r *answerDraftRepository) GetByStudentAndAssignmentIDs(studentID string, assignmentIDs []string) ([]model.AnswerDraft, error) {
+	var drafts []model.AnswerDraft
+	if len(assignmentIDs) == 0 {
+		return drafts, nil
+	}
+	err := r.db.Where("student_id = ? AND assignment_id IN ?", studentID, assignmentIDs).Find(&drafts).Error
+	return drafts, err
+}
+
+func (r *answerDraftRepository) Delete(assignmentID, studentID string) error {
+	return r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Delete(&model.AnswerDraft{}).Error
+}
+
+func (r *answerDraftRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.AnswerDraft{}).Error
+}
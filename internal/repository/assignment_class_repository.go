@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"GoCodeMentor/internal/model"
 
 	"gorm.io/gorm"
@@ -52,3 +54,11 @@ func (r *assignmentClassRepository) DeleteByAssignmentID(assignmentID string) er
 func (r *assignmentClassRepository) DeleteByAssignmentAndClass(assignmentID, classID string) error {
 	return r.db.Where("assignment_id = ? AND class_id = ?", assignmentID, classID).Delete(&model.AssignmentClass{}).Error
 }
+
+func (r *assignmentClassRepository) GetDueSchedules(now time.Time) ([]model.AssignmentClass, error) {
+	var assignmentClasses []model.AssignmentClass
+	err := r.db.Where("(status = ? AND released_at <= ?) OR (status = ? AND closes_at <= ?)",
+		model.AssignmentClassScheduled, now, model.AssignmentClassReleased, now).
+		Find(&assignmentClasses).Error
+	return assignmentClasses, err
+}
@@ -33,11 +33,19 @@ func (r *assignmentRepository) GetByTeacherID(teacherID string) ([]model.Assignm
 }
 
 func (r *assignmentRepository) GetByClassID(classID string) ([]model.Assignment, error) {
+	return r.GetByClassIDs([]string{classID})
+}
+
+func (r *assignmentRepository) GetByClassIDs(classIDs []string) ([]model.Assignment, error) {
 	var assignments []model.Assignment
-	// 查询通过assignment_class关联的作业，以及直接设置class_id的作业（向后兼容）
+	if len(classIDs) == 0 {
+		return assignments, nil
+	}
+	// 查询通过assignment_class关联且已发放（含已关闭）的作业，以及直接设置class_id的作业（向后兼容）
 	err := r.db.Distinct("assignments.*").
 		Joins("LEFT JOIN assignment_classes ac ON assignments.id = ac.assignment_id").
-		Where("(ac.class_id = ? OR assignments.class_id = ?) AND assignments.status = ?", classID, classID, "published").
+		Where("((ac.class_id IN ? AND ac.status IN ?) OR assignments.class_id IN ?) AND assignments.status IN ?",
+			classIDs, []string{model.AssignmentClassReleased, model.AssignmentClassClosed}, classIDs, []string{"published", "closed"}).
 		Order("assignments.created_at desc").
 		Find(&assignments).Error
 	return assignments, err
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// bankQuestionRepository implements the BankQuestionRepository interface.
+type bankQuestionRepository struct {
+	db *gorm.DB
+}
+
+// NewBankQuestionRepository creates a new BankQuestionRepository.
+func NewBankQuestionRepository(db *gorm.DB) BankQuestionRepository {
+	return &bankQuestionRepository{db: db}
+}
+
+func (r *bankQuestionRepository) Create(item *model.BankQuestion) error {
+	return r.db.Create(item).Error
+}
+
+func (r *bankQuestionRepository) GetByID(id string) (*model.BankQuestion, error) {
+	var item model.BankQuestion
+	err := r.db.Where("id = ?", id).First(&item).Error
+	return &item, err
+}
+
+func (r *bankQuestionRepository) GetByIDs(ids []string) ([]model.BankQuestion, error) {
+	var items []model.BankQuestion
+	if len(ids) == 0 {
+		return items, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&items).Error
+	return items, err
+}
+
+func (r *bankQuestionRepository) Update(item *model.BankQuestion) error {
+	return r.db.Save(item).Error
+}
+
+func (r *bankQuestionRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.BankQuestion{}).Error
+}
+
+func (r *bankQuestionRepository) Search(filter BankQuestionFilter) ([]model.BankQuestion, error) {
+	var items []model.BankQuestion
+	query := r.db.Model(&model.BankQuestion{})
+
+	if filter.OnlyMine {
+		query = query.Where("teacher_id = ?", filter.ViewerID)
+	} else {
+		query = query.Where("teacher_id = ? OR shared = ?", filter.ViewerID, true)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Difficulty != "" {
+		query = query.Where("difficulty = ?", filter.Difficulty)
+	}
+	if filter.KnowledgePointID != nil {
+		query = query.Where("knowledge_point_id = ?", *filter.KnowledgePointID)
+	}
+	if filter.Keyword != "" {
+		query = query.Where("content ILIKE ?", "%"+filter.Keyword+"%")
+	}
+	if len(filter.ExcludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", filter.ExcludeIDs)
+	}
+
+	if filter.Random {
+		query = query.Order("RANDOM()")
+	} else {
+		query = query.Order("updated_at desc")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	err := query.Find(&items).Error
+	return items, err
+}
+
+func (r *bankQuestionRepository) IncrementUsage(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.BankQuestion{}).
+		Where("id IN ?", ids).
+		Update("usage_count", gorm.Expr("usage_count + 1")).Error
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// classGroupRepository implements the ClassGroupRepository interface.
+type classGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewClassGroupRepository creates a new ClassGroupRepository.
+func NewClassGroupRepository(db *gorm.DB) ClassGroupRepository {
+	return &classGroupRepository{db: db}
+}
+
+func (r *classGroupRepository) Create(group *model.ClassGroup) error {
+	return r.db.Create(group).Error
+}
+
+func (r *classGroupRepository) GetByID(id string) (*model.ClassGroup, error) {
+	var group model.ClassGroup
+	err := r.db.Where("id = ?", id).First(&group).Error
+	return &group, err
+}
+
+func (r *classGroupRepository) GetByClassID(classID string) ([]model.ClassGroup, error) {
+	var groups []model.ClassGroup
+	err := r.db.Where("class_id = ?", classID).Order("created_at asc, name asc").Find(&groups).Error
+	return groups, err
+}
+
+func (r *classGroupRepository) Update(group *model.ClassGroup) error {
+	return r.db.Save(group).Error
+}
+
+func (r *classGroupRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&model.ClassGroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.ClassGroup{}).Error
+	})
+}
+
+func (r *classGroupRepository) Replace(classID string, groups []model.ClassGroup, members []model.ClassGroupMember) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteClassGroups(tx, classID); err != nil {
+			return err
+		}
+		if len(groups) > 0 {
+			if err := tx.Create(&groups).Error; err != nil {
+				return err
+			}
+		}
+		if len(members) > 0 {
+			if err := tx.Create(&members).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *classGroupRepository) DeleteByClassID(classID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteClassGroups(tx, classID)
+	})
+}
+
+func deleteClassGroups(tx *gorm.DB, classID string) error {
+	if err := tx.Where("class_id = ?", classID).Delete(&model.ClassGroupMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("class_id = ?", classID).Delete(&model.ClassGroup{}).Error
+}
+
+func (r *classGroupRepository) GetMembersByClassID(classID string) ([]model.ClassGroupMember, error) {
+	var members []model.ClassGroupMember
+	err := r.db.Where("class_id = ?", classID).Order("created_at asc").Find(&members).Error
+	return members, err
+}
+
+func (r *classGroupRepository) GetMembersByGroupID(groupID string) ([]model.ClassGroupMember, error) {
+	var members []model.ClassGroupMember
+	err := r.db.Where("group_id = ?", groupID).Order("created_at asc").Find(&members).Error
+	return members, err
+}
+
+func (r *classGroupRepository) GetMember(classID, studentID string) (*model.ClassGroupMember, error) {
+	var member model.ClassGroupMember
+	result := r.db.Where("class_id = ? AND student_id = ?", classID, studentID).Limit(1).Find(&member)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &member, nil
+}
+
+func (r *classGroupRepository) SaveMember(member *model.ClassGroupMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"group_id", "created_at"}),
+	}).Create(member).Error
+}
+
+func (r *classGroupRepository) DeleteMember(classID, studentID string) error {
+	return r.db.Where("class_id = ? AND student_id = ?", classID, studentID).Delete(&model.ClassGroupMember{}).Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// classJoinRequestRepository implements the ClassJoinRequestRepository interface.
+type classJoinRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewClassJoinRequestRepository creates a new ClassJoinRequestRepository.
+func NewClassJoinRequestRepository(db *gorm.DB) ClassJoinRequestRepository {
+	return &classJoinRequestRepository{db: db}
+}
+
+func (r *classJoinRequestRepository) Save(request *model.ClassJoinRequest) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "reviewed_by", "reviewed_at", "created_at", "updated_at"}),
+	}).Create(request).Error
+}
+
+func (r *classJoinRequestRepository) GetByID(id string) (*model.ClassJoinRequest, error) {
+	var request model.ClassJoinRequest
+	err := r.db.Where("id = ?", id).First(&request).Error
+	return &request, err
+}
+
+func (r *classJoinRequestRepository) GetByClassAndStudent(classID, studentID string) (*model.ClassJoinRequest, error) {
+	var request model.ClassJoinRequest
+	result := r.db.Where("class_id = ? AND student_id = ?", classID, studentID).Limit(1).Find(&request)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &request, nil
+}
+
+func (r *classJoinRequestRepository) GetByClassID(classID string, statuses []string) ([]model.ClassJoinRequest, error) {
+	var requests []model.ClassJoinRequest
+	query := r.db.Where("class_id = ?", classID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	err := query.Order("created_at asc").Find(&requests).Error
+	return requests, err
+}
+
+func (r *classJoinRequestRepository) GetByStudentID(studentID string) ([]model.ClassJoinRequest, error) {
+	var requests []model.ClassJoinRequest
+	err := r.db.Where("student_id = ?", studentID).Order("created_at desc").Find(&requests).Error
+	return requests, err
+}
+
+func (r *classJoinRequestRepository) UpdateStatus(request *model.ClassJoinRequest) (bool, error) {
+	result := r.db.Model(&model.ClassJoinRequest{}).
+		Where("id = ? AND status = ?", request.ID, model.JoinPending).
+		Updates(map[string]interface{}{
+			"status":      request.Status,
+			"reviewed_by": request.ReviewedBy,
+			"reviewed_at": request.ReviewedAt,
+			"updated_at":  request.UpdatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *classJoinRequestRepository) DeleteByClassID(classID string) error {
+	return r.db.Where("class_id = ?", classID).Delete(&model.ClassJoinRequest{}).Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// classMemberRepository implements the ClassMemberRepository interface.
+type classMemberRepository struct {
+	db *gorm.DB
+}
+
+// NewClassMemberRepository creates a new ClassMemberRepository.
+func NewClassMemberRepository(db *gorm.DB) ClassMemberRepository {
+	return &classMemberRepository{db: db}
+}
+
+func (r *classMemberRepository) Save(member *model.ClassMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "status", "joined_at", "left_at", "updated_at"}),
+	}).Create(member).Error
+}
+
+func (r *classMemberRepository) GetByClassAndStudent(classID, studentID string) (*model.ClassMember, error) {
+	var member model.ClassMember
+	result := r.db.Where("class_id = ? AND student_id = ?", classID, studentID).Limit(1).Find(&member)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &member, nil
+}
+
+func (r *classMemberRepository) GetByStudentID(studentID string) ([]model.ClassMember, error) {
+	var members []model.ClassMember
+	err := r.db.Where("student_id = ? AND status = ?", studentID, model.MemberActive).
+		Order("joined_at asc").Find(&members).Error
+	return members, err
+}
+
+func (r *classMemberRepository) GetByClassID(classID string) ([]model.ClassMember, error) {
+	var members []model.ClassMember
+	err := r.db.Where("class_id = ? AND status = ?", classID, model.MemberActive).
+		Order("joined_at asc").Find(&members).Error
+	return members, err
+}
+
+func (r *classMemberRepository) Remove(classID, studentID string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.ClassMember{}).
+		Where("class_id = ? AND student_id = ? AND status = ?", classID, studentID, model.MemberActive).
+		Updates(map[string]interface{}{"status": model.MemberRemoved, "left_at": at, "updated_at": at})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *classMemberRepository) DeleteByClassID(classID string) error {
+	return r.db.Where("class_id = ?", classID).Delete(&model.ClassMember{}).Error
+}
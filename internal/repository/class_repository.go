@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"GoCodeMentor/internal/model"
 
 	"gorm.io/gorm"
@@ -44,6 +46,25 @@ func (r *classRepository) GetByIDs(ids []string) ([]model.Class, error) {
 	return classes, err
 }
 
+func (r *classRepository) Update(class *model.Class) error {
+	return r.db.Save(class).Error
+}
+
+func (r *classRepository) ConsumeCode(id string, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Class{}).
+		Where("id = ? AND code_revoked = ?", id, false).
+		Where("code_expires_at IS NULL OR code_expires_at > ?", now).
+		Where("code_max_uses = 0 OR code_uses < code_max_uses").
+		UpdateColumn("code_uses", gorm.Expr("code_uses + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *classRepository) GetByCourseID(courseID string) ([]model.Class, error) {
+	var classes []model.Class
+	err := r.db.Where("course_id = ?", courseID).Order("created_at asc").Find(&classes).Error
+	return classes, err
+}
+
 func (r *classRepository) Delete(id string) error {
 	return r.db.Where("id = ?", id).Delete(&model.Class{}).Error
 }
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// classStaffRepository implements the ClassStaffRepository interface.
+type classStaffRepository struct {
+	db *gorm.DB
+}
+
+// NewClassStaffRepository creates a new ClassStaffRepository.
+func NewClassStaffRepository(db *gorm.DB) ClassStaffRepository {
+	return &classStaffRepository{db: db}
+}
+
+func (r *classStaffRepository) Save(staff *model.ClassStaff) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "status", "invited_by", "responded_at", "updated_at"}),
+	}).Create(staff).Error
+}
+
+func (r *classStaffRepository) GetByID(id string) (*model.ClassStaff, error) {
+	var staff model.ClassStaff
+	err := r.db.Where("id = ?", id).First(&staff).Error
+	if err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+func (r *classStaffRepository) GetByClassAndUser(classID, userID string) (*model.ClassStaff, error) {
+	var staff model.ClassStaff
+	result := r.db.Where("class_id = ? AND user_id = ?", classID, userID).Limit(1).Find(&staff)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &staff, nil
+}
+
+func (r *classStaffRepository) GetByClassID(classID string) ([]model.ClassStaff, error) {
+	var staff []model.ClassStaff
+	err := r.db.Where("class_id = ?", classID).Order("created_at asc").Find(&staff).Error
+	return staff, err
+}
+
+func (r *classStaffRepository) GetByUserID(userID string, statuses []string) ([]model.ClassStaff, error) {
+	var staff []model.ClassStaff
+	query := r.db.Where("user_id = ?", userID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	err := query.Order("created_at desc").Find(&staff).Error
+	return staff, err
+}
+
+func (r *classStaffRepository) Update(staff *model.ClassStaff) error {
+	return r.db.Save(staff).Error
+}
+
+func (r *classStaffRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.ClassStaff{}).Error
+}
+
+func (r *classStaffRepository) DeleteByClassID(classID string) error {
+	return r.db.Where("class_id = ?", classID).Delete(&model.ClassStaff{}).Error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// courseRepository implements the CourseRepository interface.
+type courseRepository struct {
+	db *gorm.DB
+}
+
+// NewCourseRepository creates a new CourseRepository.
+func NewCourseRepository(db *gorm.DB) CourseRepository {
+	return &courseRepository{db: db}
+}
+
+func (r *courseRepository) Create(course *model.Course) error {
+	return r.db.Create(course).Error
+}
+
+func (r *courseRepository) GetByID(id string) (*model.Course, error) {
+	var course model.Course
+	err := r.db.Where("id = ?", id).First(&course).Error
+	return &course, err
+}
+
+func (r *courseRepository) GetByIDs(ids []string) ([]model.Course, error) {
+	var courses []model.Course
+	err := r.db.Where("id IN ?", ids).Find(&courses).Error
+	return courses, err
+}
+
+func (r *courseRepository) GetByTeacherID(teacherID string) ([]model.Course, error) {
+	var courses []model.Course
+	err := r.db.Where("teacher_id = ?", teacherID).Order("created_at asc").Find(&courses).Error
+	return courses, err
+}
+
+func (r *courseRepository) Update(course *model.Course) error {
+	return r.db.Save(course).Error
+}
+
+func (r *courseRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.Course{}).Error
+}
@@ -15,6 +15,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -80,18 +81,42 @@ func InitDB() (*gorm.DB, error) {
 		}
 	}
 
+	// 批改任务的唯一索引要求同一提交最多一个排队中或执行中的任务，建立索引前先合并旧数据中的重复任务
+	if err := dedupeGradingJobs(db); err != nil {
+		return nil, fmt.Errorf("合并重复的批改任务失败: %w", err)
+	}
+
 	// 自动创建表结构
 	// fmt.Println("正在创建数据表...")
 	err = db.AutoMigrate(
 		&model.User{},
+		&model.UserSession{},
+		&model.Course{},
+		&model.Term{},
 		&model.Class{},
+		&model.ClassMember{},
+		&model.ClassStaff{},
+		&model.ClassJoinRequest{},
+		&model.ClassGroup{},
+		&model.ClassGroupMember{},
 		&model.ChatSession{},
 		&model.ChatMessage{},
 		&model.Assignment{},
 		&model.Question{},
+		&model.BankQuestion{},
 		&model.Submission{},
+		&model.SubmissionVersion{},
+		&model.AnswerDraft{},
+		&model.GradingJob{},
+		&model.SimilarityReport{},
 		&model.Feedback{},
 		&model.AssignmentClass{},
+		&model.DeadlineExtension{},
+		&model.ExamSession{},
+		&model.GradebookSettings{},
+		&model.GradeOverride{},
+		&model.GradeAuditLog{},
+		&model.GradeAppeal{},
 		&model.ResourceLike{}, // 新增资源点赞模型
 		&model.Resource{},
 		&model.KnowledgePoint{},
@@ -101,6 +126,11 @@ func InitDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// 把旧版本 users.class_id 中的班级迁移到班级成员表
+	if err := migrateClassMembers(db); err != nil {
+		return nil, fmt.Errorf("迁移班级成员失败: %w", err)
+	}
+
 	// 初始化推荐资源数据
 	seedInitialResources(db)
 
@@ -113,6 +143,65 @@ func InitDB() (*gorm.DB, error) {
 	return db, nil
 }
 
+// migrateClassMembers 旧版本中每个学生只能属于一个班级，记录在 users.class_id 上。
+// 为这些学生创建班级成员记录后清空该列，之后重启不会重复迁移
+func migrateClassMembers(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&model.User{}, "class_id") {
+		return nil
+	}
+
+	var rows []struct {
+		ID        string
+		ClassID   string
+		CreatedAt time.Time
+	}
+	if err := db.Table("users").Select("id, class_id, created_at").
+		Where("class_id IS NOT NULL AND deleted_at IS NULL").Scan(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	log.Printf("Migrating %d class memberships...", len(rows))
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, row := range rows {
+			member := model.ClassMember{
+				ID:        uuid.New().String(),
+				ClassID:   row.ClassID,
+				StudentID: row.ID,
+				Role:      model.ClassRoleStudent,
+				Status:    model.MemberActive,
+				JoinedAt:  row.CreatedAt,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Table("users").Where("class_id IS NOT NULL").Update("class_id", nil).Error
+	})
+}
+
+// dedupeGradingJobs 同一提交有多个排队中或执行中的批改任务时只保留最早的一个，其余标记为已完成；
+// 保留的任务会批改该提交全部待批改的版本。唯一索引建立之后不再执行
+func dedupeGradingJobs(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&model.GradingJob{}) || db.Migrator().HasIndex(&model.GradingJob{}, "idx_grading_job_active") {
+		return nil
+	}
+	keep := db.Model(&model.GradingJob{}).Select("DISTINCT ON (submission_id) id").
+		Where("status IN ?", activeJobStatuses).Order("submission_id, created_at")
+	return db.Model(&model.GradingJob{}).
+		Where("status IN ? AND id NOT IN (?)", activeJobStatuses, keep).
+		Updates(map[string]interface{}{
+			"status":      model.GradingJobSucceeded,
+			"last_error":  "已合并到同一提交的其他批改任务",
+			"finished_at": time.Now(),
+		}).Error
+}
+
 // seedInitialResources seeds the database with a predefined list of resources
 // if the resources table is empty.
 func seedInitialResources(db *gorm.DB) {
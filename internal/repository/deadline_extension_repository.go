@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deadlineExtensionRepository implements the DeadlineExtensionRepository interface.
+type deadlineExtensionRepository struct {
+	db *gorm.DB
+}
+
+// NewDeadlineExtensionRepository creates a new DeadlineExtensionRepository.
+func NewDeadlineExtensionRepository(db *gorm.DB) DeadlineExtensionRepository {
+	return &deadlineExtensionRepository{db: db}
+}
+
+func (r *deadlineExtensionRepository) Save(extension *model.DeadlineExtension) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "assignment_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"deadline", "reason", "granted_by", "updated_at"}),
+	}).Create(extension).Error
+}
+
+func (r *deadlineExtensionRepository) GetByAssignmentAndStudent(assignmentID, studentID string) (*model.DeadlineExtension, error) {
+	var extension model.DeadlineExtension
+	result := r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Limit(1).Find(&extension)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &extension, nil
+}
+
+func (r *deadlineExtensionRepository) GetByAssignmentID(assignmentID string) ([]model.DeadlineExtension, error) {
+	var extensions []model.DeadlineExtension
+	err := r.db.Where("assignment_id = ?", assignmentID).Order("deadline asc").Find(&extensions).Error
+	return extensions, err
+}
+
+func (r *deadlineExtensionRepository) Delete(assignmentID, studentID string) error {
+	return r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Delete(&model.DeadlineExtension{}).Error
+}
+
+func (r *deadlineExtensionRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.DeadlineExtension{}).Error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// examSessionRepository implements the ExamSessionRepository interface.
+type examSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewExamSessionRepository creates a new ExamSessionRepository.
+func NewExamSessionRepository(db *gorm.DB) ExamSessionRepository {
+	return &examSessionRepository{db: db}
+}
+
+func (r *examSessionRepository) Create(session *model.ExamSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *examSessionRepository) Update(session *model.ExamSession) error {
+	return r.db.Save(session).Error
+}
+
+func (r *examSessionRepository) SaveAnswers(id, answers, code string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.ExamSession{}).
+		Where("id = ? AND status = ?", id, model.ExamInProgress).
+		Updates(map[string]interface{}{"saved_answers": answers, "saved_code": code, "last_saved_at": at, "updated_at": at})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *examSessionRepository) GetByAssignmentAndStudent(assignmentID, studentID string) (*model.ExamSession, error) {
+	var session model.ExamSession
+	result := r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Limit(1).Find(&session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (r *examSessionRepository) ListByAssignment(assignmentID string) ([]model.ExamSession, error) {
+	var sessions []model.ExamSession
+	err := r.db.Where("assignment_id = ?", assignmentID).Order("started_at asc").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *examSessionRepository) ListExpired(before time.Time) ([]model.ExamSession, error) {
+	var sessions []model.ExamSession
+	err := r.db.Where("status = ? AND ends_at < ?", model.ExamInProgress, before).Order("ends_at asc").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *examSessionRepository) MarkSubmitted(id, status string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.ExamSession{}).
+		Where("id = ? AND status = ?", id, model.ExamInProgress).
+		Updates(map[string]interface{}{"status": status, "submitted_at": at, "updated_at": at})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *examSessionRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.ExamSession{}).Error
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// gradeAppealRepository implements the GradeAppealRepository interface.
+type gradeAppealRepository struct {
+	db *gorm.DB
+}
+
+// NewGradeAppealRepository creates a new GradeAppealRepository.
+func NewGradeAppealRepository(db *gorm.DB) GradeAppealRepository {
+	return &gradeAppealRepository{db: db}
+}
+
+func (r *gradeAppealRepository) Create(appeal *model.GradeAppeal) error {
+	return r.db.Create(appeal).Error
+}
+
+func (r *gradeAppealRepository) GetByID(id string) (*model.GradeAppeal, error) {
+	var appeal model.GradeAppeal
+	err := r.db.Where("id = ?", id).First(&appeal).Error
+	return &appeal, err
+}
+
+func (r *gradeAppealRepository) GetBySubmissionID(submissionID string) ([]model.GradeAppeal, error) {
+	var appeals []model.GradeAppeal
+	err := r.db.Where("submission_id = ?", submissionID).Order("created_at DESC").Find(&appeals).Error
+	return appeals, err
+}
+
+func (r *gradeAppealRepository) GetByClassID(classID string, statuses []string) ([]model.GradeAppeal, error) {
+	var appeals []model.GradeAppeal
+	query := r.db.Where("class_id = ?", classID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	err := query.Order("created_at ASC").Find(&appeals).Error
+	return appeals, err
+}
+
+func (r *gradeAppealRepository) UpdateStatus(appeal *model.GradeAppeal, fromStatuses []string) (bool, error) {
+	result := r.db.Model(&model.GradeAppeal{}).
+		Where("id = ? AND status IN ?", appeal.ID, fromStatuses).
+		Updates(map[string]interface{}{
+			"status":          appeal.Status,
+			"resolved_score":  appeal.ResolvedScore,
+			"teacher_comment": appeal.TeacherComment,
+			"resolved_by":     appeal.ResolvedBy,
+			"resolved_at":     appeal.ResolvedAt,
+			"updated_at":      appeal.UpdatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gradeAppealRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.GradeAppeal{}).Error
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gradebookSettingsRepository implements the GradebookSettingsRepository interface.
+type gradebookSettingsRepository struct {
+	db *gorm.DB
+}
+
+// NewGradebookSettingsRepository creates a new GradebookSettingsRepository.
+func NewGradebookSettingsRepository(db *gorm.DB) GradebookSettingsRepository {
+	return &gradebookSettingsRepository{db: db}
+}
+
+func (r *gradebookSettingsRepository) Save(settings *model.GradebookSettings) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"categories", "missing_as_zero", "letter_scale", "updated_by", "updated_at"}),
+	}).Create(settings).Error
+}
+
+func (r *gradebookSettingsRepository) GetByClassID(classID string) (*model.GradebookSettings, error) {
+	var settings model.GradebookSettings
+	result := r.db.Where("class_id = ?", classID).Limit(1).Find(&settings)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+// gradeOverrideRepository implements the GradeOverrideRepository interface.
+type gradeOverrideRepository struct {
+	db *gorm.DB
+}
+
+// NewGradeOverrideRepository creates a new GradeOverrideRepository.
+func NewGradeOverrideRepository(db *gorm.DB) GradeOverrideRepository {
+	return &gradeOverrideRepository{db: db}
+}
+
+func (r *gradeOverrideRepository) Save(override *model.GradeOverride, audit *model.GradeAuditLog) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "class_id"}, {Name: "assignment_id"}, {Name: "student_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "reason", "updated_by", "updated_at"}),
+		}).Create(override).Error; err != nil {
+			return err
+		}
+		return tx.Create(audit).Error
+	})
+}
+
+func (r *gradeOverrideRepository) Get(classID, assignmentID, studentID string) (*model.GradeOverride, error) {
+	var override model.GradeOverride
+	result := r.db.Where("class_id = ? AND assignment_id = ? AND student_id = ?", classID, assignmentID, studentID).Limit(1).Find(&override)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &override, nil
+}
+
+func (r *gradeOverrideRepository) GetByClassID(classID string) ([]model.GradeOverride, error) {
+	var overrides []model.GradeOverride
+	err := r.db.Where("class_id = ?", classID).Find(&overrides).Error
+	return overrides, err
+}
+
+func (r *gradeOverrideRepository) Delete(classID, assignmentID, studentID string, audit *model.GradeAuditLog) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("class_id = ? AND assignment_id = ? AND student_id = ?", classID, assignmentID, studentID).Delete(&model.GradeOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(audit).Error
+	})
+}
+
+// gradeAuditLogRepository implements the GradeAuditLogRepository interface.
+type gradeAuditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewGradeAuditLogRepository creates a new GradeAuditLogRepository.
+func NewGradeAuditLogRepository(db *gorm.DB) GradeAuditLogRepository {
+	return &gradeAuditLogRepository{db: db}
+}
+
+func (r *gradeAuditLogRepository) Create(log *model.GradeAuditLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *gradeAuditLogRepository) GetByClassID(classID, studentID string) ([]model.GradeAuditLog, error) {
+	var logs []model.GradeAuditLog
+	query := r.db.Where("class_id = ?", classID)
+	if studentID != "" {
+		query = query.Where("student_id = ?", studentID)
+	}
+	err := query.Order("created_at desc, id desc").Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gradingJobRepository implements the GradingJobRepository interface.
+type gradingJobRepository struct {
+	db *gorm.DB
+}
+
+// NewGradingJobRepository creates a new GradingJobRepository.
+func NewGradingJobRepository(db *gorm.DB) GradingJobRepository {
+	return &gradingJobRepository{db: db}
+}
+
+// activeJobStatuses 每个提交最多有一个处于这些状态的任务
+var activeJobStatuses = []string{model.GradingJobQueued, model.GradingJobRunning}
+
+func (r *gradingJobRepository) CreateIfAbsent(job *model.GradingJob) (bool, error) {
+	// 唯一索引 idx_grading_job_active 保证并发创建时只有一个成功
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gradingJobRepository) Activate(submissionID string, now time.Time) (bool, error) {
+	found := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var job model.GradingJob
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("submission_id = ? AND status IN ?", submissionID, activeJobStatuses).
+			Limit(1).Find(&job)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		found = true
+		if job.Status == model.GradingJobRunning {
+			return tx.Model(&job).Update("rerun", true).Error
+		}
+		return tx.Model(&job).Update("next_run_at", now).Error
+	})
+	return found, err
+}
+
+func (r *gradingJobRepository) Finish(job *model.GradingJob) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current model.GradingJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", job.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if current.Rerun {
+			// 执行期间学生又提交了新版本，重新排队批改新版本，重试次数重新计算
+			job.Status = model.GradingJobQueued
+			job.Attempts = 0
+			job.NextRunAt = time.Now()
+			job.FinishedAt = nil
+		}
+		job.Rerun = false
+		return tx.Save(job).Error
+	})
+}
+
+func (r *gradingJobRepository) ClaimNext(now time.Time) (*model.GradingJob, error) {
+	var job model.GradingJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED 保证多个 worker 不会领取到同一个任务
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", model.GradingJobQueued, now).
+			Order("next_run_at").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+		job.Status = model.GradingJobRunning
+		job.Attempts++
+		job.StartedAt = &now
+		return tx.Save(&job).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *gradingJobRepository) ListByAssignment(assignmentID string, statuses []string) ([]model.GradingJob, error) {
+	var jobs []model.GradingJob
+	query := r.db.Where("assignment_id = ?", assignmentID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	err := query.Order("created_at DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *gradingJobRepository) Requeue(assignmentID string, jobIDs []string, statuses []string) (int64, error) {
+	matching := func() *gorm.DB {
+		query := r.db.Model(&model.GradingJob{}).Where("assignment_id = ? AND status IN ?", assignmentID, statuses)
+		if len(jobIDs) > 0 {
+			query = query.Where("id IN ?", jobIDs)
+		}
+		return query
+	}
+	// 每个提交最多一个排队中或执行中的任务：只重新排队最近的任务，并跳过已有其他排队中或执行中任务的提交
+	latest := matching().Select("DISTINCT ON (submission_id) id").Order("submission_id, created_at DESC")
+	result := matching().Where("id IN (?)", latest).
+		Where("NOT EXISTS (SELECT 1 FROM grading_jobs a WHERE a.submission_id = grading_jobs.submission_id AND a.id <> grading_jobs.id AND a.status IN ?)", activeJobStatuses).
+		Updates(map[string]interface{}{
+			"status":      model.GradingJobQueued,
+			"attempts":    0,
+			"last_error":  "",
+			"next_run_at": time.Now(),
+			"finished_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gradingJobRepository) ResetRunning() error {
+	return r.db.Model(&model.GradingJob{}).
+		Where("status = ?", model.GradingJobRunning).
+		Updates(map[string]interface{}{
+			"status":      model.GradingJobQueued,
+			"rerun":       false,
+			"next_run_at": time.Now(),
+		}).Error
+}
+
+func (r *gradingJobRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.GradingJob{}).Error
+}
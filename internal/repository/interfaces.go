@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"GoCodeMentor/internal/model"
 )
 
@@ -12,7 +14,7 @@ type UserRepository interface {
 	GetByUsername(username string) (*model.User, error)
 	// GetByID 根据用户 ID 获取用户
 	GetByID(id string) (*model.User, error)
-	// GetByClassID 根据班级 ID 获取该班级下的所有用户
+	// GetByClassID 根据班级 ID 获取该班级当前的所有成员
 	GetByClassID(classID string) ([]model.User, error)
 	// GetAll 获取所有用户
 	GetAll() ([]model.User, error)
@@ -22,6 +24,20 @@ type UserRepository interface {
 	Delete(user *model.User) error
 }
 
+// UserSessionRepository 定义了登录会话数据操作的接口。
+type UserSessionRepository interface {
+	// Create 创建一个新的登录会话
+	Create(session *model.UserSession) error
+	// GetByTokenHash 根据令牌哈希获取会话
+	GetByTokenHash(tokenHash string) (*model.UserSession, error)
+	// Update 更新会话信息（如最近访问时间、吊销时间）
+	Update(session *model.UserSession) error
+	// RevokeByUserID 吊销某个用户的所有有效会话
+	RevokeByUserID(userID string) error
+	// DeleteExpired 删除在指定时间之前已过期的会话
+	DeleteExpired(before time.Time) error
+}
+
 // ClassRepository 定义了班级数据操作的接口。
 type ClassRepository interface {
 	// Create 创建一个新班级
@@ -33,10 +49,127 @@ type ClassRepository interface {
 	// GetByTeacherID 获取教师创建的所有班级
 	GetByTeacherID(teacherID string) ([]model.Class, error)
 	GetByIDs(ids []string) ([]model.Class, error)
+	// Update 更新班级信息
+	Update(class *model.Class) error
+	// ConsumeCode 邀请码未停用、未过期且未达到使用上限时使用次数加一，返回是否成功
+	ConsumeCode(id string, now time.Time) (bool, error)
+	// GetByCourseID 获取课程下的所有班级，按创建时间升序
+	GetByCourseID(courseID string) ([]model.Class, error)
 	// Delete 根据 ID 删除班级
 	Delete(id string) error
 }
 
+// CourseRepository 定义了课程数据操作的接口。
+type CourseRepository interface {
+	// Create 创建课程
+	Create(course *model.Course) error
+	// GetByID 根据 ID 获取课程
+	GetByID(id string) (*model.Course, error)
+	// GetByIDs 根据 ID 批量获取课程
+	GetByIDs(ids []string) ([]model.Course, error)
+	// GetByTeacherID 获取教师创建的所有课程
+	GetByTeacherID(teacherID string) ([]model.Course, error)
+	// Update 更新课程信息
+	Update(course *model.Course) error
+	// Delete 根据 ID 删除课程
+	Delete(id string) error
+}
+
+// TermRepository 定义了学期数据操作的接口。
+type TermRepository interface {
+	// Create 创建学期
+	Create(term *model.Term) error
+	// GetByID 根据 ID 获取学期
+	GetByID(id string) (*model.Term, error)
+	// GetAll 获取全部学期，按开始日期降序
+	GetAll() ([]model.Term, error)
+	// Update 更新学期信息
+	Update(term *model.Term) error
+}
+
+// ClassMemberRepository 定义了班级成员关系数据操作的接口。
+type ClassMemberRepository interface {
+	// Save 创建或更新学生在班级中的成员关系（重新加入时恢复已移除的记录）
+	Save(member *model.ClassMember) error
+	// GetByClassAndStudent 获取学生在班级中的成员关系（含已移除的），不存在时返回 nil
+	GetByClassAndStudent(classID, studentID string) (*model.ClassMember, error)
+	// GetByStudentID 获取学生当前所在的全部班级的成员关系，按加入时间升序
+	GetByStudentID(studentID string) ([]model.ClassMember, error)
+	// GetByClassID 获取班级当前的全部成员关系
+	GetByClassID(classID string) ([]model.ClassMember, error)
+	// Remove 把学生标记为已离开班级；学生不在班级中时返回 false
+	Remove(classID, studentID string, at time.Time) (bool, error)
+	// DeleteByClassID 删除班级的全部成员关系
+	DeleteByClassID(classID string) error
+}
+
+// ClassStaffRepository 定义了班级教学人员（合作教师、助教）数据操作的接口。
+type ClassStaffRepository interface {
+	// Save 创建或更新教学人员记录（重新邀请已拒绝的用户时覆盖原记录）
+	Save(staff *model.ClassStaff) error
+	// GetByID 根据 ID 获取教学人员记录
+	GetByID(id string) (*model.ClassStaff, error)
+	// GetByClassAndUser 获取用户在班级中的教学人员记录，不存在时返回 nil
+	GetByClassAndUser(classID, userID string) (*model.ClassStaff, error)
+	// GetByClassID 获取班级的全部教学人员记录（含待接受的邀请），按邀请时间升序
+	GetByClassID(classID string) ([]model.ClassStaff, error)
+	// GetByUserID 获取用户在各班级的教学人员记录，statuses 为空时返回全部状态
+	GetByUserID(userID string, statuses []string) ([]model.ClassStaff, error)
+	// Update 更新教学人员记录
+	Update(staff *model.ClassStaff) error
+	// Delete 删除教学人员记录
+	Delete(id string) error
+	// DeleteByClassID 删除班级的全部教学人员记录
+	DeleteByClassID(classID string) error
+}
+
+// ClassJoinRequestRepository 定义了学生加入班级申请数据操作的接口。
+type ClassJoinRequestRepository interface {
+	// Save 创建或更新学生的加入申请（被拒绝后重新申请时覆盖原记录）
+	Save(request *model.ClassJoinRequest) error
+	// GetByID 根据 ID 获取申请
+	GetByID(id string) (*model.ClassJoinRequest, error)
+	// GetByClassAndStudent 获取学生对班级的申请，不存在时返回 nil
+	GetByClassAndStudent(classID, studentID string) (*model.ClassJoinRequest, error)
+	// GetByClassID 获取班级的申请，statuses 为空时返回全部状态，按申请时间升序
+	GetByClassID(classID string, statuses []string) ([]model.ClassJoinRequest, error)
+	// GetByStudentID 获取学生提交的全部申请，按申请时间降序
+	GetByStudentID(studentID string) ([]model.ClassJoinRequest, error)
+	// UpdateStatus 仅当申请仍处于待审批状态时更新审批结果，返回是否更新成功
+	UpdateStatus(request *model.ClassJoinRequest) (bool, error)
+	// DeleteByClassID 删除班级的全部申请
+	DeleteByClassID(classID string) error
+}
+
+// ClassGroupRepository 定义了班级小组及组员数据操作的接口。
+type ClassGroupRepository interface {
+	// Create 创建小组
+	Create(group *model.ClassGroup) error
+	// GetByID 根据 ID 获取小组
+	GetByID(id string) (*model.ClassGroup, error)
+	// GetByClassID 获取班级的全部小组，按创建时间升序
+	GetByClassID(classID string) ([]model.ClassGroup, error)
+	// Update 更新小组信息
+	Update(group *model.ClassGroup) error
+	// Delete 删除小组及其组员关系
+	Delete(id string) error
+	// Replace 用新的分组替换班级原有的全部小组和组员关系
+	Replace(classID string, groups []model.ClassGroup, members []model.ClassGroupMember) error
+	// DeleteByClassID 删除班级的全部小组和组员关系
+	DeleteByClassID(classID string) error
+
+	// GetMembersByClassID 获取班级的全部组员关系
+	GetMembersByClassID(classID string) ([]model.ClassGroupMember, error)
+	// GetMembersByGroupID 获取小组的组员关系，按加入时间升序
+	GetMembersByGroupID(groupID string) ([]model.ClassGroupMember, error)
+	// GetMember 获取学生在班级中所在小组的组员关系，没有分组时返回 nil
+	GetMember(classID, studentID string) (*model.ClassGroupMember, error)
+	// SaveMember 把学生放入小组，学生已在班级的其他小组时移到该小组
+	SaveMember(member *model.ClassGroupMember) error
+	// DeleteMember 把学生移出所在小组
+	DeleteMember(classID, studentID string) error
+}
+
 // AssignmentRepository 定义了作业数据操作的接口。
 type AssignmentRepository interface {
 	// Create 创建一个新作业
@@ -47,6 +180,8 @@ type AssignmentRepository interface {
 	GetByTeacherID(teacherID string) ([]model.Assignment, error)
 	// GetByClassID 获取发布到特定班级的所有作业
 	GetByClassID(classID string) ([]model.Assignment, error)
+	// GetByClassIDs 获取发布到任一班级的所有作业（去重），用于属于多个班级的学生
+	GetByClassIDs(classIDs []string) ([]model.Assignment, error)
 	// Update 更新作业信息
 	Update(assignment *model.Assignment) error
 	// DeleteByID 根据 ID 删除作业
@@ -69,37 +204,235 @@ type AssignmentClassRepository interface {
 	DeleteByAssignmentID(assignmentID string) error
 	// DeleteByAssignmentAndClass 根据作业 ID 和班级 ID 删除特定的关联关系（取消发布）
 	DeleteByAssignmentAndClass(assignmentID, classID string) error
+	// GetDueSchedules 获取到了发放时间仍未发放、或到了关闭时间仍未关闭的关联关系
+	GetDueSchedules(now time.Time) ([]model.AssignmentClass, error)
+}
+
+// DeadlineExtensionRepository 定义了学生个人延期数据操作的接口。
+type DeadlineExtensionRepository interface {
+	// Save 创建或更新某个学生在某个作业上的延期
+	Save(extension *model.DeadlineExtension) error
+	// GetByAssignmentAndStudent 获取学生的延期，没有延期时返回 nil
+	GetByAssignmentAndStudent(assignmentID, studentID string) (*model.DeadlineExtension, error)
+	// GetByAssignmentID 获取作业的全部延期
+	GetByAssignmentID(assignmentID string) ([]model.DeadlineExtension, error)
+	// Delete 删除学生的延期
+	Delete(assignmentID, studentID string) error
+	// DeleteByAssignmentID 根据作业 ID 删除所有延期
+	DeleteByAssignmentID(assignmentID string) error
+}
+
+// ExamSessionRepository 定义了考试会话数据操作的接口。
+type ExamSessionRepository interface {
+	// Create 创建考试会话
+	Create(session *model.ExamSession) error
+	// Update 更新考试会话
+	Update(session *model.ExamSession) error
+	// SaveAnswers 仅当会话仍在作答中时保存答案草稿，返回是否保存成功
+	SaveAnswers(id, answers, code string, at time.Time) (bool, error)
+	// GetByAssignmentAndStudent 获取学生的考试会话，没有开始考试时返回 nil
+	GetByAssignmentAndStudent(assignmentID, studentID string) (*model.ExamSession, error)
+	// ListByAssignment 获取作业的全部考试会话，按开始时间升序
+	ListByAssignment(assignmentID string) ([]model.ExamSession, error)
+	// ListExpired 获取在 before 之前已到结束时间但仍在作答中的会话
+	ListExpired(before time.Time) ([]model.ExamSession, error)
+	// MarkSubmitted 仅当会话仍在作答中时将其标记为已交卷，返回是否标记成功
+	MarkSubmitted(id, status string, at time.Time) (bool, error)
+	// DeleteByAssignmentID 根据作业 ID 删除所有考试会话
+	DeleteByAssignmentID(assignmentID string) error
+}
+
+// GradebookSettingsRepository 定义了班级成绩册规则数据操作的接口。
+type GradebookSettingsRepository interface {
+	// Save 创建或更新班级的成绩册规则
+	Save(settings *model.GradebookSettings) error
+	// GetByClassID 获取班级的成绩册规则，没有设置过时返回 nil
+	GetByClassID(classID string) (*model.GradebookSettings, error)
+}
+
+// GradeOverrideRepository 定义了成绩册手动成绩数据操作的接口。
+type GradeOverrideRepository interface {
+	// Save 创建或更新某个单元格的手动成绩，并在同一事务中写入修改记录
+	Save(override *model.GradeOverride, audit *model.GradeAuditLog) error
+	// Get 获取某个单元格的手动成绩，没有时返回 nil
+	Get(classID, assignmentID, studentID string) (*model.GradeOverride, error)
+	// GetByClassID 获取班级的全部手动成绩
+	GetByClassID(classID string) ([]model.GradeOverride, error)
+	// Delete 删除某个单元格的手动成绩，并在同一事务中写入修改记录
+	Delete(classID, assignmentID, studentID string, audit *model.GradeAuditLog) error
+}
+
+// GradeAuditLogRepository 定义了成绩修改记录数据操作的接口。
+type GradeAuditLogRepository interface {
+	// Create 记录一次成绩修改
+	Create(log *model.GradeAuditLog) error
+	// GetByClassID 获取班级的修改记录，按时间倒序；studentID 不为空时只返回该学生的记录
+	GetByClassID(classID, studentID string) ([]model.GradeAuditLog, error)
+}
+
+// GradeAppealRepository 定义了成绩申诉数据操作的接口。
+type GradeAppealRepository interface {
+	// Create 创建申诉
+	Create(appeal *model.GradeAppeal) error
+	// GetByID 根据 ID 获取申诉
+	GetByID(id string) (*model.GradeAppeal, error)
+	// GetBySubmissionID 获取提交的全部申诉，按时间倒序
+	GetBySubmissionID(submissionID string) ([]model.GradeAppeal, error)
+	// GetByClassID 获取班级的申诉，按时间正序；statuses 为空时返回全部状态
+	GetByClassID(classID string, statuses []string) ([]model.GradeAppeal, error)
+	// UpdateStatus 仅当申诉仍处于 fromStatuses 之一时更新状态和处理结果，返回是否更新成功
+	UpdateStatus(appeal *model.GradeAppeal, fromStatuses []string) (bool, error)
+	// DeleteByAssignmentID 根据作业 ID 删除所有申诉
+	DeleteByAssignmentID(assignmentID string) error
 }
 
 // QuestionRepository 定义了题目数据操作的接口。
 type QuestionRepository interface {
 	// Create 创建一个新题目
 	Create(question *model.Question) error
+	// GetByID 根据题目 ID 获取题目
+	GetByID(id string) (*model.Question, error)
 	// GetByAssignmentID 根据作业 ID 获取该作业下的所有题目
 	GetByAssignmentID(assignmentID string) ([]model.Question, error)
+	// Update 更新题目信息
+	Update(question *model.Question) error
+	// Delete 删除单个题目
+	Delete(id string) error
+	// UpdateOrder 按给定的题目 ID 顺序重新设置题号（从 1 开始）
+	UpdateOrder(assignmentID string, questionIDs []string) error
 	// DeleteByAssignmentID 根据作业 ID 删除该作业下的所有题目
 	DeleteByAssignmentID(assignmentID string) error
 }
 
+// BankQuestionFilter 题库检索条件，零值字段不参与过滤。
+type BankQuestionFilter struct {
+	ViewerID         string // 只返回该教师自己的或已共享的题目
+	OnlyMine         bool   // 只返回该教师自己的题目
+	Type             string
+	Difficulty       string
+	KnowledgePointID *uint
+	Keyword          string // 题干模糊匹配
+	ExcludeIDs       []string
+	Random           bool // 随机排序（按规则抽题）
+	Limit            int
+}
+
+// BankQuestionRepository 定义了题库数据操作的接口。
+type BankQuestionRepository interface {
+	// Create 创建一道题库题目
+	Create(item *model.BankQuestion) error
+	// GetByID 根据 ID 获取题库题目
+	GetByID(id string) (*model.BankQuestion, error)
+	// GetByIDs 根据 ID 批量获取题库题目
+	GetByIDs(ids []string) ([]model.BankQuestion, error)
+	// Update 更新题库题目
+	Update(item *model.BankQuestion) error
+	// Delete 删除题库题目
+	Delete(id string) error
+	// Search 按条件检索题库
+	Search(filter BankQuestionFilter) ([]model.BankQuestion, error)
+	// IncrementUsage 增加题目被使用的次数
+	IncrementUsage(ids []string) error
+}
+
 // SubmissionRepository 定义了学生提交记录数据操作的接口。
 type SubmissionRepository interface {
 	// Create 创建一个新的提交记录
 	Create(submission *model.Submission) error
 	// GetByID 根据提交 ID 获取提交详情
 	GetByID(id string) (*model.Submission, error)
-	// GetByAssignmentAndStudent 根据作业 ID 和学生 ID 获取特定的提交记录
+	// GetByAssignmentAndStudent 根据作业 ID 和学生 ID 获取特定的提交记录；
+	// 小组作业返回学生的副本，这里和下面两个按学生、作业列出的方法都不返回小组主提交
 	GetByAssignmentAndStudent(assignmentID, studentID string) (*model.Submission, error)
 	// GetByAssignmentIDs a
 	GetByAssignmentIDs(assignmentIDs []string) ([]model.Submission, error)
 	GetByStudentAndAssignmentIDs(studentID string, assignmentIDs []string) ([]model.Submission, error)
+	// GetGroupSubmission 获取小组在作业上的主提交，不存在时返回 nil
+	GetGroupSubmission(assignmentID, groupID string) (*model.Submission, error)
+	// GetGroupCopies 获取主提交的全部组员副本
+	GetGroupCopies(submissionID string) ([]model.Submission, error)
+	// GetSubmittedWithoutJob 获取待批改但没有排队、运行中或失败批改任务的提交（不含组员副本），
+	// assignmentID 为空时查询全部作业
+	GetSubmittedWithoutJob(assignmentID string) ([]model.Submission, error)
 	// Update 更新提交记录（如批改结果、分数等）
 	Update(submission *model.Submission) error
-	// CountByAssignmentID 根据作业 ID 和状态统计提交数量
+	// UpdateLocked 在事务中锁定并重新读取提交记录，交给 fn 修改后保存；
+	// 用于批改结果和学生的新提交并发时不互相覆盖，fn 返回错误时不保存
+	UpdateLocked(id string, fn func(submission *model.Submission) error) error
+	// CountByAssignmentID 根据作业 ID 和状态统计提交数量，小组作业只计主提交
 	CountByAssignmentID(assignmentID string, status string) (int64, error)
 	// DeleteByAssignmentID 根据作业 ID 删除所有相关的提交记录
 	DeleteByAssignmentID(assignmentID string) error
 }
 
+// SubmissionVersionRepository 定义了提交版本数据操作的接口。
+type SubmissionVersionRepository interface {
+	// Create 创建一个新的提交版本
+	Create(version *model.SubmissionVersion) error
+	// Update 更新提交版本的全部字段
+	Update(version *model.SubmissionVersion) error
+	// SaveGrading 只写入批改结果相关的字段，不覆盖批改期间对版本的其他修改
+	SaveGrading(version *model.SubmissionVersion) error
+	// MarkUngraded 把作业下已批改的提交版本标记为待批改，用于整体重新批改
+	MarkUngraded(assignmentID string) error
+	// GetBySubmissionID 获取某个提交的全部版本，按版本号升序
+	GetBySubmissionID(submissionID string) ([]model.SubmissionVersion, error)
+	// GetByAttempt 获取某个提交的指定版本
+	GetByAttempt(submissionID string, attempt int) (*model.SubmissionVersion, error)
+	// DeleteByAssignmentID 根据作业 ID 删除所有相关的提交版本
+	DeleteByAssignmentID(assignmentID string) error
+}
+
+// AnswerDraftRepository 定义了答案草稿数据操作的接口。
+type AnswerDraftRepository interface {
+	// Save 创建或覆盖学生在某个作业上的草稿
+	Save(draft *model.AnswerDraft) error
+	// GetByAssignmentAndStudent 获取学生的草稿，没有草稿时返回 nil
+	GetByAssignmentAndStudent(assignmentID, studentID string) (*model.AnswerDraft, error)
+	// GetByStudentAndAssignmentIDs 批量获取学生在多个作业上的草稿
+	GetByStudentAndAssignmentIDs(studentID string, assignmentIDs []string) ([]model.AnswerDraft, error)
+	// Delete 删除学生的草稿
+	Delete(assignmentID, studentID string) error
+	// DeleteByAssignmentID 根据作业 ID 删除所有草稿
+	DeleteByAssignmentID(assignmentID string) error
+}
+
+// GradingJobRepository 定义了批改任务数据操作的接口。
+type GradingJobRepository interface {
+	// CreateIfAbsent 创建批改任务；该提交已有排队中或执行中的任务时不创建并返回 false
+	CreateIfAbsent(job *model.GradingJob) (bool, error)
+	// Activate 让提交已有的任务尽快执行：排队中的任务提前到 now，执行中的任务标记为完成后重新排队；
+	// 没有排队中或执行中的任务时返回 false
+	Activate(submissionID string, now time.Time) (bool, error)
+	// Finish 保存任务的执行结果；执行期间被标记为需要重新排队的任务改为立即重新排队
+	Finish(job *model.GradingJob) error
+	// ClaimNext 领取一个已到执行时间的排队任务并标记为执行中，没有可执行任务时返回 nil
+	ClaimNext(now time.Time) (*model.GradingJob, error)
+	// ListByAssignment 按状态列出某个作业的批改任务，statuses 为空时返回全部
+	ListByAssignment(assignmentID string, statuses []string) ([]model.GradingJob, error)
+	// Requeue 将指定作业下处于给定状态的任务重新排队，jobIDs 为空时作用于全部匹配任务；
+	// 每个提交只重新排队最近的一个任务，已有其他排队中或执行中任务的提交跳过
+	Requeue(assignmentID string, jobIDs []string, statuses []string) (int64, error)
+	// ResetRunning 将遗留的执行中任务（如服务异常退出）重新排队
+	ResetRunning() error
+	// DeleteByAssignmentID 根据作业 ID 删除所有相关的批改任务
+	DeleteByAssignmentID(assignmentID string) error
+}
+
+// SimilarityReportRepository 定义了相似度检测报告数据操作的接口。
+type SimilarityReportRepository interface {
+	// Create 创建一份检测报告
+	Create(report *model.SimilarityReport) error
+	// Update 更新检测报告（写入检测结果）
+	Update(report *model.SimilarityReport) error
+	// GetLatestByAssignment 获取作业最近一次的检测报告，没有时返回 nil
+	GetLatestByAssignment(assignmentID string) (*model.SimilarityReport, error)
+	// FailRunning 将未完成的检测（如服务异常退出）标记为失败
+	FailRunning(reason string) (int64, error)
+	// DeleteByAssignmentID 根据作业 ID 删除所有检测报告
+	DeleteByAssignmentID(assignmentID string) error
+}
+
 // ChatSessionRepository 定义了 AI 聊天会话数据操作的接口。
 type ChatSessionRepository interface {
 	// Create 创建一个新的聊天会话
@@ -20,12 +20,40 @@ func (r *questionRepository) Create(question *model.Question) error {
 	return r.db.Create(question).Error
 }
 
+func (r *questionRepository) GetByID(id string) (*model.Question, error) {
+	var question model.Question
+	err := r.db.Where("id = ?", id).First(&question).Error
+	return &question, err
+}
+
 func (r *questionRepository) GetByAssignmentID(assignmentID string) ([]model.Question, error) {
 	var questions []model.Question
 	err := r.db.Where("assignment_id = ?", assignmentID).Order("order_num asc").Find(&questions).Error
 	return questions, err
 }
 
+func (r *questionRepository) Update(question *model.Question) error {
+	return r.db.Save(question).Error
+}
+
+func (r *questionRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.Question{}).Error
+}
+
+func (r *questionRepository) UpdateOrder(assignmentID string, questionIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range questionIDs {
+			err := tx.Model(&model.Question{}).
+				Where("id = ? AND assignment_id = ?", id, assignmentID).
+				Update("order_num", i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *questionRepository) DeleteByAssignmentID(assignmentID string) error {
 	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.Question{}).Error
 }
@@ -4,28 +4,64 @@ import "gorm.io/gorm"
 
 // Repositories holds all repositories.
 type Repositories struct {
-	UserRepo            UserRepository
-	ClassRepo           ClassRepository
-	AssignmentRepo      AssignmentRepository
-	AssignmentClassRepo AssignmentClassRepository
-	QuestionRepo        QuestionRepository
-	SubmissionRepo      SubmissionRepository
-	FeedbackRepo        FeedbackRepository
-	SessionRepo         ChatSessionRepository
-	MessageRepo         ChatMessageRepository
+	UserRepo              UserRepository
+	UserSessionRepo       UserSessionRepository
+	CourseRepo            CourseRepository
+	TermRepo              TermRepository
+	ClassRepo             ClassRepository
+	ClassMemberRepo       ClassMemberRepository
+	ClassStaffRepo        ClassStaffRepository
+	JoinRequestRepo       ClassJoinRequestRepository
+	GroupRepo             ClassGroupRepository
+	AssignmentRepo        AssignmentRepository
+	AssignmentClassRepo   AssignmentClassRepository
+	ExtensionRepo         DeadlineExtensionRepository
+	ExamSessionRepo       ExamSessionRepository
+	GradebookRepo         GradebookSettingsRepository
+	GradeOverrideRepo     GradeOverrideRepository
+	GradeAuditRepo        GradeAuditLogRepository
+	GradeAppealRepo       GradeAppealRepository
+	QuestionRepo          QuestionRepository
+	BankQuestionRepo      BankQuestionRepository
+	SubmissionRepo        SubmissionRepository
+	SubmissionVersionRepo SubmissionVersionRepository
+	AnswerDraftRepo       AnswerDraftRepository
+	GradingJobRepo        GradingJobRepository
+	SimilarityRepo        SimilarityReportRepository
+	FeedbackRepo          FeedbackRepository
+	SessionRepo           ChatSessionRepository
+	MessageRepo           ChatMessageRepository
 }
 
 // NewRepositories creates a new Repositories struct.
 func NewRepositories(db *gorm.DB) *Repositories {
 	return &Repositories{
-		UserRepo:            NewUserRepository(db),
-		ClassRepo:           NewClassRepository(db),
-		AssignmentRepo:      NewAssignmentRepository(db),
-		AssignmentClassRepo: NewAssignmentClassRepository(db),
-		QuestionRepo:        NewQuestionRepository(db),
-		SubmissionRepo:      NewSubmissionRepository(db),
-		FeedbackRepo:        NewFeedbackRepository(db),
-		SessionRepo:         NewChatSessionRepository(db),
-		MessageRepo:         NewChatMessageRepository(db),
+		UserRepo:              NewUserRepository(db),
+		UserSessionRepo:       NewUserSessionRepository(db),
+		CourseRepo:            NewCourseRepository(db),
+		TermRepo:              NewTermRepository(db),
+		ClassRepo:             NewClassRepository(db),
+		ClassMemberRepo:       NewClassMemberRepository(db),
+		ClassStaffRepo:        NewClassStaffRepository(db),
+		JoinRequestRepo:       NewClassJoinRequestRepository(db),
+		GroupRepo:             NewClassGroupRepository(db),
+		AssignmentRepo:        NewAssignmentRepository(db),
+		AssignmentClassRepo:   NewAssignmentClassRepository(db),
+		ExtensionRepo:         NewDeadlineExtensionRepository(db),
+		ExamSessionRepo:       NewExamSessionRepository(db),
+		GradebookRepo:         NewGradebookSettingsRepository(db),
+		GradeOverrideRepo:     NewGradeOverrideRepository(db),
+		GradeAuditRepo:        NewGradeAuditLogRepository(db),
+		GradeAppealRepo:       NewGradeAppealRepository(db),
+		QuestionRepo:          NewQuestionRepository(db),
+		BankQuestionRepo:      NewBankQuestionRepository(db),
+		SubmissionRepo:        NewSubmissionRepository(db),
+		SubmissionVersionRepo: NewSubmissionVersionRepository(db),
+		AnswerDraftRepo:       NewAnswerDraftRepository(db),
+		GradingJobRepo:        NewGradingJobRepository(db),
+		SimilarityRepo:        NewSimilarityReportRepository(db),
+		FeedbackRepo:          NewFeedbackRepository(db),
+		SessionRepo:           NewChatSessionRepository(db),
+		MessageRepo:           NewChatMessageRepository(db),
 	}
 }
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// similarityReportRepository implements the SimilarityReportRepository interface.
+type similarityReportRepository struct {
+	db *gorm.DB
+}
+
+// NewSimilarityReportRepository creates a new SimilarityReportRepository.
+func NewSimilarityReportRepository(db *gorm.DB) SimilarityReportRepository {
+	return &similarityReportRepository{db: db}
+}
+
+func (r *similarityReportRepository) Create(report *model.SimilarityReport) error {
+	return r.db.Create(report).Error
+}
+
+func (r *similarityReportRepository) Update(report *model.SimilarityReport) error {
+	return r.db.Save(report).Error
+}
+
+func (r *similarityReportRepository) GetLatestByAssignment(assignmentID string) (*model.SimilarityReport, error) {
+	var report model.SimilarityReport
+	result := r.db.Where("assignment_id = ?", assignmentID).Order("created_at desc").Limit(1).Find(&report)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &report, nil
+}
+
+func (r *similarityReportRepository) FailRunning(reason string) (int64, error) {
+	now := time.Now()
+	result := r.db.Model(&model.SimilarityReport{}).
+		Where("status = ?", model.SimilarityRunning).
+		Updates(map[string]interface{}{"status": model.SimilarityFailed, "error": reason, "finished_at": now})
+	return result.RowsAffected, result.Error
+}
+
+func (r *similarityReportRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.SimilarityReport{}).Error
+}
@@ -4,8 +4,13 @@ import (
 	"GoCodeMentor/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// notGroupRecord 排除小组作业的主提交：主提交按小组批改，组员通过各自的副本查看成绩，
+// 按学生或作业列出提交时只返回副本
+const notGroupRecord = "(group_id IS NULL OR group_submission_id IS NOT NULL)"
+
 // submissionRepository implements the SubmissionRepository interface.
 type submissionRepository struct {
 	db *gorm.DB
@@ -28,7 +33,7 @@ func (r *submissionRepository) GetByID(id string) (*model.Submission, error) {
 
 func (r *submissionRepository) GetByAssignmentAndStudent(assignmentID, studentID string) (*model.Submission, error) {
 	var submission model.Submission
-	result := r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Limit(1).Find(&submission)
+	result := r.db.Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).Where(notGroupRecord).Limit(1).Find(&submission)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -40,13 +45,44 @@ func (r *submissionRepository) GetByAssignmentAndStudent(assignmentID, studentID
 
 func (r *submissionRepository) GetByAssignmentIDs(assignmentIDs []string) ([]model.Submission, error) {
 	var submissions []model.Submission
-	err := r.db.Where("assignment_id IN ?", assignmentIDs).Find(&submissions).Error
+	err := r.db.Where("assignment_id IN ?", assignmentIDs).Where(notGroupRecord).Find(&submissions).Error
 	return submissions, err
 }
 
 func (r *submissionRepository) GetByStudentAndAssignmentIDs(studentID string, assignmentIDs []string) ([]model.Submission, error) {
 	var submissions []model.Submission
-	err := r.db.Where("student_id = ? AND assignment_id IN ?", studentID, assignmentIDs).Find(&submissions).Error
+	err := r.db.Where("student_id = ? AND assignment_id IN ?", studentID, assignmentIDs).Where(notGroupRecord).Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *submissionRepository) GetGroupSubmission(assignmentID, groupID string) (*model.Submission, error) {
+	var submission model.Submission
+	result := r.db.Where("assignment_id = ? AND group_id = ? AND group_submission_id IS NULL", assignmentID, groupID).
+		Order("created_at asc").Limit(1).Find(&submission)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &submission, nil
+}
+
+func (r *submissionRepository) GetGroupCopies(submissionID string) ([]model.Submission, error) {
+	var submissions []model.Submission
+	err := r.db.Where("group_submission_id = ?", submissionID).Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *submissionRepository) GetSubmittedWithoutJob(assignmentID string) ([]model.Submission, error) {
+	var submissions []model.Submission
+	db := r.db.Where("status = ? AND group_submission_id IS NULL", "submitted").
+		Where("NOT EXISTS (SELECT 1 FROM grading_jobs j WHERE j.submission_id = submissions.id AND j.status IN ?)",
+			[]string{model.GradingJobQueued, model.GradingJobRunning, model.GradingJobFailed})
+	if assignmentID != "" {
+		db = db.Where("assignment_id = ?", assignmentID)
+	}
+	err := db.Find(&submissions).Error
 	return submissions, err
 }
 
@@ -54,9 +90,23 @@ func (r *submissionRepository) Update(submission *model.Submission) error {
 	return r.db.Save(submission).Error
 }
 
+func (r *submissionRepository) UpdateLocked(id string, fn func(submission *model.Submission) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var submission model.Submission
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&submission).Error; err != nil {
+			return err
+		}
+		if err := fn(&submission); err != nil {
+			return err
+		}
+		return tx.Save(&submission).Error
+	})
+}
+
 func (r *submissionRepository) CountByAssignmentID(assignmentID string, status string) (int64, error) {
 	var count int64
-	db := r.db.Model(&model.Submission{}).Where("assignment_id = ?", assignmentID)
+	// 小组作业按主提交计数，组员副本不单独计数
+	db := r.db.Model(&model.Submission{}).Where("assignment_id = ? AND group_submission_id IS NULL", assignmentID)
 	if status != "" {
 		db = db.Where("status = ?", status)
 	}
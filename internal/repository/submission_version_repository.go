@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// submissionVersionRepository implements the SubmissionVersionRepository interface.
+type submissionVersionRepository struct {
+	db *gorm.DB
+}
+
+// NewSubmissionVersionRepository creates a new SubmissionVersionRepository.
+func NewSubmissionVersionRepository(db *gorm.DB) SubmissionVersionRepository {
+	return &submissionVersionRepository{db: db}
+}
+
+func (r *submissionVersionRepository) Create(version *model.SubmissionVersion) error {
+	return r.db.Create(version).Error
+}
+
+func (r *submissionVersionRepository) Update(version *model.SubmissionVersion) error {
+	return r.db.Save(version).Error
+}
+
+// gradingColumns 批改一个版本时写入的字段
+var gradingColumns = []string{
+	"total_score", "raw_score", "ai_feedback", "question_scores", "question_feedback",
+	"detailed_score", "run_results", "static_analysis", "ai_question_scores", "ai_raw_score",
+	"teacher_reviewed", "reviewed_by", "reviewed_at", "status", "graded_at",
+}
+
+func (r *submissionVersionRepository) SaveGrading(version *model.SubmissionVersion) error {
+	return r.db.Model(&model.SubmissionVersion{}).Where("id = ?", version.ID).
+		Select(gradingColumns).Updates(version).Error
+}
+
+func (r *submissionVersionRepository) MarkUngraded(assignmentID string) error {
+	return r.db.Model(&model.SubmissionVersion{}).
+		Where("assignment_id = ? AND status = ?", assignmentID, "graded").
+		Update("status", "submitted").Error
+}
+
+func (r *submissionVersionRepository) GetBySubmissionID(submissionID string) ([]model.SubmissionVersion, error) {
+	var versions []model.SubmissionVersion
+	err := r.db.Where("submission_id = ?", submissionID).Order("attempt asc").Find(&versions).Error
+	return versions, err
+}
+
+func (r *submissionVersionRepository) GetByAttempt(submissionID string, attempt int) (*model.SubmissionVersion, error) {
+	var version model.SubmissionVersion
+	err := r.db.Where("submission_id = ? AND attempt = ?", submissionID, attempt).First(&version).Error
+	return &version, err
+}
+
+func (r *submissionVersionRepository) DeleteByAssignmentID(assignmentID string) error {
+	return r.db.Where("assignment_id = ?", assignmentID).Delete(&model.SubmissionVersion{}).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// termRepository implements the TermRepository interface.
+type termRepository struct {
+	db *gorm.DB
+}
+
+// NewTermRepository creates a new TermRepository.
+func NewTermRepository(db *gorm.DB) TermRepository {
+	return &termRepository{db: db}
+}
+
+func (r *termRepository) Create(term *model.Term) error {
+	return r.db.Create(term).Error
+}
+
+func (r *termRepository) GetByID(id string) (*model.Term, error) {
+	var term model.Term
+	err := r.db.Where("id = ?", id).First(&term).Error
+	return &term, err
+}
+
+func (r *termRepository) GetAll() ([]model.Term, error) {
+	var terms []model.Term
+	err := r.db.Order("start_date desc nulls last, created_at desc").Find(&terms).Error
+	return terms, err
+}
+
+func (r *termRepository) Update(term *model.Term) error {
+	return r.db.Save(term).Error
+}
@@ -34,7 +34,10 @@ func (r *userRepository) GetByID(id string) (*model.User, error) {
 
 func (r *userRepository) GetByClassID(classID string) ([]model.User, error) {
 	var users []model.User
-	err := r.db.Where("class_id = ?", classID).Find(&users).Error
+	err := r.db.Select("users.*").
+		Joins("JOIN class_members cm ON cm.student_id = users.id").
+		Where("cm.class_id = ? AND cm.status = ?", classID, model.MemberActive).
+		Find(&users).Error
 	return users, err
 }
 
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"GoCodeMentor/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// userSessionRepository implements the UserSessionRepository interface.
+type userSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewUserSessionRepository creates a new UserSessionRepository.
+func NewUserSessionRepository(db *gorm.DB) UserSessionRepository {
+	return &userSessionRepository{db: db}
+}
+
+func (r *userSessionRepository) Create(session *model.UserSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *userSessionRepository) GetByTokenHash(tokenHash string) (*model.UserSession, error) {
+	var session model.UserSession
+	err := r.db.Where("token_hash = ?", tokenHash).First(&session).Error
+	return &session, err
+}
+
+func (r *userSessionRepository) Update(session *model.UserSession) error {
+	return r.db.Save(session).Error
+}
+
+func (r *userSessionRepository) RevokeByUserID(userID string) error {
+	return r.db.Model(&model.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *userSessionRepository) DeleteExpired(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&model.UserSession{}).Error
+}
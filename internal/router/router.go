@@ -17,6 +17,10 @@ func Setup(
 	pageHandler *handler.PageHandler,
 	excelHandler *handler.ExcelHandler,
 	wisdomGraphHandler *handler.WisdomGraphHandler,
+	questionBankHandler *handler.QuestionBankHandler,
+	similarityHandler *handler.SimilarityHandler,
+	gradebookHandler *handler.GradebookHandler,
+	courseHandler *handler.CourseHandler,
 	authMiddleware gin.HandlerFunc,
 	teacherAuthMiddleware gin.HandlerFunc,
 	adminAuthMiddleware gin.HandlerFunc,
@@ -28,6 +32,7 @@ func Setup(
 	r.GET("/login", pageHandler.LoginPage)
 	r.POST("/api/register", userHandler.Register)
 	r.POST("api/login", userHandler.Login)
+	r.POST("/api/logout", userHandler.Logout)
 
 	// Handle common browser/tool ghost requests to keep logs clean
 	r.GET("/.well-known/appspecific/com.chrome.devtools.json", func(c *gin.Context) { c.Status(204) })
@@ -50,6 +55,8 @@ func Setup(
 	{
 		teacherOnly.GET("/teacher/classes", pageHandler.TeacherClassesPage)
 		teacherOnly.GET("/class/:id/students", pageHandler.ClassStudentsPage)
+		teacherOnly.GET("/class/:id/gradebook", pageHandler.ClassGradebookPage)
+		teacherOnly.GET("/class/:id/appeals", pageHandler.ClassAppealsPage)
 	}
 
 	// Admin-only routes
@@ -66,6 +73,7 @@ func Setup(
 	api.Use(authMiddleware)
 	{
 		api.POST("/chat", sessionHandler.Chat)
+		api.POST("/chat/stream", sessionHandler.ChatStream)
 		api.GET("/history", sessionHandler.GetHistory)
 		api.GET("/sessions", sessionHandler.GetUserSessions)
 
@@ -88,6 +96,52 @@ func Setup(
 		api.GET("/classes/:id/stats", teacherAuthMiddleware, classHandler.GetClassStats)
 		api.GET("/classes/:id/ai-analysis", teacherAuthMiddleware, classHandler.AnalyzeClass)
 
+		// Class invite code and join requests
+		api.PUT("/classes/:id/invite-code", teacherAuthMiddleware, classHandler.UpdateInviteSettings)
+		api.POST("/classes/:id/invite-code/regenerate", teacherAuthMiddleware, classHandler.RegenerateInviteCode)
+		api.POST("/classes/:id/invite-code/revoke", teacherAuthMiddleware, classHandler.RevokeInviteCode)
+		api.GET("/classes/:id/invite-code/qrcode", teacherAuthMiddleware, classHandler.GetJoinQRCode)
+		api.GET("/classes/:id/join-requests", teacherAuthMiddleware, classHandler.GetJoinRequests)
+		api.POST("/classes/:id/join-requests/:requestId/approve", teacherAuthMiddleware, classHandler.ApproveJoinRequest)
+		api.POST("/classes/:id/join-requests/:requestId/reject", teacherAuthMiddleware, classHandler.RejectJoinRequest)
+
+		// Class staff: co-teachers and TAs
+		api.GET("/classes/:id/staff", teacherAuthMiddleware, classHandler.GetClassStaff)
+		api.POST("/classes/:id/staff", teacherAuthMiddleware, classHandler.InviteStaff)
+		api.PUT("/classes/:id/staff/:staffId", teacherAuthMiddleware, classHandler.UpdateStaffRole)
+		api.DELETE("/classes/:id/staff/:staffId", teacherAuthMiddleware, classHandler.RemoveStaff)
+		api.GET("/staff-invitations", teacherAuthMiddleware, classHandler.GetStaffInvitations)
+		api.POST("/staff-invitations/:id/accept", teacherAuthMiddleware, classHandler.AcceptStaffInvitation)
+		api.POST("/staff-invitations/:id/decline", teacherAuthMiddleware, classHandler.DeclineStaffInvitation)
+
+		// Courses, terms and class archiving
+		api.GET("/courses", teacherAuthMiddleware, courseHandler.GetCourses)
+		api.POST("/courses", teacherAuthMiddleware, courseHandler.CreateCourse)
+		api.PUT("/courses/:id", teacherAuthMiddleware, courseHandler.UpdateCourse)
+		api.DELETE("/courses/:id", teacherAuthMiddleware, courseHandler.DeleteCourse)
+		api.GET("/courses/:id/classes", teacherAuthMiddleware, courseHandler.GetCourseClasses)
+		api.GET("/terms", teacherAuthMiddleware, courseHandler.GetTerms)
+		api.POST("/terms", teacherAuthMiddleware, courseHandler.CreateTerm)
+		api.PUT("/classes/:id/course", teacherAuthMiddleware, courseHandler.SetClassCourse)
+		api.POST("/classes/:id/archive", teacherAuthMiddleware, classHandler.ArchiveClass)
+		api.POST("/classes/:id/unarchive", teacherAuthMiddleware, classHandler.UnarchiveClass)
+		api.POST("/classes/:id/clone-assignments", teacherAuthMiddleware, classHandler.CloneAssignments)
+
+		// Class groups
+		api.GET("/classes/:id/groups", teacherAuthMiddleware, classHandler.GetGroups)
+		api.POST("/classes/:id/groups", teacherAuthMiddleware, classHandler.CreateGroup)
+		api.POST("/classes/:id/groups/generate", teacherAuthMiddleware, classHandler.GenerateGroups)
+		api.PUT("/classes/:id/groups/members/:studentId", teacherAuthMiddleware, classHandler.SetStudentGroup)
+		api.PUT("/classes/:id/groups/:groupId", teacherAuthMiddleware, classHandler.RenameGroup)
+		api.DELETE("/classes/:id/groups/:groupId", teacherAuthMiddleware, classHandler.DeleteGroup)
+
+		// Gradebook
+		api.GET("/classes/:id/gradebook", teacherAuthMiddleware, gradebookHandler.GetGradebook)
+		api.PUT("/classes/:id/gradebook/settings", teacherAuthMiddleware, gradebookHandler.UpdateSettings)
+		api.PUT("/classes/:id/gradebook/cells/:assignmentId/:studentId", teacherAuthMiddleware, gradebookHandler.OverrideCell)
+		api.GET("/classes/:id/gradebook/audit", teacherAuthMiddleware, gradebookHandler.GetAuditLog)
+		api.GET("/classes/:id/gradebook/export", teacherAuthMiddleware, excelHandler.ExportClassGradebook)
+
 		// User management
 		api.GET("/users/find", userHandler.FindUser)
 
@@ -97,23 +151,79 @@ func Setup(
 
 		// Student's own data
 		api.GET("/student/assignments", assignmentHandler.GetMyAssignments)
+		api.GET("/student/classes", classHandler.GetMyClasses)
+		api.GET("/student/join-requests", classHandler.GetMyJoinRequests)
 
 		// Assignment management
 		api.POST("/assignments/generate", teacherAuthMiddleware, assignmentHandler.GenerateAssignmentByAI)
+		api.POST("/assignments", teacherAuthMiddleware, assignmentHandler.CreateAssignment)
 		api.GET("/assignments", assignmentHandler.GetAssignments)
 		api.POST("/assignments/:id/publish", teacherAuthMiddleware, assignmentHandler.PublishAssignment)
+		api.DELETE("/assignments/:id/classes/:classId", teacherAuthMiddleware, assignmentHandler.UnpublishAssignment)
 		api.GET("/assignments/:id", assignmentHandler.GetAssignmentDetail)
 		api.GET("/assignments/:id/qrcode", assignmentHandler.GetAssignmentQRCode)
 		api.POST("/assignments/:id/submit", assignmentHandler.SubmitAssignment)
+		api.GET("/assignments/:id/draft", assignmentHandler.GetDraft)
+		api.PUT("/assignments/:id/draft", assignmentHandler.SaveDraft)
 		api.GET("/assignments/:id/student/:studentId", assignmentHandler.GetAssignmentSubmissionForStudent)
 		api.GET("/assignments/:id/published", assignmentHandler.GetPublishedClasses)
+		api.PUT("/assignments/:id", teacherAuthMiddleware, assignmentHandler.UpdateAssignment)
 		api.DELETE("/assignments/:id", teacherAuthMiddleware, assignmentHandler.DeleteAssignment)
+		api.POST("/assignments/:id/questions", teacherAuthMiddleware, assignmentHandler.CreateQuestion)
+		api.PUT("/assignments/:id/questions/order", teacherAuthMiddleware, assignmentHandler.ReorderQuestions)
+		api.POST("/assignments/:id/questions/save-to-bank", teacherAuthMiddleware, questionBankHandler.SaveFromAssignment)
+		api.POST("/assignments/:id/questions/from-bank", teacherAuthMiddleware, questionBankHandler.AddToAssignment)
+		api.PUT("/questions/:id", teacherAuthMiddleware, assignmentHandler.UpdateQuestion)
+		api.DELETE("/questions/:id", teacherAuthMiddleware, assignmentHandler.DeleteQuestion)
+		api.PUT("/assignments/:id/attempt-policy", teacherAuthMiddleware, assignmentHandler.UpdateAttemptPolicy)
+		api.PUT("/assignments/:id/classes/:classId/late-policy", teacherAuthMiddleware, assignmentHandler.UpdateLatePolicy)
+		api.GET("/assignments/:id/extensions", teacherAuthMiddleware, assignmentHandler.GetExtensions)
+		api.PUT("/assignments/:id/extensions/:studentId", teacherAuthMiddleware, assignmentHandler.GrantExtension)
+		api.DELETE("/assignments/:id/extensions/:studentId", teacherAuthMiddleware, assignmentHandler.RevokeExtension)
+		api.PUT("/assignments/:id/exam-settings", teacherAuthMiddleware, assignmentHandler.UpdateExamSettings)
+		api.GET("/assignments/:id/exam/sessions", teacherAuthMiddleware, assignmentHandler.GetExamSessions)
+		api.POST("/assignments/:id/exam/start", assignmentHandler.StartExam)
+		api.GET("/assignments/:id/exam", assignmentHandler.GetExam)
+		api.PUT("/assignments/:id/exam/answers", assignmentHandler.SaveExamAnswers)
+		api.POST("/assignments/:id/exam/submit", assignmentHandler.SubmitExam)
+		api.GET("/assignments/:id/grading-jobs", teacherAuthMiddleware, assignmentHandler.GetGradingJobs)
+		api.POST("/assignments/:id/grading-jobs/requeue", teacherAuthMiddleware, assignmentHandler.RequeueGradingJobs)
+		api.PUT("/questions/:id/tests", teacherAuthMiddleware, assignmentHandler.UpdateQuestionTests)
+		api.PUT("/questions/:id/grading-rule", teacherAuthMiddleware, assignmentHandler.UpdateQuestionGradingRule)
+		api.PUT("/questions/:id/rubric", teacherAuthMiddleware, assignmentHandler.UpdateQuestionRubric)
+
+		// Similarity (plagiarism) detection
+		api.POST("/assignments/:id/similarity", teacherAuthMiddleware, similarityHandler.StartCheck)
+		api.GET("/assignments/:id/similarity", teacherAuthMiddleware, similarityHandler.GetReport)
+		api.GET("/assignments/:id/similarity/pair", teacherAuthMiddleware, similarityHandler.GetPairDetail)
+
+		// Question bank
+		api.GET("/question-bank", teacherAuthMiddleware, questionBankHandler.SearchItems)
+		api.POST("/question-bank", teacherAuthMiddleware, questionBankHandler.CreateItem)
+		api.GET("/question-bank/:id", teacherAuthMiddleware, questionBankHandler.GetItem)
+		api.PUT("/question-bank/:id", teacherAuthMiddleware, questionBankHandler.UpdateItem)
+		api.DELETE("/question-bank/:id", teacherAuthMiddleware, questionBankHandler.DeleteItem)
 
 		// Teacher submission management
 		api.PUT("/submissions/:id/score", teacherAuthMiddleware, assignmentHandler.UpdateSubmissionScore)
 		api.PUT("/submissions/:id/feedback", teacherAuthMiddleware, assignmentHandler.UpdateTeacherFeedback)
+		api.GET("/submissions/:id/question/:questionId", assignmentHandler.GetQuestionGrade)
+		api.PUT("/submissions/:id/question/:questionId/score", teacherAuthMiddleware, assignmentHandler.UpdateQuestionScore)
+		api.PUT("/submissions/:id/question/:questionId/feedback", teacherAuthMiddleware, assignmentHandler.UpdateQuestionFeedback)
 		api.POST("/submissions/:id/regrade", teacherAuthMiddleware, assignmentHandler.RegradeSubmission)
+		api.PUT("/submissions/:id/adjustment", teacherAuthMiddleware, assignmentHandler.SetScoreAdjustment)
+		api.GET("/submissions/:id/group-members", teacherAuthMiddleware, assignmentHandler.GetGroupMembersSubmissions)
 		api.GET("/submissions/:id/download", teacherAuthMiddleware, assignmentHandler.DownloadSubmissionCode)
+		api.GET("/submissions/:id/versions", assignmentHandler.GetSubmissionVersions)
+		api.GET("/submissions/:id/diff", assignmentHandler.DiffSubmissionVersions)
+		api.POST("/submissions/:id/appeals", assignmentHandler.CreateAppeal)
+		api.GET("/submissions/:id/appeals", assignmentHandler.GetSubmissionAppeals)
+
+		// Grade appeals
+		api.GET("/classes/:id/appeals", teacherAuthMiddleware, assignmentHandler.GetClassAppeals)
+		api.POST("/appeals/:id/accept", teacherAuthMiddleware, assignmentHandler.AcceptAppeal)
+		api.POST("/appeals/:id/reject", teacherAuthMiddleware, assignmentHandler.RejectAppeal)
+		api.POST("/appeals/:id/reevaluate", teacherAuthMiddleware, assignmentHandler.ReevaluateAppeal)
 
 		// Feedback
 		api.POST("/feedback", feedbackHandler.CreateFeedback)
@@ -138,6 +248,7 @@ func Setup(
 		api.GET("/templates/classes", teacherAuthMiddleware, excelHandler.DownloadClassTemplate)
 		api.POST("/classes/:id/students/import", teacherAuthMiddleware, excelHandler.ImportStudentsToClass)
 		api.POST("/classes/import", teacherAuthMiddleware, excelHandler.ImportClassesAndStudents)
+		api.GET("/assignments/:id/export", teacherAuthMiddleware, excelHandler.ExportAssignmentScores)
 	}
 
 	// Standalone page routes
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// CloneClassAssignments 把源班级的作业复制一份到目标班级，通常用于新学期开设同一课程的班级。
+// 复制出的作业归操作者所有；能确定时间偏移时按偏移后的发放、截止和关闭时间发布到目标班级，
+// 否则（或偏移后截止时间已过）保留为草稿由教师重新发布
+func (s *AssignmentService) CloneClassAssignments(userID, sourceClassID string, req dto.CloneAssignmentsRequest) ([]model.Assignment, error) {
+	source, err := s.authz.AuthorizeClass(userID, sourceClassID, CapViewClass)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.authz.AuthorizeClass(userID, req.TargetClassID, CapPublish)
+	if err != nil {
+		return nil, err
+	}
+	if source.ID == target.ID {
+		return nil, errors.New("目标班级不能与源班级相同")
+	}
+	shift, err := s.cloneShift(source, target, req.ShiftDays)
+	if err != nil {
+		return nil, err
+	}
+
+	assigns, schedules, err := s.classAssignments(sourceClassID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.AssignmentIDs) > 0 {
+		inClass := make(map[string]model.Assignment, len(assigns))
+		for _, a := range assigns {
+			inClass[a.ID] = a
+		}
+		selected := make([]model.Assignment, 0, len(req.AssignmentIDs))
+		for _, id := range req.AssignmentIDs {
+			a, ok := inClass[id]
+			if !ok {
+				return nil, errors.New("作业不存在或未发布到源班级")
+			}
+			selected = append(selected, a)
+		}
+		assigns = selected
+	}
+	if len(assigns) == 0 {
+		return nil, errors.New("源班级没有可复制的作业")
+	}
+
+	now := time.Now()
+	clones := make([]model.Assignment, 0, len(assigns))
+	for i := range assigns {
+		clone, err := s.cloneAssignment(&assigns[i], userID, now)
+		if err != nil {
+			return nil, err
+		}
+
+		if shift != nil {
+			ac := schedules[assigns[i].ID]
+			if ac == nil {
+				// 直接记录了班级的旧作业没有发布记录，截止时间在作业上
+				ac = &model.AssignmentClass{Deadline: assigns[i].Deadline}
+			}
+			published, err := s.publishClone(clone.ID, target.ID, ac, *shift, now)
+			if err != nil {
+				return nil, err
+			}
+			if published {
+				if err := s.syncAssignmentStatus(clone.ID); err != nil {
+					return nil, err
+				}
+				if updated, err := s.assignRepo.GetByID(clone.ID); err == nil {
+					clone = updated
+				}
+			}
+		}
+		clones = append(clones, *clone)
+	}
+	return clones, nil
+}
+
+// classAssignments 获取发布到班级的全部作业（含尚未发放的定时作业）及其在该班级的发布记录
+func (s *AssignmentService) classAssignments(classID string) ([]model.Assignment, map[string]*model.AssignmentClass, error) {
+	assigns, err := s.assignRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, nil, err
+	}
+	acs, err := s.assignmentClassRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool, len(assigns))
+	for _, a := range assigns {
+		seen[a.ID] = true
+	}
+	schedules := make(map[string]*model.AssignmentClass, len(acs))
+	for i := range acs {
+		ac := &acs[i]
+		schedules[ac.AssignmentID] = ac
+		if seen[ac.AssignmentID] {
+			continue
+		}
+		assign, err := s.assignRepo.GetByID(ac.AssignmentID)
+		if err != nil {
+			continue
+		}
+		seen[assign.ID] = true
+		assigns = append(assigns, *assign)
+	}
+	return assigns, schedules, nil
+}
+
+// cloneShift 计算复制作业时的时间偏移：优先使用指定的天数，否则取两个班级所在学期开始日期之差；
+// 无法确定时返回 nil，复制出的作业不发布
+func (s *AssignmentService) cloneShift(source, target *model.Class, shiftDays *int) (*time.Duration, error) {
+	if shiftDays != nil {
+		shift := time.Duration(*shiftDays) * 24 * time.Hour
+		return &shift, nil
+	}
+	if source.TermID == nil || target.TermID == nil {
+		return nil, nil
+	}
+	sourceTerm, err := s.termRepo.GetByID(*source.TermID)
+	if err != nil {
+		return nil, errors.New("源班级的学期不存在")
+	}
+	targetTerm, err := s.termRepo.GetByID(*target.TermID)
+	if err != nil {
+		return nil, errors.New("目标班级的学期不存在")
+	}
+	if sourceTerm.StartDate == nil || targetTerm.StartDate == nil {
+		return nil, nil
+	}
+	shift := targetTerm.StartDate.Sub(*sourceTerm.StartDate)
+	return &shift, nil
+}
+
+// cloneAssignment 复制作业及其题目，新作业为草稿
+func (s *AssignmentService) cloneAssignment(src *model.Assignment, teacherID string, now time.Time) (*model.Assignment, error) {
+	clone := *src
+	clone.ID = uuid.New().String()
+	clone.TeacherID = teacherID
+	clone.Status = "draft"
+	clone.ClassID = nil
+	clone.Deadline = nil
+	clone.CreatedAt = now
+	clone.UpdatedAt = now
+	questions, err := s.questionRepo.GetByAssignmentID(src.ID)
+	if err != nil {
+		return nil, err
+	}
+	// 评分标准按题目 ID 索引，复制出的题目换了新 ID，评分标准也要改用新 ID
+	ids := make(map[string]string, len(questions))
+	for _, q := range questions {
+		ids[q.ID] = uuid.New().String()
+	}
+	rubric, err := parseRubric(src.Rubric)
+	if err != nil {
+		return nil, err
+	}
+	if len(rubric.Questions) > 0 {
+		remapped := make(map[string]QuestionRubric, len(rubric.Questions))
+		for id, qr := range rubric.Questions {
+			if newID, ok := ids[id]; ok {
+				remapped[newID] = qr
+			}
+		}
+		rubric.Questions = remapped
+		rubricJSON, err := json.Marshal(rubric)
+		if err != nil {
+			return nil, err
+		}
+		clone.Rubric = string(rubricJSON)
+	}
+	if err := s.assignRepo.Create(&clone); err != nil {
+		return nil, err
+	}
+
+	for _, q := range questions {
+		q.ID = ids[q.ID]
+		q.AssignmentID = clone.ID
+		if err := s.questionRepo.Create(&q); err != nil {
+			return nil, err
+		}
+	}
+	return &clone, nil
+}
+
+// publishClone 按偏移后的时间把复制出的作业发布到目标班级，迟交规则沿用源班级；
+// 源班级没有截止时间或偏移后截止时间已过时不发布
+func (s *AssignmentService) publishClone(assignID, classID string, src *model.AssignmentClass, shift time.Duration, now time.Time) (bool, error) {
+	deadline := shiftTime(src.Deadline, shift)
+	if deadline == nil || deadline.Before(now) {
+		return false, nil
+	}
+	ac := &model.AssignmentClass{
+		ID:                uuid.New().String(),
+		AssignmentID:      assignID,
+		ClassID:           classID,
+		Deadline:          deadline,
+		ReleasedAt:        shiftTime(src.ReleasedAt, shift),
+		ClosesAt:          shiftTime(src.ClosesAt, shift),
+		AllowLate:         src.AllowLate,
+		GraceMinutes:      src.GraceMinutes,
+		LatePenaltyPerDay: src.LatePenaltyPerDay,
+		HardCutoff:        shiftTime(src.HardCutoff, shift),
+		CreatedAt:         now,
+	}
+	if ac.ReleasedAt == nil {
+		ac.ReleasedAt = &now
+	}
+	ac.Status = scheduleStatus(ac, now)
+	if err := s.assignmentClassRepo.Create(ac); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func shiftTime(t *time.Time, shift time.Duration) *time.Time {
+	if t == nil {
+		return nil
+	}
+	shifted := t.Add(shift)
+	return &shifted
+}
@@ -1,51 +1,67 @@
 package service
 
 import (
+	"GoCodeMentor/internal/dto"
 	"GoCodeMentor/internal/model"
-	"GoCodeMentor/internal/pkg/siliconflow"
+	"GoCodeMentor/internal/pkg/codecheck"
+	"GoCodeMentor/internal/pkg/llm"
+	"GoCodeMentor/internal/pkg/runner"
+	"GoCodeMentor/internal/pkg/textdiff"
 	"GoCodeMentor/internal/repository"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
-	"strconv"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// AssignmentService 作业服务
+// AssignmentService 作业服务：作业和题目管理、发布、提交、批改与复核；
+// 考试、迟交规则和小组作业由各自的服务负责，与本服务共用 SubmissionStore
 type AssignmentService struct {
-	assignRepo          repository.AssignmentRepository
-	assignmentClassRepo repository.AssignmentClassRepository
-	questionRepo        repository.QuestionRepository
-	submissionRepo      repository.SubmissionRepository
-	userRepo            repository.UserRepository
-	classRepo           repository.ClassRepository
-	siliconFlow         *siliconflow.Client
+	*SubmissionStore
+	examSessionRepo repository.ExamSessionRepository
+	generator       llm.LLMProvider // 生成作业使用的模型
+	grader          llm.LLMProvider // 批改作业使用的模型
+	codeRunner      *runner.Runner  // 编程题自动测试，为 nil 时跳过
+	gradingJobRepo  repository.GradingJobRepository
+	similarityRepo  repository.SimilarityReportRepository
+	appealRepo      repository.GradeAppealRepository
+	termRepo        repository.TermRepository
+	authz           IAuthorizationService
 }
 
 // NewAssignmentService 创建作业服务
 func NewAssignmentService(
-	assignRepo repository.AssignmentRepository,
-	assignmentClassRepo repository.AssignmentClassRepository,
-	questionRepo repository.QuestionRepository,
-	submissionRepo repository.SubmissionRepository,
-	userRepo repository.UserRepository,
-	classRepo repository.ClassRepository,
-	siliconFlow *siliconflow.Client,
+	store *SubmissionStore,
+	examSessionRepo repository.ExamSessionRepository,
+	generator llm.LLMProvider,
+	grader llm.LLMProvider,
+	codeRunner *runner.Runner,
+	gradingJobRepo repository.GradingJobRepository,
+	similarityRepo repository.SimilarityReportRepository,
+	appealRepo repository.GradeAppealRepository,
+	termRepo repository.TermRepository,
+	authz IAuthorizationService,
 ) IAssignmentService {
 	return &AssignmentService{
-		assignRepo:          assignRepo,
-		assignmentClassRepo: assignmentClassRepo,
-		questionRepo:        questionRepo,
-		submissionRepo:      submissionRepo,
-		userRepo:            userRepo,
-		classRepo:           classRepo,
-		siliconFlow:         siliconFlow,
+		SubmissionStore: store,
+		examSessionRepo: examSessionRepo,
+		generator:       generator,
+		grader:          grader,
+		codeRunner:      codeRunner,
+		gradingJobRepo:  gradingJobRepo,
+		similarityRepo:  similarityRepo,
+		appealRepo:      appealRepo,
+		termRepo:        termRepo,
+		authz:           authz,
 	}
 }
 
@@ -101,11 +117,7 @@ func (s *AssignmentService) GenerateAssignmentByAI(ctx context.Context, topic st
 		userPrompt = fmt.Sprintf(userPromptTmpl, topic, difficulty)
 	}
 
-	messages := []siliconflow.Message{
-		{Role: "system", Content: systemPrompt},
-	}
-
-	response, err := s.siliconFlow.ChatCompletion(ctx, userPrompt, messages)
+	response, err := s.generator.Chat(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI接口失败: %w", err)
 	}
@@ -187,6 +199,8 @@ func (s *AssignmentService) GenerateAssignmentByAI(ctx context.Context, topic st
 			Content:      q.Content,
 			Answer:       answerContent,
 			Options:      optionsJSON,
+			TestCases:    "[]",
+			GradingRule:  "{}",
 			Score:        q.Score,
 			OrderNum:     i + 1,
 		}
@@ -202,9 +216,35 @@ func (s *AssignmentService) GetSubmissionsByAssignmentIDs(assignmentIDs []string
 	return s.submissionRepo.GetByAssignmentIDs(assignmentIDs)
 }
 
-// GetAssignmentList 获取教师创建的作业列表
+// GetAssignmentList 获取教师创建的作业，以及作为合作教师、助教所在班级中已发布的其他教师的作业
 func (s *AssignmentService) GetAssignmentList(teacherID string) ([]model.Assignment, error) {
-	return s.assignRepo.GetByTeacherID(teacherID)
+	assignments, err := s.assignRepo.GetByTeacherID(teacherID)
+	if err != nil {
+		return nil, err
+	}
+	classes, err := s.authz.StaffClasses(teacherID)
+	if err != nil {
+		return nil, err
+	}
+	var classIDs []string
+	for _, class := range classes {
+		if class.TeacherID != teacherID {
+			classIDs = append(classIDs, class.ID)
+		}
+	}
+	if len(classIDs) == 0 {
+		return assignments, nil
+	}
+	shared, err := s.assignRepo.GetByClassIDs(classIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, assign := range shared {
+		if assign.TeacherID != teacherID {
+			assignments = append(assignments, assign)
+		}
+	}
+	return assignments, nil
 }
 
 // GetAssignmentsByClass 获取班级的作业列表
@@ -212,8 +252,18 @@ func (s *AssignmentService) GetAssignmentsByClass(classID string) ([]model.Assig
 	return s.assignRepo.GetByClassID(classID)
 }
 
-// PublishAssignment 发布作业到班级
-func (s *AssignmentService) PublishAssignment(assignID string, classID string, deadline *time.Time) error {
+// GetAssignmentsForStudent 获取学生所在全部班级的作业列表
+func (s *AssignmentService) GetAssignmentsForStudent(studentID string) ([]model.Assignment, error) {
+	classIDs, err := studentClassIDs(s.memberRepo, studentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.assignRepo.GetByClassIDs(classIDs)
+}
+
+// PublishAssignment 发布作业到班级；releaseAt 为空时立即发放，晚于当前时间时到时由定时任务发放，
+// closesAt 为空时不关闭。已发布到该班级时更新截止、发放和关闭时间
+func (s *AssignmentService) PublishAssignment(assignID string, classID string, deadline, releaseAt, closesAt *time.Time, groupWork bool) error {
 	// 检查作业是否存在
 	assign, err := s.assignRepo.GetByID(assignID)
 	if err != nil {
@@ -226,37 +276,78 @@ func (s *AssignmentService) PublishAssignment(assignID string, classID string, d
 		return fmt.Errorf("班级不存在: %w", err)
 	}
 
+	now := time.Now()
+	if releaseAt == nil {
+		releaseAt = &now
+	}
+	if closesAt != nil {
+		if !closesAt.After(*releaseAt) {
+			return errors.New("关闭时间必须晚于发放时间")
+		}
+		if deadline != nil && closesAt.Before(*deadline) {
+			return errors.New("关闭时间不能早于截止时间")
+		}
+	}
+
+	if groupWork {
+		if assign.ExamMode {
+			return errors.New("考试不能作为小组作业发布")
+		}
+		groups, err := s.groupRepo.GetByClassID(classID)
+		if err != nil {
+			return err
+		}
+		if len(groups) == 0 {
+			return errors.New("班级还没有分组，请先在班级中创建小组")
+		}
+	}
+
 	// 检查是否已发布到该班级
 	existing, err := s.assignmentClassRepo.GetByAssignmentAndClass(assignID, classID)
-	// 如果记录已存在，则只更新截止日期
+	// 如果记录已存在，则更新截止日期和发放计划
 	if err == nil && existing != nil {
+		if existing.GroupWork != groupWork {
+			// 已有提交时个人提交和小组提交无法互相转换
+			count, err := s.submissionRepo.CountByAssignmentID(assignID, "")
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return errors.New("作业已有学生提交，不能再修改是否为小组作业")
+			}
+		}
 		existing.Deadline = deadline
-		return s.assignmentClassRepo.Update(existing)
+		existing.ReleasedAt = releaseAt
+		existing.ClosesAt = closesAt
+		existing.GroupWork = groupWork
+		existing.Status = scheduleStatus(existing, now)
+		if err := s.assignmentClassRepo.Update(existing); err != nil {
+			return err
+		}
+		return s.syncAssignmentStatus(assignID)
 	}
 
 	// 如果记录不存在，则创建新的发布记录
-	now := time.Now()
 	newAssignmentClass := &model.AssignmentClass{
 		ID:           uuid.New().String(),
 		AssignmentID: assignID,
 		ClassID:      classID,
 		Deadline:     deadline,
-		ReleasedAt:   &now,
+		ReleasedAt:   releaseAt,
+		ClosesAt:     closesAt,
+		GroupWork:    groupWork,
 		CreatedAt:    now,
 	}
+	newAssignmentClass.Status = scheduleStatus(newAssignmentClass, now)
 
 	if err := s.assignmentClassRepo.Create(newAssignmentClass); err != nil {
 		return fmt.Errorf("创建发布记录失败: %w", err)
 	}
 
-	// 如果作业当前是草稿状态，则更新为已发布
-	if assign.Status == "draft" {
-		assign.Status = "published"
-		assign.UpdatedAt = time.Now()
-		if err := s.assignRepo.Update(assign); err != nil {
-			// 如果这里失败，可以选择回滚上面的创建操作，但为简化，我们先只记录错误
-			log.Printf("警告: 更新作业 '%s' 状态为 'published' 失败: %v", assignID, err)
-		}
+	// 作业状态跟随各班级的发放状态：有班级已发放时为已发布
+	if err := s.syncAssignmentStatus(assignID); err != nil {
+		// 如果这里失败，可以选择回滚上面的创建操作，但为简化，我们先只记录错误
+		log.Printf("警告: 更新作业 '%s' 状态失败: %v", assignID, err)
 	}
 
 	return nil
@@ -279,85 +370,62 @@ func (s *AssignmentService) GetAssignmentDetail(id string) (*model.Assignment, [
 
 // SubmitAssignment 学生提交作业
 func (s *AssignmentService) SubmitAssignment(assignID string, studentID string, studentName string, answers map[string]string, code string) (string, error) {
-	// 获取学生信息
-	student, err := s.userRepo.GetByID(studentID)
+	assign, _, policy, err := s.studentAssignment(assignID, studentID)
 	if err != nil {
-		return "", fmt.Errorf("获取学生信息失败: %w", err)
+		return "", err
 	}
-
-	// 检查学生是否在班级中
-	if student.ClassID == nil {
-		return "", fmt.Errorf("学生未加入任何班级，无法提交作业")
+	if assign.ExamMode {
+		return "", errors.New("该作业为考试，请开始考试后在考试页面交卷")
 	}
 
-	// 获取作业信息
-	assign, err := s.assignRepo.GetByID(assignID)
+	now := time.Now()
+	if err := policy.check(now); err != nil {
+		return "", err
+	}
+	groupID, err := s.studentGroupID(assignID, studentID)
 	if err != nil {
-		return "", fmt.Errorf("获取作业失败: %w", err)
+		return "", err
 	}
+	return s.submit(assign, studentID, studentName, answers, code, now, policy.evaluate(now), groupID)
+}
 
-	// 检查作业是否已发布到学生班级
-	assignmentClass, err := s.assignmentClassRepo.GetByAssignmentAndClass(assignID, *student.ClassID)
-	if err != nil || assignmentClass == nil {
-		return "", fmt.Errorf("作业未发布到该班级，无法提交")
+// SaveDraft 自动保存学生的答案草稿；草稿只用于恢复作答，不会触发批改
+func (s *AssignmentService) SaveDraft(assignID, studentID string, answers map[string]string, code string) (*model.AnswerDraft, error) {
+	assign, _, policy, err := s.studentAssignment(assignID, studentID)
+	if err != nil {
+		return nil, err
 	}
-
-	// 检查作业状态（兼容性检查）
-	if assign.Status != "published" {
-		return "", fmt.Errorf("作业未发布，无法提交")
+	if assign.ExamMode {
+		return nil, errors.New("考试的答案请通过考试页面保存")
 	}
-
-	// 检查截止时间（使用班级特定的截止时间）
-	if assignmentClass.Deadline != nil {
-		now := time.Now()
-		if now.After(*assignmentClass.Deadline) {
-			return "", fmt.Errorf("作业提交已截止，截止时间为: %s", assignmentClass.Deadline.Format("2006-01-02 15:04:05"))
-		}
+	now := time.Now()
+	if err := policy.check(now); err != nil {
+		return nil, err
 	}
 
-	// 检查是否已经提交过
-	existing, err := s.submissionRepo.GetByAssignmentAndStudent(assignID, studentID)
-	if err == nil && existing != nil {
-		// 更新现有提交
-		existing.Answers = answersToString(answers)
-		existing.CodeContent = code
-		existing.UpdatedAt = time.Now()
-		if err := s.submissionRepo.Update(existing); err != nil {
-			return "", err
-		}
-		return existing.ID, nil
-	}
-
-	// 创建新的提交
-	submission := &model.Submission{
-		ID:               uuid.New().String(),
-		AssignmentID:     assignID,
-		StudentID:        studentID,
-		StudentName:      studentName,
-		Answers:          answersToString(answers),
-		CodeContent:      code,
-		QuestionFeedback: "{}",
-		QuestionScores:   "{}",
-		DetailedScore:    "{}",
-		Status:           "submitted",
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
-	}
-
-	if err := s.submissionRepo.Create(submission); err != nil {
-		return "", err
+	draft := &model.AnswerDraft{
+		ID:           uuid.New().String(),
+		AssignmentID: assignID,
+		StudentID:    studentID,
+		Answers:      answersToString(answers),
+		CodeContent:  code,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.draftRepo.Save(draft); err != nil {
+		return nil, err
 	}
+	return draft, nil
+}
 
-	// 异步进行AI批改
-	go func() {
-		// 给数据库一点时间完成事务（如果存在）
-		time.Sleep(200 * time.Millisecond)
-		if err := s.GradeSubmission(context.Background(), submission.ID); err != nil {
-			log.Printf("AI批改失败: %v", err)
-		}
-	}()
+// GetDraft 获取学生的答案草稿，没有草稿时返回 nil
+func (s *AssignmentService) GetDraft(assignID, studentID string) (*model.AnswerDraft, error) {
+	return s.draftRepo.GetByAssignmentAndStudent(assignID, studentID)
+}
 
-	return submission.ID, nil
+// GetDraftsByStudentAndAssignments 批量获取学生在多个作业上的答案草稿
+func (s *AssignmentService) GetDraftsByStudentAndAssignments(studentID string, assignmentIDs []string) ([]model.AnswerDraft, error) {
+	return s.draftRepo.GetByStudentAndAssignmentIDs(studentID, assignmentIDs)
 }
 
 // GetSubmissionByAssignmentAndStudent 获取学生的作业提交
@@ -390,7 +458,8 @@ func (s *AssignmentService) GetPendingSubmissionCountByAssignment(assignmentID s
 	return s.submissionRepo.CountByAssignmentID(assignmentID, "submitted")
 }
 
-// GradeSubmission AI批改作业
+// GradeSubmission 批改提交中所有尚未批改的版本（没有时重新批改最新版本），
+// 结果写入各版本后按作业计分方式更新提交记录
 func (s *AssignmentService) GradeSubmission(ctx context.Context, submissionID string) error {
 	submission, err := s.submissionRepo.GetByID(submissionID)
 	if err != nil {
@@ -402,95 +471,351 @@ func (s *AssignmentService) GradeSubmission(ctx context.Context, submissionID st
 		return err
 	}
 
-	// 构建批改提示
+	versions, err := s.ensureVersions(submission)
+	if err != nil {
+		return err
+	}
+	// 批改任务会合并同一提交的多次入队，因此一次要批改所有待批改的版本
+	var pending []*model.SubmissionVersion
+	for i := range versions {
+		if versions[i].Status != "graded" {
+			pending = append(pending, &versions[i])
+		}
+	}
+	if len(pending) == 0 {
+		pending = append(pending, &versions[len(versions)-1])
+	}
+
+	var gradeErr error
+	graded := 0
+	for _, v := range pending {
+		if gradeErr = s.gradeVersion(ctx, assign, questions, v); gradeErr != nil {
+			break
+		}
+		graded++
+	}
+	// 部分版本批改失败时仍保存已批改的结果，剩下的版本由批改队列重试。
+	// 批改期间学生可能又提交了新版本，因此锁定提交记录并重新读取全部版本后再选定计入成绩的版本
+	if graded > 0 {
+		counted := false
+		err := s.submissionRepo.UpdateLocked(submission.ID, func(current *model.Submission) error {
+			versions, err := s.versionRepo.GetBySubmissionID(current.ID)
+			if err != nil {
+				return err
+			}
+			counted = countVersion(assign, current, versions)
+			submission = current
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if counted {
+			s.syncGroup(submission)
+		}
+		s.resolveReevaluatedAppeals(submission)
+	}
+	return gradeErr
+}
+
+// gradeVersion 批改一个提交版本并写入批改结果
+func (s *AssignmentService) gradeVersion(ctx context.Context, assign *model.Assignment, questions []model.Question, version *model.SubmissionVersion) error {
+	graded := &model.Submission{
+		ID:           version.SubmissionID,
+		AssignmentID: version.AssignmentID,
+		Answers:      version.Answers,
+		CodeContent:  version.CodeContent,
+	}
+	if err := s.gradeContent(ctx, assign, questions, graded); err != nil {
+		return err
+	}
+
+	if err := keepTeacherOverrides(version, graded); err != nil {
+		return err
+	}
+
+	// 迟交扣分在批改之后进行，同时保留原始得分
+	now := time.Now()
+	version.RawScore = graded.TotalScore
+	version.TotalScore = graded.TotalScore
+	if graded.TotalScore != nil && version.LatePenalty > 0 {
+		adjusted := applyLatePenalty(*graded.TotalScore, version.LatePenalty)
+		version.TotalScore = &adjusted
+	}
+	version.AIFeedback = graded.AIFeedback
+	version.QuestionScores = defaultJSON(graded.QuestionScores, "{}")
+	version.QuestionFeedback = defaultJSON(graded.QuestionFeedback, "{}")
+	version.DetailedScore = defaultJSON(graded.DetailedScore, "{}")
+	version.RunResults = defaultJSON(graded.RunResults, "{}")
+	version.StaticAnalysis = defaultJSON(graded.StaticAnalysis, "{}")
+	version.Status = "graded"
+	version.GradedAt = &now
+	return s.versionRepo.SaveGrading(version)
+}
+
+// keepTeacherOverrides 重新批改时保留版本上教师的改分，复核标记不变：教师改过分的题目沿用教师的分数，
+// 新的 AI 得分记为这些题目的 AI 原始得分；教师改过总分时沿用教师的总分，新算出的总分记为 AI 原始总分。
+// 得分写回 graded，AI 原始得分写入 version
+func keepTeacherOverrides(version *model.SubmissionVersion, graded *model.Submission) error {
+	aiScores := decodeScores(version.AIQuestionScores)
+	teacherScores := decodeScores(version.QuestionScores)
+	scores := decodeScores(graded.QuestionScores)
+	for id := range aiScores {
+		teacher, ok := teacherScores[id]
+		ai, scored := scores[id]
+		if !ok || !scored {
+			delete(aiScores, id) // 题目已删除或不再单独计分
+			continue
+		}
+		aiScores[id] = ai
+		scores[id] = teacher
+	}
+
+	// 教师改总分时记录的 AI 原始总分同样包含教师的逐题改分
+	computed := graded.TotalScore
+	if len(aiScores) > 0 {
+		scoresJSON, err := json.Marshal(scores)
+		if err != nil {
+			return fmt.Errorf("序列化分数失败: %w", err)
+		}
+		raw := 0
+		for _, score := range scores {
+			raw += score
+		}
+		graded.QuestionScores = string(scoresJSON)
+		graded.TotalScore = &raw
+		computed = &raw
+	}
+	aiScoresJSON, err := json.Marshal(aiScores)
+	if err != nil {
+		return fmt.Errorf("序列化分数失败: %w", err)
+	}
+	version.AIQuestionScores = string(aiScoresJSON)
+
+	if version.AIRawScore != nil && version.RawScore != nil {
+		teacherRaw := *version.RawScore
+		graded.TotalScore = &teacherRaw
+		version.AIRawScore = computed
+	} else {
+		version.AIRawScore = nil
+	}
+	return nil
+}
+
+// gradeContent 批改一份作答：选择题、填空题由程序直接判分，编程题先运行测试，
+// 只有主观题和编程题交给 AI，最后把每题得分和总分写入 submission（不落库）
+func (s *AssignmentService) gradeContent(ctx context.Context, assign *model.Assignment, questions []model.Question, submission *model.Submission) error {
+	var answers map[string]string
+	if err := json.Unmarshal([]byte(submission.Answers), &answers); err != nil || answers == nil {
+		answers = make(map[string]string)
+	}
+
+	// 1. 客观题直接判分
+	objective := gradeObjective(questions, answers)
+
+	// 2. 在沙箱中运行编程题的测试，测试结果作为事实提供给 AI，并决定编程题得分；
+	// 静态检查结果同样作为事实提供给 AI，并展示给学生
+	runResults := s.runCodeTests(ctx, questions, answers, submission.CodeContent)
+	staticAnalysis := analyzeCode(questions, answers, submission.CodeContent)
+	if analysisJSON, err := json.Marshal(staticAnalysis); err == nil {
+		submission.StaticAnalysis = string(analysisJSON)
+	}
+
+	// 3. 其余题目交给 AI，设置了评分标准的题目要求逐项打分
+	rubric, err := parseRubric(assign.Rubric)
+	if err != nil {
+		log.Printf("解析作业 %s 的评分标准失败，按无评分标准批改: %v", assign.ID, err)
+	}
+	var aiQuestions []model.Question
+	for _, q := range questions {
+		if _, ok := objective[q.ID]; !ok {
+			aiQuestions = append(aiQuestions, q)
+		}
+	}
+	hasRubric := rubric.covers(aiQuestions)
+
+	questionScores := make(map[string]int)
+	questionFeedback := make(map[string]string)
+	breakdowns := make(map[string]QuestionBreakdown)
+	aiFeedback := ""
+	legacyScore := 0 // 没有题目的旧作业只有一个整体代码框，直接采用 AI 给出的总分
+
+	if len(aiQuestions) > 0 || (len(questions) == 0 && submission.CodeContent != "") {
+		prompt := s.buildGradingPrompt(assign, aiQuestions, answers, submission.CodeContent, runResults, staticAnalysis, rubric)
+
+		// 记录生成的 Prompt 以便调试
+		log.Printf("--- AI Grading Prompt ---\n%s\n-----------------------", prompt)
+
+		response, err := s.grader.Chat(ctx, "", prompt)
+		if err != nil {
+			return err
+		}
+
+		// 解析 AI 返回的 JSON
+		var gradeResult struct {
+			TotalScore       int                                     `json:"total_score"`
+			AIFeedback       string                                  `json:"ai_feedback"`
+			QuestionScores   map[string]int                          `json:"question_scores"`
+			QuestionFeedback map[string]string                       `json:"question_feedback"`
+			CriterionScores  map[string]map[string]rubricScoreResult `json:"criterion_scores"`
+		}
+
+		cleanJSON := s.cleanAIJSON(response)
+		if err := json.Unmarshal([]byte(cleanJSON), &gradeResult); err != nil {
+			if hasRubric {
+				// 评分标准要求逐项得分，无法降级为纯文本，交给批改队列重试
+				return fmt.Errorf("解析AI批改JSON失败: %w", err)
+			}
+			log.Printf("解析AI批改JSON失败，降级为纯文本处理: %v\n原始响应: %s", err, response)
+			// 降级处理：如果解析 JSON 失败，将整个响应作为评语，AI 负责的题目记 0 分
+			aiFeedback = response
+		} else {
+			aiFeedback = gradeResult.AIFeedback
+			if len(questions) == 0 {
+				legacyScore = gradeResult.TotalScore
+			}
+			breakdowns, err = applyRubricScores(aiQuestions, rubric, gradeResult.CriterionScores)
+			if err != nil {
+				return err
+			}
+			// 只采纳 AI 负责的题目，并把分数限制在 0 ~ 满分之间
+			for _, q := range aiQuestions {
+				score := gradeResult.QuestionScores[q.ID]
+				if score < 0 {
+					score = 0
+				}
+				if score > q.Score {
+					score = q.Score
+				}
+				// 有评分标准的题目以逐项得分之和为准
+				if breakdown, ok := breakdowns[q.ID]; ok {
+					score = breakdown.Score
+				}
+				questionScores[q.ID] = score
+				if fb, ok := gradeResult.QuestionFeedback[q.ID]; ok {
+					questionFeedback[q.ID] = fb
+				}
+			}
+		}
+	}
+
+	// 4. 合并客观题结果
+	for id, r := range objective {
+		questionScores[id] = r.Score
+		questionFeedback[id] = r.Feedback
+	}
+
+	total := legacyScore
+	for _, score := range questionScores {
+		total += score
+	}
+
+	submission.TotalScore = &total
+	submission.AIFeedback = objectiveSummary(questions, objective) + aiFeedback
+	if scoresJSON, err := json.Marshal(questionScores); err == nil {
+		submission.QuestionScores = string(scoresJSON)
+	}
+	if feedbackJSON, err := json.Marshal(questionFeedback); err == nil {
+		submission.QuestionFeedback = string(feedbackJSON)
+	}
+	if detailedJSON, err := json.Marshal(breakdowns); err == nil {
+		submission.DetailedScore = string(detailedJSON)
+	}
+
+	if len(runResults) > 0 {
+		s.applyRunResults(submission, questions, runResults, breakdowns)
+	}
+	return nil
+}
+
+// buildGradingPrompt 构建交给 AI 的批改提示，只包含主观题和编程题
+func (s *AssignmentService) buildGradingPrompt(assign *model.Assignment, questions []model.Question, answers map[string]string, codeContent string, runResults map[string]*runner.Result, staticAnalysis map[string]*codecheck.Report, rubric Rubric) string {
 	var promptBuilder strings.Builder
-	promptBuilder.WriteString(`你是一位冷酷无情且极其严谨的编程考官。你的任务是核对学生的作业答案并给出分数。
+	promptBuilder.WriteString(`你是一位冷酷无情且极其严谨的编程考官。你的任务是批改学生的主观题和编程题并给出分数。
 
 ### 规则库（必须死板地执行）：
-1. **完全匹配原则**：对于选择题和填空题，学生答案必须与标准答案在字符级别完全一致。任何微小的差别（如多一个空格、大小写不一、或者填了无关内容如"1"）都必须判为 0 分。
-2. **拒绝同情分**：如果学生答案错误，或者编程题代码无法运行、逻辑不通、或是填写的与题目无关（如 "1"、"不知道"、"..."），该题得分必须为 0。严禁给任何形式的辛苦分。
+1. **拒绝同情分**：如果学生答案错误，或者编程题代码无法运行、逻辑不通、或是填写的与题目无关（如 "1"、"不知道"、"..."），该题得分必须为 0。严禁给任何形式的辛苦分。
+2. **以事实为准**：如果提供了 [编程题自动测试结果] 或 [静态分析结果]，它们是程序真实检查得到的，评价必须与之一致。
 3. **负面示例参考**：
-   - 题目：Go 的并发原语是什么？ 标准答案：goroutine
+   - 题目：简述 goroutine 与线程的区别
    - 学生答案：1  => 判定：错误，得分：0
    - 学生答案：不知道 => 判定：错误，得分：0
-   - 学生答案：Goroutine => 判定：错误（大小写不一致），得分：0
 
 ### 评分数据源：
 `)
 
 	promptBuilder.WriteString(fmt.Sprintf("\n[作业上下文]\n标题：%s\n描述：%s\n\n", assign.Title, assign.Description))
 
-	promptBuilder.WriteString("[标准答案库]\n")
+	promptBuilder.WriteString("[参考答案库]\n")
 	for i, q := range questions {
-		correctAnswerText := q.Answer
-		if q.Type == "choice" {
-			var options []string
-			if err := json.Unmarshal([]byte(q.Options), &options); err == nil {
-				if correctAnswerText != "" {
-					// 兼容 'A', 'B' 格式
-					if correctAnswerText[0] >= 'A' && correctAnswerText[0] <= 'Z' {
-						correctOptionIndex := int(correctAnswerText[0] - 'A')
-						if correctOptionIndex >= 0 && correctOptionIndex < len(options) {
-							correctAnswerText = options[correctOptionIndex]
-						}
-					} else if index, err := strconv.Atoi(correctAnswerText); err == nil {
-						// 兼容 '1', '2' 格式 (数据库存储的是从1开始的)
-						correctOptionIndex := index - 1
-						if correctOptionIndex >= 0 && correctOptionIndex < len(options) {
-							correctAnswerText = options[correctOptionIndex]
-						}
-					}
-				}
-			}
-		}
-		promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) | 类型: %s | 满分: %d | 标准答案: %s\n",
-			i+1, q.ID, q.Type, q.Score, correctAnswerText))
+		promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) | 类型: %s | 满分: %d | 题目: %s | 参考答案: %s\n",
+			i+1, q.ID, q.Type, q.Score, q.Content, q.Answer))
 	}
 
+	writeRubricPrompt(&promptBuilder, questions, rubric)
+
 	// 添加学生答案
-	var answers map[string]string
-	if err := json.Unmarshal([]byte(submission.Answers), &answers); err == nil {
-		promptBuilder.WriteString("\n[学生提交内容]\n")
+	promptBuilder.WriteString("\n[学生提交内容]\n")
+	for i, q := range questions {
+		studentAns, ok := answers[q.ID]
+		if !ok || studentAns == "" {
+			studentAns = "[未回答]"
+		}
+		promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) 学生答案: %s\n", i+1, q.ID, studentAns))
+	}
+
+	if codeContent != "" {
+		promptBuilder.WriteString(fmt.Sprintf("\n[学生编程代码]\n%s\n", codeContent))
+	}
+
+	if len(runResults) > 0 {
+		promptBuilder.WriteString("\n[编程题自动测试结果]（沙箱中真实编译运行得到的事实，未设置评分标准的编程题得分以通过率为准，请在反馈中据此点评）\n")
 		for i, q := range questions {
-			studentAns, ok := answers[q.ID]
-			if !ok || studentAns == "" {
-				studentAns = "[未回答]"
-			} else {
-				// 如果是选择题，将学生的答案（如 "A"）转换成完整的选项文本
-				if q.Type == "choice" {
-					var options []string
-					if err := json.Unmarshal([]byte(q.Options), &options); err == nil {
-						// 学生的答案总是 "A", "B" 格式
-						if len(studentAns) > 0 && studentAns[0] >= 'A' && studentAns[0] <= 'Z' {
-							optionIndex := int(studentAns[0] - 'A') // "A" -> 0, "B" -> 1, ...
-							if optionIndex >= 0 && optionIndex < len(options) {
-								studentAns = options[optionIndex] // 替换为选项的完整内容
-							} else {
-								studentAns = "[无效选项]"
-							}
-						} else {
-							// 如果不是 A,B,C,D 格式，则可能是脏数据
-							studentAns = "[无效选项]"
-						}
-					} else {
-						studentAns = "[选项解析失败]"
-					}
+			result, ok := runResults[q.ID]
+			if !ok {
+				continue
+			}
+			if result.NotRun != "" {
+				promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) %s，请根据代码本身评分\n", i+1, q.ID, result.NotRun))
+				continue
+			}
+			if !result.Compiled {
+				promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) 编译失败:\n%s\n", i+1, q.ID, result.CompileError))
+				continue
+			}
+			promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) 通过 %d/%d 个测试\n", i+1, q.ID, result.Passed, result.Total))
+			for _, c := range result.Cases {
+				if !c.Passed {
+					promptBuilder.WriteString(fmt.Sprintf("  - 未通过: %s %s\n", c.Name, c.Error))
 				}
 			}
-			promptBuilder.WriteString(fmt.Sprintf("Q%d (ID: %s) 学生答案: %s\n", i+1, q.ID, studentAns))
 		}
 	}
 
-	if submission.CodeContent != "" {
-		promptBuilder.WriteString(fmt.Sprintf("\n[学生编程代码]\n%s\n", submission.CodeContent))
-	}
+	writeStaticAnalysisPrompt(&promptBuilder, questions, staticAnalysis)
 
 	promptBuilder.WriteString(`
 ### 执行指令：
-1. 逐一比对 [学生提交内容] 与 [标准答案库]。
+1. 逐一比对 [学生提交内容] 与 [参考答案库]，只为上面列出的题目打分。
 2. 计算总分 (total_score)，确保它等于所有单题得分的数学总和。
 3. 生成 ai_feedback，必须包含一个 Markdown 表格展示每题的得分情况，随后进行毒舌但客观的评价。
+`)
+	if rubric.covers(questions) {
+		promptBuilder.WriteString(`4. 对 [评分标准] 中列出的题目，必须在 criterion_scores 中给出每个评分项的得分和理由，题目得分等于各项得分之和。
+
+直接返回 JSON 格式，不要包含 Markdown 代码块标记：
+{
+  "total_score": 整数,
+  "ai_feedback": "Markdown 报告",
+  "question_scores": {"题目ID": 分数, ...},
+  "question_feedback": {"题目ID": "为什么给这个分", ...},
+  "criterion_scores": {"题目ID": {"评分项ID": {"score": 分数, "comment": "理由"}, ...}, ...}
+}
+`)
+		return promptBuilder.String()
+	}
 
+	promptBuilder.WriteString(`
 直接返回 JSON 格式，不要包含 Markdown 代码块标记：
 {
   "total_score": 整数,
@@ -499,171 +824,752 @@ func (s *AssignmentService) GradeSubmission(ctx context.Context, submissionID st
   "question_feedback": {"题目ID": "为什么给这个分", ...}
 }
 `)
+	return promptBuilder.String()
+}
 
-	// 记录生成的 Prompt 以便调试
-	log.Printf("--- AI Grading Prompt ---\n%s\n-----------------------", promptBuilder.String())
+// objectiveSummary 生成客观题判分结果的 Markdown 表格，放在 AI 评语之前
+func objectiveSummary(questions []model.Question, objective map[string]objectiveResult) string {
+	if len(objective) == 0 {
+		return ""
+	}
 
-	// 调用AI批改
-	response, err := s.siliconFlow.ChatCompletion(ctx, promptBuilder.String(), nil)
-	if err != nil {
-		return err
+	var b strings.Builder
+	b.WriteString("### 客观题自动判分\n\n| 题号 | 类型 | 得分 | 说明 |\n| --- | --- | --- | --- |\n")
+	for i, q := range questions {
+		r, ok := objective[q.ID]
+		if !ok {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("| Q%d | %s | %d/%d | %s |\n", i+1, q.Type, r.Score, q.Score, r.Feedback))
 	}
+	b.WriteString("\n")
+	return b.String()
+}
 
-	// 解析 AI 返回的 JSON
-	var gradeResult struct {
-		TotalScore       int               `json:"total_score"`
-		AIFeedback       string            `json:"ai_feedback"`
-		QuestionScores   map[string]int    `json:"question_scores"`
-		QuestionFeedback map[string]string `json:"question_feedback"`
+// runCodeTests 对配置了测试的编程题运行学生代码，返回 题目ID -> 运行结果
+func (s *AssignmentService) runCodeTests(ctx context.Context, questions []model.Question, answers map[string]string, codeContent string) map[string]*runner.Result {
+	results := make(map[string]*runner.Result)
+	if s.codeRunner == nil {
+		return results
 	}
 
-	cleanJSON := s.cleanAIJSON(response)
+	for _, q := range questions {
+		if q.Type != "code" {
+			continue
+		}
+		spec, err := questionRunSpec(q)
+		if err != nil {
+			log.Printf("[代码运行] 题目 %s 的测试用例解析失败: %v", q.ID, err)
+			continue
+		}
+		if spec.Empty() {
+			continue
+		}
 
-	if err := json.Unmarshal([]byte(cleanJSON), &gradeResult); err != nil {
-		log.Printf("解析AI批改JSON失败，降级为纯文本处理: %v\n原始响应: %s", err, response)
-		// 降级处理：如果解析 JSON 失败，将整个响应作为评语，给个默认分
-		submission.AIFeedback = response
-		defaultScore := 0
-		submission.TotalScore = &defaultScore
-	} else {
-		submission.TotalScore = &gradeResult.TotalScore
-		submission.AIFeedback = gradeResult.AIFeedback
+		// 逐题作答的代码优先，兼容只有一个整体代码框的旧作业
+		code := answers[q.ID]
+		if strings.TrimSpace(code) == "" {
+			code = codeContent
+		}
+		if strings.TrimSpace(code) == "" {
+			results[q.ID] = &runner.Result{CompileError: "未提交代码", Cases: []runner.CaseResult{}, Total: len(spec.TestCases)}
+			continue
+		}
 
-		// 序列化题目分数和批注
-		if scoresJSON, err := json.Marshal(gradeResult.QuestionScores); err == nil {
-			submission.QuestionScores = string(scoresJSON)
+		result, err := s.codeRunner.Run(ctx, code, spec)
+		if errors.Is(err, runner.ErrSandboxUnavailable) {
+			// 标记为未运行，该题不按通过率计分，由 AI 根据代码本身评分
+			results[q.ID] = &runner.Result{NotRun: err.Error(), Cases: []runner.CaseResult{}}
+			continue
 		}
-		if feedbackJSON, err := json.Marshal(gradeResult.QuestionFeedback); err == nil {
-			submission.QuestionFeedback = string(feedbackJSON)
+		if err != nil {
+			log.Printf("[代码运行] 题目 %s 运行失败: %v", q.ID, err)
+			continue
 		}
+		results[q.ID] = result
 	}
-
-	submission.Status = "graded"
-	submission.UpdatedAt = time.Now()
-
-	return s.submissionRepo.Update(submission)
+	return results
 }
 
-// 辅助函数：将答案map转换为JSON字符串
-func answersToString(answers map[string]string) string {
-	if len(answers) == 0 {
-		return "{}"
-	}
-	bytes, err := json.Marshal(answers)
-	if err != nil {
-		log.Printf("Error marshaling answers: %v", err)
-		return "{}"
+// applyRunResults 保存测试结果，并按通过率覆盖编程题得分、重新计算总分
+// 按评分标准打分的题目保留逐项得分之和，测试结果只作为 AI 打分的依据
+func (s *AssignmentService) applyRunResults(submission *model.Submission, questions []model.Question, runResults map[string]*runner.Result, breakdowns map[string]QuestionBreakdown) {
+	if resultsJSON, err := json.Marshal(runResults); err == nil {
+		submission.RunResults = string(resultsJSON)
 	}
-	return string(bytes)
-}
 
-// 辅助函数：将JSON字符串转换为答案map
-func stringToAnswers(s string) (map[string]string, error) {
-	var answers map[string]string
-	err := json.Unmarshal([]byte(s), &answers)
-	return answers, err
-}
+	questionScores := make(map[string]int)
+	json.Unmarshal([]byte(submission.QuestionScores), &questionScores)
+	if questionScores == nil {
+		questionScores = make(map[string]int)
+	}
 
-// UpdateSubmissionScore 手动更新提交分数
-func (s *AssignmentService) UpdateSubmissionScore(submissionID string, score int) error {
-	submission, err := s.submissionRepo.GetByID(submissionID)
-	if err != nil {
-		return fmt.Errorf("获取提交记录失败: %w", err)
+	for _, q := range questions {
+		if _, ok := breakdowns[q.ID]; ok {
+			continue
+		}
+		if result, ok := runResults[q.ID]; ok && result.NotRun == "" {
+			questionScores[q.ID] = int(math.Round(float64(q.Score) * result.PassRate()))
+		}
 	}
 
-	submission.TotalScore = &score
-	submission.UpdatedAt = time.Now()
+	total := 0
+	for _, score := range questionScores {
+		total += score
+	}
+	submission.TotalScore = &total
 
-	return s.submissionRepo.Update(submission)
+	if scoresJSON, err := json.Marshal(questionScores); err == nil {
+		submission.QuestionScores = string(scoresJSON)
+	}
 }
 
-// UpdateTeacherFeedback 更新教师批注
-func (s *AssignmentService) UpdateTeacherFeedback(submissionID string, feedback string) error {
-	submission, err := s.submissionRepo.GetByID(submissionID)
-	if err != nil {
-		return fmt.Errorf("获取提交记录失败: %w", err)
+// questionRunSpec 从题目中读取教师配置的测试文件和输入输出用例
+func questionRunSpec(q model.Question) (runner.Spec, error) {
+	spec := runner.Spec{TestCode: q.TestCode}
+	if strings.TrimSpace(q.TestCases) != "" {
+		if err := json.Unmarshal([]byte(q.TestCases), &spec.TestCases); err != nil {
+			return spec, err
+		}
 	}
-
-	submission.TeacherFeedback = feedback
-	submission.UpdatedAt = time.Now()
-
-	return s.submissionRepo.Update(submission)
+	return spec, nil
 }
 
-// UpdateQuestionScore 更新单个题目的分数
-func (s *AssignmentService) UpdateQuestionScore(submissionID string, questionID string, score int, maxScore int) error {
-	submission, err := s.submissionRepo.GetByID(submissionID)
+// CreateAssignment 教师手动创建作业（草稿状态，题目另行添加）
+func (s *AssignmentService) CreateAssignment(teacherID string, req dto.AssignmentRequest) (*model.Assignment, error) {
+	assignType, err := normalizeAssignmentType(req.Type)
 	if err != nil {
-		return fmt.Errorf("获取提交记录失败: %w", err)
+		return nil, err
 	}
-
-	// 验证分数范围
-	if score < 0 || score > maxScore {
-		return fmt.Errorf("分数必须在0-%d之间", maxScore)
+	if strings.TrimSpace(req.Title) == "" {
+		return nil, errors.New("作业标题不能为空")
 	}
-
-	// 解析现有的题目分数
-	var questionScores map[string]int
-	if submission.QuestionScores != "" {
-		if err := json.Unmarshal([]byte(submission.QuestionScores), &questionScores); err != nil {
-			questionScores = make(map[string]int)
+	category := model.CategoryHomework
+	if req.Category != "" {
+		if category, err = normalizeCategory(req.Category); err != nil {
+			return nil, err
 		}
-	} else {
-		questionScores = make(map[string]int)
 	}
 
-	// 更新分数
-	questionScores[questionID] = score
-
-	// 转换为JSON
-	scoresJSON, err := json.Marshal(questionScores)
-	if err != nil {
-		return fmt.Errorf("序列化分数失败: %w", err)
-	}
-
-	submission.QuestionScores = string(scoresJSON)
-	submission.UpdatedAt = time.Now()
+	assign := &model.Assignment{
+		ID:          uuid.New().String(),
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		TeacherID:   teacherID,
+		Type:        assignType,
+		Category:    category,
+		Status:      "draft",
+		Rubric:      "{}",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.assignRepo.Create(assign); err != nil {
+		return nil, err
+	}
+	return assign, nil
+}
 
-	// 重新计算总分
-	totalScore := 0
-	for _, s := range questionScores {
-		totalScore += s
+// UpdateAssignment 修改作业信息；已有学生提交后只能修改标题
+func (s *AssignmentService) UpdateAssignment(teacherID, assignID string, req dto.AssignmentRequest) (*model.Assignment, error) {
+	assign, err := s.authorizeAssignment(teacherID, assignID, CapEditAssignment)
+	if err != nil {
+		return nil, err
+	}
+	assignType, err := normalizeAssignmentType(req.Type)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		return nil, errors.New("作业标题不能为空")
+	}
+
+	if req.Description != assign.Description || assignType != assign.Type {
+		if err := s.ensureNoSubmissions(assignID); err != nil {
+			return nil, err
+		}
+	}
+
+	assign.Title = strings.TrimSpace(req.Title)
+	assign.Description = req.Description
+	assign.Type = assignType
+	if req.Category != "" {
+		if assign.Category, err = normalizeCategory(req.Category); err != nil {
+			return nil, err
+		}
+	}
+	assign.UpdatedAt = time.Now()
+	if err := s.assignRepo.Update(assign); err != nil {
+		return nil, err
+	}
+	return assign, nil
+}
+
+// CreateQuestion 为作业添加题目，题号排在最后
+func (s *AssignmentService) CreateQuestion(teacherID, assignID string, req dto.QuestionRequest) (*model.Question, error) {
+	if _, err := s.authorizeAssignment(teacherID, assignID, CapEditAssignment); err != nil {
+		return nil, err
+	}
+	if err := validateQuestionRequest(req); err != nil {
+		return nil, err
+	}
+	if err := s.ensureNoSubmissions(assignID); err != nil {
+		return nil, err
+	}
+
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, err
+	}
+	orderNum := 1
+	for _, q := range questions {
+		if q.OrderNum >= orderNum {
+			orderNum = q.OrderNum + 1
+		}
+	}
+
+	question := &model.Question{
+		ID:           uuid.New().String(),
+		AssignmentID: assignID,
+		Type:         req.Type,
+		Content:      req.Content,
+		Options:      optionsToString(req.Options),
+		Answer:       req.Answer,
+		GradingRule:  "{}",
+		TestCases:    "[]",
+		Score:        req.Score,
+		OrderNum:     orderNum,
+	}
+	if err := s.questionRepo.Create(question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// UpdateQuestion 修改题目。已有学生提交后题型、题干和选项被锁定，
+// 只能修正参考答案和分值，修改后已有提交会重新排队批改
+func (s *AssignmentService) UpdateQuestion(teacherID, questionID string, req dto.QuestionRequest) (*model.Question, error) {
+	question, err := s.getOwnedQuestion(teacherID, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuestionRequest(req); err != nil {
+		return nil, err
+	}
+
+	options := optionsToString(req.Options)
+	structureChanged := req.Type != question.Type || req.Content != question.Content || !sameOptions(options, question.Options)
+	keyChanged := req.Answer != question.Answer || req.Score != question.Score
+
+	count, err := s.submissionRepo.CountByAssignmentID(question.AssignmentID, "")
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 && structureChanged {
+		return nil, errors.New("作业已有学生提交，只能修改参考答案和分值")
+	}
+
+	question.Type = req.Type
+	question.Content = req.Content
+	question.Options = options
+	question.Answer = req.Answer
+	question.Score = req.Score
+	if err := s.questionRepo.Update(question); err != nil {
+		return nil, err
+	}
+
+	if count > 0 && keyChanged {
+		s.regradeAssignment(question.AssignmentID)
+	}
+	return question, nil
+}
+
+// DeleteQuestion 删除题目并重新编号剩余题目
+func (s *AssignmentService) DeleteQuestion(teacherID, questionID string) error {
+	question, err := s.getOwnedQuestion(teacherID, questionID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureNoSubmissions(question.AssignmentID); err != nil {
+		return err
+	}
+
+	if err := s.questionRepo.Delete(questionID); err != nil {
+		return err
+	}
+
+	remaining, err := s.questionRepo.GetByAssignmentID(question.AssignmentID)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(remaining))
+	for _, q := range remaining {
+		ids = append(ids, q.ID)
+	}
+	return s.questionRepo.UpdateOrder(question.AssignmentID, ids)
+}
+
+// ReorderQuestions 按给定顺序重排作业题目，questionIDs 必须恰好包含该作业的全部题目
+func (s *AssignmentService) ReorderQuestions(teacherID, assignID string, questionIDs []string) error {
+	if _, err := s.authorizeAssignment(teacherID, assignID, CapEditAssignment); err != nil {
+		return err
+	}
+	if err := s.ensureNoSubmissions(assignID); err != nil {
+		return err
+	}
+
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return err
+	}
+	if len(questionIDs) != len(questions) {
+		return errors.New("题目列表与作业题目不一致")
+	}
+	existing := make(map[string]bool, len(questions))
+	for _, q := range questions {
+		existing[q.ID] = true
+	}
+	for _, id := range questionIDs {
+		if !existing[id] {
+			return errors.New("题目列表与作业题目不一致")
+		}
+		delete(existing, id) // 防止重复的题目 ID
+	}
+
+	return s.questionRepo.UpdateOrder(assignID, questionIDs)
+}
+
+// authorizeAssignment 获取作业并校验用户拥有该权限（作业创建者或作业所在班级的教学人员）
+func (s *AssignmentService) authorizeAssignment(userID, assignID string, capability Capability) (*model.Assignment, error) {
+	return s.authz.AuthorizeAssignment(userID, assignID, capability)
+}
+
+// ensureNoSubmissions 作业已有学生提交时拒绝会改变题目本身的修改
+func (s *AssignmentService) ensureNoSubmissions(assignID string) error {
+	count, err := s.submissionRepo.CountByAssignmentID(assignID, "")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("作业已有学生提交，不能再修改题目内容")
+	}
+	return nil
+}
+
+// regradeAssignment 把作业全部提交的所有版本标记为待批改，并将提交重新加入批改队列
+func (s *AssignmentService) regradeAssignment(assignID string) {
+	// 题目或判分规则变化后，每个版本的得分都可能改变，计分方式才能在新的得分中重新选择
+	if err := s.versionRepo.MarkUngraded(assignID); err != nil {
+		log.Printf("标记作业 %s 的提交版本待批改失败: %v", assignID, err)
+		return
+	}
+	submissions, err := s.submissionRepo.GetByAssignmentIDs([]string{assignID})
+	if err == nil {
+		submissions, err = s.gradingUnits(submissions)
+	}
+	if err != nil {
+		log.Printf("获取作业提交失败，无法重新批改: %v", err)
+		return
+	}
+	for _, sub := range submissions {
+		if err := s.gradingQueue.Enqueue(sub.ID, assignID); err != nil {
+			log.Printf("提交 %s 重新批改排队失败: %v", sub.ID, err)
+		}
+	}
+}
+
+// regradeIfSubmitted 判分依据变化后，作业已有提交时全部重新批改
+func (s *AssignmentService) regradeIfSubmitted(assignID string) error {
+	count, err := s.submissionRepo.CountByAssignmentID(assignID, "")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.regradeAssignment(assignID)
+	}
+	return nil
+}
+
+// UpdateQuestionTests 教师为编程题设置测试文件和输入输出用例；
+// 已有提交会按新的测试重新排队批改
+func (s *AssignmentService) UpdateQuestionTests(teacherID, questionID, testCode string, cases []runner.TestCase) error {
+	question, err := s.getOwnedQuestion(teacherID, questionID)
+	if err != nil {
+		return err
+	}
+	if question.Type != "code" {
+		return errors.New("只有编程题可以设置测试")
+	}
+
+	if cases == nil {
+		cases = []runner.TestCase{}
+	}
+	casesJSON, err := json.Marshal(cases)
+	if err != nil {
+		return fmt.Errorf("序列化测试用例失败: %w", err)
+	}
+
+	question.TestCode = testCode
+	question.TestCases = string(casesJSON)
+	if err := s.questionRepo.Update(question); err != nil {
+		return err
+	}
+	return s.regradeIfSubmitted(question.AssignmentID)
+}
+
+// UpdateQuestionGradingRule 教师为选择题/填空题设置判分规则；
+// 已有提交会按新的规则重新排队批改
+func (s *AssignmentService) UpdateQuestionGradingRule(teacherID, questionID string, rule GradingRule) error {
+	question, err := s.getOwnedQuestion(teacherID, questionID)
+	if err != nil {
+		return err
+	}
+	if !isObjectiveQuestion(*question) {
+		return errors.New("只有选择题和填空题可以设置判分规则")
+	}
+	if rule.Regex != "" {
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return fmt.Errorf("正则表达式无效: %w", err)
+		}
+	}
+
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("序列化判分规则失败: %w", err)
+	}
+
+	question.GradingRule = string(ruleJSON)
+	if err := s.questionRepo.Update(question); err != nil {
+		return err
+	}
+	return s.regradeIfSubmitted(question.AssignmentID)
+}
+
+// UpdateQuestionRubric 教师为主观题/编程题设置评分标准，评分项为空时删除该题的评分标准；
+// 已有提交会按新的评分标准重新排队批改
+func (s *AssignmentService) UpdateQuestionRubric(teacherID, questionID string, qr QuestionRubric) error {
+	question, err := s.getOwnedQuestion(teacherID, questionID)
+	if err != nil {
+		return err
+	}
+	if isObjectiveQuestion(*question) {
+		return errors.New("选择题和填空题由程序判分，不能设置评分标准")
+	}
+
+	assign, err := s.assignRepo.GetByID(question.AssignmentID)
+	if err != nil {
+		return errors.New("作业不存在")
+	}
+	rubric, err := parseRubric(assign.Rubric)
+	if err != nil {
+		return fmt.Errorf("解析作业评分标准失败: %w", err)
+	}
+
+	if len(qr.Criteria) == 0 {
+		delete(rubric.Questions, questionID)
+	} else {
+		if err := qr.normalize(question.Score); err != nil {
+			return err
+		}
+		rubric.Questions[questionID] = qr
+	}
+
+	rubricJSON, err := json.Marshal(rubric)
+	if err != nil {
+		return fmt.Errorf("序列化评分标准失败: %w", err)
+	}
+	assign.Rubric = string(rubricJSON)
+	assign.UpdatedAt = time.Now()
+	if err := s.assignRepo.Update(assign); err != nil {
+		return err
+	}
+
+	count, err := s.submissionRepo.CountByAssignmentID(assign.ID, "")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.regradeAssignment(assign.ID)
+	}
+	return nil
+}
+
+// getOwnedQuestion 获取题目并校验用户有权修改其所属作业
+func (s *AssignmentService) getOwnedQuestion(teacherID, questionID string) (*model.Question, error) {
+	question, err := s.questionRepo.GetByID(questionID)
+	if err != nil {
+		return nil, errors.New("题目不存在")
+	}
+
+	if _, err := s.authorizeAssignment(teacherID, question.AssignmentID, CapEditAssignment); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// normalizeAssignmentType 校验作业类型，为空时视为混合题型
+func normalizeAssignmentType(assignType string) (string, error) {
+	switch assignType {
+	case "":
+		return "mixed", nil
+	case "code", "choice", "fill", "mixed":
+		return assignType, nil
+	default:
+		return "", fmt.Errorf("不支持的作业类型: %s", assignType)
+	}
+}
+
+// validateQuestionRequest 校验题目的题型、选项、答案和分值
+func validateQuestionRequest(req dto.QuestionRequest) error {
+	if strings.TrimSpace(req.Content) == "" {
+		return errors.New("题目内容不能为空")
+	}
+	if req.Score < 0 {
+		return errors.New("分值不能为负数")
+	}
+	switch req.Type {
+	case "choice":
+		if len(req.Options) < 2 {
+			return errors.New("选择题至少需要两个选项")
+		}
+		if len(choiceLetters(req.Answer, req.Options)) == 0 {
+			return errors.New("选择题答案必须是有效的选项")
+		}
+	case "fill":
+		if strings.TrimSpace(req.Answer) == "" {
+			return errors.New("填空题必须提供参考答案")
+		}
+	case "code":
+	default:
+		return fmt.Errorf("不支持的题目类型: %s", req.Type)
+	}
+	return nil
+}
+
+// optionsToString 将选项列表序列化为题目中保存的 JSON
+func optionsToString(options []string) string {
+	if len(options) == 0 {
+		return "{}"
+	}
+	bytes, _ := json.Marshal(options)
+	return string(bytes)
+}
+
+// sameOptions 比较两份选项 JSON 是否表示相同的选项
+func sameOptions(a, b string) bool {
+	var left, right interface{}
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return a == b
+	}
+	leftJSON, _ := json.Marshal(left)
+	rightJSON, _ := json.Marshal(right)
+	return string(leftJSON) == string(rightJSON)
+}
+
+// 辅助函数：将答案map转换为JSON字符串
+func answersToString(answers map[string]string) string {
+	if len(answers) == 0 {
+		return "{}"
+	}
+	bytes, err := json.Marshal(answers)
+	if err != nil {
+		log.Printf("Error marshaling answers: %v", err)
+		return "{}"
+	}
+	return string(bytes)
+}
+
+// 辅助函数：将JSON字符串转换为答案map
+func stringToAnswers(s string) (map[string]string, error) {
+	var answers map[string]string
+	err := json.Unmarshal([]byte(s), &answers)
+	return answers, err
+}
+
+// UpdateSubmissionScore 教师直接修改提交的原始总分：首次修改时保留 AI 给出的原始得分，
+// 与逐题改分一致扣除迟交罚分，并把提交和计入成绩的版本标记为教师已复核
+func (s *AssignmentService) UpdateSubmissionScore(teacherID, submissionID string, score int) error {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return fmt.Errorf("获取提交记录失败: %w", err)
+	}
+	if submission, err = s.sharedSubmission(submission); err != nil {
+		return err
+	}
+	if _, err := s.authorizeAssignment(teacherID, submission.AssignmentID, CapGrade); err != nil {
+		return err
 	}
-	submission.TotalScore = &totalScore
 
-	return s.submissionRepo.Update(submission)
+	maxScore, err := s.assignmentMaxScore(submission.AssignmentID)
+	if err != nil {
+		return err
+	}
+	if maxScore == 0 {
+		maxScore = 100 // 没有题目的旧作业按百分制
+	}
+	if score < 0 || score > maxScore {
+		return fmt.Errorf("分数必须在0-%d之间", maxScore)
+	}
+
+	if submission.AIRawScore == nil {
+		original := submission.RawScore
+		if original == nil {
+			original = submission.TotalScore
+		}
+		submission.AIRawScore = original
+	}
+	total := applyLatePenalty(score, submission.LatePenalty)
+	submission.RawScore = &score
+	submission.TotalScore = &total
+
+	return s.saveReview(teacherID, submission)
 }
 
-// UpdateQuestionFeedback 更新单个题目的批注
-func (s *AssignmentService) UpdateQuestionFeedback(submissionID string, questionID string, feedback string) error {
+// UpdateTeacherFeedback 更新教师批注
+func (s *AssignmentService) UpdateTeacherFeedback(submissionID string, feedback string) error {
 	submission, err := s.submissionRepo.GetByID(submissionID)
 	if err != nil {
 		return fmt.Errorf("获取提交记录失败: %w", err)
 	}
+	if submission, err = s.sharedSubmission(submission); err != nil {
+		return err
+	}
+
+	submission.TeacherFeedback = feedback
+	submission.UpdatedAt = time.Now()
+
+	if err := s.submissionRepo.Update(submission); err != nil {
+		return err
+	}
+	s.syncGroup(submission)
+	return nil
+}
+
+// UpdateQuestionScore 教师修改单个题目的分数：首次修改时保留 AI 给出的原始得分，
+// 按各题得分重新计算原始总分并扣除迟交罚分，并把提交标记为教师已复核
+func (s *AssignmentService) UpdateQuestionScore(teacherID, submissionID, questionID string, score int) error {
+	submission, question, err := s.getReviewTarget(teacherID, submissionID, questionID)
+	if err != nil {
+		return err
+	}
+
+	// 验证分数范围
+	if score < 0 || score > question.Score {
+		return fmt.Errorf("分数必须在0-%d之间", question.Score)
+	}
+
+	questionScores := decodeScores(submission.QuestionScores)
+	aiScores := decodeScores(submission.AIQuestionScores)
+	if _, overridden := aiScores[questionID]; !overridden {
+		if original, ok := questionScores[questionID]; ok {
+			aiScores[questionID] = original
+		}
+	}
+	questionScores[questionID] = score
+
+	scoresJSON, err := json.Marshal(questionScores)
+	if err != nil {
+		return fmt.Errorf("序列化分数失败: %w", err)
+	}
+	aiScoresJSON, err := json.Marshal(aiScores)
+	if err != nil {
+		return fmt.Errorf("序列化分数失败: %w", err)
+	}
+	submission.QuestionScores = string(scoresJSON)
+	submission.AIQuestionScores = string(aiScoresJSON)
+
+	// 重新计算总分，与批改时一致：迟交罚分作用在各题得分之和上
+	raw := 0
+	for _, s := range questionScores {
+		raw += s
+	}
+	total := applyLatePenalty(raw, submission.LatePenalty)
+	submission.RawScore = &raw
+	submission.TotalScore = &total
+
+	return s.saveReview(teacherID, submission)
+}
+
+// UpdateQuestionFeedback 教师修改单个题目的批注，并把提交标记为教师已复核
+func (s *AssignmentService) UpdateQuestionFeedback(teacherID, submissionID, questionID, feedback string) error {
+	submission, _, err := s.getReviewTarget(teacherID, submissionID, questionID)
+	if err != nil {
+		return err
+	}
 
 	// 解析现有的题目批注
 	var questionFeedback map[string]string
-	if submission.QuestionFeedback != "" {
-		if err := json.Unmarshal([]byte(submission.QuestionFeedback), &questionFeedback); err != nil {
-			questionFeedback = make(map[string]string)
-		}
-	} else {
+	if err := json.Unmarshal([]byte(submission.QuestionFeedback), &questionFeedback); err != nil || questionFeedback == nil {
 		questionFeedback = make(map[string]string)
 	}
-
-	// 更新批注
 	questionFeedback[questionID] = feedback
 
-	// 转换为JSON
 	feedbackJSON, err := json.Marshal(questionFeedback)
 	if err != nil {
 		return fmt.Errorf("序列化批注失败: %w", err)
 	}
-
 	submission.QuestionFeedback = string(feedbackJSON)
-	submission.UpdatedAt = time.Now()
 
-	return s.submissionRepo.Update(submission)
+	return s.saveReview(teacherID, submission)
+}
+
+// getReviewTarget 获取教师有权复核的提交及其中的题目，小组作业返回小组的主提交
+func (s *AssignmentService) getReviewTarget(teacherID, submissionID, questionID string) (*model.Submission, *model.Question, error) {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取提交记录失败: %w", err)
+	}
+	if submission, err = s.sharedSubmission(submission); err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.authorizeAssignment(teacherID, submission.AssignmentID, CapGrade); err != nil {
+		return nil, nil, err
+	}
+	question, err := s.questionRepo.GetByID(questionID)
+	if err != nil || question.AssignmentID != submission.AssignmentID {
+		return nil, nil, errors.New("题目不存在")
+	}
+	return submission, question, nil
+}
+
+// saveReview 把提交标记为教师已复核并保存，同时同步到计入成绩的版本，
+// 使版本记录和按计分方式重新选版本时的成绩与提交记录一致
+func (s *AssignmentService) saveReview(teacherID string, submission *model.Submission) error {
+	now := time.Now()
+	submission.TeacherReviewed = true
+	submission.ReviewedBy = teacherID
+	submission.ReviewedAt = &now
+	submission.UpdatedAt = now
+	if err := s.submissionRepo.Update(submission); err != nil {
+		return err
+	}
+	s.syncGroup(submission)
+
+	if submission.CountedAttempt == 0 {
+		return nil
+	}
+	version, err := s.versionRepo.GetByAttempt(submission.ID, submission.CountedAttempt)
+	if err != nil {
+		return fmt.Errorf("获取提交版本失败: %w", err)
+	}
+	version.QuestionScores = submission.QuestionScores
+	version.QuestionFeedback = defaultJSON(submission.QuestionFeedback, "{}")
+	version.AIQuestionScores = submission.AIQuestionScores
+	version.AIRawScore = submission.AIRawScore
+	version.RawScore = submission.RawScore
+	version.TotalScore = submission.TotalScore
+	version.TeacherReviewed = true
+	version.ReviewedBy = teacherID
+	version.ReviewedAt = &now
+	return s.versionRepo.Update(version)
+}
+
+// decodeScores 解析题目分数 JSON，内容为空或无效时返回空表
+func decodeScores(raw string) map[string]int {
+	var scores map[string]int
+	if err := json.Unmarshal([]byte(raw), &scores); err != nil || scores == nil {
+		scores = make(map[string]int)
+	}
+	return scores
 }
 
 // GetQuestionScore 获取单个题目的分数
@@ -714,19 +1620,236 @@ func (s *AssignmentService) GetQuestionFeedback(submissionID string, questionID
 	return feedback, nil
 }
 
-// RegradeSubmission 重新触发AI批改
-func (s *AssignmentService) RegradeSubmission(submissionID string) error {
-	// 异步重新批改
-	go func() {
-		ctx := context.Background()
-		if err := s.GradeSubmission(ctx, submissionID); err != nil {
-			log.Printf("重新批改失败: %v", err)
+// RegradeSubmission 重新触发AI批改，小组作业重新批改小组的主提交。
+// 默认保留教师的复核结果；discardReview 为 true 时清除教师的改分，教师复核过的版本全部按 AI 结果重新批改
+func (s *AssignmentService) RegradeSubmission(submissionID string, discardReview bool) error {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return fmt.Errorf("获取提交记录失败: %w", err)
+	}
+	if submission, err = s.sharedSubmission(submission); err != nil {
+		return err
+	}
+	if discardReview {
+		if err := s.discardReview(submission.ID); err != nil {
+			return err
 		}
-	}()
+	}
 
+	if err := s.gradingQueue.Enqueue(submission.ID, submission.AssignmentID); err != nil {
+		return fmt.Errorf("创建批改任务失败: %w", err)
+	}
+	return nil
+}
+
+// discardReview 清除提交各版本上教师的复核结果，并把复核过的版本标记为待批改，
+// 重新批改后得分完全由 AI 给出
+func (s *AssignmentService) discardReview(submissionID string) error {
+	versions, err := s.versionRepo.GetBySubmissionID(submissionID)
+	if err != nil {
+		return fmt.Errorf("获取提交版本失败: %w", err)
+	}
+	for i := range versions {
+		v := &versions[i]
+		if !v.TeacherReviewed {
+			continue
+		}
+		v.AIQuestionScores = "{}"
+		v.AIRawScore = nil
+		v.TeacherReviewed = false
+		v.ReviewedBy = ""
+		v.ReviewedAt = nil
+		v.Status = "submitted"
+		if err := s.versionRepo.SaveGrading(v); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// VersionDiff 两个提交版本之间的差异
+type VersionDiff struct {
+	FromAttempt int            `json:"from_attempt"`
+	ToAttempt   int            `json:"to_attempt"`
+	Questions   []QuestionDiff `json:"questions"`
+	Code        *TextDiff      `json:"code,omitempty"` // 整体代码框（旧作业）的差异
+}
+
+// QuestionDiff 单个题目的作答差异和得分变化
+type QuestionDiff struct {
+	QuestionID string `json:"question_id"`
+	OrderNum   int    `json:"order_num"`
+	Type       string `json:"type"`
+	FromScore  *int   `json:"from_score"`
+	ToScore    *int   `json:"to_score"`
+	TextDiff
+}
+
+// TextDiff 一段文本的逐行差异
+type TextDiff struct {
+	Changed bool            `json:"changed"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Lines   []textdiff.Line `json:"lines"`
+}
+
+// GetSubmissionVersions 获取提交的全部版本，小组作业返回小组主提交的版本
+func (s *AssignmentService) GetSubmissionVersions(submissionID string) ([]model.SubmissionVersion, error) {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取提交记录失败: %w", err)
+	}
+	if submission, err = s.sharedSubmission(submission); err != nil {
+		return nil, err
+	}
+	return s.ensureVersions(submission)
+}
+
+// DiffSubmissionVersions 比较同一提交的两个版本，逐题给出作答差异和得分变化
+func (s *AssignmentService) DiffSubmissionVersions(submissionID string, fromAttempt, toAttempt int) (*VersionDiff, error) {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取提交记录失败: %w", err)
+	}
+	if submission, err = s.sharedSubmission(submission); err != nil {
+		return nil, err
+	}
+	from, err := s.versionRepo.GetByAttempt(submission.ID, fromAttempt)
+	if err != nil {
+		return nil, fmt.Errorf("版本 %d 不存在", fromAttempt)
+	}
+	to, err := s.versionRepo.GetByAttempt(submission.ID, toAttempt)
+	if err != nil {
+		return nil, fmt.Errorf("版本 %d 不存在", toAttempt)
+	}
+	questions, err := s.questionRepo.GetByAssignmentID(submission.AssignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromAnswers, toAnswers map[string]string
+	json.Unmarshal([]byte(from.Answers), &fromAnswers)
+	json.Unmarshal([]byte(to.Answers), &toAnswers)
+	var fromScores, toScores map[string]int
+	json.Unmarshal([]byte(from.QuestionScores), &fromScores)
+	json.Unmarshal([]byte(to.QuestionScores), &toScores)
+
+	diff := &VersionDiff{FromAttempt: fromAttempt, ToAttempt: toAttempt, Questions: []QuestionDiff{}}
+	for _, q := range questions {
+		qd := QuestionDiff{
+			QuestionID: q.ID,
+			OrderNum:   q.OrderNum,
+			Type:       q.Type,
+			TextDiff:   newTextDiff(fromAnswers[q.ID], toAnswers[q.ID]),
+		}
+		if score, ok := fromScores[q.ID]; ok {
+			qd.FromScore = &score
+		}
+		if score, ok := toScores[q.ID]; ok {
+			qd.ToScore = &score
+		}
+		diff.Questions = append(diff.Questions, qd)
+	}
+	if from.CodeContent != "" || to.CodeContent != "" {
+		code := newTextDiff(from.CodeContent, to.CodeContent)
+		diff.Code = &code
+	}
+	return diff, nil
+}
+
+// UpdateAttemptPolicy 设置作业的最多提交次数和计分方式，并按新的计分方式重新选定每个提交计入成绩的版本
+func (s *AssignmentService) UpdateAttemptPolicy(teacherID, assignID string, maxAttempts int, scorePolicy string) error {
+	assign, err := s.authorizeAssignment(teacherID, assignID, CapEditAssignment)
+	if err != nil {
+		return err
+	}
+	if maxAttempts < 0 {
+		return errors.New("最多提交次数不能为负数")
+	}
+	if scorePolicy == "" {
+		scorePolicy = "last"
+	}
+	if scorePolicy != "last" && scorePolicy != "best" {
+		return fmt.Errorf("不支持的计分方式: %s", scorePolicy)
+	}
+
+	policyChanged := assign.ScorePolicy != scorePolicy
+	assign.MaxAttempts = maxAttempts
+	assign.ScorePolicy = scorePolicy
+	assign.UpdatedAt = time.Now()
+	if err := s.assignRepo.Update(assign); err != nil {
+		return err
+	}
+	if !policyChanged {
+		return nil
+	}
+
+	submissions, err := s.submissionRepo.GetByAssignmentIDs([]string{assignID})
+	if err != nil {
+		return err
+	}
+	if submissions, err = s.gradingUnits(submissions); err != nil {
+		return err
+	}
+	for i := range submissions {
+		versions, err := s.ensureVersions(&submissions[i])
+		if err != nil {
+			return err
+		}
+		if err := s.applyScorePolicy(assign, &submissions[i], versions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTextDiff(from, to string) TextDiff {
+	lines := textdiff.Lines(from, to)
+	return TextDiff{Changed: textdiff.Changed(lines), From: from, To: to, Lines: lines}
+}
+
+// GetGradingJobs 获取作业的批改任务，statuses 为空时返回全部
+func (s *AssignmentService) GetGradingJobs(assignID string, statuses []string) ([]model.GradingJob, error) {
+	return s.gradingJobRepo.ListByAssignment(assignID, statuses)
+}
+
+// RequeueGradingJobs 将作业下失败或等待重试的批改任务重新排队并立即执行，jobIDs 为空时作用于全部
+func (s *AssignmentService) RequeueGradingJobs(assignID string, jobIDs []string) (int64, error) {
+	count, err := s.gradingJobRepo.Requeue(assignID, jobIDs, []string{model.GradingJobFailed, model.GradingJobQueued})
+	if err != nil {
+		return 0, err
+	}
+	// 未指定任务时，一并补建提交后没能创建批改任务的提交
+	if len(jobIDs) == 0 {
+		recovered, err := s.EnqueueOrphanedSubmissions(assignID)
+		if err != nil {
+			return count, err
+		}
+		count += int64(recovered)
+	}
+	if count > 0 {
+		s.gradingQueue.Notify()
+	}
+	return count, nil
+}
+
+// EnqueueOrphanedSubmissions 为待批改但没有批改任务的提交补建任务（如提交时入队失败），
+// assignID 为空时处理全部作业，返回补建的数量
+func (s *AssignmentService) EnqueueOrphanedSubmissions(assignID string) (int, error) {
+	submissions, err := s.submissionRepo.GetSubmittedWithoutJob(assignID)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, sub := range submissions {
+		if err := s.gradingQueue.Enqueue(sub.ID, sub.AssignmentID); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
 // GetSubmissionCodeForDownload 获取要下载的代码内容和文件名
 func (s *AssignmentService) GetSubmissionCodeForDownload(submissionID string) (string, string, error) {
 	submission, err := s.submissionRepo.GetByID(submissionID)
@@ -810,6 +1933,41 @@ func (s *AssignmentService) DeleteAssignment(assignID string) error {
 		return fmt.Errorf("删除提交记录失败: %w", err)
 	}
 
+	// 删除提交版本
+	if err := s.versionRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除提交版本失败: %w", err)
+	}
+
+	// 删除答案草稿
+	if err := s.draftRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除答案草稿失败: %w", err)
+	}
+
+	// 删除个人延期
+	if err := s.extensionRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除延期记录失败: %w", err)
+	}
+
+	// 删除考试会话
+	if err := s.examSessionRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除考试记录失败: %w", err)
+	}
+
+	// 删除批改任务
+	if err := s.gradingJobRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除批改任务失败: %w", err)
+	}
+
+	// 删除相似度检测报告
+	if err := s.similarityRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除相似度检测报告失败: %w", err)
+	}
+
+	// 删除成绩申诉
+	if err := s.appealRepo.DeleteByAssignmentID(assignID); err != nil {
+		return fmt.Errorf("删除成绩申诉失败: %w", err)
+	}
+
 	// 删除所有题目
 	if err := s.questionRepo.DeleteByAssignmentID(assignID); err != nil {
 		return fmt.Errorf("删除题目失败: %w", err)
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sessionTTL 会话的绝对有效期
+	sessionTTL = 7 * 24 * time.Hour
+	// sessionIdleTimeout 超过该时间没有任何请求则会话失效
+	sessionIdleTimeout = 2 * time.Hour
+	// sessionTouchInterval 最近访问时间的最小刷新间隔，避免每个请求都写库
+	sessionTouchInterval = time.Minute
+)
+
+// ErrSessionInvalid 会话不存在、已过期或已被吊销
+var ErrSessionInvalid = errors.New("登录已失效，请重新登录")
+
+type AuthService struct {
+	sessionRepo repository.UserSessionRepository
+	userRepo    repository.UserRepository
+}
+
+func NewAuthService(sessionRepo repository.UserSessionRepository, userRepo repository.UserRepository) IAuthService {
+	return &AuthService{sessionRepo: sessionRepo, userRepo: userRepo}
+}
+
+// CreateSession 为用户创建新的登录会话，返回仅下发给客户端一次的明文令牌
+func (s *AuthService) CreateSession(userID, userAgent, clientIP string) (string, *model.UserSession, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(buf)
+
+	now := time.Now()
+	session := &model.UserSession{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		TokenHash:    hashSessionToken(token),
+		ExpiresAt:    now.Add(sessionTTL),
+		LastActiveAt: now,
+		UserAgent:    truncate(userAgent, 255),
+		ClientIP:     truncate(clientIP, 64),
+		CreatedAt:    now,
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return "", nil, err
+	}
+
+	// 顺带清理早已过期的会话，失败不影响登录
+	s.sessionRepo.DeleteExpired(now.Add(-sessionTTL))
+
+	return token, session, nil
+}
+
+// ValidateSession 校验令牌并返回当前用户，角色始终以数据库中的用户记录为准
+func (s *AuthService) ValidateSession(token string) (*model.User, error) {
+	if token == "" {
+		return nil, ErrSessionInvalid
+	}
+
+	session, err := s.sessionRepo.GetByTokenHash(hashSessionToken(token))
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) || now.Sub(session.LastActiveAt) > sessionIdleTimeout {
+		return nil, ErrSessionInvalid
+	}
+
+	user, err := s.userRepo.GetByID(session.UserID)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+
+	if now.Sub(session.LastActiveAt) > sessionTouchInterval {
+		session.LastActiveAt = now
+		s.sessionRepo.Update(session)
+	}
+
+	return user, nil
+}
+
+// RevokeSession 吊销单个会话（登出）
+func (s *AuthService) RevokeSession(token string) error {
+	session, err := s.sessionRepo.GetByTokenHash(hashSessionToken(token))
+	if err != nil {
+		return ErrSessionInvalid
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return s.sessionRepo.Update(session)
+}
+
+// RevokeUserSessions 吊销某个用户的全部会话（如重置密码后强制重新登录）
+func (s *AuthService) RevokeUserSessions(userID string) error {
+	return s.sessionRepo.RevokeByUserID(userID)
+}
+
+// hashSessionToken 数据库只保存令牌的 SHA-256，泄露会话表也无法直接冒用
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
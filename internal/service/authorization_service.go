@@ -0,0 +1,292 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+	"errors"
+	"fmt"
+)
+
+// Capability 班级教学人员可以执行的一类操作
+type Capability string
+
+const (
+	CapViewClass      Capability = "view_class"      // 查看学生名单、作业、提交、成绩册和统计
+	CapGrade          Capability = "grade"           // 批改、调整分数和批注、处理成绩申诉
+	CapViewChats      Capability = "view_chats"      // 查看学生的答疑记录
+	CapManageStudents Capability = "manage_students" // 添加、导入和移除学生
+	CapPublish        Capability = "publish"         // 发布和撤回作业，设置截止时间、迟交规则和个人延期
+	CapEditAssignment Capability = "edit_assignment" // 修改作业的题目和设置
+	CapManageStaff    Capability = "manage_staff"    // 邀请和移除合作教师、助教
+	CapDeleteClass    Capability = "delete_class"
+	CapManageClass    Capability = "manage_class" // 设置课程和学期、归档班级
+
+	// CapDeleteAssignment 删除作业会影响发布到的所有班级，只有作业的创建者拥有
+	CapDeleteAssignment Capability = "delete_assignment"
+)
+
+// ErrForbidden 用户没有执行该操作的权限
+var ErrForbidden = errors.New("无权执行该操作")
+
+// ErrArchived 班级已归档，只能查看，不能修改
+var ErrArchived = errors.New("班级已归档，只能查看")
+
+// roleCapabilities 每种班级角色拥有的权限
+var roleCapabilities = map[string][]Capability{
+	model.StaffOwner: {
+		CapViewClass, CapGrade, CapViewChats, CapManageStudents, CapPublish,
+		CapEditAssignment, CapManageStaff, CapDeleteClass, CapManageClass,
+	},
+	model.StaffCoTeacher: {
+		CapViewClass, CapGrade, CapViewChats, CapManageStudents, CapPublish, CapEditAssignment,
+	},
+	model.StaffTA: {
+		CapViewClass, CapGrade, CapViewChats,
+	},
+}
+
+// archivedCapabilities 班级归档后仍然可以使用的权限
+var archivedCapabilities = map[Capability]bool{
+	CapViewClass:   true,
+	CapViewChats:   true,
+	CapManageStaff: true,
+	CapDeleteClass: true,
+	CapManageClass: true,
+}
+
+// capabilityLabels 权限不足时提示的操作名称
+var capabilityLabels = map[Capability]string{
+	CapViewClass:        "查看班级",
+	CapGrade:            "批改作业",
+	CapViewChats:        "查看答疑记录",
+	CapManageStudents:   "管理学生",
+	CapPublish:          "发布作业",
+	CapEditAssignment:   "修改作业",
+	CapManageStaff:      "管理教学人员",
+	CapDeleteClass:      "删除班级",
+	CapManageClass:      "管理班级设置",
+	CapDeleteAssignment: "删除作业",
+}
+
+func roleHas(role string, capability Capability) bool {
+	for _, c := range roleCapabilities[role] {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func forbidden(capability Capability) error {
+	return fmt.Errorf("%w：%s", ErrForbidden, capabilityLabels[capability])
+}
+
+// AuthorizationService 统一校验教师、合作教师和助教对班级、作业和学生的操作权限
+type AuthorizationService struct {
+	classRepo           repository.ClassRepository
+	staffRepo           repository.ClassStaffRepository
+	memberRepo          repository.ClassMemberRepository
+	assignRepo          repository.AssignmentRepository
+	assignmentClassRepo repository.AssignmentClassRepository
+}
+
+// NewAuthorizationService 创建权限校验服务
+func NewAuthorizationService(
+	classRepo repository.ClassRepository,
+	staffRepo repository.ClassStaffRepository,
+	memberRepo repository.ClassMemberRepository,
+	assignRepo repository.AssignmentRepository,
+	assignmentClassRepo repository.AssignmentClassRepository,
+) IAuthorizationService {
+	return &AuthorizationService{
+		classRepo:           classRepo,
+		staffRepo:           staffRepo,
+		memberRepo:          memberRepo,
+		assignRepo:          assignRepo,
+		assignmentClassRepo: assignmentClassRepo,
+	}
+}
+
+// ClassRole 返回用户在班级中的角色，不是班级的教学人员时返回空字符串
+func (s *AuthorizationService) ClassRole(userID string, class *model.Class) (string, error) {
+	if class.TeacherID == userID {
+		return model.StaffOwner, nil
+	}
+	staff, err := s.staffRepo.GetByClassAndUser(class.ID, userID)
+	if err != nil {
+		return "", err
+	}
+	if staff == nil || staff.Status != model.StaffActive {
+		return "", nil
+	}
+	return staff.Role, nil
+}
+
+// ClassCapabilities 返回用户在班级中的角色和拥有的权限，供前端决定显示哪些操作
+func (s *AuthorizationService) ClassCapabilities(userID, classID string) (string, []Capability, error) {
+	class, err := s.classRepo.GetByID(classID)
+	if err != nil {
+		return "", nil, errors.New("班级不存在")
+	}
+	role, err := s.ClassRole(userID, class)
+	if err != nil {
+		return "", nil, err
+	}
+	if class.ArchivedAt == nil {
+		return role, roleCapabilities[role], nil
+	}
+	var capabilities []Capability
+	for _, c := range roleCapabilities[role] {
+		if archivedCapabilities[c] {
+			capabilities = append(capabilities, c)
+		}
+	}
+	return role, capabilities, nil
+}
+
+// AuthorizeClass 校验用户在班级中拥有该权限，返回班级
+func (s *AuthorizationService) AuthorizeClass(userID, classID string, capability Capability) (*model.Class, error) {
+	class, err := s.classRepo.GetByID(classID)
+	if err != nil {
+		return nil, errors.New("班级不存在")
+	}
+	role, err := s.ClassRole(userID, class)
+	if err != nil {
+		return nil, err
+	}
+	if !roleHas(role, capability) {
+		return nil, forbidden(capability)
+	}
+	if class.ArchivedAt != nil && !archivedCapabilities[capability] {
+		return nil, ErrArchived
+	}
+	return class, nil
+}
+
+// AuthorizeAssignment 校验用户对作业拥有该权限，返回作业。作业的创建者拥有全部权限，
+// 但发布到的班级都已归档时同样只能查看；其他教学人员的权限来自作业发布到的班级中的角色
+func (s *AuthorizationService) AuthorizeAssignment(userID, assignID string, capability Capability) (*model.Assignment, error) {
+	assign, err := s.assignRepo.GetByID(assignID)
+	if err != nil {
+		return nil, errors.New("作业不存在")
+	}
+
+	acs, err := s.assignmentClassRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, err
+	}
+	classIDs := make([]string, 0, len(acs)+1)
+	for _, ac := range acs {
+		classIDs = append(classIDs, ac.ClassID)
+	}
+	// 直接设置 class_id 的旧作业没有发布记录
+	if assign.ClassID != nil {
+		classIDs = append(classIDs, *assign.ClassID)
+	}
+
+	if assign.TeacherID == userID {
+		if !archivedCapabilities[capability] {
+			archived, err := s.allArchived(classIDs)
+			if err != nil {
+				return nil, err
+			}
+			if archived {
+				return nil, ErrArchived
+			}
+		}
+		return assign, nil
+	}
+	if capability == CapDeleteAssignment {
+		return nil, forbidden(capability)
+	}
+
+	ok, err := s.anyClassGrants(userID, classIDs, capability)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, forbidden(capability)
+	}
+	return assign, nil
+}
+
+// AuthorizeStudent 校验用户在学生当前所在的任一班级中拥有该权限
+func (s *AuthorizationService) AuthorizeStudent(userID, studentID string, capability Capability) error {
+	classIDs, err := studentClassIDs(s.memberRepo, studentID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.anyClassGrants(userID, classIDs, capability)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return forbidden(capability)
+	}
+	return nil
+}
+
+// StaffClasses 获取用户创建的班级以及作为合作教师、助教加入的班级
+func (s *AuthorizationService) StaffClasses(userID string) ([]model.Class, error) {
+	classes, err := s.classRepo.GetByTeacherID(userID)
+	if err != nil {
+		return nil, err
+	}
+	staff, err := s.staffRepo.GetByUserID(userID, []string{model.StaffActive})
+	if err != nil {
+		return nil, err
+	}
+	if len(staff) == 0 {
+		return classes, nil
+	}
+	classIDs := make([]string, len(staff))
+	for i, st := range staff {
+		classIDs[i] = st.ClassID
+	}
+	staffed, err := s.classRepo.GetByIDs(classIDs)
+	if err != nil {
+		return nil, err
+	}
+	return append(classes, staffed...), nil
+}
+
+// allArchived 作业发布到的班级是否全部已归档；还没有发布的作业不受归档影响
+func (s *AuthorizationService) allArchived(classIDs []string) (bool, error) {
+	if len(classIDs) == 0 {
+		return false, nil
+	}
+	classes, err := s.classRepo.GetByIDs(classIDs)
+	if err != nil {
+		return false, err
+	}
+	for _, class := range classes {
+		if class.ArchivedAt == nil {
+			return false, nil
+		}
+	}
+	return len(classes) > 0, nil
+}
+
+// anyClassGrants 判断用户在任一班级中拥有该权限，已归档的班级只授予查看类权限
+func (s *AuthorizationService) anyClassGrants(userID string, classIDs []string, capability Capability) (bool, error) {
+	if len(classIDs) == 0 {
+		return false, nil
+	}
+	classes, err := s.classRepo.GetByIDs(classIDs)
+	if err != nil {
+		return false, err
+	}
+	for i := range classes {
+		if classes[i].ArchivedAt != nil && !archivedCapabilities[capability] {
+			continue
+		}
+		role, err := s.ClassRole(userID, &classes[i])
+		if err != nil {
+			return false, err
+		}
+		if roleHas(role, capability) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"GoCodeMentor/internal/model"
+)
+
+// ArchiveClass 归档班级：班级变为只读，学生不能再加入和提交，教师不能再发布作业，
+// 成绩和答疑记录仍可查看
+func (s *ClassService) ArchiveClass(userID, classID string) (*model.Class, error) {
+	class, err := s.authz.AuthorizeClass(userID, classID, CapManageClass)
+	if err != nil {
+		return nil, err
+	}
+	if class.ArchivedAt != nil {
+		return nil, errors.New("班级已归档")
+	}
+
+	now := time.Now()
+	class.ArchivedAt = &now
+	class.UpdatedAt = now
+	if err := s.classRepo.Update(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// UnarchiveClass 取消归档，恢复班级的正常使用
+func (s *ClassService) UnarchiveClass(userID, classID string) (*model.Class, error) {
+	class, err := s.authz.AuthorizeClass(userID, classID, CapManageClass)
+	if err != nil {
+		return nil, err
+	}
+	if class.ArchivedAt == nil {
+		return nil, errors.New("班级未归档")
+	}
+
+	class.ArchivedAt = nil
+	class.UpdatedAt = time.Now()
+	if err := s.classRepo.Update(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// 自动分组方式
+const (
+	GroupModeRandom   = "random"   // 随机分组
+	GroupModeBalanced = "balanced" // 按以往平均分均衡分组
+)
+
+// GroupStudent 小组中的学生
+type GroupStudent struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+// ClassGroupView 班级中的一个小组及其成员
+type ClassGroupView struct {
+	ID      string         `json:"id"`
+	Name    string         `json:"name"`
+	Members []GroupStudent `json:"members"`
+}
+
+// ClassGroups 班级的全部小组，以及还没有分组的学生
+type ClassGroups struct {
+	ClassID   string           `json:"class_id"`
+	Groups    []ClassGroupView `json:"groups"`
+	Ungrouped []GroupStudent   `json:"ungrouped"`
+}
+
+// GetGroups 获取班级的小组和组员；已离开班级的学生不再显示
+func (s *ClassService) GetGroups(userID, classID string) (*ClassGroups, error) {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapViewClass); err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groupRepo.GetMembersByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.userRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupOf := make(map[string]string, len(members))
+	for _, m := range members {
+		groupOf[m.StudentID] = m.GroupID
+	}
+	result := &ClassGroups{
+		ClassID:   classID,
+		Groups:    make([]ClassGroupView, 0, len(groups)),
+		Ungrouped: []GroupStudent{},
+	}
+	index := make(map[string]int, len(groups))
+	for i, g := range groups {
+		index[g.ID] = i
+		result.Groups = append(result.Groups, ClassGroupView{ID: g.ID, Name: g.Name, Members: []GroupStudent{}})
+	}
+	for _, student := range students {
+		gs := GroupStudent{ID: student.ID, Name: student.Name, Username: student.Username}
+		if i, ok := index[groupOf[student.ID]]; ok {
+			result.Groups[i].Members = append(result.Groups[i].Members, gs)
+		} else {
+			result.Ungrouped = append(result.Ungrouped, gs)
+		}
+	}
+	return result, nil
+}
+
+// CreateGroup 在班级中创建一个空的小组
+func (s *ClassService) CreateGroup(userID, classID, name string) (*model.ClassGroup, error) {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("小组名称不能为空")
+	}
+	now := time.Now()
+	group := &model.ClassGroup{
+		ID:        uuid.New().String(),
+		ClassID:   classID,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// RenameGroup 修改小组名称
+func (s *ClassService) RenameGroup(userID, classID, groupID, name string) (*model.ClassGroup, error) {
+	group, err := s.classGroup(userID, classID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("小组名称不能为空")
+	}
+	group.Name = name
+	group.UpdatedAt = time.Now()
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroup 删除小组，组员变为未分组；已经产生的小组提交不受影响
+func (s *ClassService) DeleteGroup(userID, classID, groupID string) error {
+	if _, err := s.classGroup(userID, classID, groupID); err != nil {
+		return err
+	}
+	return s.groupRepo.Delete(groupID)
+}
+
+// SetStudentGroup 把学生移入小组，groupID 为空时把学生移出所在小组
+func (s *ClassService) SetStudentGroup(userID, classID, studentID, groupID string) error {
+	if groupID == "" {
+		if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents); err != nil {
+			return err
+		}
+		return s.groupRepo.DeleteMember(classID, studentID)
+	}
+	if _, err := s.classGroup(userID, classID, groupID); err != nil {
+		return err
+	}
+	if !isClassMember(s.memberRepo, classID, studentID) {
+		return errors.New("学生不在该班级")
+	}
+	return s.groupRepo.SaveMember(&model.ClassGroupMember{
+		ID:        uuid.New().String(),
+		GroupID:   groupID,
+		ClassID:   classID,
+		StudentID: studentID,
+		CreatedAt: time.Now(),
+	})
+}
+
+// GenerateGroups 按组数或每组人数自动分组并替换班级现有的小组。
+// random 随机分组；balanced 按学生以往已批改作业的平均分蛇形分配，使各组水平接近
+func (s *ClassService) GenerateGroups(userID, classID string, req dto.GenerateGroupsRequest) (*ClassGroups, error) {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents); err != nil {
+		return nil, err
+	}
+	if req.Mode == "" {
+		req.Mode = GroupModeRandom
+	}
+	if req.Mode != GroupModeRandom && req.Mode != GroupModeBalanced {
+		return nil, errors.New("分组方式只能是 random 或 balanced")
+	}
+	students, err := s.userRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if len(students) == 0 {
+		return nil, errors.New("班级暂无学生，无法分组")
+	}
+
+	count := req.GroupCount
+	if count <= 0 && req.GroupSize > 0 {
+		count = (len(students) + req.GroupSize - 1) / req.GroupSize
+	}
+	if count <= 0 {
+		return nil, errors.New("请设置小组数量或每组人数")
+	}
+	if count > len(students) {
+		return nil, fmt.Errorf("小组数量不能超过班级人数 %d", len(students))
+	}
+
+	rand.Shuffle(len(students), func(i, j int) { students[i], students[j] = students[j], students[i] })
+	if req.Mode == GroupModeBalanced {
+		averages, err := s.averageScores(classID)
+		if err != nil {
+			return nil, err
+		}
+		// 打乱后稳定排序，平均分相同（含没有成绩）的学生之间仍是随机顺序
+		sort.SliceStable(students, func(i, j int) bool {
+			return averages[students[i].ID] > averages[students[j].ID]
+		})
+	}
+
+	now := time.Now()
+	groups := make([]model.ClassGroup, count)
+	for i := range groups {
+		groups[i] = model.ClassGroup{
+			ID:        uuid.New().String(),
+			ClassID:   classID,
+			Name:      fmt.Sprintf("第%d组", i+1),
+			CreatedAt: now.Add(time.Duration(i) * time.Millisecond), // 保持小组按编号排序
+			UpdatedAt: now,
+		}
+	}
+	members := make([]model.ClassGroupMember, 0, len(students))
+	for i, student := range students {
+		// 蛇形分配：1..n, n..1, 1..n ...，随机分组时顺序本身是随机的
+		slot := i % count
+		if (i/count)%2 == 1 {
+			slot = count - 1 - slot
+		}
+		members = append(members, model.ClassGroupMember{
+			ID:        uuid.New().String(),
+			GroupID:   groups[slot].ID,
+			ClassID:   classID,
+			StudentID: student.ID,
+			CreatedAt: now,
+		})
+	}
+	if err := s.groupRepo.Replace(classID, groups, members); err != nil {
+		return nil, err
+	}
+	return s.GetGroups(userID, classID)
+}
+
+// averageScores 学生在班级已批改作业上的平均得分
+func (s *ClassService) averageScores(classID string) (map[string]float64, error) {
+	assignments, err := s.assignmentRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return map[string]float64{}, nil
+	}
+	ids := make([]string, len(assignments))
+	for i, a := range assignments {
+		ids[i] = a.ID
+	}
+	submissions, err := s.submissionRepo.GetByAssignmentIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]int)
+	counts := make(map[string]int)
+	for _, sub := range submissions {
+		if sub.Status != "graded" || sub.TotalScore == nil {
+			continue
+		}
+		totals[sub.StudentID] += *sub.TotalScore
+		counts[sub.StudentID]++
+	}
+	averages := make(map[string]float64, len(totals))
+	for id, total := range totals {
+		averages[id] = float64(total) / float64(counts[id])
+	}
+	return averages, nil
+}
+
+// classGroup 校验管理权限并获取班级中的小组
+func (s *ClassService) classGroup(userID, classID, groupID string) (*model.ClassGroup, error) {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents); err != nil {
+		return nil, err
+	}
+	group, err := s.groupRepo.GetByID(groupID)
+	if err != nil || group.ClassID != classID {
+		return nil, errors.New("小组不存在")
+	}
+	return group, nil
+}
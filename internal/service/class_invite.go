@@ -0,0 +1,147 @@
+package service
+
+import (
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxCodeAttempts 生成不重复邀请码的最大尝试次数
+const maxCodeAttempts = 10
+
+// uniqueClassCode 生成一个没有被其他班级使用的邀请码
+func (s *ClassService) uniqueClassCode() (string, error) {
+	for i := 0; i < maxCodeAttempts; i++ {
+		code := generateClassCode()
+		if _, err := s.classRepo.GetByCode(code); err != nil {
+			return code, nil
+		}
+	}
+	return "", errors.New("生成邀请码失败，请重试")
+}
+
+// checkInviteCode 检查邀请码是否仍可使用
+func checkInviteCode(class *model.Class, now time.Time) error {
+	if class.CodeRevoked {
+		return errors.New("邀请码已停用")
+	}
+	if class.CodeExpiresAt != nil && !now.Before(*class.CodeExpiresAt) {
+		return errors.New("邀请码已过期")
+	}
+	if class.CodeMaxUses > 0 && class.CodeUses >= class.CodeMaxUses {
+		return errors.New("邀请码使用次数已达上限")
+	}
+	return nil
+}
+
+// UpdateInviteSettings 设置邀请码的过期时间、使用次数上限以及是否需要审批
+func (s *ClassService) UpdateInviteSettings(userID, classID string, req dto.InviteCodeSettingsRequest) (*model.Class, error) {
+	class, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents)
+	if err != nil {
+		return nil, err
+	}
+	if req.MaxUses < 0 {
+		return nil, errors.New("使用次数上限不能为负数")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("过期时间必须晚于当前时间")
+	}
+
+	class.CodeExpiresAt = req.ExpiresAt
+	class.CodeMaxUses = req.MaxUses
+	class.RequireApproval = req.RequireApproval
+	class.UpdatedAt = time.Now()
+	if err := s.classRepo.Update(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// RegenerateInviteCode 生成新的邀请码，旧邀请码立即失效，使用次数清零并取消停用
+func (s *ClassService) RegenerateInviteCode(userID, classID string) (*model.Class, error) {
+	class, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents)
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.uniqueClassCode()
+	if err != nil {
+		return nil, err
+	}
+
+	class.Code = code
+	class.CodeUses = 0
+	class.CodeRevoked = false
+	class.UpdatedAt = time.Now()
+	if err := s.classRepo.Update(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// RevokeInviteCode 停用邀请码，重新生成后才能再次凭邀请码加入
+func (s *ClassService) RevokeInviteCode(userID, classID string) (*model.Class, error) {
+	class, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents)
+	if err != nil {
+		return nil, err
+	}
+
+	class.CodeRevoked = true
+	class.UpdatedAt = time.Now()
+	if err := s.classRepo.Update(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// GetJoinRequests 获取班级的加入申请，statuses 为空时返回全部状态
+func (s *ClassService) GetJoinRequests(userID, classID string, statuses []string) ([]model.ClassJoinRequest, error) {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents); err != nil {
+		return nil, err
+	}
+	return s.joinRepo.GetByClassID(classID, statuses)
+}
+
+// GetMyJoinRequests 获取学生提交的全部加入申请
+func (s *ClassService) GetMyJoinRequests(studentID string) ([]model.ClassJoinRequest, error) {
+	return s.joinRepo.GetByStudentID(studentID)
+}
+
+// ReviewJoinRequest 通过或拒绝学生的加入申请，通过后学生作为正式学生加入班级
+func (s *ClassService) ReviewJoinRequest(userID, classID, requestID string, approve bool) error {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStudents); err != nil {
+		return err
+	}
+	request, err := s.joinRepo.GetByID(requestID)
+	if err != nil || request.ClassID != classID {
+		return errors.New("申请不存在")
+	}
+	if request.Status != model.JoinPending {
+		return errors.New("申请已处理")
+	}
+
+	now := time.Now()
+	request.Status = model.JoinRejected
+	if approve {
+		request.Status = model.JoinApproved
+	}
+	request.ReviewedBy = userID
+	request.ReviewedAt = &now
+	request.UpdatedAt = now
+	ok, err := s.joinRepo.UpdateStatus(request)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("申请已处理")
+	}
+
+	if !approve || isClassMember(s.memberRepo, classID, request.StudentID) {
+		return nil
+	}
+	if err := s.addMember(classID, request.StudentID, model.ClassRoleStudent); err != nil {
+		return fmt.Errorf("添加学生失败: %w", err)
+	}
+	return nil
+}
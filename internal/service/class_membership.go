@@ -0,0 +1,25 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+)
+
+// studentClassIDs 获取学生当前所在的全部班级 ID，按加入时间升序
+func studentClassIDs(memberRepo repository.ClassMemberRepository, studentID string) ([]string, error) {
+	members, err := memberRepo.GetByStudentID(studentID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.ClassID
+	}
+	return ids, nil
+}
+
+// isClassMember 判断学生当前是否在班级中
+func isClassMember(memberRepo repository.ClassMemberRepository, classID, studentID string) bool {
+	member, err := memberRepo.GetByClassAndStudent(classID, studentID)
+	return err == nil && member != nil && member.Status == model.MemberActive
+}
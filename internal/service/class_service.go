@@ -2,7 +2,7 @@ package service
 
 import (
 	"GoCodeMentor/internal/model"
-	"GoCodeMentor/internal/pkg/siliconflow"
+	"GoCodeMentor/internal/pkg/llm"
 	"GoCodeMentor/internal/repository"
 	"context"
 	"encoding/json"
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,20 +18,30 @@ import (
 
 type ClassService struct {
 	classRepo      repository.ClassRepository
+	memberRepo     repository.ClassMemberRepository
+	staffRepo      repository.ClassStaffRepository
+	joinRepo       repository.ClassJoinRequestRepository
+	groupRepo      repository.ClassGroupRepository
 	userRepo       repository.UserRepository
 	assignmentRepo repository.AssignmentRepository
 	submissionRepo repository.SubmissionRepository
-	sfClient       *siliconflow.Client
+	analyzer       llm.LLMProvider
+	authz          IAuthorizationService
 }
 
-func NewClassService(classRepo repository.ClassRepository, userRepo repository.UserRepository, assignmentRepo repository.AssignmentRepository, submissionRepo repository.SubmissionRepository, sfClient *siliconflow.Client) IClassService {
+func NewClassService(classRepo repository.ClassRepository, memberRepo repository.ClassMemberRepository, staffRepo repository.ClassStaffRepository, joinRepo repository.ClassJoinRequestRepository, groupRepo repository.ClassGroupRepository, userRepo repository.UserRepository, assignmentRepo repository.AssignmentRepository, submissionRepo repository.SubmissionRepository, analyzer llm.LLMProvider, authz IAuthorizationService) IClassService {
 	rand.Seed(time.Now().UnixNano()) // 全局初始化一次随机数种子
 	return &ClassService{
 		classRepo:      classRepo,
+		memberRepo:     memberRepo,
+		staffRepo:      staffRepo,
+		joinRepo:       joinRepo,
+		groupRepo:      groupRepo,
 		userRepo:       userRepo,
 		assignmentRepo: assignmentRepo,
 		submissionRepo: submissionRepo,
-		sfClient:       sfClient,
+		analyzer:       analyzer,
+		authz:          authz,
 	}
 }
 
@@ -41,11 +52,15 @@ func generateClassCode() string {
 
 // CreateClass 创建班级
 func (s *ClassService) CreateClass(name, teacherID string) (*model.Class, error) {
+	code, err := s.uniqueClassCode()
+	if err != nil {
+		return nil, err
+	}
 	class := &model.Class{
 		ID:        uuid.New().String(),
 		Name:      name,
 		TeacherID: teacherID,
-		Code:      generateClassCode(),
+		Code:      code,
 	}
 
 	if err := s.classRepo.Create(class); err != nil {
@@ -60,9 +75,9 @@ func (s *ClassService) GetClassByID(id string) (*model.Class, error) {
 	return s.classRepo.GetByID(id)
 }
 
-// GetClassesByTeacherID 获取教师创建的所有班级
+// GetClassesByTeacherID 获取教师创建的以及作为合作教师、助教加入的所有班级
 func (s *ClassService) GetClassesByTeacherID(teacherID string) ([]model.Class, error) {
-	classes, err := s.classRepo.GetByTeacherID(teacherID)
+	classes, err := s.authz.StaffClasses(teacherID)
 	if err != nil {
 		return nil, err
 	}
@@ -77,24 +92,63 @@ func (s *ClassService) GetClassByCode(code string) (*model.Class, error) {
 	return s.classRepo.GetByCode(code)
 }
 
-// JoinClass 学生加入班级
-func (s *ClassService) JoinClass(studentID, code string) error {
-	class, err := s.classRepo.GetByCode(code)
+// JoinClass 学生凭邀请码加入班级，已在其他班级中的学生同时保留原来的班级。
+// 班级开启审批时只提交申请，返回 pending 为 true
+func (s *ClassService) JoinClass(studentID, code string) (pending bool, err error) {
+	class, err := s.classRepo.GetByCode(strings.TrimSpace(code))
 	if err != nil {
-		return err
+		return false, errors.New("邀请码无效")
+	}
+	if class.ArchivedAt != nil {
+		return false, errors.New("班级已归档，不能加入")
+	}
+	now := time.Now()
+	if err := checkInviteCode(class, now); err != nil {
+		return false, err
 	}
 
 	user, err := s.userRepo.GetByID(studentID)
+	if err != nil || user.Role != "student" {
+		return false, errors.New("学生不存在")
+	}
+
+	if isClassMember(s.memberRepo, class.ID, user.ID) {
+		return false, errors.New("你已经在该班级中")
+	}
+	if class.RequireApproval {
+		existing, err := s.joinRepo.GetByClassAndStudent(class.ID, user.ID)
+		if err != nil {
+			return false, err
+		}
+		if existing != nil && existing.Status == model.JoinPending {
+			return false, errors.New("已提交申请，请等待教师审批")
+		}
+	}
+
+	// 使用次数在加入或提交申请时计算，并发时以数据库中的条件更新为准
+	ok, err := s.classRepo.ConsumeCode(class.ID, now)
 	if err != nil {
-		return errors.New("学生不存在")
+		return false, err
+	}
+	if !ok {
+		return false, errors.New("邀请码已失效")
 	}
 
-	user.ClassID = &class.ID
-	return s.userRepo.Update(user)
+	if class.RequireApproval {
+		return true, s.joinRepo.Save(&model.ClassJoinRequest{
+			ID:        uuid.New().String(),
+			ClassID:   class.ID,
+			StudentID: user.ID,
+			Status:    model.JoinPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return false, s.addMember(class.ID, user.ID, model.ClassRoleStudent)
 }
 
-// AddStudentToClass 教师添加学生到班级
-func (s *ClassService) AddStudentToClass(studentID, classID string) error {
+// AddStudentToClass 教师添加学生到班级，role 为空时作为正式学生加入
+func (s *ClassService) AddStudentToClass(studentID, classID, role string) error {
 	// 验证班级存在
 	if _, err := s.classRepo.GetByID(classID); err != nil {
 		return errors.New("班级不存在")
@@ -110,26 +164,54 @@ func (s *ClassService) AddStudentToClass(studentID, classID string) error {
 		return errors.New("该用户不是学生")
 	}
 
-	// 更新学生的班级ID
-	user.ClassID = &classID
-	return s.userRepo.Update(user)
+	switch role {
+	case "":
+		role = model.ClassRoleStudent
+	case model.ClassRoleStudent, model.ClassRoleAuditor:
+	default:
+		return errors.New("无效的班级身份")
+	}
+	return s.addMember(classID, studentID, role)
 }
 
-// RemoveStudentFromClass 教师从班级移除学生
+// addMember 创建学生的成员关系；之前离开过该班级的学生恢复原记录并更新加入时间
+func (s *ClassService) addMember(classID, studentID, role string) error {
+	now := time.Now()
+	return s.memberRepo.Save(&model.ClassMember{
+		ID:        uuid.New().String(),
+		ClassID:   classID,
+		StudentID: studentID,
+		Role:      role,
+		Status:    model.MemberActive,
+		JoinedAt:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// RemoveStudentFromClass 教师从班级移除学生，学生在其他班级中的成员关系不受影响
 func (s *ClassService) RemoveStudentFromClass(studentID, classID string) error {
-	// 验证学生确实在这个班级
-	user, err := s.userRepo.GetByID(studentID)
+	ok, err := s.memberRepo.Remove(classID, studentID, time.Now())
 	if err != nil {
-		return errors.New("学生不存在")
+		return err
 	}
-
-	if user.ClassID == nil || *user.ClassID != classID {
+	if !ok {
 		return errors.New("学生不在该班级")
 	}
+	// 离开班级的学生同时退出所在小组
+	return s.groupRepo.DeleteMember(classID, studentID)
+}
 
-	// 清空学生的班级ID
-	user.ClassID = nil
-	return s.userRepo.Update(user)
+// GetStudentClasses 获取学生当前所在的全部班级
+func (s *ClassService) GetStudentClasses(studentID string) ([]model.Class, error) {
+	classIDs, err := studentClassIDs(s.memberRepo, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(classIDs) == 0 {
+		return []model.Class{}, nil
+	}
+	return s.classRepo.GetByIDs(classIDs)
 }
 
 // DeleteClass 删除班级（只有教师可以删除自己创建的班级）
@@ -140,11 +222,15 @@ func (s *ClassService) DeleteClass(classID string) error {
 	}
 
 	// 注意：这里我们依赖上层handler已经验证了教师权限
-	// 删除班级前，需要先删除所有学生用户记录
+	// 删除班级前，需要先删除只属于该班级的学生用户记录，同时在其他班级的学生只移出本班
 	students, err := s.userRepo.GetByClassID(classID)
 	if err == nil && len(students) > 0 {
 		// 批量删除学生用户记录
 		for _, student := range students {
+			classIDs, err := studentClassIDs(s.memberRepo, student.ID)
+			if err != nil || len(classIDs) > 1 {
+				continue
+			}
 			if err := s.userRepo.Delete(&student); err != nil {
 				// 如果删除失败，记录错误但继续尝试
 				fmt.Printf("删除学生%s失败: %v\n", student.ID, err)
@@ -152,6 +238,19 @@ func (s *ClassService) DeleteClass(classID string) error {
 		}
 	}
 
+	if err := s.memberRepo.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := s.staffRepo.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := s.joinRepo.DeleteByClassID(classID); err != nil {
+		return err
+	}
+	if err := s.groupRepo.DeleteByClassID(classID); err != nil {
+		return err
+	}
+
 	// 删除班级记录
 	return s.classRepo.Delete(classID)
 }
@@ -260,12 +359,7 @@ func (s *ClassService) GenerateClassAnalysisReport(ctx context.Context, classID
 
 	userPrompt := fmt.Sprintf("请为以下班级生成学情分析报告：\n\n%s", string(jsonData))
 
-	messages := []siliconflow.Message{
-		{Role: "system", Content: string(systemPrompt)},
-		{Role: "user", Content: userPrompt},
-	}
-
-	report, err := s.sfClient.ChatWithHistory(ctx, messages)
+	report, err := s.analyzer.Chat(ctx, string(systemPrompt), userPrompt)
 	if err != nil {
 		return "", fmt.Errorf("AI生成报告失败: %w", err)
 	}
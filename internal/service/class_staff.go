@@ -0,0 +1,140 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InviteStaff 班级所有者按用户名邀请教师作为合作教师或助教，对方接受后生效；
+// 已邀请但尚未接受时更新邀请的角色
+func (s *ClassService) InviteStaff(ownerID, classID, username, role string) (*model.ClassStaff, error) {
+	class, err := s.authz.AuthorizeClass(ownerID, classID, CapManageStaff)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStaffRole(role); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUsername(strings.TrimSpace(username))
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Role != "teacher" {
+		return nil, errors.New("只能邀请教师账号")
+	}
+	if user.ID == class.TeacherID {
+		return nil, errors.New("不能邀请班级所有者")
+	}
+
+	existing, err := s.staffRepo.GetByClassAndUser(classID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Status == model.StaffActive {
+		return nil, errors.New("该用户已是班级的教学人员")
+	}
+
+	now := time.Now()
+	staff := &model.ClassStaff{
+		ID:        uuid.New().String(),
+		ClassID:   classID,
+		UserID:    user.ID,
+		Role:      role,
+		Status:    model.StaffInvited,
+		InvitedBy: ownerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if existing != nil {
+		staff.ID = existing.ID
+		staff.CreatedAt = existing.CreatedAt
+	}
+	if err := s.staffRepo.Save(staff); err != nil {
+		return nil, err
+	}
+	return staff, nil
+}
+
+// GetClassStaff 获取班级的教学人员和待接受的邀请
+func (s *ClassService) GetClassStaff(userID, classID string) ([]model.ClassStaff, error) {
+	if _, err := s.authz.AuthorizeClass(userID, classID, CapViewClass); err != nil {
+		return nil, err
+	}
+	return s.staffRepo.GetByClassID(classID)
+}
+
+// GetStaffInvitations 获取用户收到的尚未处理的邀请
+func (s *ClassService) GetStaffInvitations(userID string) ([]model.ClassStaff, error) {
+	return s.staffRepo.GetByUserID(userID, []string{model.StaffInvited})
+}
+
+// RespondStaffInvitation 接受或拒绝邀请
+func (s *ClassService) RespondStaffInvitation(userID, staffID string, accept bool) error {
+	staff, err := s.staffRepo.GetByID(staffID)
+	if err != nil || staff.UserID != userID {
+		return errors.New("邀请不存在")
+	}
+	if staff.Status != model.StaffInvited {
+		return errors.New("邀请已处理")
+	}
+
+	now := time.Now()
+	staff.Status = model.StaffDeclined
+	if accept {
+		staff.Status = model.StaffActive
+	}
+	staff.RespondedAt = &now
+	staff.UpdatedAt = now
+	return s.staffRepo.Update(staff)
+}
+
+// UpdateStaffRole 班级所有者修改合作教师或助教的角色
+func (s *ClassService) UpdateStaffRole(ownerID, classID, staffID, role string) error {
+	staff, err := s.getClassStaff(classID, staffID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authz.AuthorizeClass(ownerID, classID, CapManageStaff); err != nil {
+		return err
+	}
+	if err := validateStaffRole(role); err != nil {
+		return err
+	}
+	staff.Role = role
+	staff.UpdatedAt = time.Now()
+	return s.staffRepo.Update(staff)
+}
+
+// RemoveStaff 班级所有者移除教学人员或撤回邀请，教学人员也可以自己退出
+func (s *ClassService) RemoveStaff(userID, classID, staffID string) error {
+	staff, err := s.getClassStaff(classID, staffID)
+	if err != nil {
+		return err
+	}
+	if staff.UserID != userID {
+		if _, err := s.authz.AuthorizeClass(userID, classID, CapManageStaff); err != nil {
+			return err
+		}
+	}
+	return s.staffRepo.Delete(staff.ID)
+}
+
+func (s *ClassService) getClassStaff(classID, staffID string) (*model.ClassStaff, error) {
+	staff, err := s.staffRepo.GetByID(staffID)
+	if err != nil || staff.ClassID != classID {
+		return nil, errors.New("教学人员不存在")
+	}
+	return staff, nil
+}
+
+func validateStaffRole(role string) error {
+	if role != model.StaffCoTeacher && role != model.StaffTA {
+		return errors.New("角色必须是合作教师（co_teacher）或助教（ta）")
+	}
+	return nil
+}
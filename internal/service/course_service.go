@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// CourseService 管理课程和学期，以及班级所属的课程和学期
+type CourseService struct {
+	courseRepo repository.CourseRepository
+	termRepo   repository.TermRepository
+	classRepo  repository.ClassRepository
+	authz      IAuthorizationService
+}
+
+// NewCourseService 创建课程服务
+func NewCourseService(
+	courseRepo repository.CourseRepository,
+	termRepo repository.TermRepository,
+	classRepo repository.ClassRepository,
+	authz IAuthorizationService,
+) ICourseService {
+	return &CourseService{
+		courseRepo: courseRepo,
+		termRepo:   termRepo,
+		classRepo:  classRepo,
+		authz:      authz,
+	}
+}
+
+// CreateCourse 创建课程
+func (s *CourseService) CreateCourse(teacherID string, req dto.CourseRequest) (*model.Course, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("课程名称不能为空")
+	}
+	now := time.Now()
+	course := &model.Course{
+		ID:          uuid.New().String(),
+		TeacherID:   teacherID,
+		Name:        name,
+		Code:        strings.TrimSpace(req.Code),
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.courseRepo.Create(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// GetCourses 获取教师创建的课程
+func (s *CourseService) GetCourses(teacherID string) ([]model.Course, error) {
+	return s.courseRepo.GetByTeacherID(teacherID)
+}
+
+// UpdateCourse 修改课程信息
+func (s *CourseService) UpdateCourse(teacherID, courseID string, req dto.CourseRequest) (*model.Course, error) {
+	course, err := s.getOwnedCourse(teacherID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("课程名称不能为空")
+	}
+	course.Name = name
+	course.Code = strings.TrimSpace(req.Code)
+	course.Description = req.Description
+	course.UpdatedAt = time.Now()
+	if err := s.courseRepo.Update(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// DeleteCourse 删除课程；课程下还有班级时不能删除
+func (s *CourseService) DeleteCourse(teacherID, courseID string) error {
+	if _, err := s.getOwnedCourse(teacherID, courseID); err != nil {
+		return err
+	}
+	classes, err := s.classRepo.GetByCourseID(courseID)
+	if err != nil {
+		return err
+	}
+	if len(classes) > 0 {
+		return errors.New("课程下还有班级，请先把班级移出课程")
+	}
+	return s.courseRepo.Delete(courseID)
+}
+
+// GetCourseClasses 获取课程下的全部班级（含已归档的班级）
+func (s *CourseService) GetCourseClasses(teacherID, courseID string) ([]model.Class, error) {
+	if _, err := s.getOwnedCourse(teacherID, courseID); err != nil {
+		return nil, err
+	}
+	return s.classRepo.GetByCourseID(courseID)
+}
+
+// CreateTerm 创建学期，学期名称不能重复
+func (s *CourseService) CreateTerm(userID string, req dto.TermRequest) (*model.Term, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("学期名称不能为空")
+	}
+	if req.StartDate != nil && req.EndDate != nil && !req.EndDate.After(*req.StartDate) {
+		return nil, errors.New("学期结束日期必须晚于开始日期")
+	}
+	now := time.Now()
+	term := &model.Term{
+		ID:        uuid.New().String(),
+		Name:      name,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.termRepo.Create(term); err != nil {
+		return nil, errors.New("学期名称已存在")
+	}
+	return term, nil
+}
+
+// GetTerms 获取全部学期，最近的学期在前
+func (s *CourseService) GetTerms() ([]model.Term, error) {
+	return s.termRepo.GetAll()
+}
+
+// SetClassCourse 设置班级所属的课程和学期，传 nil 时清除；课程必须是班级所有者创建的
+func (s *CourseService) SetClassCourse(userID, classID string, req dto.ClassCourseRequest) (*model.Class, error) {
+	class, err := s.authz.AuthorizeClass(userID, classID, CapManageClass)
+	if err != nil {
+		return nil, err
+	}
+	if req.CourseID != nil {
+		course, err := s.courseRepo.GetByID(*req.CourseID)
+		if err != nil || course.TeacherID != class.TeacherID {
+			return nil, errors.New("课程不存在")
+		}
+	}
+	if req.TermID != nil {
+		if _, err := s.termRepo.GetByID(*req.TermID); err != nil {
+			return nil, errors.New("学期不存在")
+		}
+	}
+
+	class.CourseID = req.CourseID
+	class.TermID = req.TermID
+	class.UpdatedAt = time.Now()
+	if err := s.classRepo.Update(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+func (s *CourseService) getOwnedCourse(teacherID, courseID string) (*model.Course, error) {
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil || course.TeacherID != teacherID {
+		return nil, errors.New("课程不存在")
+	}
+	return course, nil
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ExamService 考试服务：限时作答、乱序试卷、答案自动保存和超时自动交卷
+type ExamService struct {
+	*SubmissionStore
+	examSessionRepo repository.ExamSessionRepository
+	authz           IAuthorizationService
+}
+
+// NewExamService 创建考试服务
+func NewExamService(store *SubmissionStore, examSessionRepo repository.ExamSessionRepository, authz IAuthorizationService) IExamService {
+	return &ExamService{SubmissionStore: store, examSessionRepo: examSessionRepo, authz: authz}
+}
+
+// examGracePeriod 考试结束后仍接受交卷的时间，用于抵消网络延迟
+const examGracePeriod = 30 * time.Second
+
+// ExamQuestion 学生在考试中看到的题目：不含答案，选项为该学生的乱序结果
+type ExamQuestion struct {
+	ID      string   `json:"id"`
+	Number  int      `json:"number"` // 该学生看到的题号，从 1 开始
+	Type    string   `json:"type"`
+	Content string   `json:"content"`
+	Options []string `json:"options,omitempty"`
+	Score   int      `json:"score"`
+}
+
+// ExamView 学生的考试页面数据
+type ExamView struct {
+	AssignmentID     string            `json:"assignment_id"`
+	Title            string            `json:"title"`
+	Description      string            `json:"description"`
+	DurationMinutes  int               `json:"duration_minutes"`
+	Status           string            `json:"status"`
+	StartedAt        time.Time         `json:"started_at"`
+	EndsAt           time.Time         `json:"ends_at"`
+	RemainingSeconds int               `json:"remaining_seconds"`
+	LastSavedAt      *time.Time        `json:"last_saved_at"`
+	SubmissionID     *string           `json:"submission_id"`
+	Questions        []ExamQuestion    `json:"questions,omitempty"` // 只在作答中返回
+	Answers          map[string]string `json:"answers"`             // 按该学生看到的选项字母
+	Code             string            `json:"code"`
+}
+
+// examItem 一道题在某个学生试卷中的呈现方式
+type examItem struct {
+	question model.Question
+	original []string // 原始选项
+	options  []string // 学生看到的选项
+	perm     []int    // options[i] 对应 original[perm[i]]
+}
+
+// examLayout 按种子确定学生看到的题目顺序和选项顺序；同一种子总是得到相同结果，交卷时据此还原原始选项
+func examLayout(assign *model.Assignment, questions []model.Question, seed int64) []examItem {
+	ordered := append([]model.Question(nil), questions...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].OrderNum < ordered[j].OrderNum })
+	if assign.ShuffleQuestions {
+		shuffled := make([]model.Question, len(ordered))
+		for i, p := range rand.New(rand.NewSource(seed)).Perm(len(ordered)) {
+			shuffled[i] = ordered[p]
+		}
+		ordered = shuffled
+	}
+
+	items := make([]examItem, len(ordered))
+	for i, q := range ordered {
+		var options []string
+		json.Unmarshal([]byte(q.Options), &options)
+
+		item := examItem{question: q, original: options, options: options, perm: make([]int, len(options))}
+		for j := range item.perm {
+			item.perm[j] = j
+		}
+		if assign.ShuffleOptions && q.Type == "choice" && len(options) > 1 {
+			// 每道题使用不同的种子，避免所有题目的选项按同样的方式打乱
+			item.perm = rand.New(rand.NewSource(seed ^ questionSeed(q.ID))).Perm(len(options))
+			item.options = make([]string, len(options))
+			for j, p := range item.perm {
+				item.options[j] = relabelOption(options[p], j)
+			}
+		}
+		items[i] = item
+	}
+	return items
+}
+
+func questionSeed(questionID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(questionID))
+	return int64(h.Sum64())
+}
+
+// relabelOption 选项原文带 "A. " 之类的前缀时，按新位置重新编号
+func relabelOption(opt string, index int) string {
+	if !optionLabelRe.MatchString(strings.TrimSpace(opt)) {
+		return opt
+	}
+	return fmt.Sprintf("%c. %s", 'A'+index, stripOptionLabel(opt))
+}
+
+// toCanonical 将学生按乱序选项作答的答案还原为原始选项字母，其他题型原样返回
+func (item examItem) toCanonical(answer string) string {
+	return item.remap(answer, item.options, func(shown int) int { return item.perm[shown] })
+}
+
+// toDisplay 将按原始选项字母保存的答案转换为学生看到的选项字母
+func (item examItem) toDisplay(answer string) string {
+	return item.remap(answer, item.original, func(original int) int {
+		for shown, p := range item.perm {
+			if p == original {
+				return shown
+			}
+		}
+		return original
+	})
+}
+
+func (item examItem) remap(answer string, options []string, mapIndex func(int) int) string {
+	if item.question.Type != "choice" || len(options) == 0 {
+		return answer
+	}
+	letters := choiceLetters(answer, options)
+	if len(letters) == 0 {
+		return answer
+	}
+	mapped := make(map[string]bool, len(letters))
+	for letter := range letters {
+		mapped[string(rune('A'+mapIndex(int(letter[0]-'A'))))] = true
+	}
+	return strings.Join(sortedKeys(mapped), "")
+}
+
+// StartExam 学生开始考试：记录开始时间和乱序种子；已经开始时返回原来的考试
+func (s *ExamService) StartExam(assignID, studentID string) (*ExamView, error) {
+	assign, student, policy, err := s.studentAssignment(assignID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !assign.ExamMode {
+		return nil, errors.New("该作业不是考试")
+	}
+
+	session, err := s.examSessionRepo.GetByAssignmentAndStudent(assignID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		return s.GetExam(assignID, studentID)
+	}
+
+	if existing, err := s.submissionRepo.GetByAssignmentAndStudent(assignID, studentID); err == nil && existing != nil {
+		return nil, errors.New("已经提交过该作业，不能再开始考试")
+	}
+	now := time.Now()
+	if err := policy.check(now); err != nil {
+		return nil, err
+	}
+
+	// 考试时长不能越过该学生的截止时间
+	endsAt := now.Add(time.Duration(assign.DurationMinutes) * time.Minute)
+	if closes := policy.closesAt(); closes != nil && closes.Before(endsAt) {
+		endsAt = *closes
+	}
+
+	session = &model.ExamSession{
+		ID:           uuid.New().String(),
+		AssignmentID: assignID,
+		StudentID:    studentID,
+		StudentName:  student.Name,
+		Seed:         rand.Int63(),
+		StartedAt:    now,
+		EndsAt:       endsAt,
+		SavedAnswers: "{}",
+		Status:       model.ExamInProgress,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.examSessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	return s.examView(assign, session)
+}
+
+// GetExam 获取学生的考试；时间已到但尚未交卷时先自动提交
+func (s *ExamService) GetExam(assignID, studentID string) (*ExamView, error) {
+	assign, session, err := s.examSession(assignID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == model.ExamInProgress && time.Now().After(session.EndsAt.Add(examGracePeriod)) {
+		if _, err := s.finishExam(assign, session, model.ExamAutoSubmitted); err != nil {
+			log.Printf("自动提交考试失败: %v", err)
+		}
+		if assign, session, err = s.examSession(assignID, studentID); err != nil {
+			return nil, err
+		}
+	}
+	return s.examView(assign, session)
+}
+
+// SaveExamAnswers 保存考试答案草稿，时间到后自动提交的就是最后保存的答案
+func (s *ExamService) SaveExamAnswers(assignID, studentID string, answers map[string]string, code string) (time.Time, error) {
+	assign, session, err := s.examSession(assignID, studentID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if session.Status != model.ExamInProgress {
+		return time.Time{}, errors.New("考试已交卷")
+	}
+	now := time.Now()
+	if now.After(session.EndsAt.Add(examGracePeriod)) {
+		return time.Time{}, errors.New("考试时间已到，无法再保存答案")
+	}
+	if err := s.saveExamAnswers(assign, session, answers, code, now); err != nil {
+		return time.Time{}, err
+	}
+	return now, nil
+}
+
+// SubmitExam 学生交卷；超时后不再接受新答案，改为提交最后保存的答案
+func (s *ExamService) SubmitExam(assignID, studentID string, answers map[string]string, code string) (string, error) {
+	assign, session, err := s.examSession(assignID, studentID)
+	if err != nil {
+		return "", err
+	}
+	if session.Status != model.ExamInProgress {
+		return "", errors.New("考试已交卷")
+	}
+
+	now := time.Now()
+	if now.After(session.EndsAt.Add(examGracePeriod)) {
+		if _, err := s.finishExam(assign, session, model.ExamAutoSubmitted); err != nil {
+			return "", err
+		}
+		return "", errors.New("考试时间已到，已自动提交最后保存的答案")
+	}
+	if err := s.saveExamAnswers(assign, session, answers, code, now); err != nil {
+		return "", err
+	}
+	return s.finishExam(assign, session, model.ExamSubmitted)
+}
+
+// GetExamSessions 教师查看作业的考试情况（谁正在考试、剩余时间、最后保存时间）
+func (s *ExamService) GetExamSessions(teacherID, assignID string) ([]model.ExamSession, error) {
+	if _, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapViewClass); err != nil {
+		return nil, err
+	}
+	return s.examSessionRepo.ListByAssignment(assignID)
+}
+
+// UpdateExamSettings 设置作业的考试模式；有学生正在考试时不能修改
+func (s *ExamService) UpdateExamSettings(teacherID, assignID string, req dto.ExamSettingsRequest) error {
+	assign, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapEditAssignment)
+	if err != nil {
+		return err
+	}
+	if req.DurationMinutes < 0 || (req.ExamMode && req.DurationMinutes == 0) {
+		return errors.New("考试时长必须大于 0")
+	}
+
+	sessions, err := s.examSessionRepo.ListByAssignment(assignID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.Status == model.ExamInProgress {
+			return errors.New("有学生正在考试，不能修改考试设置")
+		}
+	}
+
+	assign.ExamMode = req.ExamMode
+	assign.DurationMinutes = req.DurationMinutes
+	assign.ShuffleQuestions = req.ShuffleQuestions
+	assign.ShuffleOptions = req.ShuffleOptions
+	assign.UpdatedAt = time.Now()
+	return s.assignRepo.Update(assign)
+}
+
+// AutoSubmitExpiredExams 自动提交已超时的考试，返回提交的数量
+func (s *ExamService) AutoSubmitExpiredExams() (int, error) {
+	sessions, err := s.examSessionRepo.ListExpired(time.Now().Add(-examGracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range sessions {
+		assign, err := s.assignRepo.GetByID(sessions[i].AssignmentID)
+		if err != nil {
+			log.Printf("自动提交考试 %s 失败: %v", sessions[i].ID, err)
+			continue
+		}
+		if _, err := s.finishExam(assign, &sessions[i], model.ExamAutoSubmitted); err != nil {
+			log.Printf("自动提交考试 %s 失败: %v", sessions[i].ID, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (s *ExamService) examSession(assignID, studentID string) (*model.Assignment, *model.ExamSession, error) {
+	assign, err := s.assignRepo.GetByID(assignID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取作业失败: %w", err)
+	}
+	if !assign.ExamMode {
+		return nil, nil, errors.New("该作业不是考试")
+	}
+	session, err := s.examSessionRepo.GetByAssignmentAndStudent(assignID, studentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, errors.New("尚未开始考试")
+	}
+	return assign, session, nil
+}
+
+// saveExamAnswers 将学生看到的选项字母还原为原始字母后保存，只保留试卷中的题目
+func (s *ExamService) saveExamAnswers(assign *model.Assignment, session *model.ExamSession, answers map[string]string, code string, at time.Time) error {
+	questions, err := s.questionRepo.GetByAssignmentID(assign.ID)
+	if err != nil {
+		return err
+	}
+	canonical := make(map[string]string, len(answers))
+	for _, item := range examLayout(assign, questions, session.Seed) {
+		if answer, ok := answers[item.question.ID]; ok {
+			canonical[item.question.ID] = item.toCanonical(answer)
+		}
+	}
+
+	saved := answersToString(canonical)
+	ok, err := s.examSessionRepo.SaveAnswers(session.ID, saved, code, at)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("考试已交卷")
+	}
+	session.SavedAnswers = saved
+	session.SavedCode = code
+	session.LastSavedAt = &at
+	return nil
+}
+
+// finishExam 将会话标记为已交卷并提交最后保存的答案；会话已被其他请求交卷时返回错误
+func (s *ExamService) finishExam(assign *model.Assignment, session *model.ExamSession, status string) (string, error) {
+	now := time.Now()
+	ok, err := s.examSessionRepo.MarkSubmitted(session.ID, status, now)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("考试已交卷")
+	}
+
+	// 超时自动提交按考试结束时间记录提交时间
+	at := now
+	if at.After(session.EndsAt) {
+		at = session.EndsAt
+	}
+	answers, _ := stringToAnswers(session.SavedAnswers)
+	if answers == nil {
+		answers = map[string]string{}
+	}
+	submissionID, err := s.submit(assign, session.StudentID, session.StudentName, answers, session.SavedCode, at, s.examLateness(assign, session.StudentID, at), "")
+	if err != nil {
+		// 恢复为作答中，等待下次交卷或自动提交
+		session.Status = model.ExamInProgress
+		if rollbackErr := s.examSessionRepo.Update(session); rollbackErr != nil {
+			log.Printf("恢复考试状态失败: %v", rollbackErr)
+		}
+		return "", err
+	}
+
+	session.Status = status
+	session.SubmittedAt = &now
+	session.SubmissionID = &submissionID
+	session.UpdatedAt = now
+	if err := s.examSessionRepo.Update(session); err != nil {
+		return "", err
+	}
+	return submissionID, nil
+}
+
+// examLateness 按学生的截止规则计算交卷的迟交情况，找不到班级规则时视为未迟交
+func (s *ExamService) examLateness(assign *model.Assignment, studentID string, at time.Time) lateness {
+	ac, err := s.studentAssignmentClass(assign.ID, studentID)
+	if err != nil || ac == nil {
+		return lateness{}
+	}
+	extension, err := s.extensionRepo.GetByAssignmentAndStudent(assign.ID, studentID)
+	if err != nil {
+		return lateness{}
+	}
+	return newLatePolicy(ac, extension).evaluate(at)
+}
+
+func (s *ExamService) examView(assign *model.Assignment, session *model.ExamSession) (*ExamView, error) {
+	view := &ExamView{
+		AssignmentID:     assign.ID,
+		Title:            assign.Title,
+		Description:      assign.Description,
+		DurationMinutes:  assign.DurationMinutes,
+		Status:           session.Status,
+		StartedAt:        session.StartedAt,
+		EndsAt:           session.EndsAt,
+		RemainingSeconds: int(math.Max(0, math.Ceil(time.Until(session.EndsAt).Seconds()))),
+		LastSavedAt:      session.LastSavedAt,
+		SubmissionID:     session.SubmissionID,
+		Answers:          map[string]string{},
+		Code:             session.SavedCode,
+	}
+	if session.Status != model.ExamInProgress {
+		view.RemainingSeconds = 0
+		return view, nil
+	}
+
+	questions, err := s.questionRepo.GetByAssignmentID(assign.ID)
+	if err != nil {
+		return nil, err
+	}
+	saved, _ := stringToAnswers(session.SavedAnswers)
+	for i, item := range examLayout(assign, questions, session.Seed) {
+		q := item.question
+		view.Questions = append(view.Questions, ExamQuestion{
+			ID:      q.ID,
+			Number:  i + 1,
+			Type:    q.Type,
+			Content: q.Content,
+			Options: item.options,
+			Score:   q.Score,
+		})
+		if answer, ok := saved[q.ID]; ok {
+			view.Answers[q.ID] = item.toDisplay(answer)
+		}
+	}
+	return view, nil
+}
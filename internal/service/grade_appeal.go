@@ -0,0 +1,271 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// maxAppealReasonLen 申诉理由的最大长度（字符）
+const maxAppealReasonLen = 1000
+
+// pendingAppealStatuses 尚未结案的申诉状态
+var pendingAppealStatuses = []string{model.AppealOpen, model.AppealReevaluating}
+
+// CreateAppeal 学生对已批改提交中某道题的得分提出申诉；同一道题有未结案的申诉时不能重复提出
+func (s *AssignmentService) CreateAppeal(studentID, submissionID string, req dto.GradeAppealRequest) (*model.GradeAppeal, error) {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return nil, errors.New("提交记录不存在")
+	}
+	if submission.StudentID != studentID {
+		return nil, errors.New("只能对自己的提交提出申诉")
+	}
+	if submission.Status != "graded" {
+		return nil, errors.New("作业批改完成后才能提出申诉")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("请填写申诉理由")
+	}
+	if len([]rune(reason)) > maxAppealReasonLen {
+		return nil, fmt.Errorf("申诉理由不能超过 %d 个字", maxAppealReasonLen)
+	}
+
+	question, err := s.questionRepo.GetByID(req.QuestionID)
+	if err != nil || question.AssignmentID != submission.AssignmentID {
+		return nil, errors.New("题目不存在")
+	}
+
+	appeals, err := s.appealRepo.GetBySubmissionID(submissionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range appeals {
+		if a.QuestionID == question.ID && isPendingAppeal(a.Status) {
+			return nil, errors.New("该题已有待处理的申诉")
+		}
+	}
+
+	// 申诉按作业发布到学生的班级进入教师的处理队列
+	classID := ""
+	if ac, err := s.studentAssignmentClass(submission.AssignmentID, studentID); err == nil && ac != nil {
+		classID = ac.ClassID
+	}
+	if classID != "" {
+		if class, err := s.classRepo.GetByID(classID); err == nil && class.ArchivedAt != nil {
+			return nil, errors.New("班级已归档，不能提出申诉")
+		}
+	}
+
+	now := time.Now()
+	appeal := &model.GradeAppeal{
+		ID:            uuid.New().String(),
+		SubmissionID:  submission.ID,
+		AssignmentID:  submission.AssignmentID,
+		ClassID:       classID,
+		StudentID:     studentID,
+		StudentName:   submission.StudentName,
+		QuestionID:    question.ID,
+		Reason:        reason,
+		Status:        model.AppealOpen,
+		OriginalScore: questionScore(submission.QuestionScores, question.ID),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.appealRepo.Create(appeal); err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// GetSubmissionAppeals 获取提交的全部申诉及处理结果
+func (s *AssignmentService) GetSubmissionAppeals(submissionID string) ([]model.GradeAppeal, error) {
+	return s.appealRepo.GetBySubmissionID(submissionID)
+}
+
+// GetClassAppeals 获取班级的申诉，statuses 为空时返回全部状态
+func (s *AssignmentService) GetClassAppeals(teacherID, classID string, statuses []string) ([]model.GradeAppeal, error) {
+	if _, err := s.authz.AuthorizeClass(teacherID, classID, CapGrade); err != nil {
+		return nil, err
+	}
+	return s.appealRepo.GetByClassID(classID, statuses)
+}
+
+// AcceptAppeal 接受申诉，把该题得分调整为 req.Score 并重新计算总分
+func (s *AssignmentService) AcceptAppeal(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error) {
+	appeal, err := s.getPendingAppeal(teacherID, appealID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Score == nil {
+		return nil, errors.New("请填写调整后的分数")
+	}
+	question, err := s.questionRepo.GetByID(appeal.QuestionID)
+	if err != nil {
+		return nil, errors.New("题目不存在")
+	}
+	if *req.Score < 0 || *req.Score > question.Score {
+		return nil, fmt.Errorf("分数必须在0-%d之间", question.Score)
+	}
+
+	// 先结案占住申诉，避免与其他教师同时处理；改分失败时恢复为原来的状态
+	previous := *appeal
+	if err := s.resolveAppeal(teacherID, appeal, model.AppealAccepted, req.Score, req.Comment, pendingAppealStatuses); err != nil {
+		return nil, err
+	}
+	if err := s.UpdateQuestionScore(teacherID, appeal.SubmissionID, appeal.QuestionID, *req.Score); err != nil {
+		previous.UpdatedAt = time.Now()
+		if _, rollbackErr := s.appealRepo.UpdateStatus(&previous, []string{model.AppealAccepted}); rollbackErr != nil {
+			log.Printf("恢复申诉 %s 状态失败: %v", appeal.ID, rollbackErr)
+		}
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// RejectAppeal 驳回申诉，必须说明理由
+func (s *AssignmentService) RejectAppeal(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error) {
+	appeal, err := s.getPendingAppeal(teacherID, appealID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Comment) == "" {
+		return nil, errors.New("驳回申诉时请填写说明")
+	}
+
+	if err := s.resolveAppeal(teacherID, appeal, model.AppealRejected, appeal.OriginalScore, req.Comment, pendingAppealStatuses); err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// ReevaluateAppeal 把申诉所在的提交重新交给 AI 批改；批改完成后申诉自动结案并记录新的得分。
+// 教师之前的复核结果默认保留，req.DiscardReview 为 true 时一并放弃
+func (s *AssignmentService) ReevaluateAppeal(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error) {
+	appeal, err := s.getPendingAppeal(teacherID, appealID)
+	if err != nil {
+		return nil, err
+	}
+	if appeal.Status != model.AppealOpen {
+		return nil, errors.New("该申诉已在重新批改中")
+	}
+
+	// 先改变状态再排队，避免批改先完成时申诉还没有进入等待结案的状态
+	appeal.Status = model.AppealReevaluating
+	appeal.TeacherComment = strings.TrimSpace(req.Comment)
+	appeal.ResolvedBy = teacherID
+	appeal.UpdatedAt = time.Now()
+	ok, err := s.appealRepo.UpdateStatus(appeal, []string{model.AppealOpen})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("申诉已处理")
+	}
+
+	if err := s.RegradeSubmission(appeal.SubmissionID, req.DiscardReview); err != nil {
+		appeal.Status = model.AppealOpen
+		if _, rollbackErr := s.appealRepo.UpdateStatus(appeal, []string{model.AppealReevaluating}); rollbackErr != nil {
+			log.Printf("恢复申诉 %s 状态失败: %v", appeal.ID, rollbackErr)
+		}
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// getPendingAppeal 获取教师有权处理且尚未结案的申诉
+func (s *AssignmentService) getPendingAppeal(teacherID, appealID string) (*model.GradeAppeal, error) {
+	appeal, err := s.appealRepo.GetByID(appealID)
+	if err != nil {
+		return nil, errors.New("申诉不存在")
+	}
+	if _, err := s.authorizeAssignment(teacherID, appeal.AssignmentID, CapGrade); err != nil {
+		return nil, err
+	}
+	if !isPendingAppeal(appeal.Status) {
+		return nil, errors.New("申诉已处理")
+	}
+	return appeal, nil
+}
+
+// resolveAppeal 结案；申诉已被他人处理时返回错误
+func (s *AssignmentService) resolveAppeal(teacherID string, appeal *model.GradeAppeal, status string, score *int, comment string, fromStatuses []string) error {
+	now := time.Now()
+	appeal.Status = status
+	appeal.ResolvedScore = score
+	appeal.TeacherComment = strings.TrimSpace(comment)
+	appeal.ResolvedBy = teacherID
+	appeal.ResolvedAt = &now
+	appeal.UpdatedAt = now
+	ok, err := s.appealRepo.UpdateStatus(appeal, fromStatuses)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("申诉已处理")
+	}
+	return nil
+}
+
+// resolveReevaluatedAppeals 提交重新批改完成后，为等待 AI 重新批改的申诉结案并记录该题的新得分；
+// 小组主提交同时处理组员在各自副本上提出的申诉
+func (s *AssignmentService) resolveReevaluatedAppeals(submission *model.Submission) {
+	appeals, err := s.appealRepo.GetBySubmissionID(submission.ID)
+	if err != nil {
+		log.Printf("获取提交 %s 的申诉失败: %v", submission.ID, err)
+		return
+	}
+	if submission.GroupID != nil {
+		copies, err := s.submissionRepo.GetGroupCopies(submission.ID)
+		if err != nil {
+			log.Printf("获取小组提交 %s 的组员副本失败: %v", submission.ID, err)
+		}
+		for _, c := range copies {
+			copyAppeals, err := s.appealRepo.GetBySubmissionID(c.ID)
+			if err != nil {
+				log.Printf("获取提交 %s 的申诉失败: %v", c.ID, err)
+				continue
+			}
+			appeals = append(appeals, copyAppeals...)
+		}
+	}
+	now := time.Now()
+	for i := range appeals {
+		appeal := &appeals[i]
+		if appeal.Status != model.AppealReevaluating {
+			continue
+		}
+		appeal.Status = model.AppealReevaluated
+		appeal.ResolvedScore = questionScore(submission.QuestionScores, appeal.QuestionID)
+		appeal.ResolvedAt = &now
+		appeal.UpdatedAt = now
+		if _, err := s.appealRepo.UpdateStatus(appeal, []string{model.AppealReevaluating}); err != nil {
+			log.Printf("更新申诉 %s 失败: %v", appeal.ID, err)
+		}
+	}
+}
+
+func isPendingAppeal(status string) bool {
+	return status == model.AppealOpen || status == model.AppealReevaluating
+}
+
+// questionScore 从题目分数 JSON 中取出某道题的得分，没有得分时返回 nil
+func questionScore(questionScores, questionID string) *int {
+	var scores map[string]int
+	json.Unmarshal([]byte(questionScores), &scores)
+	score, ok := scores[questionID]
+	if !ok {
+		return nil
+	}
+	return &score
+}
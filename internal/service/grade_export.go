@@ -0,0 +1,260 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/pkg/excel"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// cellStatusLabels 导出时单元格状态的中文名称
+var cellStatusLabels = map[string]string{
+	CellGraded:  "已批改",
+	CellPending: "待批改",
+	CellMissing: "缺交",
+	CellOpen:    "未提交",
+}
+
+// exportGroup 导出成绩单时的一个班级及其学生
+type exportGroup struct {
+	className string
+	students  []model.User
+	policy    columnPolicy
+}
+
+// ExportAssignment 导出作业的成绩单；classID 不为空时只导出该班级的学生，否则导出所有发布班级
+func (s *GradebookService) ExportAssignment(teacherID, assignID, classID string) (*excel.Sheet, error) {
+	assign, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapViewClass)
+	if err != nil {
+		return nil, err
+	}
+
+	var classIDs []string
+	if classID != "" {
+		if _, err := s.authz.AuthorizeClass(teacherID, classID, CapViewClass); err != nil {
+			return nil, err
+		}
+		if !s.publishedToClass(assignID, classID) {
+			return nil, errors.New("作业未发布到该班级")
+		}
+		classIDs = []string{classID}
+	} else {
+		acs, err := s.assignmentClassRepo.GetByAssignmentID(assignID)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, ac := range acs {
+			if !seen[ac.ClassID] {
+				seen[ac.ClassID] = true
+				classIDs = append(classIDs, ac.ClassID)
+			}
+		}
+		if assign.ClassID != nil && !seen[*assign.ClassID] {
+			classIDs = append(classIDs, *assign.ClassID)
+		}
+		// 合作教师和助教只导出自己有权查看的班级
+		if assign.TeacherID != teacherID {
+			visible := classIDs[:0]
+			for _, id := range classIDs {
+				if _, err := s.authz.AuthorizeClass(teacherID, id, CapViewClass); err == nil {
+					visible = append(visible, id)
+				}
+			}
+			classIDs = visible
+		}
+	}
+
+	classNames := make(map[string]string)
+	if len(classIDs) > 0 {
+		classes, err := s.classRepo.GetByIDs(classIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range classes {
+			classNames[c.ID] = c.Name
+		}
+	}
+
+	groups := make([]exportGroup, 0, len(classIDs))
+	for _, id := range classIDs {
+		students, err := s.classStudents(id)
+		if err != nil {
+			return nil, err
+		}
+		policy, err := s.columnPolicy(assign, id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, exportGroup{className: classNames[id], students: students, policy: policy})
+	}
+
+	// 导出全部班级时，已离开班级的学生的提交也一并导出
+	return s.assignmentSheet(assign, groups, classID == "")
+}
+
+// ExportClass 导出班级成绩册：第一个工作表为汇总（与成绩册一致，含手动成绩、分类得分和总评），
+// 之后每个作业一个工作表列出逐题得分
+func (s *GradebookService) ExportClass(teacherID, classID string) ([]excel.Sheet, error) {
+	book, err := s.GetGradebook(teacherID, classID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.classStudents(classID)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := []excel.Sheet{gradebookSummarySheet(book)}
+	for _, col := range book.Assignments {
+		assign, err := s.assignRepo.GetByID(col.ID)
+		if err != nil {
+			return nil, fmt.Errorf("获取作业失败: %w", err)
+		}
+		policy, err := s.columnPolicy(assign, classID)
+		if err != nil {
+			return nil, err
+		}
+		sheet, err := s.assignmentSheet(assign, []exportGroup{{className: book.ClassName, students: students, policy: policy}}, false)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, *sheet)
+	}
+	return sheets, nil
+}
+
+// gradebookSummarySheet 成绩册汇总表：每个作业计入总评的得分、分类得分、总评和等级
+func gradebookSummarySheet(book *Gradebook) excel.Sheet {
+	header := []string{"学号", "姓名"}
+	for _, col := range book.Assignments {
+		header = append(header, fmt.Sprintf("%s（满分%d）", col.Title, col.MaxScore))
+	}
+	for _, category := range book.Settings.Categories {
+		header = append(header, fmt.Sprintf("%s（权重%g）", categoryLabel(category.Name), category.Weight))
+	}
+	header = append(header, "总评(%)", "等级")
+
+	rows := make([][]interface{}, 0, len(book.Rows))
+	for _, r := range book.Rows {
+		row := []interface{}{r.Username, r.StudentName}
+		for _, cell := range r.Cells {
+			if cell.Score != nil {
+				row = append(row, *cell.Score)
+			} else {
+				row = append(row, cellStatusLabels[cell.Status])
+			}
+		}
+		for _, category := range book.Settings.Categories {
+			row = append(row, floatValue(r.Categories[category.Name]))
+		}
+		row = append(row, floatValue(r.FinalPercent), r.LetterGrade)
+		rows = append(rows, row)
+	}
+	return excel.Sheet{Name: "汇总", Header: header, Rows: rows}
+}
+
+// assignmentSheet 作业成绩单：每个学生一行，列出逐题得分、总分、状态、迟交情况和教师评语
+func (s *GradebookService) assignmentSheet(assign *model.Assignment, groups []exportGroup, includeOthers bool) (*excel.Sheet, error) {
+	questions, err := s.questionRepo.GetByAssignmentID(assign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取题目失败: %w", err)
+	}
+	subs, err := s.submissionRepo.GetByAssignmentIDs([]string{assign.ID})
+	if err != nil {
+		return nil, fmt.Errorf("获取提交记录失败: %w", err)
+	}
+	submissions := make(map[string]*model.Submission, len(subs))
+	for i := range subs {
+		submissions[subs[i].StudentID] = &subs[i]
+	}
+
+	header := []string{"学号", "姓名", "班级"}
+	for i, q := range questions {
+		header = append(header, fmt.Sprintf("第%d题（%d分）", i+1, q.Score))
+	}
+	header = append(header, "总分", "状态", "迟交", "迟交扣分(%)", "教师评语")
+
+	now := time.Now()
+	col := GradebookAssignment{ID: assign.ID}
+	var rows [][]interface{}
+	exported := make(map[string]bool)
+	for _, group := range groups {
+		for _, student := range group.students {
+			if exported[student.ID] {
+				continue
+			}
+			exported[student.ID] = true
+			sub := submissions[student.ID]
+			status := buildCell(col, sub, nil, group.policy.forStudent(student.ID), false, now).Status
+			rows = append(rows, scoreRow(student.Username, student.Name, group.className, questions, sub, status))
+		}
+	}
+	if includeOthers {
+		for i := range subs {
+			sub := &subs[i]
+			if exported[sub.StudentID] {
+				continue
+			}
+			exported[sub.StudentID] = true
+			username := sub.StudentID
+			if user, err := s.userRepo.GetByID(sub.StudentID); err == nil {
+				username = user.Username
+			}
+			status := buildCell(col, sub, nil, latePolicy{}, false, now).Status
+			rows = append(rows, scoreRow(username, sub.StudentName, "", questions, sub, status))
+		}
+	}
+
+	return &excel.Sheet{Name: assign.Title, Header: header, Rows: rows}, nil
+}
+
+func scoreRow(username, name, className string, questions []model.Question, sub *model.Submission, status string) []interface{} {
+	row := []interface{}{username, name, className}
+
+	var questionScores map[string]int
+	if sub != nil {
+		json.Unmarshal([]byte(sub.QuestionScores), &questionScores)
+	}
+	for _, q := range questions {
+		if score, ok := questionScores[q.ID]; ok {
+			row = append(row, score)
+		} else {
+			row = append(row, nil)
+		}
+	}
+
+	if sub == nil {
+		return append(row, nil, cellStatusLabels[status], nil, nil, nil)
+	}
+	var total interface{}
+	if sub.TotalScore != nil {
+		total = *sub.TotalScore
+	}
+	late := "否"
+	if sub.IsLate {
+		late = fmt.Sprintf("是（%d分钟）", sub.LateMinutes)
+	}
+	return append(row, total, cellStatusLabels[status], late, sub.LatePenalty, sub.TeacherFeedback)
+}
+
+func floatValue(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func categoryLabel(name string) string {
+	switch name {
+	case model.CategoryHomework:
+		return "作业"
+	case model.CategoryQuiz:
+		return "小测"
+	case model.CategoryExam:
+		return "考试"
+	}
+	return name
+}
@@ -0,0 +1,719 @@
+package service
+
+import (
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 成绩册单元格状态
+const (
+	CellGraded  = "graded"  // 已批改
+	CellPending = "pending" // 已提交，等待批改
+	CellMissing = "missing" // 截止后仍未提交
+	CellOpen    = "open"    // 未提交，尚未截止
+)
+
+// legacyMaxScore 没有题目的旧作业按百分制计分
+const legacyMaxScore = 100
+
+// gradeCategoryNames 成绩册支持的作业分类，按展示顺序排列
+var gradeCategoryNames = []string{model.CategoryHomework, model.CategoryQuiz, model.CategoryExam}
+
+// defaultGradeCategories 班级没有设置时的分类权重
+var defaultGradeCategories = []dto.GradeCategory{
+	{Name: model.CategoryHomework, Weight: 40},
+	{Name: model.CategoryQuiz, Weight: 20},
+	{Name: model.CategoryExam, Weight: 40},
+}
+
+// defaultLetterScale 班级没有设置时的等级划分
+var defaultLetterScale = []dto.LetterGrade{
+	{Letter: "A", Min: 90},
+	{Letter: "B", Min: 80},
+	{Letter: "C", Min: 70},
+	{Letter: "D", Min: 60},
+	{Letter: "F", Min: 0},
+}
+
+// GradebookConfig 成绩册的计算规则
+type GradebookConfig struct {
+	Categories    []dto.GradeCategory `json:"categories"`
+	MissingAsZero bool                `json:"missing_as_zero"`
+	LetterScale   []dto.LetterGrade   `json:"letter_scale"`
+	UpdatedBy     string              `json:"updated_by,omitempty"`
+	UpdatedAt     *time.Time          `json:"updated_at,omitempty"`
+}
+
+// GradebookAssignment 成绩册中的一列
+type GradebookAssignment struct {
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Category string     `json:"category"`
+	MaxScore int        `json:"max_score"`
+	Deadline *time.Time `json:"deadline"`
+}
+
+// GradebookCell 某个学生在某个作业上的成绩
+type GradebookCell struct {
+	AssignmentID    string   `json:"assignment_id"`
+	Status          string   `json:"status"`
+	SubmissionID    string   `json:"submission_id,omitempty"`
+	SubmissionScore *int     `json:"submission_score"` // 提交记录中的得分（已扣除迟交罚分）
+	Score           *float64 `json:"score"`            // 计入总评的得分，为空表示不计入
+	IsLate          bool     `json:"is_late"`
+	Overridden      bool     `json:"overridden"` // 教师手动修改过
+	OverrideReason  string   `json:"override_reason,omitempty"`
+	Dropped         bool     `json:"dropped"` // 作为分类内最低分被去掉
+}
+
+// GradebookRow 成绩册中的一行
+type GradebookRow struct {
+	StudentID    string              `json:"student_id"`
+	StudentName  string              `json:"student_name"`
+	Username     string              `json:"username"`
+	Cells        []GradebookCell     `json:"cells"`
+	Categories   map[string]*float64 `json:"categories"`    // 分类 -> 得分百分比，没有成绩的分类为 null
+	FinalPercent *float64            `json:"final_percent"` // 按权重计算的总评百分比
+	LetterGrade  string              `json:"letter_grade"`
+}
+
+// Gradebook 班级成绩册：学生 × 已发布作业
+type Gradebook struct {
+	ClassID     string                `json:"class_id"`
+	ClassName   string                `json:"class_name"`
+	Settings    GradebookConfig       `json:"settings"`
+	Assignments []GradebookAssignment `json:"assignments"`
+	Rows        []GradebookRow        `json:"rows"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// GradebookService 班级成绩册服务
+type GradebookService struct {
+	gradebookRepo       repository.GradebookSettingsRepository
+	overrideRepo        repository.GradeOverrideRepository
+	auditRepo           repository.GradeAuditLogRepository
+	classRepo           repository.ClassRepository
+	memberRepo          repository.ClassMemberRepository
+	userRepo            repository.UserRepository
+	assignRepo          repository.AssignmentRepository
+	assignmentClassRepo repository.AssignmentClassRepository
+	extensionRepo       repository.DeadlineExtensionRepository
+	questionRepo        repository.QuestionRepository
+	submissionRepo      repository.SubmissionRepository
+	authz               IAuthorizationService
+}
+
+// NewGradebookService 创建成绩册服务
+func NewGradebookService(
+	gradebookRepo repository.GradebookSettingsRepository,
+	overrideRepo repository.GradeOverrideRepository,
+	auditRepo repository.GradeAuditLogRepository,
+	classRepo repository.ClassRepository,
+	memberRepo repository.ClassMemberRepository,
+	userRepo repository.UserRepository,
+	assignRepo repository.AssignmentRepository,
+	assignmentClassRepo repository.AssignmentClassRepository,
+	extensionRepo repository.DeadlineExtensionRepository,
+	questionRepo repository.QuestionRepository,
+	submissionRepo repository.SubmissionRepository,
+	authz IAuthorizationService,
+) IGradebookService {
+	return &GradebookService{
+		gradebookRepo:       gradebookRepo,
+		overrideRepo:        overrideRepo,
+		auditRepo:           auditRepo,
+		classRepo:           classRepo,
+		memberRepo:          memberRepo,
+		userRepo:            userRepo,
+		assignRepo:          assignRepo,
+		assignmentClassRepo: assignmentClassRepo,
+		extensionRepo:       extensionRepo,
+		questionRepo:        questionRepo,
+		submissionRepo:      submissionRepo,
+		authz:               authz,
+	}
+}
+
+// GetGradebook 生成班级成绩册
+func (s *GradebookService) GetGradebook(teacherID, classID string) (*Gradebook, error) {
+	class, err := s.authz.AuthorizeClass(teacherID, classID, CapViewClass)
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.loadConfig(classID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.classStudents(classID)
+	if err != nil {
+		return nil, err
+	}
+	columns, policies, err := s.loadColumns(classID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignmentIDs := make([]string, len(columns))
+	for i, col := range columns {
+		assignmentIDs[i] = col.ID
+	}
+	submissions := make(map[string]*model.Submission)
+	if len(assignmentIDs) > 0 {
+		subs, err := s.submissionRepo.GetByAssignmentIDs(assignmentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("获取提交记录失败: %w", err)
+		}
+		for i := range subs {
+			submissions[cellKey(subs[i].AssignmentID, subs[i].StudentID)] = &subs[i]
+		}
+	}
+	overrideList, err := s.overrideRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, fmt.Errorf("获取手动成绩失败: %w", err)
+	}
+	overrides := make(map[string]*model.GradeOverride, len(overrideList))
+	for i := range overrideList {
+		overrides[cellKey(overrideList[i].AssignmentID, overrideList[i].StudentID)] = &overrideList[i]
+	}
+
+	now := time.Now()
+	book := &Gradebook{
+		ClassID:     class.ID,
+		ClassName:   class.Name,
+		Settings:    config,
+		Assignments: columns,
+		Rows:        make([]GradebookRow, 0, len(students)),
+		GeneratedAt: now,
+	}
+	for _, student := range students {
+		row := GradebookRow{
+			StudentID:   student.ID,
+			StudentName: student.Name,
+			Username:    student.Username,
+			Cells:       make([]GradebookCell, len(columns)),
+		}
+		for i, col := range columns {
+			key := cellKey(col.ID, student.ID)
+			row.Cells[i] = buildCell(col, submissions[key], overrides[key], policies[col.ID].forStudent(student.ID), config.MissingAsZero, now)
+		}
+		row.Categories, row.FinalPercent = computeFinal(config, columns, row.Cells)
+		if row.FinalPercent != nil {
+			row.LetterGrade = letterFor(config.LetterScale, *row.FinalPercent)
+		}
+		book.Rows = append(book.Rows, row)
+	}
+	return book, nil
+}
+
+// UpdateSettings 修改班级成绩册的计算规则，并可同时调整作业所属的分类
+func (s *GradebookService) UpdateSettings(teacherID, classID string, req dto.GradebookSettingsRequest) (*GradebookConfig, error) {
+	if _, err := s.authz.AuthorizeClass(teacherID, classID, CapEditAssignment); err != nil {
+		return nil, err
+	}
+
+	categories, err := normalizeGradeCategories(req.Categories)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := normalizeLetterScale(req.LetterScale)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先校验全部作业再修改，避免只改了一部分
+	var changed []*model.Assignment
+	for assignID, category := range req.AssignmentCategories {
+		normalized, err := normalizeCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		assign, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapEditAssignment)
+		if err != nil {
+			return nil, err
+		}
+		if assign.Category != normalized {
+			assign.Category = normalized
+			changed = append(changed, assign)
+		}
+	}
+	for _, assign := range changed {
+		assign.UpdatedAt = time.Now()
+		if err := s.assignRepo.Update(assign); err != nil {
+			return nil, err
+		}
+	}
+
+	categoriesJSON, err := json.Marshal(categories)
+	if err != nil {
+		return nil, err
+	}
+	scaleJSON, err := json.Marshal(scale)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	settings := &model.GradebookSettings{
+		ID:            uuid.New().String(),
+		ClassID:       classID,
+		Categories:    string(categoriesJSON),
+		MissingAsZero: req.MissingAsZero,
+		LetterScale:   string(scaleJSON),
+		UpdatedBy:     teacherID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.gradebookRepo.Save(settings); err != nil {
+		return nil, err
+	}
+	return &GradebookConfig{
+		Categories:    categories,
+		MissingAsZero: req.MissingAsZero,
+		LetterScale:   scale,
+		UpdatedBy:     teacherID,
+		UpdatedAt:     &now,
+	}, nil
+}
+
+// OverrideCell 手动修改或撤销某个单元格的成绩，每次修改都会记录修改人、前后得分和原因
+func (s *GradebookService) OverrideCell(teacherID, classID, assignID, studentID string, req dto.GradeOverrideRequest) error {
+	if _, err := s.authz.AuthorizeClass(teacherID, classID, CapGrade); err != nil {
+		return err
+	}
+	if !s.publishedToClass(assignID, classID) {
+		return errors.New("作业未发布到该班级")
+	}
+	student, err := s.userRepo.GetByID(studentID)
+	if err != nil || student.Role != "student" || !isClassMember(s.memberRepo, classID, studentID) {
+		return errors.New("该学生不在此班级")
+	}
+	teacher, err := s.userRepo.GetByID(teacherID)
+	if err != nil {
+		return errors.New("教师不存在")
+	}
+
+	existing, err := s.overrideRepo.Get(classID, assignID, studentID)
+	if err != nil {
+		return err
+	}
+	submission, err := s.submissionRepo.GetByAssignmentAndStudent(assignID, studentID)
+	if err != nil {
+		submission = nil
+	}
+	var submissionScore *float64
+	if submission != nil && submission.TotalScore != nil {
+		score := float64(*submission.TotalScore)
+		submissionScore = &score
+	}
+
+	oldScore := submissionScore
+	if existing != nil {
+		score := existing.Score
+		oldScore = &score
+	}
+
+	audit := &model.GradeAuditLog{
+		ClassID:       classID,
+		AssignmentID:  assignID,
+		StudentID:     studentID,
+		OldScore:      oldScore,
+		Reason:        strings.TrimSpace(req.Reason),
+		ChangedBy:     teacherID,
+		ChangedByName: teacher.Name,
+		CreatedAt:     time.Now(),
+	}
+
+	if req.Score == nil {
+		if existing == nil {
+			return errors.New("该成绩没有被手动修改过")
+		}
+		audit.NewScore = submissionScore
+		return s.overrideRepo.Delete(classID, assignID, studentID, audit)
+	}
+
+	maxScore, err := s.maxScore(assignID)
+	if err != nil {
+		return err
+	}
+	score := round2(*req.Score)
+	if score < 0 || score > float64(maxScore) {
+		return fmt.Errorf("成绩必须在 0 到 %d 之间", maxScore)
+	}
+	audit.NewScore = &score
+	audit.Overridden = true
+	now := time.Now()
+	return s.overrideRepo.Save(&model.GradeOverride{
+		ID:           uuid.New().String(),
+		ClassID:      classID,
+		AssignmentID: assignID,
+		StudentID:    studentID,
+		Score:        score,
+		Reason:       audit.Reason,
+		UpdatedBy:    teacherID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, audit)
+}
+
+// GetAuditLog 获取班级成绩的修改记录，studentID 不为空时只返回该学生的记录
+func (s *GradebookService) GetAuditLog(teacherID, classID, studentID string) ([]model.GradeAuditLog, error) {
+	if _, err := s.authz.AuthorizeClass(teacherID, classID, CapViewClass); err != nil {
+		return nil, err
+	}
+	logs, err := s.auditRepo.GetByClassID(classID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if logs == nil {
+		logs = []model.GradeAuditLog{}
+	}
+	return logs, nil
+}
+
+// loadConfig 读取班级的成绩册规则，没有设置或设置无效时使用默认规则
+func (s *GradebookService) loadConfig(classID string) (GradebookConfig, error) {
+	config := GradebookConfig{Categories: defaultGradeCategories, LetterScale: defaultLetterScale}
+	settings, err := s.gradebookRepo.GetByClassID(classID)
+	if err != nil {
+		return config, err
+	}
+	if settings == nil {
+		return config, nil
+	}
+
+	var categories []dto.GradeCategory
+	if err := json.Unmarshal([]byte(settings.Categories), &categories); err == nil && len(categories) > 0 {
+		config.Categories = categories
+	}
+	var scale []dto.LetterGrade
+	if err := json.Unmarshal([]byte(settings.LetterScale), &scale); err == nil && len(scale) > 0 {
+		config.LetterScale = scale
+	}
+	config.MissingAsZero = settings.MissingAsZero
+	config.UpdatedBy = settings.UpdatedBy
+	config.UpdatedAt = &settings.UpdatedAt
+	return config, nil
+}
+
+// publishedToClass 判断作业是否已发布到班级（兼容直接设置 class_id 的旧作业）
+func (s *GradebookService) publishedToClass(assignID, classID string) bool {
+	if _, err := s.assignmentClassRepo.GetByAssignmentAndClass(assignID, classID); err == nil {
+		return true
+	}
+	assign, err := s.assignRepo.GetByID(assignID)
+	return err == nil && assign.Status != "draft" && assign.ClassID != nil && *assign.ClassID == classID
+}
+
+// classStudents 获取班级中计入成绩的学生（不含旁听生），按姓名排序
+func (s *GradebookService) classStudents(classID string) ([]model.User, error) {
+	users, err := s.userRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.memberRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, err
+	}
+	auditors := make(map[string]bool)
+	for _, m := range members {
+		if m.Role == model.ClassRoleAuditor {
+			auditors[m.StudentID] = true
+		}
+	}
+	students := make([]model.User, 0, len(users))
+	for _, u := range users {
+		if u.Role == "student" && !auditors[u.ID] {
+			students = append(students, u)
+		}
+	}
+	sort.SliceStable(students, func(i, j int) bool {
+		if students[i].Name != students[j].Name {
+			return students[i].Name < students[j].Name
+		}
+		return students[i].Username < students[j].Username
+	})
+	return students, nil
+}
+
+// columnPolicy 某个作业在班级中的截止规则和学生个人延期，用于判断是否缺交
+type columnPolicy struct {
+	assignmentClass *model.AssignmentClass
+	extensions      map[string]*model.DeadlineExtension
+}
+
+func (p columnPolicy) forStudent(studentID string) latePolicy {
+	if p.assignmentClass == nil {
+		return latePolicy{}
+	}
+	return newLatePolicy(p.assignmentClass, p.extensions[studentID])
+}
+
+// loadColumns 获取发布到班级的作业（按创建时间升序）及其满分和截止规则
+func (s *GradebookService) loadColumns(classID string) ([]GradebookAssignment, map[string]columnPolicy, error) {
+	assignments, err := s.assignRepo.GetByClassID(classID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取作业失败: %w", err)
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].CreatedAt.Before(assignments[j].CreatedAt)
+	})
+
+	columns := make([]GradebookAssignment, 0, len(assignments))
+	policies := make(map[string]columnPolicy, len(assignments))
+	for i := range assignments {
+		assign := &assignments[i]
+		maxScore, err := s.maxScore(assign.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		policy, err := s.columnPolicy(assign, classID)
+		if err != nil {
+			return nil, nil, err
+		}
+		policies[assign.ID] = policy
+
+		category := assign.Category
+		if category == "" {
+			category = model.CategoryHomework
+		}
+		columns = append(columns, GradebookAssignment{
+			ID:       assign.ID,
+			Title:    assign.Title,
+			Category: category,
+			MaxScore: maxScore,
+			Deadline: policy.assignmentClass.Deadline,
+		})
+	}
+	return columns, policies, nil
+}
+
+// columnPolicy 获取作业在班级中的截止规则和全部学生延期
+func (s *GradebookService) columnPolicy(assign *model.Assignment, classID string) (columnPolicy, error) {
+	policy := columnPolicy{extensions: make(map[string]*model.DeadlineExtension)}
+	if ac, err := s.assignmentClassRepo.GetByAssignmentAndClass(assign.ID, classID); err == nil {
+		policy.assignmentClass = ac
+	} else {
+		// 直接设置 class_id 的旧作业没有关联记录，使用作业本身的截止时间
+		policy.assignmentClass = &model.AssignmentClass{AssignmentID: assign.ID, ClassID: classID, Deadline: assign.Deadline}
+	}
+	extensions, err := s.extensionRepo.GetByAssignmentID(assign.ID)
+	if err != nil {
+		return policy, fmt.Errorf("获取延期信息失败: %w", err)
+	}
+	for i := range extensions {
+		policy.extensions[extensions[i].StudentID] = &extensions[i]
+	}
+	return policy, nil
+}
+
+// maxScore 作业满分为各题分值之和，没有题目的旧作业按百分制
+func (s *GradebookService) maxScore(assignID string) (int, error) {
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return 0, fmt.Errorf("获取题目失败: %w", err)
+	}
+	total := 0
+	for _, q := range questions {
+		total += q.Score
+	}
+	if total <= 0 {
+		return legacyMaxScore, nil
+	}
+	return total, nil
+}
+
+func cellKey(assignID, studentID string) string {
+	return assignID + "/" + studentID
+}
+
+// buildCell 根据提交记录、手动成绩和截止时间确定单元格的状态和计入总评的得分
+func buildCell(col GradebookAssignment, sub *model.Submission, override *model.GradeOverride, policy latePolicy, missingAsZero bool, now time.Time) GradebookCell {
+	cell := GradebookCell{AssignmentID: col.ID}
+	switch {
+	case sub != nil && sub.TotalScore != nil:
+		cell.Status = CellGraded
+		score := float64(*sub.TotalScore)
+		cell.Score = &score
+	case sub != nil:
+		cell.Status = CellPending
+	case policy.pastDue(now):
+		cell.Status = CellMissing
+		if missingAsZero {
+			zero := 0.0
+			cell.Score = &zero
+		}
+	default:
+		cell.Status = CellOpen
+	}
+	if sub != nil {
+		cell.SubmissionID = sub.ID
+		cell.SubmissionScore = sub.TotalScore
+		cell.IsLate = sub.IsLate
+	}
+
+	if override != nil {
+		score := override.Score
+		cell.Score = &score
+		cell.Overridden = true
+		cell.OverrideReason = override.Reason
+	}
+	return cell
+}
+
+// computeFinal 计算各分类得分百分比和总评；每个分类先去掉最低的若干次成绩（至少保留一次），
+// 总评是有成绩的分类按权重的加权平均，权重在这些分类之间重新归一
+func computeFinal(config GradebookConfig, columns []GradebookAssignment, cells []GradebookCell) (map[string]*float64, *float64) {
+	byCategory := make(map[string][]int)
+	for i, col := range columns {
+		if cells[i].Score != nil && col.MaxScore > 0 {
+			byCategory[col.Category] = append(byCategory[col.Category], i)
+		}
+	}
+
+	categories := make(map[string]*float64, len(config.Categories))
+	weighted, totalWeight := 0.0, 0.0
+	for _, category := range config.Categories {
+		indices := byCategory[category.Name]
+		if len(indices) == 0 {
+			categories[category.Name] = nil
+			continue
+		}
+
+		if category.DropLowest > 0 && len(indices) > category.DropLowest {
+			sort.SliceStable(indices, func(a, b int) bool {
+				return *cells[indices[a]].Score/float64(columns[indices[a]].MaxScore) < *cells[indices[b]].Score/float64(columns[indices[b]].MaxScore)
+			})
+			for _, i := range indices[:category.DropLowest] {
+				cells[i].Dropped = true
+			}
+			indices = indices[category.DropLowest:]
+		}
+
+		earned, possible := 0.0, 0.0
+		for _, i := range indices {
+			earned += *cells[i].Score
+			possible += float64(columns[i].MaxScore)
+		}
+		percent := round2(earned / possible * 100)
+		categories[category.Name] = &percent
+		if category.Weight > 0 {
+			weighted += category.Weight * percent
+			totalWeight += category.Weight
+		}
+	}
+
+	if totalWeight == 0 {
+		return categories, nil
+	}
+	final := round2(weighted / totalWeight)
+	return categories, &final
+}
+
+// letterFor 按等级划分（最低百分比降序）确定等级
+func letterFor(scale []dto.LetterGrade, percent float64) string {
+	for _, grade := range scale {
+		if percent >= grade.Min {
+			return grade.Letter
+		}
+	}
+	if len(scale) > 0 {
+		return scale[len(scale)-1].Letter
+	}
+	return ""
+}
+
+// round2 保留两位小数
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// normalizeCategory 校验作业分类
+func normalizeCategory(category string) (string, error) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	for _, name := range gradeCategoryNames {
+		if category == name {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的作业分类: %s", category)
+}
+
+// normalizeGradeCategories 校验分类权重，未设置时使用默认权重
+func normalizeGradeCategories(categories []dto.GradeCategory) ([]dto.GradeCategory, error) {
+	if len(categories) == 0 {
+		return defaultGradeCategories, nil
+	}
+	seen := make(map[string]bool)
+	totalWeight := 0.0
+	result := make([]dto.GradeCategory, 0, len(categories))
+	for _, c := range categories {
+		name, err := normalizeCategory(c.Name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("分类 %s 重复", name)
+		}
+		seen[name] = true
+		if c.Weight < 0 {
+			return nil, errors.New("分类权重不能为负数")
+		}
+		if c.DropLowest < 0 {
+			return nil, errors.New("去掉最低分的次数不能为负数")
+		}
+		totalWeight += c.Weight
+		result = append(result, dto.GradeCategory{Name: name, Weight: c.Weight, DropLowest: c.DropLowest})
+	}
+	if totalWeight <= 0 {
+		return nil, errors.New("至少需要一个分类的权重大于 0")
+	}
+	// 按固定顺序保存，便于展示
+	sort.SliceStable(result, func(i, j int) bool {
+		return categoryOrder(result[i].Name) < categoryOrder(result[j].Name)
+	})
+	return result, nil
+}
+
+func categoryOrder(name string) int {
+	for i, n := range gradeCategoryNames {
+		if n == name {
+			return i
+		}
+	}
+	return len(gradeCategoryNames)
+}
+
+// normalizeLetterScale 校验等级划分并按最低百分比降序排列，未设置时使用默认划分
+func normalizeLetterScale(scale []dto.LetterGrade) ([]dto.LetterGrade, error) {
+	if len(scale) == 0 {
+		return defaultLetterScale, nil
+	}
+	result := make([]dto.LetterGrade, 0, len(scale))
+	seen := make(map[float64]bool)
+	for _, g := range scale {
+		letter := strings.TrimSpace(g.Letter)
+		if letter == "" {
+			return nil, errors.New("等级名称不能为空")
+		}
+		if g.Min < 0 || g.Min > 100 {
+			return nil, errors.New("等级的最低百分比必须在 0 到 100 之间")
+		}
+		if seen[g.Min] {
+			return nil, fmt.Errorf("等级的最低百分比 %.2f 重复", g.Min)
+		}
+		seen[g.Min] = true
+		result = append(result, dto.LetterGrade{Letter: letter, Min: g.Min})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Min > result[j].Min })
+	return result, nil
+}
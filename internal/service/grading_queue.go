@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// GradingHandler 执行一次批改，返回错误时任务会按退避策略重试
+type GradingHandler func(ctx context.Context, submissionID string) error
+
+// GradingQueueOptions 批改队列参数
+type GradingQueueOptions struct {
+	Workers      int           // 并发 worker 数量
+	MaxAttempts  int           // 单个任务最多执行次数
+	PollInterval time.Duration // 队列为空时的轮询间隔
+	BaseBackoff  time.Duration // 第一次重试的等待时间，之后每次翻倍
+	MaxBackoff   time.Duration // 重试等待时间上限
+	JobTimeout   time.Duration // 单次批改的超时时间
+}
+
+// DefaultGradingQueueOptions 返回默认的批改队列参数
+func DefaultGradingQueueOptions() GradingQueueOptions {
+	return GradingQueueOptions{
+		Workers:      4,
+		MaxAttempts:  5,
+		PollInterval: 5 * time.Second,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+		JobTimeout:   5 * time.Minute,
+	}
+}
+
+// GradingQueue 持久化的批改队列：任务先写入数据库，再由固定数量的 worker 领取执行，
+// 因此服务重启不会丢失任务，并发量也不会随提交数增长
+type GradingQueue struct {
+	jobRepo repository.GradingJobRepository
+	opts    GradingQueueOptions
+	handler GradingHandler
+
+	wake       chan struct{} // 有新任务时唤醒空闲 worker
+	stopping   chan struct{} // 关闭后 worker 不再领取新任务
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
+	wg         sync.WaitGroup
+	stopOnce   sync.Once
+}
+
+// NewGradingQueue 创建批改队列，调用 Start 后才开始执行任务
+func NewGradingQueue(jobRepo repository.GradingJobRepository, opts GradingQueueOptions) *GradingQueue {
+	defaults := DefaultGradingQueueOptions()
+	if opts.Workers <= 0 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaults.MaxAttempts
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaults.PollInterval
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = defaults.BaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+	if opts.JobTimeout <= 0 {
+		opts.JobTimeout = defaults.JobTimeout
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	return &GradingQueue{
+		jobRepo:    jobRepo,
+		opts:       opts,
+		wake:       make(chan struct{}, opts.Workers),
+		stopping:   make(chan struct{}),
+		jobCtx:     jobCtx,
+		cancelJobs: cancel,
+	}
+}
+
+// Enqueue 为提交创建批改任务。每个提交最多一个排队中或执行中的任务：已有排队中的任务时只将其提前到立即执行，
+// 已有执行中的任务时在其完成后重新排队，由下一次执行批改新的版本，避免两个 worker 同时批改同一提交
+func (q *GradingQueue) Enqueue(submissionID, assignmentID string) error {
+	// 已有任务刚好结束或被并发创建时重新检查，几轮之内一定能落到其中一种情况
+	for i := 0; i < 3; i++ {
+		now := time.Now()
+		active, err := q.jobRepo.Activate(submissionID, now)
+		if err != nil {
+			return err
+		}
+		if active {
+			q.notify()
+			return nil
+		}
+
+		job := &model.GradingJob{
+			ID:           uuid.New().String(),
+			SubmissionID: submissionID,
+			AssignmentID: assignmentID,
+			Status:       model.GradingJobQueued,
+			MaxAttempts:  q.opts.MaxAttempts,
+			NextRunAt:    now,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		created, err := q.jobRepo.CreateIfAbsent(job)
+		if err != nil {
+			return err
+		}
+		if created {
+			q.notify()
+			return nil
+		}
+	}
+	return errors.New("批改任务状态冲突，请稍后重试")
+}
+
+// Notify 唤醒空闲 worker，用于任务被批量重新排队之后
+func (q *GradingQueue) Notify() {
+	q.notify()
+}
+
+// Start 恢复上次未执行完的任务并启动 worker
+func (q *GradingQueue) Start(handler GradingHandler) error {
+	if handler == nil {
+		return errors.New("批改队列缺少处理函数")
+	}
+	q.handler = handler
+
+	// 单实例部署：启动时仍处于执行中的任务一定是上次退出时被中断的
+	if err := q.jobRepo.ResetRunning(); err != nil {
+		return err
+	}
+
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return nil
+}
+
+// Shutdown 停止领取新任务并等待执行中的任务完成；
+// ctx 到期时取消仍在执行的批改，这些任务会重新排队，下次启动后继续
+func (q *GradingQueue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancelJobs()
+		return nil
+	case <-ctx.Done():
+		q.cancelJobs()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *GradingQueue) notify() {
+	for i := 0; i < q.opts.Workers; i++ {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
+
+func (q *GradingQueue) worker() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stopping:
+			return
+		default:
+		}
+
+		job, err := q.jobRepo.ClaimNext(time.Now())
+		if err != nil {
+			log.Printf("领取批改任务失败: %v", err)
+		}
+		if job != nil {
+			q.run(job)
+			continue
+		}
+
+		select {
+		case <-q.stopping:
+			return
+		case <-q.wake:
+		case <-time.After(q.opts.PollInterval):
+		}
+	}
+}
+
+// run 执行一个已领取的任务并记录结果
+func (q *GradingQueue) run(job *model.GradingJob) {
+	ctx, cancel := context.WithTimeout(q.jobCtx, q.opts.JobTimeout)
+	err := q.handler(ctx, job.SubmissionID)
+	cancel()
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		job.Status = model.GradingJobSucceeded
+		job.LastError = ""
+		job.FinishedAt = &now
+	case q.jobCtx.Err() != nil:
+		// 因服务关闭被中断，不计入重试次数
+		job.Status = model.GradingJobQueued
+		job.Attempts--
+		job.LastError = "服务关闭，批改被中断"
+		job.NextRunAt = now
+	case job.Attempts >= job.MaxAttempts:
+		job.Status = model.GradingJobFailed
+		job.LastError = err.Error()
+		job.FinishedAt = &now
+		log.Printf("批改任务 %s 失败，已重试 %d 次: %v", job.ID, job.Attempts, err)
+	default:
+		job.Status = model.GradingJobQueued
+		job.LastError = err.Error()
+		job.NextRunAt = now.Add(q.backoff(job.Attempts))
+		log.Printf("批改任务 %s 第 %d 次执行失败，将于 %s 重试: %v", job.ID, job.Attempts, job.NextRunAt.Format("15:04:05"), err)
+	}
+
+	if err := q.jobRepo.Finish(job); err != nil {
+		log.Printf("更新批改任务 %s 失败: %v", job.ID, err)
+	}
+}
+
+// backoff 第 n 次失败后的等待时间：BaseBackoff * 2^(n-1)，不超过 MaxBackoff
+func (q *GradingQueue) backoff(attempts int) time.Duration {
+	d := q.opts.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= q.opts.MaxBackoff {
+			return q.opts.MaxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"GoCodeMentor/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// 小组作业：同组学生共用一份主提交，批改、教师复核、版本和迟交规则都作用在主提交上；
+// 每个组员另有一份副本用于查看、申诉和计入成绩册，副本的得分为小组得分加上个人调整。
+
+// isGroupCopy 判断提交是否是小组主提交的组员副本
+func isGroupCopy(submission *model.Submission) bool {
+	return submission.GroupSubmissionID != nil
+}
+
+// sharedSubmission 组员副本返回对应的小组主提交，其他提交原样返回；教师对副本的改分、批注和重新批改都转到主提交上
+func (s *SubmissionStore) sharedSubmission(submission *model.Submission) (*model.Submission, error) {
+	if !isGroupCopy(submission) {
+		return submission, nil
+	}
+	primary, err := s.submissionRepo.GetByID(*submission.GroupSubmissionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取小组提交失败: %w", err)
+	}
+	return primary, nil
+}
+
+// gradingUnits 把提交列表中的组员副本换成对应的主提交并去重，得到需要批改或重新计分的提交
+func (s *SubmissionStore) gradingUnits(submissions []model.Submission) ([]model.Submission, error) {
+	seen := make(map[string]bool, len(submissions))
+	units := make([]model.Submission, 0, len(submissions))
+	for i := range submissions {
+		sub, err := s.sharedSubmission(&submissions[i])
+		if err != nil {
+			return nil, err
+		}
+		if seen[sub.ID] {
+			continue
+		}
+		seen[sub.ID] = true
+		units = append(units, *sub)
+	}
+	return units, nil
+}
+
+// studentGroupID 作业在学生的班级中发布为小组作业时返回学生所在的小组，个人作业返回空字符串
+func (s *SubmissionStore) studentGroupID(assignID, studentID string) (string, error) {
+	ac, err := s.studentAssignmentClass(assignID, studentID)
+	if err != nil || ac == nil || !ac.GroupWork {
+		return "", err
+	}
+	member, err := s.groupRepo.GetMember(ac.ClassID, studentID)
+	if err != nil {
+		return "", err
+	}
+	if member == nil {
+		return "", errors.New("这是小组作业，你还没有分组，请联系教师")
+	}
+	return member.GroupID, nil
+}
+
+// syncGroupSubmission 把小组主提交的作答、批改结果和状态同步到每个组员的副本；
+// 已离开小组的学生保留原来的副本并继续同步。不是小组主提交时不做处理
+func (s *SubmissionStore) syncGroupSubmission(primary *model.Submission) ([]model.Submission, error) {
+	if primary.GroupID == nil || isGroupCopy(primary) {
+		return nil, nil
+	}
+	maxScore, err := s.assignmentMaxScore(primary.AssignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	copies, err := s.submissionRepo.GetGroupCopies(primary.ID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groupRepo.GetMembersByGroupID(*primary.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	synced := make(map[string]bool, len(copies))
+	for i := range copies {
+		copyGroupSubmission(primary, &copies[i], maxScore)
+		if err := s.submissionRepo.Update(&copies[i]); err != nil {
+			return nil, err
+		}
+		synced[copies[i].StudentID] = true
+	}
+
+	for _, m := range members {
+		if synced[m.StudentID] {
+			continue
+		}
+		// 组员可能已有个人提交或其他小组的副本（调整过分组），改为本组主提交的副本
+		c, err := s.submissionRepo.GetByAssignmentAndStudent(primary.AssignmentID, m.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			c.ScoreAdjustment = 0
+			copyGroupSubmission(primary, c, maxScore)
+			err = s.submissionRepo.Update(c)
+		} else {
+			c = &model.Submission{ID: uuid.New().String(), StudentID: m.StudentID, CreatedAt: time.Now()}
+			if user, err := s.userRepo.GetByID(m.StudentID); err == nil {
+				c.StudentName = user.Name
+			}
+			copyGroupSubmission(primary, c, maxScore)
+			err = s.submissionRepo.Create(c)
+		}
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, *c)
+	}
+	return copies, nil
+}
+
+// syncGroup 同步小组主提交，失败只记录日志，不影响主提交已经保存的结果
+func (s *SubmissionStore) syncGroup(primary *model.Submission) {
+	if _, err := s.syncGroupSubmission(primary); err != nil {
+		log.Printf("同步小组提交 %s 到组员失败: %v", primary.ID, err)
+	}
+}
+
+// copyGroupSubmission 用主提交覆盖副本的作答和批改结果，保留副本自己的学生、个人调整和创建时间
+func copyGroupSubmission(primary, c *model.Submission, maxScore int) {
+	c.AssignmentID = primary.AssignmentID
+	c.Answers = primary.Answers
+	c.CodeContent = primary.CodeContent
+	c.AIFeedback = primary.AIFeedback
+	c.TeacherFeedback = primary.TeacherFeedback
+	c.QuestionFeedback = primary.QuestionFeedback
+	c.QuestionScores = primary.QuestionScores
+	c.DetailedScore = primary.DetailedScore
+	c.RunResults = primary.RunResults
+	c.StaticAnalysis = primary.StaticAnalysis
+	c.Status = primary.Status
+	c.Attempts = primary.Attempts
+	c.CountedAttempt = primary.CountedAttempt
+	c.RawScore = primary.RawScore
+	c.IsLate = primary.IsLate
+	c.LateMinutes = primary.LateMinutes
+	c.LatePenalty = primary.LatePenalty
+	c.AIQuestionScores = primary.AIQuestionScores
+	c.AIRawScore = primary.AIRawScore
+	c.TeacherReviewed = primary.TeacherReviewed
+	c.ReviewedBy = primary.ReviewedBy
+	c.ReviewedAt = primary.ReviewedAt
+	c.GroupID = primary.GroupID
+	c.GroupSubmissionID = &primary.ID
+	c.GroupScore = primary.TotalScore
+	c.TotalScore = adjustGroupScore(primary.TotalScore, c.ScoreAdjustment, maxScore)
+	c.UpdatedAt = primary.UpdatedAt
+}
+
+// adjustGroupScore 小组得分加上个人调整，结果限制在 0 到满分之间；小组还没有得分时返回 nil
+func adjustGroupScore(groupScore *int, adjustment, maxScore int) *int {
+	if groupScore == nil {
+		return nil
+	}
+	score := *groupScore + adjustment
+	if score < 0 {
+		score = 0
+	}
+	if maxScore > 0 && score > maxScore {
+		score = maxScore
+	}
+	return &score
+}
+
+// assignmentMaxScore 作业各题分值之和
+func (s *SubmissionStore) assignmentMaxScore(assignID string) (int, error) {
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, q := range questions {
+		total += q.Score
+	}
+	return total, nil
+}
+
+// GroupSubmissionService 小组作业服务：教师查看组员副本并设置个人调整分
+type GroupSubmissionService struct {
+	*SubmissionStore
+	authz IAuthorizationService
+}
+
+// NewGroupSubmissionService 创建小组作业服务
+func NewGroupSubmissionService(store *SubmissionStore, authz IAuthorizationService) IGroupSubmissionService {
+	return &GroupSubmissionService{SubmissionStore: store, authz: authz}
+}
+
+// SetScoreAdjustment 教师为小组作业中的某个组员设置个人调整分，在小组得分的基础上加减
+func (s *GroupSubmissionService) SetScoreAdjustment(teacherID, submissionID string, adjustment int) (*model.Submission, error) {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return nil, errors.New("提交记录不存在")
+	}
+	if _, err := s.authz.AuthorizeAssignment(teacherID, submission.AssignmentID, CapGrade); err != nil {
+		return nil, err
+	}
+	if !isGroupCopy(submission) {
+		return nil, errors.New("只有小组作业可以设置个人调整分")
+	}
+	maxScore, err := s.assignmentMaxScore(submission.AssignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if adjustment < -maxScore || adjustment > maxScore {
+		return nil, fmt.Errorf("个人调整分必须在 -%d 到 %d 之间", maxScore, maxScore)
+	}
+
+	submission.ScoreAdjustment = adjustment
+	submission.TotalScore = adjustGroupScore(submission.GroupScore, adjustment, maxScore)
+	submission.UpdatedAt = time.Now()
+	if err := s.submissionRepo.Update(submission); err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+// GetGroupMembersSubmissions 获取与该提交同属一个小组主提交的全部组员副本，用于教师查看和调整个人得分
+func (s *GroupSubmissionService) GetGroupMembersSubmissions(teacherID, submissionID string) ([]model.Submission, error) {
+	submission, err := s.submissionRepo.GetByID(submissionID)
+	if err != nil {
+		return nil, errors.New("提交记录不存在")
+	}
+	if _, err := s.authz.AuthorizeAssignment(teacherID, submission.AssignmentID, CapViewClass); err != nil {
+		return nil, err
+	}
+	primary, err := s.sharedSubmission(submission)
+	if err != nil {
+		return nil, err
+	}
+	if primary.GroupID == nil {
+		return nil, errors.New("不是小组作业的提交")
+	}
+	return s.submissionRepo.GetGroupCopies(primary.ID)
+}
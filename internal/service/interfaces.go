@@ -3,6 +3,8 @@ package service
 import (
 	"GoCodeMentor/internal/dto"
 	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/pkg/excel"
+	"GoCodeMentor/internal/pkg/runner"
 	"context"
 	"time"
 )
@@ -27,22 +29,93 @@ type IUserService interface {
 	ResetPassword(userID, newPassword string) error
 }
 
+// IAuthService 定义了登录会话的签发、校验与吊销接口。
+type IAuthService interface {
+	// CreateSession 为用户创建登录会话，返回明文令牌
+	CreateSession(userID, userAgent, clientIP string) (string, *model.UserSession, error)
+	// ValidateSession 校验令牌并返回对应的用户
+	ValidateSession(token string) (*model.User, error)
+	// RevokeSession 吊销单个会话（登出）
+	RevokeSession(token string) error
+	// RevokeUserSessions 吊销用户的所有会话
+	RevokeUserSessions(userID string) error
+}
+
+// IAuthorizationService 定义了班级所有者、合作教师和助教的权限校验接口，
+// 所有教师端的权限检查都通过它完成。
+type IAuthorizationService interface {
+	// ClassRole 返回用户在班级中的角色（owner、co_teacher、ta），不是教学人员时返回空字符串
+	ClassRole(userID string, class *model.Class) (string, error)
+	// ClassCapabilities 返回用户在班级中的角色和拥有的权限
+	ClassCapabilities(userID, classID string) (string, []Capability, error)
+	// AuthorizeClass 校验用户在班级中拥有该权限，返回班级
+	AuthorizeClass(userID, classID string, capability Capability) (*model.Class, error)
+	// AuthorizeAssignment 校验用户对作业拥有该权限（创建者或作业所在班级的教学人员），返回作业
+	AuthorizeAssignment(userID, assignID string, capability Capability) (*model.Assignment, error)
+	// AuthorizeStudent 校验用户在学生所在的任一班级中拥有该权限
+	AuthorizeStudent(userID, studentID string, capability Capability) error
+	// StaffClasses 获取用户创建或作为教学人员加入的全部班级
+	StaffClasses(userID string) ([]model.Class, error)
+}
+
 // IClassService 定义了班级管理相关的业务逻辑接口。
 type IClassService interface {
 	// CreateClass 创建一个新班级
 	CreateClass(name, teacherID string) (*model.Class, error)
 	// GetClassByID 获取班级详情
 	GetClassByID(id string) (*model.Class, error)
-	// GetClassesByTeacherID 获取某位教师创建的所有班级
+	// GetClassesByTeacherID 获取教师创建的班级以及作为合作教师、助教加入的班级
 	GetClassesByTeacherID(teacherID string) ([]model.Class, error)
 	// GetClassByCode 根据邀请码查找班级
 	GetClassByCode(code string) (*model.Class, error)
-	// JoinClass 学生通过邀请码加入班级
-	JoinClass(studentID, code string) error
-	// AddStudentToClass 将学生手动添加到班级
-	AddStudentToClass(studentID, classID string) error
+	// JoinClass 学生通过邀请码加入班级（可以同时属于多个班级），班级需要审批时提交申请并返回 pending 为 true
+	JoinClass(studentID, code string) (pending bool, err error)
+	// AddStudentToClass 将学生手动添加到班级，role 为学生在班级中的身份
+	AddStudentToClass(studentID, classID, role string) error
 	// RemoveStudentFromClass 将学生从班级中移除
 	RemoveStudentFromClass(studentID, classID string) error
+	// GetStudentClasses 获取学生当前所在的全部班级
+	GetStudentClasses(studentID string) ([]model.Class, error)
+	// InviteStaff 邀请教师作为班级的合作教师或助教
+	InviteStaff(ownerID, classID, username, role string) (*model.ClassStaff, error)
+	// GetClassStaff 获取班级的教学人员和待接受的邀请
+	GetClassStaff(userID, classID string) ([]model.ClassStaff, error)
+	// GetStaffInvitations 获取用户收到的待处理邀请
+	GetStaffInvitations(userID string) ([]model.ClassStaff, error)
+	// RespondStaffInvitation 接受或拒绝邀请
+	RespondStaffInvitation(userID, staffID string, accept bool) error
+	// UpdateStaffRole 修改教学人员的角色
+	UpdateStaffRole(ownerID, classID, staffID, role string) error
+	// RemoveStaff 移除教学人员、撤回邀请或自己退出
+	RemoveStaff(userID, classID, staffID string) error
+	// UpdateInviteSettings 设置邀请码的过期时间、使用次数上限和是否需要审批
+	UpdateInviteSettings(userID, classID string, req dto.InviteCodeSettingsRequest) (*model.Class, error)
+	// RegenerateInviteCode 重新生成邀请码，旧邀请码立即失效
+	RegenerateInviteCode(userID, classID string) (*model.Class, error)
+	// RevokeInviteCode 停用邀请码
+	RevokeInviteCode(userID, classID string) (*model.Class, error)
+	// GetJoinRequests 获取班级的加入申请
+	GetJoinRequests(userID, classID string, statuses []string) ([]model.ClassJoinRequest, error)
+	// GetMyJoinRequests 获取学生提交的加入申请
+	GetMyJoinRequests(studentID string) ([]model.ClassJoinRequest, error)
+	// ReviewJoinRequest 通过或拒绝加入申请
+	ReviewJoinRequest(userID, classID, requestID string, approve bool) error
+	// ArchiveClass 归档班级，归档后班级只读
+	ArchiveClass(userID, classID string) (*model.Class, error)
+	// UnarchiveClass 取消归档
+	UnarchiveClass(userID, classID string) (*model.Class, error)
+	// GetGroups 获取班级的小组、组员和未分组的学生
+	GetGroups(userID, classID string) (*ClassGroups, error)
+	// CreateGroup 在班级中创建小组
+	CreateGroup(userID, classID, name string) (*model.ClassGroup, error)
+	// RenameGroup 修改小组名称
+	RenameGroup(userID, classID, groupID, name string) (*model.ClassGroup, error)
+	// DeleteGroup 删除小组，组员变为未分组
+	DeleteGroup(userID, classID, groupID string) error
+	// SetStudentGroup 把学生移入小组，groupID 为空时移出所在小组
+	SetStudentGroup(userID, classID, studentID, groupID string) error
+	// GenerateGroups 随机或按以往成绩均衡地自动分组，替换现有小组
+	GenerateGroups(userID, classID string, req dto.GenerateGroupsRequest) (*ClassGroups, error)
 	// DeleteClass 删除班级及其相关关联
 	DeleteClass(classID string) error
 	// GenerateClassAnalysisReport 生成班级学情分析报告
@@ -54,20 +127,34 @@ type IAssignmentService interface {
 	GetSubmissionsByAssignmentIDs(assignmentIDs []string) ([]model.Submission, error)
 	// GenerateAssignmentByAI 使用 AI 技术根据主题和难度生成作业题目
 	GenerateAssignmentByAI(ctx context.Context, topic, difficulty, teacherID string) (*model.Assignment, error)
-	// GetAssignmentList 获取教师创建的作业列表
+	// GetAssignmentList 获取教师创建的作业，以及所在班级中其他教师发布的作业
 	GetAssignmentList(teacherID string) ([]model.Assignment, error)
 	// GetAllAssignments 获取系统内所有作业
 	GetAllAssignments() ([]model.Assignment, error)
 	// GetAssignmentsByClass 获取发布到特定班级的作业
 	GetAssignmentsByClass(classID string) ([]model.Assignment, error)
-	// PublishAssignment 将作业发布到指定班级并设置截止日期
-	PublishAssignment(assignID, classID string, deadline *time.Time) error
+	// GetAssignmentsForStudent 获取学生所在全部班级的作业（学生可以同时属于多个班级）
+	GetAssignmentsForStudent(studentID string) ([]model.Assignment, error)
+	// PublishAssignment 将作业发布到指定班级并设置截止日期、发放时间、关闭时间以及是否为小组作业
+	PublishAssignment(assignID, classID string, deadline, releaseAt, closesAt *time.Time, groupWork bool) error
+	// UnpublishAssignment 从单个班级撤回作业
+	UnpublishAssignment(teacherID, assignID, classID string) error
+	// CloneClassAssignments 把源班级的作业复制到目标班级，能确定时间偏移时按偏移后的时间发布
+	CloneClassAssignments(userID, sourceClassID string, req dto.CloneAssignmentsRequest) ([]model.Assignment, error)
+	// ApplyPublishSchedules 按发放和关闭时间切换作业在各班级的状态
+	ApplyPublishSchedules() (int, error)
 	// GetPublishedClasses 获取作业已发布到的班级列表（包含班级名称）
 	GetPublishedClasses(assignID string) ([]model.AssignmentClassWithClassName, error)
 	// GetAssignmentDetail 获取作业详情及包含的所有题目
 	GetAssignmentDetail(assignID string) (*model.Assignment, []model.Question, error)
 	// SubmitAssignment 学生提交作业答案
 	SubmitAssignment(assignID, studentID, studentName string, answers map[string]string, code string) (string, error)
+	// SaveDraft 自动保存学生的答案草稿（不触发批改）
+	SaveDraft(assignID, studentID string, answers map[string]string, code string) (*model.AnswerDraft, error)
+	// GetDraft 获取学生的答案草稿，没有草稿时返回 nil
+	GetDraft(assignID, studentID string) (*model.AnswerDraft, error)
+	// GetDraftsByStudentAndAssignments 批量获取学生在多个作业上的答案草稿
+	GetDraftsByStudentAndAssignments(studentID string, assignmentIDs []string) ([]model.AnswerDraft, error)
 	// GradeSubmission 使用 AI 对学生提交的作业进行自动评分和反馈
 	GradeSubmission(ctx context.Context, subID string) error
 	// GetSubmission 获取特定的提交记录
@@ -77,18 +164,172 @@ type IAssignmentService interface {
 	GetSubmissionsByStudentAndAssignments(studentID string, assignmentIDs []string) ([]model.Submission, error)
 	// GetPendingSubmissionCountByAssignment 统计作业待批改的提交数
 	GetPendingSubmissionCountByAssignment(assignmentID string) (int64, error)
-	// UpdateSubmissionScore 手动更新学生作业得分
-	UpdateSubmissionScore(submissionID string, score int) error
+	// UpdateSubmissionScore 教师手动修改学生作业的原始总分，迟交罚分照常扣除
+	UpdateSubmissionScore(teacherID, submissionID string, score int) error
 	// UpdateTeacherFeedback 更新教师对作业的评语
 	UpdateTeacherFeedback(submissionID string, feedback string) error
-	// RegradeSubmission 重新触发 AI 对作业的批改过程
-	RegradeSubmission(submissionID string) error
+	// UpdateQuestionScore 教师修改单个题目的分数，重新计算总分并保留 AI 原始得分
+	UpdateQuestionScore(teacherID, submissionID, questionID string, score int) error
+	// UpdateQuestionFeedback 教师修改单个题目的批注
+	UpdateQuestionFeedback(teacherID, submissionID, questionID, feedback string) error
+	// GetQuestionScore 获取单个题目的分数
+	GetQuestionScore(submissionID string, questionID string) (int, error)
+	// GetQuestionFeedback 获取单个题目的批注
+	GetQuestionFeedback(submissionID string, questionID string) (string, error)
+	// RegradeSubmission 重新触发 AI 对作业的批改过程，discardReview 为 true 时放弃教师的复核结果
+	RegradeSubmission(submissionID string, discardReview bool) error
+	// CreateAppeal 学生对已批改提交中某道题的得分提出申诉
+	CreateAppeal(studentID, submissionID string, req dto.GradeAppealRequest) (*model.GradeAppeal, error)
+	// GetSubmissionAppeals 获取提交的全部申诉及处理结果
+	GetSubmissionAppeals(submissionID string) ([]model.GradeAppeal, error)
+	// GetClassAppeals 教师查看班级的申诉，statuses 为空时返回全部状态
+	GetClassAppeals(teacherID, classID string, statuses []string) ([]model.GradeAppeal, error)
+	// AcceptAppeal 接受申诉并调整该题得分
+	AcceptAppeal(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error)
+	// RejectAppeal 驳回申诉
+	RejectAppeal(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error)
+	// ReevaluateAppeal 把申诉所在的提交交给 AI 重新批改，批改完成后自动结案
+	ReevaluateAppeal(teacherID, appealID string, req dto.AppealResolutionRequest) (*model.GradeAppeal, error)
+	// GetSubmissionVersions 获取提交的全部历史版本
+	GetSubmissionVersions(submissionID string) ([]model.SubmissionVersion, error)
+	// DiffSubmissionVersions 比较同一提交的两个版本
+	DiffSubmissionVersions(submissionID string, fromAttempt, toAttempt int) (*VersionDiff, error)
+	// UpdateAttemptPolicy 设置作业的最多提交次数和计分方式（last / best）
+	UpdateAttemptPolicy(teacherID, assignID string, maxAttempts int, scorePolicy string) error
+	// GetGradingJobs 获取作业的批改任务，可按状态过滤
+	GetGradingJobs(assignID string, statuses []string) ([]model.GradingJob, error)
+	// RequeueGradingJobs 批量重新排队失败或等待重试的批改任务
+	RequeueGradingJobs(assignID string, jobIDs []string) (int64, error)
+	// EnqueueOrphanedSubmissions 为待批改但没有批改任务的提交补建任务，assignID 为空时处理全部作业
+	EnqueueOrphanedSubmissions(assignID string) (int, error)
+	// CreateAssignment 教师手动创建作业
+	CreateAssignment(teacherID string, req dto.AssignmentRequest) (*model.Assignment, error)
+	// UpdateAssignment 修改作业信息
+	UpdateAssignment(teacherID, assignID string, req dto.AssignmentRequest) (*model.Assignment, error)
+	// CreateQuestion 为作业添加题目
+	CreateQuestion(teacherID, assignID string, req dto.QuestionRequest) (*model.Question, error)
+	// UpdateQuestion 修改题目（已有提交时只能修改答案和分值）
+	UpdateQuestion(teacherID, questionID string, req dto.QuestionRequest) (*model.Question, error)
+	// DeleteQuestion 删除题目
+	DeleteQuestion(teacherID, questionID string) error
+	// ReorderQuestions 调整作业题目顺序
+	ReorderQuestions(teacherID, assignID string, questionIDs []string) error
+	// UpdateQuestionTests 为编程题设置教师测试文件和输入输出用例
+	UpdateQuestionTests(teacherID, questionID, testCode string, cases []runner.TestCase) error
+	// UpdateQuestionGradingRule 为选择题/填空题设置判分规则
+	UpdateQuestionGradingRule(teacherID, questionID string, rule GradingRule) error
+	// UpdateQuestionRubric 为主观题/编程题设置评分标准
+	UpdateQuestionRubric(teacherID, questionID string, rubric QuestionRubric) error
 	// GetSubmissionCodeForDownload 获取提交的代码内容用于下载
 	GetSubmissionCodeForDownload(submissionID string) (string, string, error)
 	// DeleteAssignment 删除作业及其关联题目和提交记录
 	DeleteAssignment(assignID string) error
 }
 
+// IExamService 定义了考试模式的设置、开考计时、答题与交卷相关的业务逻辑接口。
+type IExamService interface {
+	// UpdateExamSettings 设置作业的考试模式、考试时长和乱序方式
+	UpdateExamSettings(teacherID, assignID string, req dto.ExamSettingsRequest) error
+	// StartExam 学生开始考试并开始计时
+	StartExam(assignID, studentID string) (*ExamView, error)
+	// GetExam 获取学生的考试题目、剩余时间和已保存的答案
+	GetExam(assignID, studentID string) (*ExamView, error)
+	// SaveExamAnswers 保存考试答案草稿
+	SaveExamAnswers(assignID, studentID string, answers map[string]string, code string) (time.Time, error)
+	// SubmitExam 学生交卷
+	SubmitExam(assignID, studentID string, answers map[string]string, code string) (string, error)
+	// GetExamSessions 教师查看作业的考试情况
+	GetExamSessions(teacherID, assignID string) ([]model.ExamSession, error)
+	// AutoSubmitExpiredExams 自动提交已超时的考试
+	AutoSubmitExpiredExams() (int, error)
+}
+
+// ILatePolicyService 定义了作业迟交规则与学生个人延期相关的业务逻辑接口。
+type ILatePolicyService interface {
+	// UpdateLatePolicy 设置作业在某个班级的迟交规则
+	UpdateLatePolicy(teacherID, assignID, classID string, req dto.LatePolicyRequest) error
+	// GrantExtension 为学生延长截止时间
+	GrantExtension(teacherID, assignID, studentID string, req dto.ExtensionRequest) error
+	// RevokeExtension 取消学生的个人延期
+	RevokeExtension(teacherID, assignID, studentID string) error
+	// GetExtensions 获取作业的全部个人延期
+	GetExtensions(teacherID, assignID string) ([]model.DeadlineExtension, error)
+}
+
+// IGroupSubmissionService 定义了小组作业中组员提交与个人调整分相关的业务逻辑接口。
+type IGroupSubmissionService interface {
+	// SetScoreAdjustment 为小组作业中的某个组员设置个人调整分
+	SetScoreAdjustment(teacherID, submissionID string, adjustment int) (*model.Submission, error)
+	// GetGroupMembersSubmissions 获取小组作业中同组全部组员的提交
+	GetGroupMembersSubmissions(teacherID, submissionID string) ([]model.Submission, error)
+}
+
+// ISimilarityService 定义了提交相似度（抄袭）检测相关的业务逻辑接口。
+type ISimilarityService interface {
+	// StartCheck 在后台启动一次作业内提交的相似度检测
+	StartCheck(teacherID, assignID string, threshold float64) (*model.SimilarityReport, error)
+	// GetLatestReport 获取作业最近一次的检测报告
+	GetLatestReport(teacherID, assignID string) (*model.SimilarityReport, *SimilarityResult, error)
+	// GetPairDetail 并排对比一对可疑提交
+	GetPairDetail(teacherID, assignID, submissionA, submissionB string) (*SimilarityPairDetail, error)
+	// Shutdown 停止正在执行的检测并等待退出
+	Shutdown(ctx context.Context) error
+}
+
+// ICourseService 定义了课程、学期以及班级所属课程和学期的业务逻辑接口
+type ICourseService interface {
+	// CreateCourse 创建课程
+	CreateCourse(teacherID string, req dto.CourseRequest) (*model.Course, error)
+	// GetCourses 获取教师创建的课程
+	GetCourses(teacherID string) ([]model.Course, error)
+	// UpdateCourse 修改课程信息
+	UpdateCourse(teacherID, courseID string, req dto.CourseRequest) (*model.Course, error)
+	// DeleteCourse 删除没有班级的课程
+	DeleteCourse(teacherID, courseID string) error
+	// GetCourseClasses 获取课程下的全部班级
+	GetCourseClasses(teacherID, courseID string) ([]model.Class, error)
+	// CreateTerm 创建学期
+	CreateTerm(userID string, req dto.TermRequest) (*model.Term, error)
+	// GetTerms 获取全部学期
+	GetTerms() ([]model.Term, error)
+	// SetClassCourse 设置班级所属的课程和学期
+	SetClassCourse(userID, classID string, req dto.ClassCourseRequest) (*model.Class, error)
+}
+
+// IGradebookService 定义了班级成绩册相关的业务逻辑接口
+type IGradebookService interface {
+	// GetGradebook 生成班级成绩册：学生 × 已发布作业的得分矩阵、分类得分、总评和等级
+	GetGradebook(teacherID, classID string) (*Gradebook, error)
+	// UpdateSettings 修改分类权重、去掉最低分次数、缺交计零分和等级划分，并可调整作业分类
+	UpdateSettings(teacherID, classID string, req dto.GradebookSettingsRequest) (*GradebookConfig, error)
+	// OverrideCell 手动修改单元格成绩，Score 为空时撤销手动成绩；每次修改都记录审计日志
+	OverrideCell(teacherID, classID, assignID, studentID string, req dto.GradeOverrideRequest) error
+	// GetAuditLog 获取班级成绩的修改记录，studentID 不为空时只返回该学生的记录
+	GetAuditLog(teacherID, classID, studentID string) ([]model.GradeAuditLog, error)
+	// ExportAssignment 导出作业成绩单（逐题得分、总分、状态、迟交和教师评语），classID 为空时包含所有发布班级
+	ExportAssignment(teacherID, assignID, classID string) (*excel.Sheet, error)
+	// ExportClass 导出班级成绩册：汇总表加每个作业一个成绩单
+	ExportClass(teacherID, classID string) ([]excel.Sheet, error)
+}
+
+// IQuestionBankService 定义了题库管理与从题库组题相关的业务逻辑接口。
+type IQuestionBankService interface {
+	// CreateItem 向题库添加题目
+	CreateItem(teacherID string, req dto.BankQuestionRequest) (*model.BankQuestion, error)
+	// UpdateItem 修改题库题目
+	UpdateItem(teacherID, itemID string, req dto.BankQuestionRequest) (*model.BankQuestion, error)
+	// DeleteItem 删除题库题目
+	DeleteItem(teacherID, itemID string) error
+	// GetItem 获取题库题目
+	GetItem(teacherID, itemID string) (*model.BankQuestion, error)
+	// Search 按题型、难度、知识点和关键字检索题库
+	Search(teacherID string, query dto.BankQuestionQuery) ([]model.BankQuestion, error)
+	// SaveFromAssignment 将作业题目保存到题库
+	SaveFromAssignment(teacherID, assignID string, req dto.SaveToBankRequest) ([]model.BankQuestion, error)
+	// AddToAssignment 从题库挑选或按规则抽取题目加入作业
+	AddToAssignment(teacherID, assignID string, req dto.AddFromBankRequest) ([]model.Question, error)
+}
+
 // IFeedbackService 定义了系统反馈与意见管理相关的业务逻辑接口。
 type IFeedbackService interface {
 	// Create 创建一条新的反馈记录
@@ -115,6 +356,8 @@ type IFeedbackService interface {
 type ISessionService interface {
 	// Chat 处理用户与 AI 助教的对话，支持上下文会话
 	Chat(ctx context.Context, sessionID, userID, userQuestion string) (string, string, error)
+	// ChatStream 流式对话，每个片段通过 onToken 回调输出，返回完整回答和会话 ID
+	ChatStream(ctx context.Context, sessionID, userID, userQuestion string, onToken func(string) error) (string, string, error)
 	// GetHistory 获取会话的历史聊天记录
 	GetHistory(sessionID, userID string) ([]model.ChatMessage, error)
 	// GetUserSessions 获取用户创建的所有对话会话
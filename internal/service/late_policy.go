@@ -0,0 +1,307 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// latePolicy 某个学生在某个作业上的有效截止时间和迟交规则
+type latePolicy struct {
+	deadline  *time.Time
+	grace     time.Duration
+	allowLate bool
+	perDay    int
+	cutoff    *time.Time
+	closes    *time.Time // 班级的关闭时间，之后无论截止规则如何都不再接受提交
+}
+
+// lateness 一次提交的迟交情况
+type lateness struct {
+	Late    bool
+	Minutes int // 相对截止时间迟交的分钟数
+	Penalty int // 扣分百分比
+}
+
+// newLatePolicy 合并班级的迟交规则和学生的个人延期；延期晚于最终截止时间或关闭时间时，两者随之顺延
+func newLatePolicy(ac *model.AssignmentClass, ext *model.DeadlineExtension) latePolicy {
+	p := latePolicy{
+		deadline:  ac.Deadline,
+		grace:     time.Duration(ac.GraceMinutes) * time.Minute,
+		allowLate: ac.AllowLate,
+		perDay:    ac.LatePenaltyPerDay,
+		cutoff:    ac.HardCutoff,
+		closes:    ac.ClosesAt,
+	}
+	if ext != nil {
+		deadline := ext.Deadline
+		p.deadline = &deadline
+		if p.cutoff != nil && deadline.After(*p.cutoff) {
+			cutoff := deadline.Add(p.grace)
+			p.cutoff = &cutoff
+		}
+		if p.closes != nil && deadline.After(*p.closes) {
+			closes := deadline.Add(p.grace)
+			p.closes = &closes
+		}
+	}
+	return p
+}
+
+// check 判断 at 时刻是否还能提交
+func (p latePolicy) check(at time.Time) error {
+	if p.closes != nil && at.After(*p.closes) {
+		return fmt.Errorf("作业已关闭，关闭时间为: %s", p.closes.Format("2006-01-02 15:04:05"))
+	}
+	if p.deadline == nil {
+		return nil
+	}
+	due := p.deadline.Add(p.grace)
+	if !at.After(due) {
+		return nil
+	}
+	if !p.allowLate {
+		return fmt.Errorf("作业提交已截止，截止时间为: %s", p.deadline.Format("2006-01-02 15:04:05"))
+	}
+	if p.cutoff != nil && at.After(*p.cutoff) {
+		return fmt.Errorf("已超过最终截止时间: %s，无法再提交", p.cutoff.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// pastDue 判断 at 时刻是否已过截止时间（含宽限期）或作业已关闭
+func (p latePolicy) pastDue(at time.Time) bool {
+	if p.closes != nil && at.After(*p.closes) {
+		return true
+	}
+	return p.deadline != nil && at.After(p.deadline.Add(p.grace))
+}
+
+// closesAt 返回之后不再接受提交的时间，为 nil 表示不限
+func (p latePolicy) closesAt() *time.Time {
+	var end *time.Time
+	if p.deadline != nil {
+		if p.allowLate {
+			end = p.cutoff
+		} else {
+			due := p.deadline.Add(p.grace)
+			end = &due
+		}
+	}
+	if p.closes != nil && (end == nil || p.closes.Before(*end)) {
+		return p.closes
+	}
+	return end
+}
+
+// evaluate 计算 at 时刻提交的迟交情况；宽限期之后不足一天按一天扣分，最多扣完
+func (p latePolicy) evaluate(at time.Time) lateness {
+	if p.deadline == nil {
+		return lateness{}
+	}
+	due := p.deadline.Add(p.grace)
+	if !at.After(due) {
+		return lateness{}
+	}
+
+	days := int(math.Ceil(at.Sub(due).Hours() / 24))
+	penalty := days * p.perDay
+	if penalty > 100 {
+		penalty = 100
+	}
+	return lateness{
+		Late:    true,
+		Minutes: int(at.Sub(*p.deadline).Minutes()),
+		Penalty: penalty,
+	}
+}
+
+// applyLatePenalty 按扣分百分比计算扣分后的得分
+func applyLatePenalty(raw, penalty int) int {
+	if penalty <= 0 {
+		return raw
+	}
+	return int(math.Round(float64(raw) * float64(100-penalty) / 100))
+}
+
+// LatePolicyService 迟交规则和个人延期服务，规则变化后重新计算已有提交的迟交扣分
+type LatePolicyService struct {
+	*SubmissionStore
+	authz IAuthorizationService
+}
+
+// NewLatePolicyService 创建迟交规则服务
+func NewLatePolicyService(store *SubmissionStore, authz IAuthorizationService) ILatePolicyService {
+	return &LatePolicyService{SubmissionStore: store, authz: authz}
+}
+
+// UpdateLatePolicy 设置作业在某个班级的迟交规则，并按新规则重新计算该班级已有提交的迟交扣分
+func (s *LatePolicyService) UpdateLatePolicy(teacherID, assignID, classID string, req dto.LatePolicyRequest) error {
+	if _, err := s.authz.AuthorizeClass(teacherID, classID, CapPublish); err != nil {
+		return err
+	}
+	assign, err := s.assignRepo.GetByID(assignID)
+	if err != nil {
+		return errors.New("作业不存在")
+	}
+	ac, err := s.assignmentClassRepo.GetByAssignmentAndClass(assignID, classID)
+	if err != nil || ac == nil {
+		return errors.New("作业未发布到该班级")
+	}
+	if req.GraceMinutes < 0 {
+		return errors.New("宽限时间不能为负数")
+	}
+	if req.LatePenaltyPerDay < 0 || req.LatePenaltyPerDay > 100 {
+		return errors.New("每天扣分比例必须在 0~100 之间")
+	}
+	if req.HardCutoff != nil && ac.Deadline != nil && req.HardCutoff.Before(*ac.Deadline) {
+		return errors.New("最终截止时间不能早于截止时间")
+	}
+
+	ac.AllowLate = req.AllowLate
+	ac.GraceMinutes = req.GraceMinutes
+	ac.LatePenaltyPerDay = req.LatePenaltyPerDay
+	ac.HardCutoff = req.HardCutoff
+	if err := s.assignmentClassRepo.Update(ac); err != nil {
+		return err
+	}
+
+	submissions, err := s.submissionRepo.GetByAssignmentIDs([]string{assignID})
+	if err != nil {
+		return err
+	}
+	if submissions, err = s.gradingUnits(submissions); err != nil {
+		return err
+	}
+	for i := range submissions {
+		if err := s.refreshLateness(assign, &submissions[i], classID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GrantExtension 为学生延长截止时间，已有的提交按新的截止时间重新计算迟交扣分
+func (s *LatePolicyService) GrantExtension(teacherID, assignID, studentID string, req dto.ExtensionRequest) error {
+	assign, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapPublish)
+	if err != nil {
+		return err
+	}
+	student, err := s.userRepo.GetByID(studentID)
+	if err != nil || student.Role != "student" {
+		return errors.New("学生不存在")
+	}
+	ac, err := s.studentAssignmentClass(assignID, studentID)
+	if err != nil {
+		return err
+	}
+	if ac == nil {
+		return errors.New("该学生不在作业发布到的班级")
+	}
+
+	now := time.Now()
+	extension := &model.DeadlineExtension{
+		ID:           uuid.New().String(),
+		AssignmentID: assignID,
+		StudentID:    studentID,
+		Deadline:     req.Deadline,
+		Reason:       req.Reason,
+		GrantedBy:    teacherID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.extensionRepo.Save(extension); err != nil {
+		return err
+	}
+	return s.refreshStudentLateness(assign, studentID)
+}
+
+// RevokeExtension 取消学生的个人延期
+func (s *LatePolicyService) RevokeExtension(teacherID, assignID, studentID string) error {
+	assign, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapPublish)
+	if err != nil {
+		return err
+	}
+	if err := s.extensionRepo.Delete(assignID, studentID); err != nil {
+		return err
+	}
+	return s.refreshStudentLateness(assign, studentID)
+}
+
+// GetExtensions 获取作业的全部个人延期
+func (s *LatePolicyService) GetExtensions(teacherID, assignID string) ([]model.DeadlineExtension, error) {
+	if _, err := s.authz.AuthorizeAssignment(teacherID, assignID, CapViewClass); err != nil {
+		return nil, err
+	}
+	return s.extensionRepo.GetByAssignmentID(assignID)
+}
+
+// refreshStudentLateness 重新计算某个学生提交的迟交扣分
+func (s *LatePolicyService) refreshStudentLateness(assign *model.Assignment, studentID string) error {
+	submission, err := s.submissionRepo.GetByAssignmentAndStudent(assign.ID, studentID)
+	if err != nil || submission == nil {
+		return err
+	}
+	return s.refreshLateness(assign, submission, "")
+}
+
+// refreshLateness 按学生当前的截止时间、迟交规则和个人延期重新计算每个版本的迟交扣分，
+// 再按计分方式更新提交记录；onlyClassID 非空时只处理该班级的学生。
+// 小组作业按主提交计算，使用第一次提交的组员的截止规则
+func (s *LatePolicyService) refreshLateness(assign *model.Assignment, submission *model.Submission, onlyClassID string) error {
+	submission, err := s.sharedSubmission(submission)
+	if err != nil {
+		return err
+	}
+	ac, err := s.studentAssignmentClass(assign.ID, submission.StudentID)
+	if err != nil || ac == nil {
+		return nil
+	}
+	if onlyClassID != "" && ac.ClassID != onlyClassID {
+		return nil
+	}
+	extension, err := s.extensionRepo.GetByAssignmentAndStudent(assign.ID, submission.StudentID)
+	if err != nil {
+		return err
+	}
+	policy := newLatePolicy(ac, extension)
+
+	versions, err := s.ensureVersions(submission)
+	if err != nil {
+		return err
+	}
+	for i := range versions {
+		v := &versions[i]
+		late := policy.evaluate(v.CreatedAt)
+		v.IsLate = late.Late
+		v.LateMinutes = late.Minutes
+		v.LatePenalty = late.Penalty
+		if v.RawScore != nil {
+			adjusted := applyLatePenalty(*v.RawScore, late.Penalty)
+			v.TotalScore = &adjusted
+		}
+		if err := s.versionRepo.Update(v); err != nil {
+			return err
+		}
+	}
+
+	// 最新版本尚未批改时提交记录上的迟交标记也要更新
+	latest := versions[len(versions)-1]
+	submission.IsLate = latest.IsLate
+	submission.LateMinutes = latest.LateMinutes
+	submission.LatePenalty = latest.LatePenalty
+	if err := s.applyScorePolicy(assign, submission, versions); err != nil {
+		return err
+	}
+	if err := s.submissionRepo.Update(submission); err != nil {
+		return err
+	}
+	s.syncGroup(submission)
+	return nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GradingRule 选择题/填空题的判分规则，保存在 Question.GradingRule 中
+type GradingRule struct {
+	IgnoreCase       bool     `json:"ignore_case"`       // 忽略大小写
+	IgnoreWhitespace bool     `json:"ignore_whitespace"` // 忽略所有空白字符（默认只去掉首尾空白）
+	Alternatives     []string `json:"alternatives"`      // 其他可接受的答案
+	Regex            string   `json:"regex"`             // 整个答案匹配该正则即判为正确，无需写 ^ 和 $
+	PartialCredit    bool     `json:"partial_credit"`    // 多选题：未选错时按选对的比例给分
+}
+
+// objectiveResult 客观题的判分结果
+type objectiveResult struct {
+	Score    int
+	Feedback string
+}
+
+// isObjectiveQuestion 选择题和填空题由程序直接判分，不再交给 AI
+func isObjectiveQuestion(q model.Question) bool {
+	return q.Type == "choice" || q.Type == "fill"
+}
+
+// parseGradingRule 解析题目的判分规则，空值或解析失败时使用默认规则
+func parseGradingRule(raw string) GradingRule {
+	var rule GradingRule
+	if strings.TrimSpace(raw) != "" {
+		json.Unmarshal([]byte(raw), &rule)
+	}
+	return rule
+}
+
+// gradeObjective 对所有客观题判分，返回 题目ID -> 结果
+func gradeObjective(questions []model.Question, answers map[string]string) map[string]objectiveResult {
+	results := make(map[string]objectiveResult)
+	for _, q := range questions {
+		if !isObjectiveQuestion(q) {
+			continue
+		}
+		rule := parseGradingRule(q.GradingRule)
+		studentAns := strings.TrimSpace(answers[q.ID])
+		if studentAns == "" {
+			results[q.ID] = objectiveResult{Score: 0, Feedback: "未作答"}
+			continue
+		}
+		if q.Type == "choice" {
+			results[q.ID] = gradeChoice(q, rule, studentAns)
+		} else {
+			results[q.ID] = gradeFill(q, rule, studentAns)
+		}
+	}
+	return results
+}
+
+// gradeChoice 选择题判分，支持单选和多选（如 "AC"、"A,C"）
+func gradeChoice(q model.Question, rule GradingRule, studentAns string) objectiveResult {
+	var options []string
+	json.Unmarshal([]byte(q.Options), &options)
+
+	correct := choiceLetters(q.Answer, options)
+	selected := choiceLetters(studentAns, options)
+	if len(correct) == 0 {
+		return objectiveResult{Score: 0, Feedback: "标准答案无法解析，请教师复核"}
+	}
+	if len(selected) == 0 {
+		return objectiveResult{Score: 0, Feedback: "无效选项"}
+	}
+
+	expected := strings.Join(sortedKeys(correct), "")
+	got := strings.Join(sortedKeys(selected), "")
+	if expected == got {
+		return objectiveResult{Score: q.Score, Feedback: fmt.Sprintf("正确（%s）", expected)}
+	}
+
+	hits := 0
+	for letter := range selected {
+		if !correct[letter] {
+			return objectiveResult{Score: 0, Feedback: fmt.Sprintf("错误，正确答案为 %s，你的答案为 %s", expected, got)}
+		}
+		hits++
+	}
+
+	if rule.PartialCredit && len(correct) > 1 {
+		score := q.Score * hits / len(correct)
+		return objectiveResult{Score: score, Feedback: fmt.Sprintf("部分正确（选对 %d/%d 项），正确答案为 %s", hits, len(correct), expected)}
+	}
+	return objectiveResult{Score: 0, Feedback: fmt.Sprintf("漏选，正确答案为 %s，你的答案为 %s", expected, got)}
+}
+
+// choiceLetters 将答案解析为选项字母集合，兼容 "A"、"AC"、"A,C"、"1"（从1开始）以及选项原文
+func choiceLetters(answer string, options []string) map[string]bool {
+	letters := make(map[string]bool)
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return letters
+	}
+
+	// 数字序号
+	if index, err := strconv.Atoi(answer); err == nil {
+		if index >= 1 && index <= len(options) {
+			letters[string(rune('A'+index-1))] = true
+		}
+		return letters
+	}
+
+	// 选项原文（可能带 "A. " 前缀）
+	for i, opt := range options {
+		if answer == strings.TrimSpace(opt) || answer == stripOptionLabel(opt) {
+			letters[string(rune('A'+i))] = true
+			return letters
+		}
+	}
+
+	// 字母组合，忽略分隔符
+	for _, r := range strings.ToUpper(answer) {
+		if r >= 'A' && r <= 'Z' {
+			if len(options) > 0 && int(r-'A') >= len(options) {
+				return map[string]bool{}
+			}
+			letters[string(r)] = true
+			continue
+		}
+		if unicode.IsSpace(r) || strings.ContainsRune(",，、;；/|", r) {
+			continue
+		}
+		// 出现无法识别的字符，视为无效答案
+		return map[string]bool{}
+	}
+	return letters
+}
+
+var optionLabelRe = regexp.MustCompile(`^[A-Za-z][\.．、:：)）]\s*`)
+
+func stripOptionLabel(opt string) string {
+	return strings.TrimSpace(optionLabelRe.ReplaceAllString(strings.TrimSpace(opt), ""))
+}
+
+// gradeFill 填空题判分：标准答案、可接受的替代答案或正则，任一匹配即得满分
+func gradeFill(q model.Question, rule GradingRule, studentAns string) objectiveResult {
+	normalized := normalizeFillAnswer(studentAns, rule)
+
+	accepted := append([]string{q.Answer}, rule.Alternatives...)
+	for _, ans := range accepted {
+		if strings.TrimSpace(ans) == "" {
+			continue
+		}
+		if normalized == normalizeFillAnswer(ans, rule) {
+			return objectiveResult{Score: q.Score, Feedback: "正确"}
+		}
+	}
+
+	if rule.Regex != "" {
+		// 要求整个答案匹配，否则正则 42 会把 142、x42y 也判为正确
+		pattern := "^(?:" + rule.Regex + ")$"
+		if rule.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return objectiveResult{Score: 0, Feedback: "判分正则无效，请教师复核"}
+		}
+		if re.MatchString(strings.TrimSpace(studentAns)) {
+			return objectiveResult{Score: q.Score, Feedback: "正确"}
+		}
+	}
+
+	return objectiveResult{Score: 0, Feedback: fmt.Sprintf("错误，参考答案：%s", q.Answer)}
+}
+
+func normalizeFillAnswer(s string, rule GradingRule) string {
+	s = strings.TrimSpace(s)
+	if rule.IgnoreWhitespace {
+		s = strings.Join(strings.Fields(s), "")
+	}
+	if rule.IgnoreCase {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
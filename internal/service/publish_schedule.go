@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"GoCodeMentor/internal/model"
+)
+
+// scheduleStatus 根据发放时间和关闭时间计算作业在班级中 now 时刻应处的状态
+func scheduleStatus(ac *model.AssignmentClass, now time.Time) string {
+	switch {
+	case ac.ReleasedAt != nil && ac.ReleasedAt.After(now):
+		return model.AssignmentClassScheduled
+	case ac.ClosesAt != nil && !ac.ClosesAt.After(now):
+		return model.AssignmentClassClosed
+	default:
+		return model.AssignmentClassReleased
+	}
+}
+
+// ApplyPublishSchedules 发放到了发放时间的作业、关闭到了关闭时间的作业，返回状态发生变化的班级数量
+func (s *AssignmentService) ApplyPublishSchedules() (int, error) {
+	now := time.Now()
+	due, err := s.assignmentClassRepo.GetDueSchedules(now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	changed := make(map[string]bool)
+	for i := range due {
+		ac := &due[i]
+		status := scheduleStatus(ac, now)
+		if status == ac.Status {
+			continue
+		}
+		ac.Status = status
+		if err := s.assignmentClassRepo.Update(ac); err != nil {
+			log.Printf("更新作业 %s 在班级 %s 的发放状态失败: %v", ac.AssignmentID, ac.ClassID, err)
+			continue
+		}
+		changed[ac.AssignmentID] = true
+		count++
+	}
+	for assignID := range changed {
+		if err := s.syncAssignmentStatus(assignID); err != nil {
+			log.Printf("更新作业 %s 状态失败: %v", assignID, err)
+		}
+	}
+	return count, nil
+}
+
+// UnpublishAssignment 从单个班级撤回作业，该班级的学生不再能看到和提交；已有的提交记录保留
+func (s *AssignmentService) UnpublishAssignment(teacherID, assignID, classID string) error {
+	if _, err := s.authz.AuthorizeClass(teacherID, classID, CapPublish); err != nil {
+		return err
+	}
+	assign, err := s.assignRepo.GetByID(assignID)
+	if err != nil {
+		return errors.New("作业不存在")
+	}
+	if _, err := s.assignmentClassRepo.GetByAssignmentAndClass(assignID, classID); err != nil {
+		return errors.New("作业未发布到该班级")
+	}
+	if err := s.assignmentClassRepo.DeleteByAssignmentAndClass(assignID, classID); err != nil {
+		return err
+	}
+
+	// 旧作业直接记录了班级，一并清除，否则仍会出现在该班级的作业列表中
+	if assign.ClassID != nil && *assign.ClassID == classID {
+		assign.ClassID = nil
+		assign.UpdatedAt = time.Now()
+		if err := s.assignRepo.Update(assign); err != nil {
+			return err
+		}
+	}
+	return s.syncAssignmentStatus(assignID)
+}
+
+// syncAssignmentStatus 按各班级的发放状态更新作业状态：有班级已发放时为 published，
+// 全部班级都已关闭时为 closed，没有发放到任何班级时回到 draft
+func (s *AssignmentService) syncAssignmentStatus(assignID string) error {
+	assign, err := s.assignRepo.GetByID(assignID)
+	if err != nil {
+		return err
+	}
+	acs, err := s.assignmentClassRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return err
+	}
+
+	status := "draft"
+	for _, ac := range acs {
+		if ac.Status == model.AssignmentClassReleased {
+			status = "published"
+			break
+		}
+		if ac.Status == model.AssignmentClassClosed {
+			status = "closed"
+		}
+	}
+	// 旧作业直接记录了班级而没有发布记录，视为已发放
+	if status == "draft" && len(acs) == 0 && assign.ClassID != nil {
+		status = "published"
+	}
+
+	if assign.Status == status {
+		return nil
+	}
+	assign.Status = status
+	assign.UpdatedAt = time.Now()
+	return s.assignRepo.Update(assign)
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"GoCodeMentor/internal/dto"
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// QuestionBankService 题库服务：维护可复用的题目，并从题库为作业组题
+type QuestionBankService struct {
+	bankRepo       repository.BankQuestionRepository
+	questionRepo   repository.QuestionRepository
+	submissionRepo repository.SubmissionRepository
+	authz          IAuthorizationService
+}
+
+// NewQuestionBankService 创建题库服务
+func NewQuestionBankService(
+	bankRepo repository.BankQuestionRepository,
+	questionRepo repository.QuestionRepository,
+	submissionRepo repository.SubmissionRepository,
+	authz IAuthorizationService,
+) IQuestionBankService {
+	return &QuestionBankService{
+		bankRepo:       bankRepo,
+		questionRepo:   questionRepo,
+		submissionRepo: submissionRepo,
+		authz:          authz,
+	}
+}
+
+// CreateItem 向题库添加一道题目
+func (s *QuestionBankService) CreateItem(teacherID string, req dto.BankQuestionRequest) (*model.BankQuestion, error) {
+	if err := validateQuestionRequest(req.QuestionRequest); err != nil {
+		return nil, err
+	}
+	difficulty, err := normalizeDifficulty(req.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &model.BankQuestion{
+		ID:               uuid.New().String(),
+		TeacherID:        teacherID,
+		Shared:           req.Shared,
+		Type:             req.Type,
+		Content:          req.Content,
+		Options:          optionsToString(req.Options),
+		Answer:           req.Answer,
+		GradingRule:      "{}",
+		TestCases:        "[]",
+		Score:            req.Score,
+		KnowledgePointID: req.KnowledgePointID,
+		Difficulty:       difficulty,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if err := s.bankRepo.Create(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateItem 修改题库题目，只有创建者可以修改；已组入作业的题目不受影响
+func (s *QuestionBankService) UpdateItem(teacherID, itemID string, req dto.BankQuestionRequest) (*model.BankQuestion, error) {
+	item, err := s.getOwnedItem(teacherID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuestionRequest(req.QuestionRequest); err != nil {
+		return nil, err
+	}
+	difficulty, err := normalizeDifficulty(req.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Type = req.Type
+	item.Content = req.Content
+	item.Options = optionsToString(req.Options)
+	item.Answer = req.Answer
+	item.Score = req.Score
+	item.KnowledgePointID = req.KnowledgePointID
+	item.Difficulty = difficulty
+	item.Shared = req.Shared
+	item.UpdatedAt = time.Now()
+	if err := s.bankRepo.Update(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteItem 删除题库题目，只有创建者可以删除
+func (s *QuestionBankService) DeleteItem(teacherID, itemID string) error {
+	if _, err := s.getOwnedItem(teacherID, itemID); err != nil {
+		return err
+	}
+	return s.bankRepo.Delete(itemID)
+}
+
+// GetItem 获取题库题目，只能查看自己的或已共享的题目
+func (s *QuestionBankService) GetItem(teacherID, itemID string) (*model.BankQuestion, error) {
+	item, err := s.bankRepo.GetByID(itemID)
+	if err != nil {
+		return nil, errors.New("题目不存在")
+	}
+	if item.TeacherID != teacherID && !item.Shared {
+		return nil, errors.New("无权查看其他教师未共享的题目")
+	}
+	return item, nil
+}
+
+// Search 检索题库
+func (s *QuestionBankService) Search(teacherID string, query dto.BankQuestionQuery) ([]model.BankQuestion, error) {
+	return s.bankRepo.Search(repository.BankQuestionFilter{
+		ViewerID:         teacherID,
+		OnlyMine:         query.Mine,
+		Type:             query.Type,
+		Difficulty:       query.Difficulty,
+		KnowledgePointID: query.KnowledgePointID,
+		Keyword:          strings.TrimSpace(query.Keyword),
+	})
+}
+
+// SaveFromAssignment 将作业中的题目（如 AI 生成的题目）保存到题库，questionIDs 为空时保存全部题目
+func (s *QuestionBankService) SaveFromAssignment(teacherID, assignID string, req dto.SaveToBankRequest) ([]model.BankQuestion, error) {
+	if _, err := s.authorizeAssignment(teacherID, assignID); err != nil {
+		return nil, err
+	}
+	difficulty, err := normalizeDifficulty(req.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(req.QuestionIDs))
+	for _, id := range req.QuestionIDs {
+		selected[id] = true
+	}
+
+	var items []model.BankQuestion
+	for _, q := range questions {
+		if len(selected) > 0 && !selected[q.ID] {
+			continue
+		}
+		sourceID := q.ID
+		item := model.BankQuestion{
+			ID:               uuid.New().String(),
+			TeacherID:        teacherID,
+			Shared:           req.Shared,
+			Type:             q.Type,
+			Content:          q.Content,
+			Options:          q.Options,
+			Answer:           q.Answer,
+			GradingRule:      defaultJSON(q.GradingRule, "{}"),
+			TestCode:         q.TestCode,
+			TestCases:        defaultJSON(q.TestCases, "[]"),
+			Score:            q.Score,
+			KnowledgePointID: req.KnowledgePointID,
+			Difficulty:       difficulty,
+			SourceQuestionID: &sourceID,
+			CreatedAt:        time.Now(),
+			UpdatedAt:        time.Now(),
+		}
+		if err := s.bankRepo.Create(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("没有可保存的题目")
+	}
+	return items, nil
+}
+
+// AddToAssignment 从题库为作业组题：先加入指定的题目，再按规则随机抽题，题号依次排在已有题目之后
+func (s *QuestionBankService) AddToAssignment(teacherID, assignID string, req dto.AddFromBankRequest) ([]model.Question, error) {
+	if _, err := s.authorizeAssignment(teacherID, assignID); err != nil {
+		return nil, err
+	}
+	count, err := s.submissionRepo.CountByAssignmentID(assignID, "")
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("作业已有学生提交，不能再添加题目")
+	}
+
+	picked, err := s.pickItems(teacherID, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(picked) == 0 {
+		return nil, errors.New("请选择题目或设置抽题规则")
+	}
+
+	existing, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, err
+	}
+	orderNum := 0
+	for _, q := range existing {
+		if q.OrderNum > orderNum {
+			orderNum = q.OrderNum
+		}
+	}
+
+	questions := make([]model.Question, 0, len(picked))
+	usedIDs := make([]string, 0, len(picked))
+	for _, item := range picked {
+		orderNum++
+		itemID := item.ID
+		question := model.Question{
+			ID:           uuid.New().String(),
+			AssignmentID: assignID,
+			Type:         item.Type,
+			Content:      item.Content,
+			Options:      item.Options,
+			Answer:       item.Answer,
+			GradingRule:  defaultJSON(item.GradingRule, "{}"),
+			TestCode:     item.TestCode,
+			TestCases:    defaultJSON(item.TestCases, "[]"),
+			Score:        item.Score,
+			OrderNum:     orderNum,
+			BankItemID:   &itemID,
+		}
+		if err := s.questionRepo.Create(&question); err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+		usedIDs = append(usedIDs, item.ID)
+	}
+
+	if err := s.bankRepo.IncrementUsage(usedIDs); err != nil {
+		return questions, fmt.Errorf("更新题目使用次数失败: %w", err)
+	}
+	return questions, nil
+}
+
+// pickItems 按请求挑选题库题目，同一道题不会被重复选中
+func (s *QuestionBankService) pickItems(teacherID string, req dto.AddFromBankRequest) ([]model.BankQuestion, error) {
+	var picked []model.BankQuestion
+	seen := make(map[string]bool)
+
+	if len(req.ItemIDs) > 0 {
+		items, err := s.bankRepo.GetByIDs(req.ItemIDs)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]model.BankQuestion, len(items))
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+		// 按请求中的顺序加入
+		for _, id := range req.ItemIDs {
+			item, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("题库题目不存在: %s", id)
+			}
+			if item.TeacherID != teacherID && !item.Shared {
+				return nil, errors.New("无权使用其他教师未共享的题目")
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			picked = append(picked, item)
+		}
+	}
+
+	for _, rule := range req.Rules {
+		if rule.Count <= 0 {
+			return nil, errors.New("抽题数量必须大于 0")
+		}
+		difficulty := rule.Difficulty
+		if difficulty != "" {
+			if _, err := normalizeDifficulty(difficulty); err != nil {
+				return nil, err
+			}
+		}
+
+		excluded := make([]string, 0, len(seen))
+		for id := range seen {
+			excluded = append(excluded, id)
+		}
+		items, err := s.bankRepo.Search(repository.BankQuestionFilter{
+			ViewerID:         teacherID,
+			Type:             rule.Type,
+			Difficulty:       difficulty,
+			KnowledgePointID: rule.KnowledgePointID,
+			ExcludeIDs:       excluded,
+			Random:           true,
+			Limit:            rule.Count,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(items) < rule.Count {
+			return nil, fmt.Errorf("题库中符合条件的题目不足：需要 %d 道，只有 %d 道", rule.Count, len(items))
+		}
+		for _, item := range items {
+			seen[item.ID] = true
+			picked = append(picked, item)
+		}
+	}
+
+	return picked, nil
+}
+
+// getOwnedItem 获取题库题目并校验其属于该教师
+func (s *QuestionBankService) getOwnedItem(teacherID, itemID string) (*model.BankQuestion, error) {
+	item, err := s.bankRepo.GetByID(itemID)
+	if err != nil {
+		return nil, errors.New("题目不存在")
+	}
+	if item.TeacherID != teacherID {
+		return nil, errors.New("只能修改自己创建的题目")
+	}
+	return item, nil
+}
+
+// authorizeAssignment 获取作业并校验操作者有修改作业题目的权限（作业创建者或所发布班级中有该权限的教师）
+func (s *QuestionBankService) authorizeAssignment(teacherID, assignID string) (*model.Assignment, error) {
+	return s.authz.AuthorizeAssignment(teacherID, assignID, CapEditAssignment)
+}
+
+// normalizeDifficulty 校验难度，为空时视为中等
+func normalizeDifficulty(difficulty string) (string, error) {
+	switch difficulty {
+	case "":
+		return "medium", nil
+	case "easy", "medium", "hard":
+		return difficulty, nil
+	default:
+		return "", fmt.Errorf("不支持的难度: %s", difficulty)
+	}
+}
+
+// defaultJSON 旧数据中 jsonb 字段可能为空字符串，写入前替换为合法的默认值
+func defaultJSON(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"GoCodeMentor/internal/model"
+)
+
+// Rubric 作业评分标准，保存在 Assignment.Rubric 中，按题目 ID 组织
+type Rubric struct {
+	Questions map[string]QuestionRubric `json:"questions"`
+}
+
+// QuestionRubric 单个题目的评分标准
+type QuestionRubric struct {
+	Criteria []RubricCriterion `json:"criteria"`
+}
+
+// RubricCriterion 评分项，例如“正确性”“代码风格”
+type RubricCriterion struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	MaxScore    int           `json:"max_score"`
+	Levels      []RubricLevel `json:"levels"` // 分档描述，例如 10 分：完全正确；5 分：部分正确
+}
+
+// RubricLevel 评分项的一个分档
+type RubricLevel struct {
+	Score      int    `json:"score"`
+	Descriptor string `json:"descriptor"`
+}
+
+// CriterionScore 单个评分项的得分，保存在 Submission.DetailedScore 中
+type CriterionScore struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	MaxScore int    `json:"max_score"`
+	Comment  string `json:"comment"`
+}
+
+// QuestionBreakdown 单个题目按评分标准的得分明细
+type QuestionBreakdown struct {
+	Score    int              `json:"score"`
+	MaxScore int              `json:"max_score"`
+	Criteria []CriterionScore `json:"criteria"`
+}
+
+// parseRubric 解析作业的评分标准，空值或 "{}" 视为没有评分标准
+func parseRubric(raw string) (Rubric, error) {
+	rubric := Rubric{Questions: make(map[string]QuestionRubric)}
+	if strings.TrimSpace(raw) == "" {
+		return rubric, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &rubric); err != nil {
+		return rubric, err
+	}
+	if rubric.Questions == nil {
+		rubric.Questions = make(map[string]QuestionRubric)
+	}
+	return rubric, nil
+}
+
+// covers 判断是否有题目设置了评分标准
+func (r Rubric) covers(questions []model.Question) bool {
+	for _, q := range questions {
+		if _, ok := r.Questions[q.ID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize 校验题目的评分标准并补全评分项 ID；各评分项满分之和必须等于题目分值
+func (r *QuestionRubric) normalize(questionScore int) error {
+	if len(r.Criteria) == 0 {
+		return errors.New("评分标准至少需要一个评分项")
+	}
+
+	total := 0
+	seen := make(map[string]bool)
+	for i := range r.Criteria {
+		c := &r.Criteria[i]
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return fmt.Errorf("第 %d 个评分项缺少名称", i+1)
+		}
+		if c.MaxScore <= 0 {
+			return fmt.Errorf("评分项“%s”的满分必须大于 0", c.Name)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("c%d", i+1)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("评分项 ID 重复: %s", c.ID)
+		}
+		seen[c.ID] = true
+
+		for _, level := range c.Levels {
+			if level.Score < 0 || level.Score > c.MaxScore {
+				return fmt.Errorf("评分项“%s”的分档 %d 超出 0~%d 的范围", c.Name, level.Score, c.MaxScore)
+			}
+		}
+		total += c.MaxScore
+	}
+
+	if total != questionScore {
+		return fmt.Errorf("评分项满分之和为 %d，与题目分值 %d 不一致", total, questionScore)
+	}
+	return nil
+}
+
+// writeRubricPrompt 把评分标准写入批改提示
+func writeRubricPrompt(b *strings.Builder, questions []model.Question, rubric Rubric) {
+	if !rubric.covers(questions) {
+		return
+	}
+
+	b.WriteString("\n[评分标准]（以下题目必须逐项打分，每项得分在 0~该项满分之间，题目得分等于各项得分之和）\n")
+	for _, q := range questions {
+		qr, ok := rubric.Questions[q.ID]
+		if !ok {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("题目 ID: %s（满分 %d）\n", q.ID, q.Score))
+		for _, c := range qr.Criteria {
+			b.WriteString(fmt.Sprintf("  - 评分项 %s「%s」满分 %d", c.ID, c.Name, c.MaxScore))
+			if c.Description != "" {
+				b.WriteString("：" + c.Description)
+			}
+			b.WriteString("\n")
+			for _, level := range c.Levels {
+				b.WriteString(fmt.Sprintf("      %d 分：%s\n", level.Score, level.Descriptor))
+			}
+		}
+	}
+}
+
+// rubricScoreResult AI 返回的单个评分项得分
+type rubricScoreResult struct {
+	Score   int    `json:"score"`
+	Comment string `json:"comment"`
+}
+
+// applyRubricScores 校验 AI 给出的逐项得分并生成得分明细。
+// 有评分标准的题目缺少逐项得分时返回错误，交给批改队列重试。
+func applyRubricScores(questions []model.Question, rubric Rubric, criterionScores map[string]map[string]rubricScoreResult) (map[string]QuestionBreakdown, error) {
+	breakdowns := make(map[string]QuestionBreakdown)
+	for _, q := range questions {
+		qr, ok := rubric.Questions[q.ID]
+		if !ok {
+			continue
+		}
+		scores, ok := criterionScores[q.ID]
+		if !ok || len(scores) == 0 {
+			return nil, fmt.Errorf("AI 未按评分标准给出题目 %s 的逐项得分", q.ID)
+		}
+
+		breakdown := QuestionBreakdown{MaxScore: q.Score, Criteria: make([]CriterionScore, 0, len(qr.Criteria))}
+		for _, c := range qr.Criteria {
+			result, ok := scores[c.ID]
+			if !ok {
+				result = rubricScoreResult{Comment: "未给出该项评分"}
+			}
+			// 每项得分限制在 0 ~ 该项满分之间
+			if result.Score < 0 {
+				result.Score = 0
+			}
+			if result.Score > c.MaxScore {
+				result.Score = c.MaxScore
+			}
+			breakdown.Score += result.Score
+			breakdown.Criteria = append(breakdown.Criteria, CriterionScore{
+				ID:       c.ID,
+				Name:     c.Name,
+				Score:    result.Score,
+				MaxScore: c.MaxScore,
+				Comment:  result.Comment,
+			})
+		}
+		// 题目分值可能在设置评分标准后被调低，总分仍不能超过题目满分
+		if breakdown.Score > q.Score {
+			breakdown.Score = q.Score
+		}
+		breakdowns[q.ID] = breakdown
+	}
+	return breakdowns, nil
+}
@@ -2,43 +2,97 @@ package service
 
 import (
 	"GoCodeMentor/internal/model"
-	"GoCodeMentor/internal/pkg/siliconflow"
+	"GoCodeMentor/internal/pkg/llm"
 	"GoCodeMentor/internal/repository"
 	"context"
 	"errors"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
 type SessionService struct {
-	client      *siliconflow.Client
+	client      llm.LLMProvider
 	sessionRepo repository.ChatSessionRepository
 	messageRepo repository.ChatMessageRepository
 	userRepo    repository.UserRepository
-	classRepo   repository.ClassRepository
+	memberRepo  repository.ClassMemberRepository
+	authz       IAuthorizationService
 }
 
 func NewSessionService(
-	client *siliconflow.Client,
+	client llm.LLMProvider,
 	sessionRepo repository.ChatSessionRepository,
 	messageRepo repository.ChatMessageRepository,
 	userRepo repository.UserRepository,
-	classRepo repository.ClassRepository,
+	memberRepo repository.ClassMemberRepository,
+	authz IAuthorizationService,
 ) ISessionService {
 	return &SessionService{
 		client:      client,
 		sessionRepo: sessionRepo,
 		messageRepo: messageRepo,
 		userRepo:    userRepo,
-		classRepo:   classRepo,
+		memberRepo:  memberRepo,
+		authz:       authz,
 	}
 }
 
 // Chat 对话并保存历史
 func (s *SessionService) Chat(ctx context.Context, sessionID, userID, userQuestion string) (string, string, error) {
+	sessionID, history := s.prepareChat(sessionID, userID, userQuestion)
+
+	// 4. 调用 AI
+	answer, err := s.client.ChatWithHistory(ctx, history)
+	if err != nil {
+		return "", sessionID, err
+	}
+
+	s.saveAnswer(sessionID, userQuestion, answer, false, len(history))
+
+	return answer, sessionID, nil
+}
+
+// ChatStream 流式对话：每收到一个片段就调用 onToken，流正常结束后才保存完整回答。
+// 客户端断开（ctx 取消）时不保存任何回答；模型中途出错时保存已收到的部分回答并标记为不完整。
+func (s *SessionService) ChatStream(ctx context.Context, sessionID, userID, userQuestion string, onToken func(string) error) (string, string, error) {
+	sessionID, history := s.prepareChat(sessionID, userID, userQuestion)
+
+	stream, err := s.client.ChatStream(ctx, history)
+	if err != nil {
+		return "", sessionID, err
+	}
+
+	var answer strings.Builder
+	for chunk := range stream {
+		if chunk.Err != nil {
+			if ctx.Err() == nil && answer.Len() > 0 {
+				s.saveAnswer(sessionID, userQuestion, answer.String(), true, len(history))
+			}
+			return answer.String(), sessionID, chunk.Err
+		}
+
+		answer.WriteString(chunk.Content)
+		if err := onToken(chunk.Content); err != nil {
+			// 写回客户端失败说明连接已断开，放弃本次回答
+			return answer.String(), sessionID, err
+		}
+	}
+
+	// 通道关闭可能是正常结束，也可能是客户端断开导致 ctx 被取消
+	if err := ctx.Err(); err != nil {
+		return answer.String(), sessionID, err
+	}
+
+	s.saveAnswer(sessionID, userQuestion, answer.String(), false, len(history))
+	return answer.String(), sessionID, nil
+}
+
+// prepareChat 创建会话（如需要）、保存用户问题并返回发送给模型的完整历史
+func (s *SessionService) prepareChat(sessionID, userID, userQuestion string) (string, []llm.Message) {
 	// 1. 如果没有 sessionID，创建新的
 	if sessionID == "" {
 		sessionID = uuid.New().String()
@@ -75,36 +129,34 @@ func (s *SessionService) Chat(ctx context.Context, sessionID, userID, userQuesti
 		// even if we can't get history, we can still proceed
 	}
 
-	// 4. 调用 AI
-	var history []siliconflow.Message
+	var history []llm.Message
 	for _, m := range messages {
-		history = append(history, siliconflow.Message{
+		history = append(history, llm.Message{
 			Role:    m.Role,
 			Content: m.Content,
 		})
 	}
-	answer, err := s.client.ChatWithHistory(ctx, history)
-	if err != nil {
-		return "", sessionID, err
-	}
+	return sessionID, history
+}
 
+// saveAnswer 保存 AI 回答，并在会话刚开始时更新标题
+func (s *SessionService) saveAnswer(sessionID, userQuestion, answer string, partial bool, historyLen int) {
 	// 5. 保存 AI 回答
 	s.messageRepo.Create(&model.ChatMessage{
 		SessionID: sessionID,
 		Role:      "assistant",
 		Content:   answer,
+		Partial:   partial,
 	})
 
 	// 6. 更新会话标题
-	if len(messages) <= 2 {
+	if historyLen <= 2 {
 		session, err := s.sessionRepo.GetByID(sessionID)
 		if err == nil {
 			session.Title = userQuestion
 			s.sessionRepo.Update(session)
 		}
 	}
-
-	return answer, sessionID, nil
 }
 
 // GetHistory 获取历史记录
@@ -129,15 +181,8 @@ func (s *SessionService) GetHistory(sessionID, userID string) ([]model.ChatMessa
 		if err != nil || student.Role != "student" {
 			return nil, errors.New("会话所属学生不存在")
 		}
-		if student.ClassID == nil {
-			return nil, errors.New("学生未加入任何班级")
-		}
-		class, err := s.classRepo.GetByID(*student.ClassID)
-		if err != nil {
-			return nil, errors.New("学生所在班级不存在")
-		}
-		if class.TeacherID != userID {
-			return nil, errors.New("无权查看该班级学生的会话")
+		if err := s.authz.AuthorizeStudent(userID, student.ID, CapViewChats); err != nil {
+			return nil, err
 		}
 	}
 
@@ -156,17 +201,17 @@ func (s *SessionService) GetStudentSessions(teacherID, studentID string) ([]mode
 		return nil, errors.New("学生不存在")
 	}
 
-	if student.ClassID == nil {
-		return []model.ChatSession{}, nil // Return empty slice instead of nil
-	}
-
-	class, err := s.classRepo.GetByID(*student.ClassID)
+	classIDs, err := studentClassIDs(s.memberRepo, studentID)
 	if err != nil {
-		return nil, errors.New("班级不存在")
+		return nil, err
+	}
+	if len(classIDs) == 0 {
+		return []model.ChatSession{}, nil // Return empty slice instead of nil
 	}
 
-	if class.TeacherID != teacherID {
-		return nil, errors.New("无权查看该学生的会话")
+	// 学生可能同时在多个班级，只要在其中任一班级有查看答疑记录的权限即可
+	if err := s.authz.AuthorizeStudent(teacherID, studentID, CapViewChats); err != nil {
+		return nil, err
 	}
 
 	return s.sessionRepo.GetByUserID(studentID)
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/pkg/similarity"
+	"GoCodeMentor/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSimilarityThreshold = 0.8
+	similarityMinMatch         = 10 // 代码中至少连续这么多个记号相同才算相同片段
+	similarityMinCodeTokens    = 15 // 记号太少的代码（如空函数）不参与比较
+	similarityMinTextRunes     = 8  // 太短的文本答案不参与比较
+	similarityMaxPairs         = 1000
+)
+
+// SimilarityItem 一对提交在某道题（或整体代码框）上的相似度
+type SimilarityItem struct {
+	QuestionID string              `json:"question_id"` // 为空表示整体代码框
+	Kind       string              `json:"kind"`        // code, text
+	Score      float64             `json:"score"`
+	Regions    []similarity.Region `json:"regions,omitempty"` // 代码中相同片段的行范围
+}
+
+// SimilarityPair 一对可疑提交
+type SimilarityPair struct {
+	SubmissionA string           `json:"submission_a"`
+	StudentA    string           `json:"student_a"`
+	NameA       string           `json:"name_a"`
+	SubmissionB string           `json:"submission_b"`
+	StudentB    string           `json:"student_b"`
+	NameB       string           `json:"name_b"`
+	Score       float64          `json:"score"` // 代码相似度的最大值与文本答案平均相似度中较高者
+	Items       []SimilarityItem `json:"items"`
+}
+
+// SimilarityMember 可疑分组中的一份提交
+type SimilarityMember struct {
+	SubmissionID string `json:"submission_id"`
+	StudentID    string `json:"student_id"`
+	StudentName  string `json:"student_name"`
+}
+
+// SimilarityCluster 互相相似的一组提交
+type SimilarityCluster struct {
+	Members  []SimilarityMember `json:"members"`
+	MaxScore float64            `json:"max_score"`
+}
+
+// SimilarityResult 检测报告的内容
+type SimilarityResult struct {
+	Pairs    []SimilarityPair    `json:"pairs"`
+	Clusters []SimilarityCluster `json:"clusters"`
+}
+
+// SimilaritySide 一道题上两份提交的原文，用于并排展示
+type SimilaritySide struct {
+	SimilarityItem
+	OrderNum int    `json:"order_num"`
+	Content  string `json:"content"`
+	A        string `json:"a"`
+	B        string `json:"b"`
+}
+
+// SimilarityPairDetail 一对提交的并排对比
+type SimilarityPairDetail struct {
+	SimilarityPair
+	Sides []SimilaritySide `json:"sides"`
+}
+
+// SimilarityService 提交相似度检测服务，检测在后台 goroutine 中执行
+type SimilarityService struct {
+	reportRepo     repository.SimilarityReportRepository
+	assignRepo     repository.AssignmentRepository
+	questionRepo   repository.QuestionRepository
+	submissionRepo repository.SubmissionRepository
+	authz          IAuthorizationService
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]bool // 正在检测的作业
+}
+
+// NewSimilarityService 创建相似度检测服务；上次运行中断的检测标记为失败
+func NewSimilarityService(
+	reportRepo repository.SimilarityReportRepository,
+	assignRepo repository.AssignmentRepository,
+	questionRepo repository.QuestionRepository,
+	submissionRepo repository.SubmissionRepository,
+	authz IAuthorizationService,
+) ISimilarityService {
+	if count, err := reportRepo.FailRunning("服务重启，检测已中断，请重新检测"); err != nil {
+		log.Printf("重置未完成的相似度检测失败: %v", err)
+	} else if count > 0 {
+		log.Printf("%d 个未完成的相似度检测已标记为失败", count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SimilarityService{
+		reportRepo:     reportRepo,
+		assignRepo:     assignRepo,
+		questionRepo:   questionRepo,
+		submissionRepo: submissionRepo,
+		authz:          authz,
+		ctx:            ctx,
+		cancel:         cancel,
+		running:        make(map[string]bool),
+	}
+}
+
+// StartCheck 为作业启动一次后台相似度检测，threshold 为 0 时使用默认值
+func (s *SimilarityService) StartCheck(teacherID, assignID string, threshold float64) (*model.SimilarityReport, error) {
+	if _, err := s.authorizeAssignment(teacherID, assignID); err != nil {
+		return nil, err
+	}
+	if threshold == 0 {
+		threshold = defaultSimilarityThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, errors.New("相似度阈值必须在 0~1 之间")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return nil, errors.New("服务正在关闭")
+	}
+	if s.running[assignID] {
+		return nil, errors.New("该作业正在进行相似度检测，请稍后查看结果")
+	}
+
+	now := time.Now()
+	report := &model.SimilarityReport{
+		ID:           uuid.New().String(),
+		AssignmentID: assignID,
+		Status:       model.SimilarityRunning,
+		Threshold:    threshold,
+		Result:       "{}",
+		RequestedBy:  teacherID,
+		StartedAt:    now,
+		CreatedAt:    now,
+	}
+	if err := s.reportRepo.Create(report); err != nil {
+		return nil, err
+	}
+
+	s.running[assignID] = true
+	s.wg.Add(1)
+	go s.run(*report)
+	return report, nil
+}
+
+// GetLatestReport 获取作业最近一次的检测报告，没有检测过时返回 nil
+func (s *SimilarityService) GetLatestReport(teacherID, assignID string) (*model.SimilarityReport, *SimilarityResult, error) {
+	if _, err := s.authorizeAssignment(teacherID, assignID); err != nil {
+		return nil, nil, err
+	}
+	report, err := s.reportRepo.GetLatestByAssignment(assignID)
+	if err != nil || report == nil {
+		return nil, nil, err
+	}
+	var result SimilarityResult
+	json.Unmarshal([]byte(report.Result), &result)
+	return report, &result, nil
+}
+
+// GetPairDetail 并排展示最近一次检测中一对可疑提交的原文和相同片段
+func (s *SimilarityService) GetPairDetail(teacherID, assignID, submissionA, submissionB string) (*SimilarityPairDetail, error) {
+	report, result, err := s.GetLatestReport(teacherID, assignID)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, errors.New("该作业还没有相似度检测报告")
+	}
+
+	var pair *SimilarityPair
+	for i := range result.Pairs {
+		p := &result.Pairs[i]
+		if (p.SubmissionA == submissionA && p.SubmissionB == submissionB) || (p.SubmissionA == submissionB && p.SubmissionB == submissionA) {
+			pair = p
+			break
+		}
+	}
+	if pair == nil {
+		return nil, errors.New("检测报告中没有这对提交")
+	}
+
+	subA, err := s.submissionRepo.GetByID(pair.SubmissionA)
+	if err != nil {
+		return nil, fmt.Errorf("获取提交失败: %w", err)
+	}
+	subB, err := s.submissionRepo.GetByID(pair.SubmissionB)
+	if err != nil {
+		return nil, fmt.Errorf("获取提交失败: %w", err)
+	}
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	answersA, _ := stringToAnswers(subA.Answers)
+	answersB, _ := stringToAnswers(subB.Answers)
+	detail := &SimilarityPairDetail{SimilarityPair: *pair}
+	for _, item := range pair.Items {
+		side := SimilaritySide{SimilarityItem: item}
+		if item.QuestionID == "" {
+			side.Content = "整体代码"
+			side.A, side.B = subA.CodeContent, subB.CodeContent
+		} else {
+			q := byID[item.QuestionID]
+			side.OrderNum = q.OrderNum
+			side.Content = q.Content
+			side.A, side.B = answersA[item.QuestionID], answersB[item.QuestionID]
+		}
+		detail.Sides = append(detail.Sides, side)
+	}
+	return detail, nil
+}
+
+// Shutdown 停止正在执行的检测并等待其退出
+func (s *SimilarityService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SimilarityService) run(report model.SimilarityReport) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, report.AssignmentID)
+		s.mu.Unlock()
+	}()
+
+	result, count, err := s.analyze(s.ctx, report.AssignmentID, report.Threshold)
+	now := time.Now()
+	report.FinishedAt = &now
+	report.SubmissionCount = count
+	if err != nil {
+		report.Status = model.SimilarityFailed
+		report.Error = err.Error()
+		if errors.Is(err, context.Canceled) {
+			report.Error = "服务关闭，检测已中断，请重新检测"
+		}
+	} else {
+		data, _ := json.Marshal(result)
+		report.Status = model.SimilaritySucceeded
+		report.Result = string(data)
+		report.SuspiciousPairs = len(result.Pairs)
+	}
+	if err := s.reportRepo.Update(&report); err != nil {
+		log.Printf("保存相似度检测报告失败: %v", err)
+	}
+}
+
+// similarityDoc 一份提交中参与比较的内容
+type similarityDoc struct {
+	submission *model.Submission
+	code       map[string][]similarity.Token // 题目 ID（整体代码框为空）-> 规范化记号
+	text       map[string]string             // 题目 ID -> 文本答案
+}
+
+// analyze 两两比较作业的全部提交：编程题和整体代码框比较代码结构，填空题等文本题比较答案文本
+// （与标准答案相同的答案不算雷同），选择题不参与比较
+func (s *SimilarityService) analyze(ctx context.Context, assignID string, threshold float64) (*SimilarityResult, int, error) {
+	questions, err := s.questionRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, 0, err
+	}
+	submissions, err := s.submissionRepo.GetByAssignmentIDs([]string{assignID})
+	if err != nil {
+		return nil, 0, err
+	}
+	// 同一小组的组员副本内容相同，每个小组只取一份参与比较
+	seenGroups := make(map[string]bool)
+	distinct := submissions[:0]
+	for _, sub := range submissions {
+		if sub.GroupSubmissionID != nil {
+			if seenGroups[*sub.GroupSubmissionID] {
+				continue
+			}
+			seenGroups[*sub.GroupSubmissionID] = true
+		}
+		distinct = append(distinct, sub)
+	}
+	submissions = distinct
+
+	keys := make([]string, 0, len(questions)+1)
+	for _, q := range questions {
+		keys = append(keys, q.ID)
+	}
+	keys = append(keys, "")
+
+	docs := make([]similarityDoc, len(submissions))
+	for i := range submissions {
+		sub := &submissions[i]
+		doc := similarityDoc{submission: sub, code: map[string][]similarity.Token{}, text: map[string]string{}}
+		answers, _ := stringToAnswers(sub.Answers)
+		for _, q := range questions {
+			answer := answers[q.ID]
+			switch q.Type {
+			case "choice":
+			case "code":
+				if tokens := similarity.Tokenize(answer); len(tokens) >= similarityMinCodeTokens {
+					doc.code[q.ID] = tokens
+				}
+			default:
+				normalized := similarity.NormalizeText(answer)
+				if len([]rune(normalized)) >= similarityMinTextRunes && normalized != similarity.NormalizeText(q.Answer) {
+					doc.text[q.ID] = answer
+				}
+			}
+		}
+		if tokens := similarity.Tokenize(sub.CodeContent); len(tokens) >= similarityMinCodeTokens {
+			doc.code[""] = tokens
+		}
+		docs[i] = doc
+	}
+
+	var pairs []SimilarityPair
+	var edges [][2]int
+	pairIndex := make(map[[2]int]int)
+	for i := 0; i < len(docs); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, len(docs), err
+		}
+		for j := i + 1; j < len(docs); j++ {
+			pair, ok := comparePair(docs[i], docs[j], keys)
+			if !ok || pair.Score < threshold {
+				continue
+			}
+			pairIndex[[2]int{i, j}] = len(pairs)
+			pairs = append(pairs, pair)
+			edges = append(edges, [2]int{i, j})
+		}
+	}
+
+	result := &SimilarityResult{Pairs: []SimilarityPair{}, Clusters: []SimilarityCluster{}}
+	for _, members := range similarity.Cluster(len(docs), edges) {
+		cluster := SimilarityCluster{}
+		for x, i := range members {
+			sub := docs[i].submission
+			cluster.Members = append(cluster.Members, SimilarityMember{SubmissionID: sub.ID, StudentID: sub.StudentID, StudentName: sub.StudentName})
+			for _, j := range members[x+1:] {
+				if idx, ok := pairIndex[[2]int{i, j}]; ok {
+					cluster.MaxScore = math.Max(cluster.MaxScore, pairs[idx].Score)
+				}
+			}
+		}
+		result.Clusters = append(result.Clusters, cluster)
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+	if len(pairs) > similarityMaxPairs {
+		pairs = pairs[:similarityMaxPairs]
+	}
+	result.Pairs = append(result.Pairs, pairs...)
+	return result, len(docs), nil
+}
+
+// comparePair 比较两份提交，没有可比较的内容时返回 false
+func comparePair(a, b similarityDoc, keys []string) (SimilarityPair, bool) {
+	pair := SimilarityPair{
+		SubmissionA: a.submission.ID, StudentA: a.submission.StudentID, NameA: a.submission.StudentName,
+		SubmissionB: b.submission.ID, StudentB: b.submission.StudentID, NameB: b.submission.StudentName,
+	}
+
+	codeScore, textTotal, textCount := 0.0, 0.0, 0
+	for _, key := range keys {
+		if ta, tb := a.code[key], b.code[key]; ta != nil && tb != nil {
+			score, matches := similarity.Compare(ta, tb, similarityMinMatch)
+			pair.Items = append(pair.Items, SimilarityItem{
+				QuestionID: key,
+				Kind:       "code",
+				Score:      roundScore(score),
+				Regions:    similarity.Regions(ta, tb, matches),
+			})
+			codeScore = math.Max(codeScore, score)
+		}
+		if ta, tb := a.text[key], b.text[key]; ta != "" && tb != "" {
+			score := similarity.TextSimilarity(ta, tb)
+			pair.Items = append(pair.Items, SimilarityItem{QuestionID: key, Kind: "text", Score: roundScore(score)})
+			textTotal += score
+			textCount++
+		}
+	}
+	if len(pair.Items) == 0 {
+		return pair, false
+	}
+
+	pair.Score = codeScore
+	if textCount > 0 {
+		pair.Score = math.Max(pair.Score, textTotal/float64(textCount))
+	}
+	pair.Score = roundScore(pair.Score)
+	return pair, true
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// authorizeAssignment 获取作业并校验用户有批改该作业的权限
+func (s *SimilarityService) authorizeAssignment(teacherID, assignID string) (*model.Assignment, error) {
+	return s.authz.AuthorizeAssignment(teacherID, assignID, CapGrade)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/pkg/codecheck"
+	"fmt"
+	"strings"
+)
+
+// legacyCodeKey 没有题目的旧作业只有一个整体代码框，其静态检查结果以此为键保存
+const legacyCodeKey = "code"
+
+// analyzeCode 对每道编程题的代码做静态检查，返回 题目ID -> 检查结果
+func analyzeCode(questions []model.Question, answers map[string]string, codeContent string) map[string]*codecheck.Report {
+	reports := make(map[string]*codecheck.Report)
+	if len(questions) == 0 {
+		if strings.TrimSpace(codeContent) != "" {
+			reports[legacyCodeKey] = codecheck.Analyze(codeContent)
+		}
+		return reports
+	}
+
+	for _, q := range questions {
+		if q.Type != "code" {
+			continue
+		}
+		// 与运行测试一致：逐题作答的代码优先，兼容只有一个整体代码框的旧作业
+		code := answers[q.ID]
+		if strings.TrimSpace(code) == "" {
+			code = codeContent
+		}
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		reports[q.ID] = codecheck.Analyze(code)
+	}
+	return reports
+}
+
+// writeStaticAnalysisPrompt 把静态检查发现的问题写入批改提示
+func writeStaticAnalysisPrompt(b *strings.Builder, questions []model.Question, reports map[string]*codecheck.Report) {
+	var sections strings.Builder
+	write := func(label string, report *codecheck.Report) {
+		if report == nil {
+			return
+		}
+		if len(report.Findings) == 0 {
+			sections.WriteString(label + " 未发现问题\n")
+			return
+		}
+		sections.WriteString(label + "\n")
+		for _, f := range report.Findings {
+			sections.WriteString(fmt.Sprintf("  - 第 %d 行 [%s/%s] %s\n", f.Line, f.Rule, f.Severity, f.Message))
+		}
+	}
+
+	for i, q := range questions {
+		write(fmt.Sprintf("Q%d (ID: %s)", i+1, q.ID), reports[q.ID])
+	}
+	write("[学生编程代码]", reports[legacyCodeKey])
+	if sections.Len() == 0 {
+		return
+	}
+
+	b.WriteString("\n[静态分析结果]（gofmt、go vet 风格检查和圈复杂度分析得到的事实，请在反馈中据此点评代码质量；error 表示代码无法通过编译）\n")
+	b.WriteString(sections.String())
+}
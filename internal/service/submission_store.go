@@ -0,0 +1,390 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"GoCodeMentor/internal/model"
+	"GoCodeMentor/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// SubmissionStore 提交相关的几个服务共用的仓储和提交逻辑：检查学生能否作答、保存提交和版本、
+// 按计分方式选出计入成绩的版本以及同步小组提交。和 GradingQueue 一样在 main 中创建一次，由各服务嵌入使用
+type SubmissionStore struct {
+	assignRepo          repository.AssignmentRepository
+	assignmentClassRepo repository.AssignmentClassRepository
+	extensionRepo       repository.DeadlineExtensionRepository
+	questionRepo        repository.QuestionRepository
+	submissionRepo      repository.SubmissionRepository
+	versionRepo         repository.SubmissionVersionRepository
+	draftRepo           repository.AnswerDraftRepository
+	userRepo            repository.UserRepository
+	classRepo           repository.ClassRepository
+	memberRepo          repository.ClassMemberRepository
+	groupRepo           repository.ClassGroupRepository
+	gradingQueue        *GradingQueue
+}
+
+// NewSubmissionStore 创建共用的提交逻辑
+func NewSubmissionStore(
+	assignRepo repository.AssignmentRepository,
+	assignmentClassRepo repository.AssignmentClassRepository,
+	extensionRepo repository.DeadlineExtensionRepository,
+	questionRepo repository.QuestionRepository,
+	submissionRepo repository.SubmissionRepository,
+	versionRepo repository.SubmissionVersionRepository,
+	draftRepo repository.AnswerDraftRepository,
+	userRepo repository.UserRepository,
+	classRepo repository.ClassRepository,
+	memberRepo repository.ClassMemberRepository,
+	groupRepo repository.ClassGroupRepository,
+	gradingQueue *GradingQueue,
+) *SubmissionStore {
+	return &SubmissionStore{
+		assignRepo:          assignRepo,
+		assignmentClassRepo: assignmentClassRepo,
+		extensionRepo:       extensionRepo,
+		questionRepo:        questionRepo,
+		submissionRepo:      submissionRepo,
+		versionRepo:         versionRepo,
+		draftRepo:           draftRepo,
+		userRepo:            userRepo,
+		classRepo:           classRepo,
+		memberRepo:          memberRepo,
+		groupRepo:           groupRepo,
+		gradingQueue:        gradingQueue,
+	}
+}
+
+// studentAssignment 检查作业是否已发布到学生所在班级，返回作业、学生和该学生的截止规则
+// （班级截止时间和迟交规则，学生有个人延期时以延期为准）
+func (s *SubmissionStore) studentAssignment(assignID, studentID string) (*model.Assignment, *model.User, latePolicy, error) {
+	// 获取学生信息
+	student, err := s.userRepo.GetByID(studentID)
+	if err != nil {
+		return nil, nil, latePolicy{}, fmt.Errorf("获取学生信息失败: %w", err)
+	}
+
+	// 检查学生是否在班级中
+	classIDs, err := studentClassIDs(s.memberRepo, studentID)
+	if err != nil {
+		return nil, nil, latePolicy{}, fmt.Errorf("获取学生班级失败: %w", err)
+	}
+	if len(classIDs) == 0 {
+		return nil, nil, latePolicy{}, fmt.Errorf("学生未加入任何班级，无法提交作业")
+	}
+
+	// 获取作业信息
+	assign, err := s.assignRepo.GetByID(assignID)
+	if err != nil {
+		return nil, nil, latePolicy{}, fmt.Errorf("获取作业失败: %w", err)
+	}
+
+	// 检查作业是否已发布到学生所在的班级
+	assignmentClass, err := s.studentAssignmentClass(assignID, studentID)
+	if err != nil || assignmentClass == nil {
+		return nil, nil, latePolicy{}, fmt.Errorf("作业未发布到该班级，无法提交")
+	}
+
+	if scheduleStatus(assignmentClass, time.Now()) == model.AssignmentClassScheduled {
+		return nil, nil, latePolicy{}, fmt.Errorf("作业尚未发放，无法提交")
+	}
+	if class, err := s.classRepo.GetByID(assignmentClass.ClassID); err == nil && class.ArchivedAt != nil {
+		return nil, nil, latePolicy{}, fmt.Errorf("班级已归档，无法提交")
+	}
+
+	// 检查作业状态（兼容性检查）；已关闭的作业由截止规则拒绝提交
+	if assign.Status != "published" && assign.Status != "closed" {
+		return nil, nil, latePolicy{}, fmt.Errorf("作业未发布，无法提交")
+	}
+
+	extension, err := s.extensionRepo.GetByAssignmentAndStudent(assignID, studentID)
+	if err != nil {
+		return nil, nil, latePolicy{}, fmt.Errorf("获取延期信息失败: %w", err)
+	}
+	return assign, student, newLatePolicy(assignmentClass, extension), nil
+}
+
+// studentAssignmentClass 在学生所在的班级中查找作业的发布记录，作业没有发布到这些班级时返回 nil。
+// 作业发布到学生的多个班级时优先使用已发放的班级，再优先截止时间最晚的班级
+func (s *SubmissionStore) studentAssignmentClass(assignID, studentID string) (*model.AssignmentClass, error) {
+	classIDs, err := studentClassIDs(s.memberRepo, studentID)
+	if err != nil || len(classIDs) == 0 {
+		return nil, err
+	}
+	member := make(map[string]bool, len(classIDs))
+	for _, id := range classIDs {
+		member[id] = true
+	}
+	acs, err := s.assignmentClassRepo.GetByAssignmentID(assignID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var best *model.AssignmentClass
+	for i := range acs {
+		ac := &acs[i]
+		if !member[ac.ClassID] {
+			continue
+		}
+		if best == nil || preferAssignmentClass(ac, best, now) {
+			best = ac
+		}
+	}
+	return best, nil
+}
+
+// preferAssignmentClass 判断 a 是否比 b 更适合作为学生的发布记录
+func preferAssignmentClass(a, b *model.AssignmentClass, now time.Time) bool {
+	aScheduled := scheduleStatus(a, now) == model.AssignmentClassScheduled
+	bScheduled := scheduleStatus(b, now) == model.AssignmentClassScheduled
+	if aScheduled != bScheduled {
+		return bScheduled
+	}
+	if a.Deadline == nil || b.Deadline == nil {
+		return a.Deadline == nil && b.Deadline != nil
+	}
+	return a.Deadline.After(*b.Deadline)
+}
+
+// submit 保存一次提交（首次提交创建提交记录，之后的提交保存为新版本）并加入批改队列，at 为记录的提交时间；
+// groupID 不为空时为小组作业，提交保存到小组的主提交上并同步给组员，提交次数由组员共用
+func (s *SubmissionStore) submit(assign *model.Assignment, studentID, studentName string, answers map[string]string, code string, at time.Time, late lateness, groupID string) (string, error) {
+	assignID := assign.ID
+
+	// 检查是否已经提交过
+	var existing *model.Submission
+	var err error
+	if groupID != "" {
+		existing, err = s.submissionRepo.GetGroupSubmission(assignID, groupID)
+	} else {
+		existing, err = s.submissionRepo.GetByAssignmentAndStudent(assignID, studentID)
+	}
+	if err == nil && existing != nil {
+		// 锁定提交记录后再检查次数并保存新版本，避免与批改结果的写入或同时的另一次提交互相覆盖
+		err = s.submissionRepo.UpdateLocked(existing.ID, func(current *model.Submission) error {
+			if assign.MaxAttempts > 0 && current.Attempts >= assign.MaxAttempts {
+				return fmt.Errorf("已达到最大提交次数（%d 次）", assign.MaxAttempts)
+			}
+			// 旧数据没有版本记录，先把已有内容补存为第一个版本
+			if _, err := s.ensureVersions(current); err != nil {
+				return err
+			}
+
+			// 新的提交保存为新版本，提交记录显示最新内容，批改完成后再按计分方式选定计入成绩的版本
+			current.Attempts++
+			current.Answers = answersToString(answers)
+			current.CodeContent = code
+			current.Status = "submitted"
+			current.IsLate = late.Late
+			current.LateMinutes = late.Minutes
+			current.LatePenalty = late.Penalty
+			current.UpdatedAt = at
+			if err := s.createVersion(current); err != nil {
+				return err
+			}
+			existing = current
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		s.syncGroup(existing)
+		s.discardDraft(assignID, studentID)
+		if err := s.gradingQueue.Enqueue(existing.ID, assignID); err != nil {
+			return "", fmt.Errorf("提交已保存，但创建批改任务失败，请稍后重新提交: %w", err)
+		}
+		return existing.ID, nil
+	}
+
+	// 创建新的提交
+	submission := &model.Submission{
+		ID:               uuid.New().String(),
+		AssignmentID:     assignID,
+		StudentID:        studentID,
+		StudentName:      studentName,
+		Answers:          answersToString(answers),
+		CodeContent:      code,
+		QuestionFeedback: "{}",
+		QuestionScores:   "{}",
+		DetailedScore:    "{}",
+		RunResults:       "{}",
+		StaticAnalysis:   "{}",
+		Status:           "submitted",
+		Attempts:         1,
+		IsLate:           late.Late,
+		LateMinutes:      late.Minutes,
+		LatePenalty:      late.Penalty,
+		CreatedAt:        at,
+		UpdatedAt:        at,
+	}
+	if groupID != "" {
+		submission.GroupID = &groupID
+	}
+
+	if err := s.submissionRepo.Create(submission); err != nil {
+		return "", err
+	}
+	if err := s.createVersion(submission); err != nil {
+		return "", err
+	}
+	s.syncGroup(submission)
+	s.discardDraft(assignID, studentID)
+
+	// 加入批改队列，由后台 worker 异步批改
+	if err := s.gradingQueue.Enqueue(submission.ID, assignID); err != nil {
+		return "", fmt.Errorf("提交已保存，但创建批改任务失败，请稍后重新提交: %w", err)
+	}
+
+	return submission.ID, nil
+}
+
+// discardDraft 提交成功后删除草稿，失败只记录日志（草稿早于提交时间，不会被当作作答中）
+func (s *SubmissionStore) discardDraft(assignID, studentID string) {
+	if err := s.draftRepo.Delete(assignID, studentID); err != nil {
+		log.Printf("删除答案草稿失败: %v", err)
+	}
+}
+
+// createVersion 把提交记录当前的答案和代码保存为第 Attempts 个版本
+func (s *SubmissionStore) createVersion(submission *model.Submission) error {
+	return s.versionRepo.Create(&model.SubmissionVersion{
+		ID:               uuid.New().String(),
+		SubmissionID:     submission.ID,
+		AssignmentID:     submission.AssignmentID,
+		StudentID:        submission.StudentID,
+		Attempt:          submission.Attempts,
+		Answers:          defaultJSON(submission.Answers, "{}"),
+		CodeContent:      submission.CodeContent,
+		QuestionScores:   "{}",
+		QuestionFeedback: "{}",
+		DetailedScore:    "{}",
+		RunResults:       "{}",
+		StaticAnalysis:   "{}",
+		AIQuestionScores: "{}",
+		IsLate:           submission.IsLate,
+		LateMinutes:      submission.LateMinutes,
+		LatePenalty:      submission.LatePenalty,
+		Status:           "submitted",
+		CreatedAt:        submission.UpdatedAt, // 提交时间
+	})
+}
+
+// ensureVersions 返回提交的全部版本；引入版本之前的旧提交没有版本记录，补存为第一个版本
+func (s *SubmissionStore) ensureVersions(submission *model.Submission) ([]model.SubmissionVersion, error) {
+	versions, err := s.versionRepo.GetBySubmissionID(submission.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > 0 {
+		return versions, nil
+	}
+
+	if submission.Attempts < 1 {
+		submission.Attempts = 1
+	}
+	version := model.SubmissionVersion{
+		ID:               uuid.New().String(),
+		SubmissionID:     submission.ID,
+		AssignmentID:     submission.AssignmentID,
+		StudentID:        submission.StudentID,
+		Attempt:          submission.Attempts,
+		Answers:          defaultJSON(submission.Answers, "{}"),
+		CodeContent:      submission.CodeContent,
+		TotalScore:       submission.TotalScore,
+		AIFeedback:       submission.AIFeedback,
+		QuestionScores:   defaultJSON(submission.QuestionScores, "{}"),
+		QuestionFeedback: defaultJSON(submission.QuestionFeedback, "{}"),
+		DetailedScore:    defaultJSON(submission.DetailedScore, "{}"),
+		RunResults:       defaultJSON(submission.RunResults, "{}"),
+		StaticAnalysis:   defaultJSON(submission.StaticAnalysis, "{}"),
+		AIQuestionScores: defaultJSON(submission.AIQuestionScores, "{}"),
+		AIRawScore:       submission.AIRawScore,
+		TeacherReviewed:  submission.TeacherReviewed,
+		ReviewedBy:       submission.ReviewedBy,
+		ReviewedAt:       submission.ReviewedAt,
+		RawScore:         submission.RawScore,
+		IsLate:           submission.IsLate,
+		LateMinutes:      submission.LateMinutes,
+		LatePenalty:      submission.LatePenalty,
+		Status:           submission.Status,
+		CreatedAt:        submission.CreatedAt,
+	}
+	if version.RawScore == nil {
+		version.RawScore = submission.TotalScore
+	}
+	if version.Status == "graded" {
+		version.GradedAt = &submission.UpdatedAt
+	}
+	if err := s.versionRepo.Create(&version); err != nil {
+		return nil, err
+	}
+	return []model.SubmissionVersion{version}, nil
+}
+
+// applyScorePolicy 按作业计分方式（最后一次 / 最高分）从已批改的版本中选出计入成绩的版本，
+// 并把它的答案和成绩同步到提交记录
+func (s *SubmissionStore) applyScorePolicy(assign *model.Assignment, submission *model.Submission, versions []model.SubmissionVersion) error {
+	if !countVersion(assign, submission, versions) {
+		return nil
+	}
+	if err := s.submissionRepo.Update(submission); err != nil {
+		return err
+	}
+	s.syncGroup(submission)
+	return nil
+}
+
+// countVersion 按作业计分方式选出计入成绩的版本并写入 submission（不落库），没有已批改的版本时返回 false
+func countVersion(assign *model.Assignment, submission *model.Submission, versions []model.SubmissionVersion) bool {
+	var counted *model.SubmissionVersion
+	for i := range versions {
+		v := &versions[i]
+		if v.Status != "graded" || v.TotalScore == nil {
+			continue
+		}
+		switch {
+		case counted == nil:
+			counted = v
+		case assign.ScorePolicy == "best":
+			if *v.TotalScore >= *counted.TotalScore {
+				counted = v
+			}
+		default:
+			counted = v // 版本按版本号升序，最后一个已批改版本即最新
+		}
+	}
+	if counted == nil {
+		return false
+	}
+
+	submission.Answers = counted.Answers
+	submission.CodeContent = counted.CodeContent
+	submission.TotalScore = counted.TotalScore
+	submission.AIFeedback = counted.AIFeedback
+	submission.QuestionScores = counted.QuestionScores
+	submission.QuestionFeedback = counted.QuestionFeedback
+	submission.DetailedScore = counted.DetailedScore
+	submission.RunResults = counted.RunResults
+	submission.StaticAnalysis = counted.StaticAnalysis
+	submission.AIQuestionScores = counted.AIQuestionScores
+	submission.AIRawScore = counted.AIRawScore
+	submission.TeacherReviewed = counted.TeacherReviewed
+	submission.ReviewedBy = counted.ReviewedBy
+	submission.ReviewedAt = counted.ReviewedAt
+	submission.RawScore = counted.RawScore
+	submission.IsLate = counted.IsLate
+	submission.LateMinutes = counted.LateMinutes
+	submission.LatePenalty = counted.LatePenalty
+	submission.CountedAttempt = counted.Attempt
+	// 最新版本还在等待批改时，提交记录保持待批改状态
+	if versions[len(versions)-1].Status == "graded" {
+		submission.Status = "graded"
+	}
+	submission.UpdatedAt = time.Now()
+	return true
+}
@@ -20,7 +20,12 @@ window.App = {
             if(adminTab) adminTab.style.display = 'block';
         }
     },
-    logout() {
+    async logout() {
+        try {
+            await fetch('/api/logout', { method: 'POST' });
+        } catch (e) {
+            console.error('logout request failed', e);
+        }
         sessionStorage.clear();
         localStorage.clear();
         document.cookie = "user_id=; expires=Thu, 01 Jan 1970 00:00:00 UTC; path=/;";
@@ -29,6 +29,7 @@ async function loadAssignments() {
             let statusClass = 'unsubmitted';
             if (statusText === '已批改' || statusText === '已查看') statusClass = 'graded';
             else if (statusText === '已提交') statusClass = 'submitted';
+            else if (statusText === '作答中') statusClass = 'in-progress';
             
             // Defensively handle null or undefined score
             const score = (sub && sub.total_score !== null && sub.total_score !== undefined) ? sub.total_score : '--';
@@ -102,6 +103,8 @@ async function viewAssignmentDetail(id) {
         const parsedQScores = (sub && sub.question_scores) ? sub.question_scores : {};
         const parsedQFeedback = (sub && sub.question_feedback) ? sub.question_feedback : {};
         const parsedStudentAnswers = (sub && sub.answers) ? sub.answers : {};
+        const parsedBreakdowns = (sub && sub.detailed_score) ? sub.detailed_score : {};
+        const parsedStaticAnalysis = (sub && sub.static_analysis) ? sub.static_analysis : {};
         
         body.innerHTML = ''; // Clear loading text
 
@@ -124,7 +127,8 @@ async function viewAssignmentDetail(id) {
         summaryFlex.style.marginBottom = '15px';
 
         const statusP = document.createElement('p');
-        statusP.innerHTML = `<strong>状态:</strong> ${sub.status === 'graded' ? '<span style="color: #059669; font-weight: 600;">✅ 已批改</span>' : '<span style="color: #2563eb; font-weight: 600;">📤 已提交</span>'}`;
+        const gradedLabel = sub.teacher_reviewed ? '✅ 已批改（教师已复核）' : '✅ 已批改';
+        statusP.innerHTML = `<strong>状态:</strong> ${sub.status === 'graded' ? `<span style="color: #059669; font-weight: 600;">${gradedLabel}</span>` : '<span style="color: #2563eb; font-weight: 600;">📤 已提交</span>'}`;
 
         const scoreP = document.createElement('p');
         scoreP.innerHTML = `<strong>总分:</strong> <span style="font-size: 20px; color: #4f46e5; font-weight: 700;">${sub.total_score || '--'}</span>`;
@@ -271,10 +275,33 @@ async function viewAssignmentDetail(id) {
                         feedbackP.innerHTML = `<strong>教师评语:</strong> ${qFeedback}`;
                         scoreFeedbackContainer.appendChild(feedbackP);
                     }
+                    // 按评分标准的逐项得分
+                    const breakdown = parsedBreakdowns[q.ID];
+                    if (breakdown && Array.isArray(breakdown.criteria) && breakdown.criteria.length > 0) {
+                        const table = document.createElement('table');
+                        table.style.width = '100%';
+                        table.style.marginTop = '10px';
+                        table.style.borderCollapse = 'collapse';
+                        table.style.fontSize = '13px';
+                        table.innerHTML = '<tr><th style="text-align:left;padding:4px;">评分项</th><th style="text-align:left;padding:4px;">得分</th><th style="text-align:left;padding:4px;">说明</th></tr>';
+                        breakdown.criteria.forEach(item => {
+                            const row = document.createElement('tr');
+                            row.innerHTML = `<td style="padding:4px;border-top:1px solid #e5e7eb;">${item.name}</td><td style="padding:4px;border-top:1px solid #e5e7eb;">${item.score}/${item.max_score}</td><td style="padding:4px;border-top:1px solid #e5e7eb;">${item.comment || ''}</td>`;
+                            table.appendChild(row);
+                        });
+                        scoreFeedbackContainer.appendChild(table);
+                    }
                 }
 
                 questionContainer.append(questionTitleP, studentAnswerP);
 
+                // 编程题附带静态检查结果时，按行展示代码并在对应行下方标注问题
+                const analysis = parsedStaticAnalysis[q.ID];
+                if (q.Type === 'code' && analysis) {
+                    studentAnswerP.innerHTML = '<strong>你的回答:</strong>';
+                    questionContainer.appendChild(renderAnnotatedCode(studentAns || sub.code || '', analysis));
+                }
+
                 if (sub.status === 'graded') {
                     questionContainer.appendChild(correctAnswerP);
                 }
@@ -283,6 +310,22 @@ async function viewAssignmentDetail(id) {
                     questionContainer.appendChild(scoreFeedbackContainer);
                 }
 
+                // 已批改的题目可以申请复核
+                if (sub.status === 'graded' && sub.id) {
+                    const appealBtn = document.createElement('button');
+                    appealBtn.textContent = '对本题得分有异议？申请复核';
+                    appealBtn.style.marginTop = '10px';
+                    appealBtn.style.padding = '6px 12px';
+                    appealBtn.style.border = '1px solid #667eea';
+                    appealBtn.style.borderRadius = '6px';
+                    appealBtn.style.background = '#fff';
+                    appealBtn.style.color = '#667eea';
+                    appealBtn.style.cursor = 'pointer';
+                    appealBtn.style.fontSize = '13px';
+                    appealBtn.onclick = () => submitAppeal(sub.id, q.ID, idx + 1, id);
+                    questionContainer.appendChild(appealBtn);
+                }
+
 
 
                 body.appendChild(questionContainer);
@@ -294,24 +337,34 @@ async function viewAssignmentDetail(id) {
             codeTitle.style.color = '#1f2937';
             body.appendChild(codeTitle);
 
-            const codeContainer = document.createElement('div');
-            codeContainer.style.position = 'relative';
-
-            const pre = document.createElement('pre');
-            pre.style.background = '#1e1e1e';
-            pre.style.color = '#d4d4d4';
-            pre.style.padding = '20px';
-            pre.style.borderRadius = '12px';
-            pre.style.overflow = 'auto';
-            pre.style.whiteSpace = 'pre-wrap';
-            pre.style.wordWrap = 'break-word';
-
-            const code = document.createElement('code');   
-            code.textContent = sub.code;
+            if (parsedStaticAnalysis.code) {
+                body.appendChild(renderAnnotatedCode(sub.code, parsedStaticAnalysis.code));
+            } else {
+                const codeContainer = document.createElement('div');
+                codeContainer.style.position = 'relative';
+
+                const pre = document.createElement('pre');
+                pre.style.background = '#1e1e1e';
+                pre.style.color = '#d4d4d4';
+                pre.style.padding = '20px';
+                pre.style.borderRadius = '12px';
+                pre.style.overflow = 'auto';
+                pre.style.whiteSpace = 'pre-wrap';
+                pre.style.wordWrap = 'break-word';
+
+                const code = document.createElement('code');
+                code.textContent = sub.code;
+
+                pre.appendChild(code);
+                codeContainer.appendChild(pre);
+                body.appendChild(codeContainer);
+            }
+        }
 
-            pre.appendChild(code);
-            codeContainer.appendChild(pre);
-            body.appendChild(codeContainer);
+        if (sub.id && data.questions && data.questions.length > 0) {
+            const appealSection = document.createElement('div');
+            body.appendChild(appealSection);
+            loadAppealHistory(sub.id, data.questions, appealSection);
         }
 
         body.querySelectorAll('pre code').forEach(el => hljs.highlightElement(el));
@@ -321,6 +374,206 @@ async function viewAssignmentDetail(id) {
     }
 }
 
+const APPEAL_STATUS_LABELS = {
+    open: { text: '等待教师处理', color: '#2563eb' },
+    reevaluating: { text: 'AI 重新批改中', color: '#b45309' },
+    accepted: { text: '已接受', color: '#059669' },
+    rejected: { text: '已驳回', color: '#dc2626' },
+    reevaluated: { text: 'AI 已重新批改', color: '#059669' }
+};
+
+// submitAppeal 学生对某道题的得分提出复核申请
+async function submitAppeal(submissionId, questionId, number, assignmentId) {
+    const reason = prompt(`请说明对第 ${number} 题得分的异议：`, '');
+    if (reason === null) return;
+    if (!reason.trim()) {
+        alert('请填写申诉理由');
+        return;
+    }
+    try {
+        const res = await fetch(`/api/submissions/${submissionId}/appeals`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ question_id: questionId, reason })
+        });
+        const data = await res.json();
+        if (!res.ok) {
+            alert(data.error || '提交申诉失败');
+            return;
+        }
+        alert('申诉已提交，请等待教师处理');
+        viewAssignmentDetail(assignmentId);
+    } catch (e) {
+        console.error('Error submitting appeal:', e);
+        alert('提交申诉失败，请稍后重试');
+    }
+}
+
+// loadAppealHistory 展示提交的申诉记录和处理结果
+async function loadAppealHistory(submissionId, questions, container) {
+    try {
+        const res = await fetch(`/api/submissions/${submissionId}/appeals`);
+        if (!res.ok) return;
+        const appeals = await res.json();
+        if (appeals.length === 0) return;
+
+        const numbers = {};
+        questions.forEach((q, i) => { numbers[q.ID] = i + 1; });
+
+        const title = document.createElement('h3');
+        title.textContent = '成绩申诉记录';
+        title.style.margin = '25px 0 15px';
+        title.style.color = '#1f2937';
+        container.appendChild(title);
+
+        appeals.forEach(a => {
+            const status = APPEAL_STATUS_LABELS[a.status] || { text: a.status, color: '#666' };
+            const item = document.createElement('div');
+            item.style.padding = '14px 16px';
+            item.style.marginBottom = '10px';
+            item.style.border = '1px solid #eee';
+            item.style.borderRadius = '10px';
+            item.style.fontSize = '14px';
+
+            const head = document.createElement('p');
+            head.innerHTML = `<strong>第 ${numbers[a.question_id] || '?'} 题</strong> · <span style="color: ${status.color}; font-weight: 600;">${status.text}</span> · <span style="color: #999;">${new Date(a.created_at).toLocaleString()}</span>`;
+            const reason = document.createElement('p');
+            reason.style.marginTop = '6px';
+            reason.textContent = `申诉理由：${a.reason}`;
+            item.append(head, reason);
+
+            if (a.resolved_at) {
+                const result = document.createElement('p');
+                result.style.marginTop = '6px';
+                const from = a.original_score == null ? '-' : a.original_score;
+                const to = a.resolved_score == null ? '-' : a.resolved_score;
+                result.textContent = `得分：${from} → ${to}`;
+                item.appendChild(result);
+            }
+            if (a.teacher_comment) {
+                const comment = document.createElement('p');
+                comment.style.marginTop = '6px';
+                comment.textContent = `教师说明：${a.teacher_comment}`;
+                item.appendChild(comment);
+            }
+            container.appendChild(item);
+        });
+    } catch (e) {
+        console.error('Error loading appeals:', e);
+    }
+}
+
+const ANALYSIS_SEVERITY_STYLES = {
+    error: { color: '#b91c1c', background: '#fef2f2', label: '错误' },
+    warning: { color: '#b45309', background: '#fffbeb', label: '警告' },
+    info: { color: '#1d4ed8', background: '#eff6ff', label: '建议' }
+};
+
+// renderAnnotatedCode 按行展示代码，把静态检查发现的问题作为批注插在对应行下方
+function renderAnnotatedCode(codeText, report) {
+    const container = document.createElement('div');
+    container.className = 'annotated-code';
+    container.style.margin = '8px 0 12px';
+
+    const findings = Array.isArray(report.findings) ? report.findings : [];
+    const summary = document.createElement('p');
+    summary.style.fontSize = '13px';
+    summary.style.color = '#6b7280';
+    summary.style.marginBottom = '6px';
+    if (findings.length === 0) {
+        summary.textContent = '静态检查：未发现问题';
+    } else {
+        const counts = {};
+        findings.forEach(f => { counts[f.severity] = (counts[f.severity] || 0) + 1; });
+        summary.textContent = '静态检查：' + Object.keys(ANALYSIS_SEVERITY_STYLES)
+            .filter(sev => counts[sev])
+            .map(sev => `${ANALYSIS_SEVERITY_STYLES[sev].label} ${counts[sev]} 处`)
+            .join('，');
+    }
+    if (Array.isArray(report.complexity) && report.complexity.length > 0) {
+        summary.textContent += '；圈复杂度 ' + report.complexity.map(c => `${c.name}: ${c.complexity}`).join('，');
+    }
+    container.appendChild(summary);
+
+    const byLine = {};
+    findings.forEach(f => { (byLine[f.line] = byLine[f.line] || []).push(f); });
+
+    const codeBox = document.createElement('div');
+    codeBox.style.background = '#1e1e1e';
+    codeBox.style.color = '#d4d4d4';
+    codeBox.style.padding = '12px 0';
+    codeBox.style.borderRadius = '8px';
+    codeBox.style.overflowX = 'auto';
+    codeBox.style.fontFamily = 'Consolas, Monaco, monospace';
+    codeBox.style.fontSize = '13px';
+    codeBox.style.lineHeight = '1.5';
+
+    const lines = (codeText || '').replace(/\n$/, '').split('\n');
+    lines.forEach((text, i) => {
+        const lineNo = i + 1;
+        const row = document.createElement('div');
+        row.style.display = 'flex';
+        row.style.whiteSpace = 'pre';
+        if (byLine[lineNo]) {
+            row.style.background = 'rgba(250, 204, 21, 0.12)';
+        }
+
+        const num = document.createElement('span');
+        num.textContent = lineNo;
+        num.style.display = 'inline-block';
+        num.style.minWidth = '40px';
+        num.style.padding = '0 10px';
+        num.style.textAlign = 'right';
+        num.style.color = '#6b7280';
+        num.style.userSelect = 'none';
+
+        const content = document.createElement('span');
+        content.textContent = text;
+
+        row.append(num, content);
+        codeBox.appendChild(row);
+
+        (byLine[lineNo] || []).forEach(f => codeBox.appendChild(renderFinding(f)));
+    });
+    // 行号超出代码范围的问题（如文件末尾缺少括号）放在最后
+    findings.filter(f => f.line > lines.length).forEach(f => codeBox.appendChild(renderFinding(f)));
+    container.appendChild(codeBox);
+
+    if (report.format_diff) {
+        const details = document.createElement('details');
+        details.style.marginTop = '6px';
+        details.style.fontSize = '13px';
+        const toggle = document.createElement('summary');
+        toggle.textContent = '查看 gofmt 格式化建议';
+        toggle.style.cursor = 'pointer';
+        toggle.style.color = '#667eea';
+        const diff = document.createElement('pre');
+        diff.style.background = '#f8f9fa';
+        diff.style.padding = '10px';
+        diff.style.borderRadius = '8px';
+        diff.style.overflowX = 'auto';
+        diff.textContent = report.format_diff;
+        details.append(toggle, diff);
+        container.appendChild(details);
+    }
+    return container;
+}
+
+function renderFinding(f) {
+    const style = ANALYSIS_SEVERITY_STYLES[f.severity] || ANALYSIS_SEVERITY_STYLES.info;
+    const note = document.createElement('div');
+    note.style.margin = '2px 10px 4px 60px';
+    note.style.padding = '4px 10px';
+    note.style.borderLeft = `3px solid ${style.color}`;
+    note.style.background = style.background;
+    note.style.color = style.color;
+    note.style.fontFamily = 'inherit';
+    note.style.whiteSpace = 'normal';
+    note.style.borderRadius = '4px';
+    note.textContent = `[${style.label}] ${f.message}`;
+    return note;
+}
+
 function closeModal() {
     document.getElementById('assignmentModal').style.display = 'none';
 }
@@ -356,4 +609,26 @@ document.addEventListener('DOMContentLoaded', function () {
     const urlParams = new URLSearchParams(window.location.search);
     const defaultTab = urlParams.get('tab') || 'course-details';
     window.App.switchTab(defaultTab);
+
+    // 扫描班级二维码进入时带有邀请码
+    const joinCode = urlParams.get('join');
+    if (joinCode) {
+        joinClassByCode(joinCode);
+    }
 });
+
+async function joinClassByCode(code) {
+    if (!confirm(`确定使用邀请码 ${code} 加入班级吗？`)) return;
+    try {
+        const response = await fetch('/api/classes/join', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ code })
+        });
+        const result = await response.json();
+        alert(response.ok ? result.message : (result.error || '加入失败'));
+    } catch (error) {
+        alert('网络错误: ' + error.message);
+    }
+    history.replaceState(null, '', window.location.pathname);
+}
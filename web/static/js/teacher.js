@@ -72,6 +72,7 @@ async function loadClasses() {
     console.log('loadClasses called');
     const classList = document.getElementById('classList');
     classList.innerHTML = '<div class="empty-state"><p>正在加载班级...</p></div>';
+    loadStaffInvitations();
 
     try {
         const response = await fetch('/api/classes');
@@ -79,6 +80,12 @@ async function loadClasses() {
             throw new Error(`网络错误: ${response.status}`);
         }
         const classes = await response.json();
+        const [courses, terms] = await Promise.all([
+            fetch('/api/courses').then(res => res.ok ? res.json() : []),
+            fetch('/api/terms').then(res => res.ok ? res.json() : [])
+        ]);
+        const courseNames = Object.fromEntries((courses || []).map(c => [c.ID, c.Name]));
+        const termNames = Object.fromEntries((terms || []).map(t => [t.ID, t.Name]));
         
         classList.innerHTML = ''; // 清空加载提示
 
@@ -97,6 +104,19 @@ async function loadClasses() {
 
             const title = document.createElement('h3');
             title.textContent = c.Name;
+            if (c.ArchivedAt) {
+                card.classList.add('archived');
+                const badge = document.createElement('span');
+                badge.className = 'archived-badge';
+                badge.textContent = '已归档';
+                title.appendChild(badge);
+            }
+
+            // 班级所属的课程和学期
+            const placement = [courseNames[c.CourseID], termNames[c.TermID]].filter(Boolean);
+            const course = document.createElement('div');
+            course.className = 'course';
+            course.textContent = placement.join(' · ');
 
             const code = document.createElement('div');
             code.className = 'code';
@@ -123,7 +143,7 @@ async function loadClasses() {
             };
 
             stats.append(studentStat, assignmentStat, unsubmittedStat);
-            card.append(title, code, stats, analysisBtn);
+            card.append(title, course, code, stats, analysisBtn);
             classList.appendChild(card);
 
             // Fetch stats for each class
@@ -139,6 +159,36 @@ async function loadClasses() {
     }
 }
 
+// 显示其他教师邀请自己担任合作教师或助教的待处理邀请
+async function loadStaffInvitations() {
+    const container = document.getElementById('staffInvitations');
+    try {
+        const response = await fetch('/api/staff-invitations');
+        if (!response.ok) return;
+        const invitations = await response.json();
+        const roleLabels = { co_teacher: '合作教师', ta: '助教' };
+        container.innerHTML = invitations.map(inv => `
+            <div class="invitation-card">
+                <span>${inv.invited_by || '有教师'} 邀请你担任「${inv.class_name || '班级'}」的${roleLabels[inv.role] || inv.role}</span>
+                <button class="btn" onclick="respondStaffInvitation('${inv.id}', true)">接受</button>
+                <button class="btn btn-secondary" onclick="respondStaffInvitation('${inv.id}', false)">拒绝</button>
+            </div>
+        `).join('');
+    } catch (error) {
+        console.error('Failed to load staff invitations:', error);
+    }
+}
+
+async function respondStaffInvitation(invitationId, accept) {
+    const action = accept ? 'accept' : 'decline';
+    const response = await fetch(`/api/staff-invitations/${invitationId}/${action}`, { method: 'POST' });
+    const result = await response.json();
+    if (!response.ok) {
+        alert(result.error || '操作失败');
+    }
+    loadClasses();
+}
+
 async function loadAssignments() {
     console.log('loadAssignments called');
     const assignmentList = document.getElementById('assignmentList');
@@ -367,7 +417,7 @@ async function publishAssignment(assignmentId) {
         const publishedResponse = await fetch(`/api/assignments/${assignmentId}/published`);
         if (!publishedResponse.ok) throw new Error(`无法加载已发布状态: ${publishedResponse.status}`);
         const publishedClasses = await publishedResponse.json();
-        const publishedClassIds = new Set(publishedClasses.map(pc => pc.ClassID));
+        const publishedByClass = new Map(publishedClasses.map(pc => [pc.ClassID, pc]));
 
         classListEl.innerHTML = '';
 
@@ -377,7 +427,8 @@ async function publishAssignment(assignmentId) {
         }
 
         classes.forEach(cls => {
-            const isPublished = publishedClassIds.has(cls.ID);
+            const published = publishedByClass.get(cls.ID);
+            const isPublished = !!published;
             const item = document.createElement('div');
             item.className = `class-publish-item ${isPublished ? 'published' : ''}`;
             item.innerHTML = `
@@ -387,7 +438,11 @@ async function publishAssignment(assignmentId) {
                     <span class="student-count">${cls.StudentCount || 0} 名学生</span>
                 </div>
                 <input type="date" class="deadline-input" id="deadline-${cls.ID}" ${isPublished ? 'disabled' : ''}>
-                ${isPublished ? '<span class="status-tag published">已发放</span>' : '<span class="status-tag not-published">未发放</span>'}
+                <label class="schedule-input">发放 <input type="datetime-local" id="release-${cls.ID}" ${isPublished ? 'disabled' : ''}></label>
+                <label class="schedule-input">关闭 <input type="datetime-local" id="closes-${cls.ID}" ${isPublished ? 'disabled' : ''}></label>
+                <label class="schedule-input"><input type="checkbox" id="group-work-${cls.ID}" ${isPublished ? 'disabled' : ''} ${published && published.GroupWork ? 'checked' : ''}> 小组作业</label>
+                ${isPublished ? publishStatusTag(published) : '<span class="status-tag not-published">未发放</span>'}
+                ${isPublished ? `<button type="button" class="btn btn-danger" onclick="unpublishAssignment('${cls.ID}')">撤回</button>` : ''}
             `;
             classListEl.appendChild(item);
         });
@@ -398,6 +453,37 @@ async function publishAssignment(assignmentId) {
     }
 }
 
+// publishStatusTag 显示作业在班级中的发放状态，定时发放和自动关闭时附带时间
+function publishStatusTag(published) {
+    const format = t => new Date(t).toLocaleString('zh-CN', { hour12: false });
+    if (published.Status === 'scheduled') {
+        return `<span class="status-tag not-published">定时发放：${format(published.ReleasedAt)}</span>`;
+    }
+    if (published.Status === 'closed') {
+        return '<span class="status-tag not-published">已关闭</span>';
+    }
+    const closes = published.ClosesAt ? `（${format(published.ClosesAt)} 关闭）` : '';
+    return `<span class="status-tag published">已发放${closes}</span>`;
+}
+
+// unpublishAssignment 从单个班级撤回当前作业，已有的提交记录保留
+async function unpublishAssignment(classId) {
+    if (!confirm('确定从该班级撤回作业吗？撤回后该班级的学生将看不到这份作业。')) {
+        return;
+    }
+    try {
+        const response = await fetch(`/api/assignments/${currentPublishingAssignmentId}/classes/${classId}`, { method: 'DELETE' });
+        const result = await response.json();
+        if (!response.ok) {
+            throw new Error(result.error || '未知错误');
+        }
+        publishAssignment(currentPublishingAssignmentId);
+        loadAssignments();
+    } catch (error) {
+        alert(`撤回失败: ${error.message}`);
+    }
+}
+
 async function confirmMultiPublish() {
     if (!currentPublishingAssignmentId) {
         console.error("发布失败：未设置当前作业ID。");
@@ -417,7 +503,17 @@ async function confirmMultiPublish() {
         const classId = cb.dataset.classId;
         const deadlineInput = document.getElementById(`deadline-${classId}`);
         if (deadlineInput && deadlineInput.value) {
-            selectedClasses.push({ classId, deadline: deadlineInput.value });
+            const releaseAt = document.getElementById(`release-${classId}`).value;
+            const closesAt = document.getElementById(`closes-${classId}`).value;
+            selectedClasses.push({
+                classId,
+                deadline: deadlineInput.value,
+                // 未填写时由服务端立即发放、不自动关闭
+                release_at: releaseAt ? new Date(releaseAt).toISOString() : null,
+                closes_at: closesAt ? new Date(closesAt).toISOString() : null,
+                // 小组作业由组员共用一份提交，班级需要先分好组
+                group_work: document.getElementById(`group-work-${classId}`).checked,
+            });
         } else {
             const label = document.querySelector(`label[for='${cb.id}']`);
             const className = label ? label.textContent : `ID ${classId}`;
@@ -438,12 +534,12 @@ async function confirmMultiPublish() {
     let successCount = 0;
     let errorCount = 0;
 
-    for (const { classId, deadline } of selectedClasses) {
+    for (const { classId, deadline, release_at, closes_at, group_work } of selectedClasses) {
         try {
             const response = await fetch(`/api/assignments/${currentPublishingAssignmentId}/publish`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ class_id: classId, deadline: deadline }),
+                body: JSON.stringify({ class_id: classId, deadline: deadline, release_at: release_at, closes_at: closes_at, group_work: group_work }),
             });
             const result = await response.json();
             if (!response.ok) {